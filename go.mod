@@ -6,6 +6,7 @@ require (
 	fyne.io/fyne/v2 v2.6.1
 	github.com/ErikKalkoken/fyne-kx v0.5.1
 	github.com/dweymouth/fyne-tooltip v0.3.1
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/hashicorp/go-version v1.7.0
 	github.com/jarcoal/httpmock v1.4.0
 	github.com/json-iterator/go v1.1.12
@@ -18,7 +19,6 @@ require (
 	github.com/BurntSushi/toml v1.5.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fredbi/uri v1.1.0 // indirect
-	github.com/fsnotify/fsnotify v1.9.0 // indirect
 	github.com/fyne-io/gl-js v0.1.0 // indirect
 	github.com/fyne-io/glfw-js v0.2.0 // indirect
 	github.com/fyne-io/image v0.1.1 // indirect
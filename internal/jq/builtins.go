@@ -0,0 +1,1378 @@
+package jq
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtinFunc is the implementation of a builtin function.
+// Arguments are passed as unevaluated expressions, so functions like map(f) can run them for each element.
+type builtinFunc func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error
+
+// builtins maps keys like "map/1" to builtin functions.
+var builtins map[string]builtinFunc
+
+func key(name string, arity int) string {
+	return fmt.Sprintf("%s/%d", name, arity)
+}
+
+func hasBuiltin(name string, arity int) bool {
+	_, ok := builtins[key(name, arity)]
+	return ok
+}
+
+func (ev *evaluator) call(n callNode, input any, e *env, emit emitFunc) error {
+	f, ok := builtins[key(n.name, len(n.args))]
+	if !ok {
+		return errorf("%s/%d is not defined", n.name, len(n.args))
+	}
+	return f(ev, input, n.args, e, emit)
+}
+
+// simple returns a builtin function without arguments, which returns exactly one output.
+func simple(f func(v any) (any, error)) builtinFunc {
+	return func(_ *evaluator, input any, _ []node, _ *env, emit emitFunc) error {
+		v, err := f(input)
+		if err != nil {
+			return err
+		}
+		return emit(v)
+	}
+}
+
+// withValues returns a builtin function which is called with the values of it's arguments.
+// It is called once for every combination of argument outputs.
+func withValues(f func(input any, args []any) (any, error)) builtinFunc {
+	return func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+		return ev.cartesian(args, input, e, nil, func(values []any) error {
+			v, err := f(input, values)
+			if err != nil {
+				return err
+			}
+			return emit(v)
+		})
+	}
+}
+
+// cartesian calls f for every combination of outputs of the given expressions.
+func (ev *evaluator) cartesian(args []node, input any, e *env, values []any, f func(values []any) error) error {
+	if len(args) == 0 {
+		return f(values)
+	}
+	return ev.eval(args[0], input, e, func(v any) error {
+		return ev.cartesian(args[1:], input, e, append(slices.Clip(values), v), f)
+	})
+}
+
+// selectType returns a builtin which emits it's input only if it has one of the given types.
+func selectType(types ...string) builtinFunc {
+	return func(_ *evaluator, input any, _ []node, _ *env, emit emitFunc) error {
+		if slices.Contains(types, typeName(input)) {
+			return emit(input)
+		}
+		return nil
+	}
+}
+
+// mathFunc returns a builtin which applies a math function to a number.
+func mathFunc(f func(float64) float64) builtinFunc {
+	return simple(func(v any) (any, error) {
+		x, ok := v.(float64)
+		if !ok {
+			return nil, errorf("%s number required", describe(v))
+		}
+		return f(x), nil
+	})
+}
+
+// stringFunc returns a builtin with one string argument for a string input.
+func stringFunc(name string, f func(s, arg string) any) builtinFunc {
+	return withValues(func(input any, args []any) (any, error) {
+		s, ok1 := input.(string)
+		arg, ok2 := args[0].(string)
+		if !ok1 || !ok2 {
+			return nil, errorf("%s input and argument must be strings", name)
+		}
+		return f(s, arg), nil
+	})
+}
+
+// byFunc returns a builtin like sort_by(f), which works on an array with keys computed by f.
+func byFunc(f func(a []any, keys []any) (any, error)) builtinFunc {
+	return func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+		a, ok := input.([]any)
+		if !ok {
+			return errorf("cannot index %s with number", typeName(input))
+		}
+		keys := make([]any, len(a))
+		for i, x := range a {
+			k, err := ev.collect(args[0], x, e)
+			if err != nil {
+				return err
+			}
+			if k == nil {
+				k = make([]any, 0)
+			}
+			keys[i] = k
+		}
+		v, err := f(a, keys)
+		if err != nil {
+			return err
+		}
+		return emit(v)
+	}
+}
+
+// sortedIndexes returns the indexes of keys in stable sort order.
+func sortedIndexes(keys []any) []int {
+	idx := make([]int, len(keys))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		return compare(keys[a], keys[b])
+	})
+	return idx
+}
+
+func sortBy(a []any, keys []any) (any, error) {
+	r := make([]any, 0, len(a))
+	for _, i := range sortedIndexes(keys) {
+		r = append(r, a[i])
+	}
+	return r, nil
+}
+
+func groupBy(a []any, keys []any) (any, error) {
+	r := make([]any, 0)
+	var last any
+	for n, i := range sortedIndexes(keys) {
+		if n == 0 || compare(keys[i], last) != 0 {
+			r = append(r, make([]any, 0))
+		}
+		r[len(r)-1] = append(r[len(r)-1].([]any), a[i])
+		last = keys[i]
+	}
+	return r, nil
+}
+
+func uniqueBy(a []any, keys []any) (any, error) {
+	r := make([]any, 0)
+	var last any
+	for n, i := range sortedIndexes(keys) {
+		if n == 0 || compare(keys[i], last) != 0 {
+			r = append(r, a[i])
+		}
+		last = keys[i]
+	}
+	return r, nil
+}
+
+func extremeBy(maximum bool) func(a []any, keys []any) (any, error) {
+	return func(a []any, keys []any) (any, error) {
+		if len(a) == 0 {
+			return nil, nil
+		}
+		best := 0
+		for i := 1; i < len(a); i++ {
+			c := compare(keys[i], keys[best])
+			if maximum && c >= 0 || !maximum && c < 0 {
+				best = i
+			}
+		}
+		return a[best], nil
+	}
+}
+
+func length(v any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0.0, nil
+	case float64:
+		return math.Abs(v), nil
+	case string:
+		return float64(utf8.RuneCountInString(v)), nil
+	case []any:
+		return float64(len(v)), nil
+	case map[string]any:
+		return float64(len(v)), nil
+	}
+	return nil, errorf("%s has no length", describe(v))
+}
+
+func keys(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		r := make([]any, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			r = append(r, k)
+		}
+		return r, nil
+	case []any:
+		r := make([]any, len(v))
+		for i := range v {
+			r[i] = float64(i)
+		}
+		return r, nil
+	}
+	return nil, errorf("%s has no keys", describe(v))
+}
+
+func has(v any, k any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		if k, ok := k.(string); ok {
+			_, found := v[k]
+			return found, nil
+		}
+	case []any:
+		if k, ok := k.(float64); ok {
+			return k >= 0 && int(k) < len(v), nil
+		}
+	}
+	return nil, errorf("cannot check whether %s has a %s key", typeName(v), typeName(k))
+}
+
+func toEntries(v any) (any, error) {
+	if a, ok := v.([]any); ok {
+		r := make([]any, len(a))
+		for i, x := range a {
+			r[i] = map[string]any{"key": float64(i), "value": x}
+		}
+		return r, nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errorf("%s has no keys", describe(v))
+	}
+	r := make([]any, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		r = append(r, map[string]any{"key": k, "value": m[k]})
+	}
+	return r, nil
+}
+
+func fromEntries(v any) (any, error) {
+	a, ok := v.([]any)
+	if !ok {
+		return nil, errorf("cannot iterate over %s", describe(v))
+	}
+	r := make(map[string]any, len(a))
+	for _, x := range a {
+		entry, ok := x.(map[string]any)
+		if !ok {
+			return nil, errorf("cannot index %s with \"key\"", typeName(x))
+		}
+		var k any
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if isTruthy(entry[name]) {
+				k = entry[name]
+				break
+			}
+		}
+		var entryKey string
+		switch x := k.(type) {
+		case string:
+			entryKey = x
+		case float64, bool:
+			entryKey = toJSON(x)
+		case nil:
+			entryKey = "null"
+		default:
+			return nil, errorf("cannot use %s as object key", describe(k))
+		}
+		var value any
+		for _, name := range []string{"value", "v", "Value", "V"} {
+			if x, ok := entry[name]; ok {
+				value = x
+				break
+			}
+		}
+		r[entryKey] = value
+	}
+	return r, nil
+}
+
+func flatten(v any, depth float64) (any, error) {
+	a, ok := v.([]any)
+	if !ok {
+		return nil, errorf("cannot flatten %s", describe(v))
+	}
+	if depth < 0 {
+		return nil, errorf("flatten depth must not be negative")
+	}
+	r := make([]any, 0, len(a))
+	for _, x := range a {
+		if y, ok := x.([]any); ok && depth > 0 {
+			z, _ := flatten(y, depth-1)
+			r = append(r, z.([]any)...)
+		} else {
+			r = append(r, x)
+		}
+	}
+	return r, nil
+}
+
+func addValues(v any) (any, error) {
+	var values []any
+	err := iterate(v, func(_ any, x any) error {
+		values = append(values, x)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var r any
+	for _, x := range values {
+		r, err = add(r, x)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func toString(v any) any {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return toJSON(v)
+}
+
+func toNumber(v any) (any, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, errorf("cannot parse %q as number", v)
+		}
+		return f, nil
+	}
+	return nil, errorf("%s cannot be parsed as a number", describe(v))
+}
+
+func join(v any, sep any) (any, error) {
+	a, ok := v.([]any)
+	if !ok {
+		return nil, errorf("cannot iterate over %s", describe(v))
+	}
+	s, ok := sep.(string)
+	if !ok {
+		return nil, errorf("separator must be a string")
+	}
+	parts := make([]string, len(a))
+	for i, x := range a {
+		switch x := x.(type) {
+		case nil:
+		case string:
+			parts[i] = x
+		case float64, bool:
+			parts[i] = toJSON(x)
+		default:
+			return nil, errorf("cannot join with %s", typeName(x))
+		}
+	}
+	return strings.Join(parts, s), nil
+}
+
+// compileRegex compiles a regular expression with jq flags.
+func compileRegex(re any, flags any) (*regexp.Regexp, bool, error) {
+	s, ok := re.(string)
+	if !ok {
+		return nil, false, errorf("%s cannot be matched, as it is not a string", describe(re))
+	}
+	var global bool
+	var prefix string
+	if flags != nil {
+		f, ok := flags.(string)
+		if !ok {
+			return nil, false, errorf("%s is not a string", describe(flags))
+		}
+		for _, c := range f {
+			switch c {
+			case 'g':
+				global = true
+			case 'i':
+				prefix += "i"
+			case 's':
+				prefix += "s"
+			case 'x', 'n', 'p', 'l':
+				// not supported by Go and ignored
+			default:
+				return nil, false, errorf("%s is not a valid modifier string", f)
+			}
+		}
+	}
+	if prefix != "" {
+		s = "(?" + prefix + ")" + s
+	}
+	r, err := regexp.Compile(s)
+	if err != nil {
+		return nil, false, errorf("%s (at offset 0) is not a valid regex: %s", s, err)
+	}
+	return r, global, nil
+}
+
+// captures returns an object with the named capture groups of a match.
+func captures(r *regexp.Regexp, s string, m []int) map[string]any {
+	o := make(map[string]any)
+	for i, name := range r.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		if m[2*i] < 0 {
+			o[name] = nil
+		} else {
+			o[name] = s[m[2*i]:m[2*i+1]]
+		}
+	}
+	return o
+}
+
+func regexTest(input any, args []any) (any, error) {
+	s, ok := input.(string)
+	if !ok {
+		return nil, errorf("%s cannot be matched, as it is not a string", describe(input))
+	}
+	var flags any
+	if len(args) > 1 {
+		flags = args[1]
+	}
+	r, _, err := compileRegex(args[0], flags)
+	if err != nil {
+		return nil, err
+	}
+	return r.MatchString(s), nil
+}
+
+// regexFunc returns a builtin which calls f with all matches of a regex in a string input.
+// The regex and optional flags are the arguments. Only the first match is found,
+// unless the flags contain g or global is set.
+func regexFunc(global bool, f func(r *regexp.Regexp, s string, matches [][]int, emit emitFunc) error) builtinFunc {
+	return func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+		return ev.cartesian(args, input, e, nil, func(values []any) error {
+			s, ok := input.(string)
+			if !ok {
+				return errorf("%s cannot be matched, as it is not a string", describe(input))
+			}
+			var flags any
+			if len(values) > 1 {
+				flags = values[1]
+			}
+			r, g, err := compileRegex(values[0], flags)
+			if err != nil {
+				return err
+			}
+			n := 1
+			if global || g {
+				n = -1
+			}
+			return f(r, s, r.FindAllStringSubmatchIndex(s, n), emit)
+		})
+	}
+}
+
+var capture = regexFunc(false, func(r *regexp.Regexp, s string, matches [][]int, emit emitFunc) error {
+	for _, m := range matches {
+		if err := emit(captures(r, s, m)); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+// match emits an object for each match with offset, length, string and captures.
+// Offsets and lengths are counted in codepoints like in jq.
+var match = regexFunc(false, func(r *regexp.Regexp, s string, matches [][]int, emit emitFunc) error {
+	names := r.SubexpNames()
+	for _, m := range matches {
+		a := make([]any, 0, len(names)-1)
+		for i := 1; i < len(names); i++ {
+			var name any
+			if names[i] != "" {
+				name = names[i]
+			}
+			c := map[string]any{"offset": -1.0, "length": 0.0, "string": nil, "name": name}
+			if m[2*i] >= 0 {
+				maps.Copy(c, matchPosition(s, m[2*i], m[2*i+1]))
+			}
+			a = append(a, c)
+		}
+		o := matchPosition(s, m[0], m[1])
+		o["captures"] = a
+		if err := emit(o); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+// matchPosition returns offset, length and string of the match between the byte offsets i and j.
+func matchPosition(s string, i, j int) map[string]any {
+	return map[string]any{
+		"offset": float64(utf8.RuneCountInString(s[:i])),
+		"length": float64(utf8.RuneCountInString(s[i:j])),
+		"string": s[i:j],
+	}
+}
+
+// scan emits the matched strings or, when the regex has capture groups, arrays of the captured strings.
+var scan = regexFunc(true, func(r *regexp.Regexp, s string, matches [][]int, emit emitFunc) error {
+	for _, m := range matches {
+		var v any
+		if r.NumSubexp() == 0 {
+			v = s[m[0]:m[1]]
+		} else {
+			a := make([]any, r.NumSubexp())
+			for i := range a {
+				if k := 2 * (i + 1); m[k] >= 0 {
+					a[i] = s[m[k]:m[k+1]]
+				}
+			}
+			v = a
+		}
+		if err := emit(v); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+// splitRegex returns the parts of s between the matches.
+func splitRegex(s string, matches [][]int) []any {
+	parts := make([]any, 0, len(matches)+1)
+	last := 0
+	for _, m := range matches {
+		parts = append(parts, s[last:m[0]])
+		last = m[1]
+	}
+	return append(parts, s[last:])
+}
+
+var splits = regexFunc(true, func(_ *regexp.Regexp, s string, matches [][]int, emit emitFunc) error {
+	for _, v := range splitRegex(s, matches) {
+		if err := emit(v); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+var splitWithRegex = regexFunc(true, func(_ *regexp.Regexp, s string, matches [][]int, emit emitFunc) error {
+	return emit(splitRegex(s, matches))
+})
+
+// substitute implements sub and gsub. The replacement is evaluated with the captures as input.
+func substitute(global bool) builtinFunc {
+	return func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+		s, ok := input.(string)
+		if !ok {
+			return errorf("%s cannot be matched, as it is not a string", describe(input))
+		}
+		var flags any
+		if len(args) > 2 {
+			v, _, err := ev.first(args[2], input, e)
+			if err != nil {
+				return err
+			}
+			flags = v
+		}
+		return ev.eval(args[0], input, e, func(re any) error {
+			r, g, err := compileRegex(re, flags)
+			if err != nil {
+				return err
+			}
+			n := 1
+			if global || g {
+				n = -1
+			}
+			var sb strings.Builder
+			last := 0
+			for _, m := range r.FindAllStringSubmatchIndex(s, n) {
+				v, ok, err := ev.first(args[1], captures(r, s, m), e)
+				if err != nil {
+					return err
+				}
+				replacement, isString := v.(string)
+				if !ok || !isString {
+					return errorf("%s cannot be added to a string", describe(v))
+				}
+				sb.WriteString(s[last:m[0]])
+				sb.WriteString(replacement)
+				last = m[1]
+			}
+			sb.WriteString(s[last:])
+			return emit(sb.String())
+		})
+	}
+}
+
+func walk(ev *evaluator, f node, v any, e *env) (any, error) {
+	switch x := v.(type) {
+	case []any:
+		a := make([]any, len(x))
+		for i, y := range x {
+			z, err := walk(ev, f, y, e)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = z
+		}
+		v = a
+	case map[string]any:
+		o := make(map[string]any, len(x))
+		for k, y := range x {
+			z, err := walk(ev, f, y, e)
+			if err != nil {
+				return nil, err
+			}
+			o[k] = z
+		}
+		v = o
+	}
+	r, _, err := ev.lastOutput(f, v, e)
+	return r, err
+}
+
+func pathValue(p []any) []any {
+	if p == nil {
+		return make([]any, 0)
+	}
+	return p
+}
+
+func init() {
+	builtins = map[string]builtinFunc{
+		"empty/0": func(*evaluator, any, []node, *env, emitFunc) error {
+			return nil
+		},
+		"error/0": func(_ *evaluator, input any, _ []node, _ *env, _ emitFunc) error {
+			return &Error{Value: input}
+		},
+		"error/1": func(ev *evaluator, input any, args []node, e *env, _ emitFunc) error {
+			return ev.eval(args[0], input, e, func(v any) error {
+				return &Error{Value: v}
+			})
+		},
+		"not/0": simple(func(v any) (any, error) {
+			return !isTruthy(v), nil
+		}),
+		"debug/0": func(_ *evaluator, input any, _ []node, _ *env, emit emitFunc) error {
+			return emit(input)
+		},
+		"length/0": simple(length),
+		"utf8bytelength/0": simple(func(v any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errorf("%s only strings have UTF-8 byte length", describe(v))
+			}
+			return float64(len(s)), nil
+		}),
+		"keys/0":          simple(keys),
+		"keys_unsorted/0": simple(keys),
+		"has/1":           withValues(func(input any, args []any) (any, error) { return has(input, args[0]) }),
+		"in/1":            withValues(func(input any, args []any) (any, error) { return has(args[0], input) }),
+		"contains/1": withValues(func(input any, args []any) (any, error) {
+			return contains(input, args[0])
+		}),
+		"inside/1": withValues(func(input any, args []any) (any, error) {
+			return contains(args[0], input)
+		}),
+		"add/0": simple(addValues),
+		"add/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			values, err := ev.collect(args[0], input, e)
+			if err != nil {
+				return err
+			}
+			v, err := addValues(append(make([]any, 0), values...))
+			if err != nil {
+				return err
+			}
+			return emit(v)
+		},
+		"any/0": simple(func(v any) (any, error) {
+			var r bool
+			err := iterate(v, func(_ any, x any) error {
+				r = r || isTruthy(x)
+				return nil
+			})
+			return r, err
+		}),
+		"all/0": simple(func(v any) (any, error) {
+			r := true
+			err := iterate(v, func(_ any, x any) error {
+				r = r && isTruthy(x)
+				return nil
+			})
+			return r, err
+		}),
+		"any/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return anyAll(ev, iterateNode{target: identityNode{}}, args[0], input, e, true, emit)
+		},
+		"all/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return anyAll(ev, iterateNode{target: identityNode{}}, args[0], input, e, false, emit)
+		},
+		"any/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return anyAll(ev, args[0], args[1], input, e, true, emit)
+		},
+		"all/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return anyAll(ev, args[0], args[1], input, e, false, emit)
+		},
+		"IN/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.inStream(args[0], input, input, e, emit)
+		},
+		"IN/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			var found bool
+			stop := newStop()
+			err := ev.eval(args[0], input, e, func(v any) error {
+				return ev.inStream(args[1], input, v, e, func(x any) error {
+					if x == true {
+						found = true
+						return stop
+					}
+					return nil
+				})
+			})
+			if err := catchStop(err, stop); err != nil {
+				return err
+			}
+			return emit(found)
+		},
+		"range/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.eval(args[0], input, e, func(to any) error {
+				return rangeNumbers(ev, 0.0, to, 1.0, emit)
+			})
+		},
+		"range/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.cartesian(args, input, e, nil, func(values []any) error {
+				return rangeNumbers(ev, values[0], values[1], 1.0, emit)
+			})
+		},
+		"range/3": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.cartesian(args, input, e, nil, func(values []any) error {
+				return rangeNumbers(ev, values[0], values[1], values[2], emit)
+			})
+		},
+		"floor/0": mathFunc(math.Floor),
+		"ceil/0":  mathFunc(math.Ceil),
+		"round/0": mathFunc(math.Round),
+		"sqrt/0":  mathFunc(math.Sqrt),
+		"fabs/0":  mathFunc(math.Abs),
+		"abs/0":   mathFunc(math.Abs),
+		"log/0":   mathFunc(math.Log),
+		"log2/0":  mathFunc(math.Log2),
+		"log10/0": mathFunc(math.Log10),
+		"exp/0":   mathFunc(math.Exp),
+		"exp10/0": mathFunc(func(x float64) float64 { return math.Pow(10, x) }),
+		"trunc/0": mathFunc(math.Trunc),
+		"isnan/0": simple(func(v any) (any, error) { f, ok := v.(float64); return ok && math.IsNaN(f), nil }),
+		"isinfinite/0": simple(func(v any) (any, error) {
+			f, ok := v.(float64)
+			return ok && math.IsInf(f, 0), nil
+		}),
+		"nan/0":      simple(func(any) (any, error) { return math.NaN(), nil }),
+		"infinite/0": simple(func(any) (any, error) { return math.Inf(1), nil }),
+		"pow/2": withValues(func(_ any, args []any) (any, error) {
+			a, ok1 := args[0].(float64)
+			b, ok2 := args[1].(float64)
+			if !ok1 || !ok2 {
+				return nil, errorf("pow requires numbers")
+			}
+			return math.Pow(a, b), nil
+		}),
+		"min/0": simple(func(v any) (any, error) {
+			a, ok := v.([]any)
+			if !ok {
+				return nil, errorf("%s cannot be searched for a minimum", describe(v))
+			}
+			return extremeBy(false)(a, a)
+		}),
+		"max/0": simple(func(v any) (any, error) {
+			a, ok := v.([]any)
+			if !ok {
+				return nil, errorf("%s cannot be searched for a maximum", describe(v))
+			}
+			return extremeBy(true)(a, a)
+		}),
+		"min_by/1": byFunc(extremeBy(false)),
+		"max_by/1": byFunc(extremeBy(true)),
+		"sort/0": simple(func(v any) (any, error) {
+			a, ok := v.([]any)
+			if !ok {
+				return nil, errorf("%s cannot be sorted, as it is not an array", describe(v))
+			}
+			return sortBy(a, a)
+		}),
+		"sort_by/1":  byFunc(sortBy),
+		"group_by/1": byFunc(groupBy),
+		"unique/0": simple(func(v any) (any, error) {
+			a, ok := v.([]any)
+			if !ok {
+				return nil, errorf("%s cannot be sorted, as it is not an array", describe(v))
+			}
+			return uniqueBy(a, a)
+		}),
+		"unique_by/1": byFunc(uniqueBy),
+		"reverse/0": simple(func(v any) (any, error) {
+			switch v := v.(type) {
+			case nil:
+				return make([]any, 0), nil
+			case string:
+				r := []rune(v)
+				slices.Reverse(r)
+				return string(r), nil
+			case []any:
+				a := slices.Clone(v)
+				slices.Reverse(a)
+				return a, nil
+			}
+			return nil, errorf("cannot reverse %s", describe(v))
+		}),
+		"flatten/0": simple(func(v any) (any, error) { return flatten(v, 1e9) }),
+		"flatten/1": withValues(func(input any, args []any) (any, error) {
+			d, ok := args[0].(float64)
+			if !ok {
+				return nil, errorf("flatten depth must be a number")
+			}
+			return flatten(input, d)
+		}),
+		"tostring/0": simple(func(v any) (any, error) { return toString(v), nil }),
+		"tonumber/0": simple(toNumber),
+		"tojson/0":   simple(func(v any) (any, error) { return toJSON(v), nil }),
+		"fromjson/0": simple(func(v any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errorf("%s cannot be parsed as JSON", describe(v))
+			}
+			var r any
+			if err := json.Unmarshal([]byte(s), &r); err != nil {
+				return nil, errorf("%s cannot be parsed as JSON: %s", describe(v), err)
+			}
+			return r, nil
+		}),
+		"type/0": simple(func(v any) (any, error) { return typeName(v), nil }),
+		"toarray/0": simple(func(v any) (any, error) {
+			if a, ok := v.([]any); ok {
+				return a, nil
+			}
+			return []any{v}, nil
+		}),
+		"values/0":    selectType("boolean", "number", "string", "array", "object"),
+		"nulls/0":     selectType("null"),
+		"booleans/0":  selectType("boolean"),
+		"numbers/0":   selectType("number"),
+		"strings/0":   selectType("string"),
+		"arrays/0":    selectType("array"),
+		"objects/0":   selectType("object"),
+		"iterables/0": selectType("array", "object"),
+		"scalars/0":   selectType("null", "boolean", "number", "string"),
+		"startswith/1": stringFunc("startswith", func(s, arg string) any {
+			return strings.HasPrefix(s, arg)
+		}),
+		"endswith/1": stringFunc("endswith", func(s, arg string) any {
+			return strings.HasSuffix(s, arg)
+		}),
+		"ltrimstr/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.eval(args[0], input, e, func(v any) error {
+				s, ok1 := input.(string)
+				p, ok2 := v.(string)
+				if ok1 && ok2 {
+					return emit(strings.TrimPrefix(s, p))
+				}
+				return emit(input)
+			})
+		},
+		"rtrimstr/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.eval(args[0], input, e, func(v any) error {
+				s, ok1 := input.(string)
+				p, ok2 := v.(string)
+				if ok1 && ok2 {
+					return emit(strings.TrimSuffix(s, p))
+				}
+				return emit(input)
+			})
+		},
+		"trim/0":  trimFunc(strings.TrimSpace),
+		"ltrim/0": trimFunc(func(s string) string { return strings.TrimLeft(s, " \t\n\r\f\v") }),
+		"rtrim/0": trimFunc(func(s string) string { return strings.TrimRight(s, " \t\n\r\f\v") }),
+		"split/1": stringFunc("split", func(s, sep string) any {
+			return splitString(s, sep)
+		}),
+		"join/1":           withValues(func(input any, args []any) (any, error) { return join(input, args[0]) }),
+		"ascii_downcase/0": trimFunc(strings.ToLower),
+		"ascii_upcase/0":   trimFunc(strings.ToUpper),
+		"explode/0": simple(func(v any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errorf("%s cannot be exploded", describe(v))
+			}
+			a := make([]any, 0, len(s))
+			for _, r := range s {
+				a = append(a, float64(r))
+			}
+			return a, nil
+		}),
+		"implode/0": simple(func(v any) (any, error) {
+			a, ok := v.([]any)
+			if !ok {
+				return nil, errorf("%s cannot be imploded", describe(v))
+			}
+			var sb strings.Builder
+			for _, x := range a {
+				f, ok := x.(float64)
+				if !ok {
+					return nil, errorf("unicode codepoint must be numeric")
+				}
+				sb.WriteRune(rune(f))
+			}
+			return sb.String(), nil
+		}),
+		"indices/1": withValues(func(input any, args []any) (any, error) {
+			return indices(input, args[0])
+		}),
+		"index/1": withValues(func(input any, args []any) (any, error) {
+			r, err := indices(input, args[0])
+			if a, ok := r.([]any); ok && len(a) > 0 {
+				return a[0], err
+			}
+			return nil, err
+		}),
+		"rindex/1": withValues(func(input any, args []any) (any, error) {
+			r, err := indices(input, args[0])
+			if a, ok := r.([]any); ok && len(a) > 0 {
+				return a[len(a)-1], err
+			}
+			return nil, err
+		}),
+		"test/1":         withValues(regexTest),
+		"test/2":         withValues(regexTest),
+		"capture/1":      capture,
+		"capture/2":      capture,
+		"match/1":        match,
+		"match/2":        match,
+		"scan/1":         scan,
+		"scan/2":         scan,
+		"splits/1":       splits,
+		"splits/2":       splits,
+		"split/2":        splitWithRegex,
+		"sub/2":          substitute(false),
+		"sub/3":          substitute(false),
+		"gsub/2":         substitute(true),
+		"gsub/3":         substitute(true),
+		"to_entries/0":   simple(toEntries),
+		"from_entries/0": simple(fromEntries),
+		"with_entries/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			entries, err := toEntries(input)
+			if err != nil {
+				return err
+			}
+			a := make([]any, 0)
+			for _, x := range entries.([]any) {
+				values, err := ev.collect(args[0], x, e)
+				if err != nil {
+					return err
+				}
+				a = append(a, values...)
+			}
+			o, err := fromEntries(a)
+			if err != nil {
+				return err
+			}
+			return emit(o)
+		},
+		"select/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.eval(args[0], input, e, func(v any) error {
+				if isTruthy(v) {
+					return emit(input)
+				}
+				return nil
+			})
+		},
+		"map/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			a := make([]any, 0)
+			err := iterate(input, func(_ any, x any) error {
+				return ev.eval(args[0], x, e, func(v any) error {
+					a = append(a, v)
+					return nil
+				})
+			})
+			if err != nil {
+				return err
+			}
+			return emit(a)
+		},
+		"map_values/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			switch v := input.(type) {
+			case []any:
+				a := make([]any, 0, len(v))
+				for _, x := range v {
+					y, ok, err := ev.first(args[0], x, e)
+					if err != nil {
+						return err
+					}
+					if ok {
+						a = append(a, y)
+					}
+				}
+				return emit(a)
+			case map[string]any:
+				o := make(map[string]any, len(v))
+				for k, x := range v {
+					y, ok, err := ev.first(args[0], x, e)
+					if err != nil {
+						return err
+					}
+					if ok {
+						o[k] = y
+					}
+				}
+				return emit(o)
+			}
+			return errorf("cannot iterate over %s", describe(input))
+		},
+		"recurse/0": func(ev *evaluator, input any, _ []node, _ *env, emit emitFunc) error {
+			return ev.recurse(input, emit)
+		},
+		"recurse/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return recurseWith(ev, args[0], nil, input, e, emit)
+		},
+		"recurse/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return recurseWith(ev, args[0], args[1], input, e, emit)
+		},
+		"walk/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			v, err := walk(ev, args[0], input, e)
+			if err != nil {
+				return err
+			}
+			return emit(v)
+		},
+		"path/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.evalPaths(args[0], input, nil, e, func(p []any, _ any) error {
+				return emit(pathValue(p))
+			})
+		},
+		"paths/0": func(ev *evaluator, input any, _ []node, _ *env, emit emitFunc) error {
+			return ev.recursePaths(input, nil, func(p []any, _ any) error {
+				if len(p) == 0 {
+					return nil
+				}
+				return emit(p)
+			})
+		},
+		"paths/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.recursePaths(input, nil, func(p []any, v any) error {
+				if len(p) == 0 {
+					return nil
+				}
+				return ev.eval(args[0], v, e, func(x any) error {
+					if isTruthy(x) {
+						return emit(p)
+					}
+					return nil
+				})
+			})
+		},
+		"leaf_paths/0": func(ev *evaluator, input any, _ []node, _ *env, emit emitFunc) error {
+			return ev.recursePaths(input, nil, func(p []any, v any) error {
+				switch v.(type) {
+				case []any, map[string]any:
+					return nil
+				}
+				if len(p) == 0 {
+					return nil
+				}
+				return emit(p)
+			})
+		},
+		"getpath/1": withValues(func(input any, args []any) (any, error) {
+			p, ok := args[0].([]any)
+			if !ok {
+				return nil, errorf("path must be specified as an array")
+			}
+			v, err := getPath(input, p)
+			if err != nil {
+				return nil, nil
+			}
+			return v, nil
+		}),
+		"setpath/2": withValues(func(input any, args []any) (any, error) {
+			p, ok := args[0].([]any)
+			if !ok {
+				return nil, errorf("path must be specified as an array")
+			}
+			return setPath(input, p, args[1])
+		}),
+		"delpaths/1": withValues(func(input any, args []any) (any, error) {
+			a, ok := args[0].([]any)
+			if !ok {
+				return nil, errorf("paths must be specified as an array")
+			}
+			paths := make([][]any, len(a))
+			for i, x := range a {
+				p, ok := x.([]any)
+				if !ok {
+					return nil, errorf("path must be specified as an array")
+				}
+				paths[i] = p
+			}
+			return deletePaths(input, paths)
+		}),
+		"del/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			paths, err := ev.paths(args[0], input, e)
+			if err != nil {
+				return err
+			}
+			v, err := deletePaths(input, paths)
+			if err != nil {
+				return err
+			}
+			return emit(v)
+		},
+		"pick/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			paths, err := ev.paths(args[0], input, e)
+			if err != nil {
+				return err
+			}
+			var r any
+			for _, p := range paths {
+				v, err := getPath(input, p)
+				if err != nil {
+					return err
+				}
+				r, err = setPath(r, p, v)
+				if err != nil {
+					return err
+				}
+			}
+			return emit(r)
+		},
+		"first/0": simple(func(v any) (any, error) { return index(v, 0.0) }),
+		"last/0":  simple(func(v any) (any, error) { return index(v, -1.0) }),
+		"nth/1":   withValues(func(input any, args []any) (any, error) { return index(input, args[0]) }),
+		"first/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			v, ok, err := ev.first(args[0], input, e)
+			if err != nil || !ok {
+				return err
+			}
+			return emit(v)
+		},
+		"last/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			v, ok, err := ev.lastOutput(args[0], input, e)
+			if err != nil || !ok {
+				return err
+			}
+			return emit(v)
+		},
+		"nth/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.eval(args[0], input, e, func(v any) error {
+				n, ok := v.(float64)
+				if !ok || n < 0 {
+					return errorf("out of bounds negative array index")
+				}
+				return limit(ev, int(n)+1, args[1], input, e, func(i int, x any) error {
+					if i == int(n) {
+						return emit(x)
+					}
+					return nil
+				})
+			})
+		},
+		"limit/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return ev.eval(args[0], input, e, func(v any) error {
+				n, ok := v.(float64)
+				if !ok {
+					return errorf("invalid limit %s", describe(v))
+				}
+				return limit(ev, int(n), args[1], input, e, func(_ int, x any) error {
+					return emit(x)
+				})
+			})
+		},
+		"until/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			v := input
+			for {
+				c, _, err := ev.first(args[0], v, e)
+				if err != nil {
+					return err
+				}
+				if isTruthy(c) {
+					return emit(v)
+				}
+				x, ok, err := ev.first(args[1], v, e)
+				if err != nil || !ok {
+					return err
+				}
+				if err := ev.checkCanceled(); err != nil {
+					return err
+				}
+				v = x
+			}
+		},
+		"while/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			v := input
+			for {
+				c, _, err := ev.first(args[0], v, e)
+				if err != nil {
+					return err
+				}
+				if !isTruthy(c) {
+					return nil
+				}
+				if err := emit(v); err != nil {
+					return err
+				}
+				x, ok, err := ev.first(args[1], v, e)
+				if err != nil || !ok {
+					return err
+				}
+				if err := ev.checkCanceled(); err != nil {
+					return err
+				}
+				v = x
+			}
+		},
+		"isempty/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			_, ok, err := ev.first(args[0], input, e)
+			if err != nil {
+				return err
+			}
+			return emit(!ok)
+		},
+		"INDEX/1": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return indexBy(ev, iterateNode{target: identityNode{}}, args[0], input, e, emit)
+		},
+		"INDEX/2": func(ev *evaluator, input any, args []node, e *env, emit emitFunc) error {
+			return indexBy(ev, args[0], args[1], input, e, emit)
+		},
+	}
+}
+
+func trimFunc(f func(string) string) builtinFunc {
+	return simple(func(v any) (any, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errorf("%s cannot be trimmed", describe(v))
+		}
+		return f(s), nil
+	})
+}
+
+func anyAll(ev *evaluator, gen, cond node, input any, e *env, isAny bool, emit emitFunc) error {
+	result := !isAny
+	stop := newStop()
+	err := ev.eval(gen, input, e, func(v any) error {
+		return ev.eval(cond, v, e, func(c any) error {
+			if isTruthy(c) == isAny {
+				result = isAny
+				return stop
+			}
+			return nil
+		})
+	})
+	if err := catchStop(err, stop); err != nil {
+		return err
+	}
+	return emit(result)
+}
+
+// inStream emits true when v is equal to any output of the stream.
+func (ev *evaluator) inStream(stream node, input, v any, e *env, emit emitFunc) error {
+	var found bool
+	stop := newStop()
+	err := ev.eval(stream, input, e, func(x any) error {
+		if compare(v, x) == 0 {
+			found = true
+			return stop
+		}
+		return nil
+	})
+	if err := catchStop(err, stop); err != nil {
+		return err
+	}
+	return emit(found)
+}
+
+func rangeNumbers(ev *evaluator, from, to, step any, emit emitFunc) error {
+	a, ok1 := from.(float64)
+	b, ok2 := to.(float64)
+	s, ok3 := step.(float64)
+	if !ok1 || !ok2 || !ok3 {
+		return errorf("range bounds must be numeric")
+	}
+	if s == 0 {
+		return nil
+	}
+	for x := a; (s > 0 && x < b) || (s < 0 && x > b); x += s {
+		if err := ev.checkCanceled(); err != nil {
+			return err
+		}
+		if err := emit(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limit calls f for the first n outputs of an expression.
+func limit(ev *evaluator, n int, f node, input any, e *env, emit func(i int, v any) error) error {
+	if n <= 0 {
+		return nil
+	}
+	var i int
+	stop := newStop()
+	err := ev.eval(f, input, e, func(v any) error {
+		if err := emit(i, v); err != nil {
+			return err
+		}
+		i++
+		if i >= n {
+			return stop
+		}
+		return nil
+	})
+	return catchStop(err, stop)
+}
+
+func recurseWith(ev *evaluator, f, cond node, v any, e *env, emit emitFunc) error {
+	if cond != nil {
+		c, _, err := ev.first(cond, v, e)
+		if err != nil {
+			return err
+		}
+		if !isTruthy(c) {
+			return nil
+		}
+	}
+	if err := emit(v); err != nil {
+		return err
+	}
+	return ev.eval(f, v, e, func(x any) error {
+		return recurseWith(ev, f, cond, x, e, emit)
+	})
+}
+
+// indexBy creates an object from a stream, with keys computed by an expression.
+func indexBy(ev *evaluator, stream, idx node, input any, e *env, emit emitFunc) error {
+	o := make(map[string]any)
+	err := ev.eval(stream, input, e, func(v any) error {
+		k, _, err := ev.first(idx, v, e)
+		if err != nil {
+			return err
+		}
+		o[toString(k).(string)] = v
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return emit(o)
+}
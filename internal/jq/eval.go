@@ -0,0 +1,658 @@
+package jq
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Number of evaluation steps between checks for cancellation
+const cancelCheckTick = 10_000
+
+// emitFunc receives an output of an expression.
+type emitFunc func(v any) error
+
+// env is a linked list of variable bindings.
+type env struct {
+	name   string
+	value  any
+	parent *env
+}
+
+func (e *env) lookup(name string) (any, bool) {
+	for ; e != nil; e = e.parent {
+		if e.name == name {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+func (e *env) bind(name string, value any) *env {
+	return &env{name: name, value: value, parent: e}
+}
+
+// stopError is used to stop a generator early, e.g. for first(f).
+// Each stop has it's own identity, so that nested generators can be stopped independently.
+type stopError struct {
+	id *int
+}
+
+func (e *stopError) Error() string {
+	return "stop"
+}
+
+func newStop() *stopError {
+	return &stopError{id: new(int)}
+}
+
+// catchStop returns nil when err is the given stop error.
+func catchStop(err error, stop *stopError) error {
+	if s, ok := err.(*stopError); ok && s.id == stop.id {
+		return nil
+	}
+	return err
+}
+
+type evaluator struct {
+	ctx   context.Context
+	steps int
+}
+
+func (ev *evaluator) checkCanceled() error {
+	ev.steps++
+	if ev.steps%cancelCheckTick == 0 {
+		return ev.ctx.Err()
+	}
+	return nil
+}
+
+// collect returns all outputs of an expression.
+func (ev *evaluator) collect(n node, input any, e *env) ([]any, error) {
+	var values []any
+	err := ev.eval(n, input, e, func(v any) error {
+		values = append(values, v)
+		return nil
+	})
+	return values, err
+}
+
+// first returns the first output of an expression and reports whether there was one.
+func (ev *evaluator) first(n node, input any, e *env) (any, bool, error) {
+	var r any
+	var found bool
+	stop := newStop()
+	err := ev.eval(n, input, e, func(v any) error {
+		r, found = v, true
+		return stop
+	})
+	return r, found, catchStop(err, stop)
+}
+
+// eval evaluates the node against the input and calls emit for every output.
+func (ev *evaluator) eval(n node, input any, e *env, emit emitFunc) error {
+	if err := ev.checkCanceled(); err != nil {
+		return err
+	}
+	switch n := n.(type) {
+	case identityNode:
+		return emit(input)
+	case recurseNode:
+		return ev.recurse(input, emit)
+	case literalNode:
+		return emit(n.value)
+	case fieldNode:
+		return ev.eval(n.target, input, e, func(v any) error {
+			x, err := index(v, n.name)
+			if err != nil {
+				return err
+			}
+			return emit(x)
+		})
+	case indexNode:
+		return ev.eval(n.target, input, e, func(v any) error {
+			return ev.eval(n.index, input, e, func(k any) error {
+				x, err := index(v, k)
+				if err != nil {
+					return err
+				}
+				return emit(x)
+			})
+		})
+	case sliceNode:
+		return ev.eval(n.target, input, e, func(v any) error {
+			return ev.evalOptional(n.to, input, e, func(to any) error {
+				return ev.evalOptional(n.from, input, e, func(from any) error {
+					x, err := slice(v, from, to)
+					if err != nil {
+						return err
+					}
+					return emit(x)
+				})
+			})
+		})
+	case iterateNode:
+		return ev.eval(n.target, input, e, func(v any) error {
+			return iterate(v, func(_ any, x any) error {
+				return emit(x)
+			})
+		})
+	case stringNode:
+		return ev.evalString(n.parts, input, e, "", emit)
+	case formatNode:
+		s, err := format(n.name, input)
+		if err != nil {
+			return err
+		}
+		return emit(s)
+	case arrayNode:
+		a := make([]any, 0)
+		if n.body != nil {
+			var err error
+			a, err = ev.collect(n.body, input, e)
+			if err != nil {
+				return err
+			}
+			if a == nil {
+				a = make([]any, 0)
+			}
+		}
+		return emit(a)
+	case objectNode:
+		return ev.evalObject(n.entries, input, e, make(map[string]any), emit)
+	case pipeNode:
+		return ev.eval(n.left, input, e, func(v any) error {
+			return ev.eval(n.right, v, e, emit)
+		})
+	case commaNode:
+		if err := ev.eval(n.left, input, e, emit); err != nil {
+			return err
+		}
+		return ev.eval(n.right, input, e, emit)
+	case negateNode:
+		return ev.eval(n.x, input, e, func(v any) error {
+			f, ok := v.(float64)
+			if !ok {
+				return errorf("%s (%s) cannot be negated", typeName(v), truncatedJSON(v))
+			}
+			return emit(-f)
+		})
+	case binaryNode:
+		return ev.evalBinary(n, input, e, emit)
+	case alternateNode:
+		var found bool
+		err := ev.eval(n.left, input, e, func(v any) error {
+			if !isTruthy(v) {
+				return nil
+			}
+			found = true
+			if err := emit(v); err != nil {
+				return &passError{err}
+			}
+			return nil
+		})
+		switch err := err.(type) {
+		case *passError:
+			return err.err
+		case *Error:
+			// errors on the left side are ignored
+		case nil:
+		default:
+			return err
+		}
+		if found {
+			return nil
+		}
+		return ev.eval(n.right, input, e, emit)
+	case assignNode:
+		return ev.evalAssign(n, input, e, emit)
+	case ifNode:
+		return ev.eval(n.cond, input, e, func(v any) error {
+			if isTruthy(v) {
+				return ev.eval(n.then, input, e, emit)
+			}
+			return ev.eval(n.otherwise, input, e, emit)
+		})
+	case tryNode:
+		return ev.evalTry(n, input, e, emit)
+	case reduceNode:
+		return ev.eval(n.init, input, e, func(acc any) error {
+			err := ev.eval(n.source, input, e, func(x any) error {
+				v, ok, err := ev.lastOutput(n.update, acc, e.bind(n.name, x))
+				if err != nil {
+					return err
+				}
+				if ok {
+					acc = v
+				} else {
+					acc = nil
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return emit(acc)
+		})
+	case foreachNode:
+		return ev.eval(n.init, input, e, func(acc any) error {
+			return ev.eval(n.source, input, e, func(x any) error {
+				e2 := e.bind(n.name, x)
+				return ev.eval(n.update, acc, e2, func(v any) error {
+					acc = v
+					if n.extract == nil {
+						return emit(v)
+					}
+					return ev.eval(n.extract, v, e2, emit)
+				})
+			})
+		})
+	case bindNode:
+		return ev.eval(n.source, input, e, func(v any) error {
+			return ev.eval(n.body, input, e.bind(n.name, v), emit)
+		})
+	case varNode:
+		v, ok := e.lookup(n.name)
+		if !ok {
+			if n.name == "ENV" {
+				return emit(map[string]any{})
+			}
+			return errorf("$%s is not defined", n.name)
+		}
+		return emit(v)
+	case callNode:
+		return ev.call(n, input, e, emit)
+	}
+	panic("unknown node type")
+}
+
+// evalOptional evaluates an optional node, which emits null when the node is nil.
+func (ev *evaluator) evalOptional(n node, input any, e *env, emit emitFunc) error {
+	if n == nil {
+		return emit(nil)
+	}
+	return ev.eval(n, input, e, emit)
+}
+
+// lastOutput returns the last output of an expression and reports whether there was one.
+func (ev *evaluator) lastOutput(n node, input any, e *env) (any, bool, error) {
+	var r any
+	var found bool
+	err := ev.eval(n, input, e, func(v any) error {
+		r, found = v, true
+		return nil
+	})
+	return r, found, err
+}
+
+func (ev *evaluator) evalString(parts []node, input any, e *env, prefix string, emit emitFunc) error {
+	if len(parts) == 0 {
+		return emit(prefix)
+	}
+	if l, ok := parts[0].(literalNode); ok {
+		if s, ok := l.value.(string); ok {
+			return ev.evalString(parts[1:], input, e, prefix+s, emit)
+		}
+	}
+	return ev.eval(parts[0], input, e, func(v any) error {
+		s, ok := v.(string)
+		if !ok {
+			s = toJSON(v)
+		}
+		return ev.evalString(parts[1:], input, e, prefix+s, emit)
+	})
+}
+
+func (ev *evaluator) evalObject(entries []objectEntry, input any, e *env, obj map[string]any, emit emitFunc) error {
+	if len(entries) == 0 {
+		return emit(obj)
+	}
+	entry := entries[0]
+	return ev.eval(entry.key, input, e, func(k any) error {
+		key, ok := k.(string)
+		if !ok {
+			return errorf("object keys must be strings, not %s", typeName(k))
+		}
+		return ev.eval(entry.value, input, e, func(v any) error {
+			o := make(map[string]any, len(obj)+1)
+			for k, v := range obj {
+				o[k] = v
+			}
+			o[key] = v
+			return ev.evalObject(entries[1:], input, e, o, emit)
+		})
+	})
+}
+
+func (ev *evaluator) evalBinary(n binaryNode, input any, e *env, emit emitFunc) error {
+	switch n.op {
+	case "and", "or":
+		return ev.eval(n.left, input, e, func(l any) error {
+			if n.op == "and" && !isTruthy(l) {
+				return emit(false)
+			}
+			if n.op == "or" && isTruthy(l) {
+				return emit(true)
+			}
+			return ev.eval(n.right, input, e, func(r any) error {
+				return emit(isTruthy(r))
+			})
+		})
+	}
+	return ev.eval(n.right, input, e, func(r any) error {
+		return ev.eval(n.left, input, e, func(l any) error {
+			v, err := binaryOp(n.op, l, r)
+			if err != nil {
+				return err
+			}
+			return emit(v)
+		})
+	})
+}
+
+// binaryOp applies an arithmetic or comparison operator.
+func binaryOp(op string, l, r any) (any, error) {
+	switch op {
+	case "+":
+		return add(l, r)
+	case "-":
+		return subtract(l, r)
+	case "*":
+		return multiply(l, r)
+	case "/":
+		return divide(l, r)
+	case "%":
+		return modulo(l, r)
+	case "==":
+		return compare(l, r) == 0, nil
+	case "!=":
+		return compare(l, r) != 0, nil
+	case "<":
+		return compare(l, r) < 0, nil
+	case "<=":
+		return compare(l, r) <= 0, nil
+	case ">":
+		return compare(l, r) > 0, nil
+	case ">=":
+		return compare(l, r) >= 0, nil
+	}
+	panic("unknown operator " + op)
+}
+
+func (ev *evaluator) evalTry(n tryNode, input any, e *env, emit emitFunc) error {
+	var bodyErr *Error
+	err := ev.eval(n.body, input, e, func(v any) error {
+		if err := emit(v); err != nil {
+			// errors raised after the body must not be caught
+			return &passError{err}
+		}
+		return nil
+	})
+	if p, ok := err.(*passError); ok {
+		return p.err
+	}
+	if err == nil {
+		return nil
+	}
+	var ok bool
+	if bodyErr, ok = err.(*Error); !ok {
+		return err
+	}
+	if n.catch == nil {
+		return nil
+	}
+	return ev.eval(n.catch, bodyErr.Value, e, emit)
+}
+
+// passError wraps errors which should not be caught by try.
+type passError struct {
+	err error
+}
+
+func (e *passError) Error() string {
+	return e.err.Error()
+}
+
+func (ev *evaluator) evalAssign(n assignNode, input any, e *env, emit emitFunc) error {
+	if n.op == "|=" {
+		paths, err := ev.paths(n.left, input, e)
+		if err != nil {
+			return err
+		}
+		r := input
+		for _, p := range paths {
+			old, err := getPath(r, p)
+			if err != nil {
+				return err
+			}
+			v, ok, err := ev.first(n.right, old, e)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				r, err = deletePaths(r, [][]any{p})
+			} else {
+				r, err = setPath(r, p, v)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return emit(r)
+	}
+	return ev.eval(n.right, input, e, func(v any) error {
+		paths, err := ev.paths(n.left, input, e)
+		if err != nil {
+			return err
+		}
+		r := input
+		for _, p := range paths {
+			x := v
+			if n.op != "=" {
+				old, err := getPath(r, p)
+				if err != nil {
+					return err
+				}
+				op := strings.TrimSuffix(n.op, "=")
+				if op == "//" {
+					if isTruthy(old) {
+						x = old
+					}
+				} else {
+					x, err = binaryOp(op, old, v)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			r, err = setPath(r, p, x)
+			if err != nil {
+				return err
+			}
+		}
+		return emit(r)
+	})
+}
+
+// recurse emits v and all values contained in it in pre-order.
+func (ev *evaluator) recurse(v any, emit emitFunc) error {
+	if err := ev.checkCanceled(); err != nil {
+		return err
+	}
+	if err := emit(v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case []any, map[string]any:
+		return iterate(v, func(_ any, x any) error {
+			return ev.recurse(x, emit)
+		})
+	}
+	return nil
+}
+
+// iterate calls f for every element of an array or every entry of an object.
+// Object entries are iterated in key order.
+func iterate(v any, f func(key any, x any) error) error {
+	switch v := v.(type) {
+	case []any:
+		for i, x := range v {
+			if err := f(float64(i), x); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			if err := f(k, v[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errorf("cannot iterate over %s", describe(v))
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// index returns the element of v at k.
+func index(v any, k any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		switch k.(type) {
+		case string, float64, nil:
+			return nil, nil
+		}
+	case map[string]any:
+		if k, ok := k.(string); ok {
+			return v[k], nil
+		}
+	case []any:
+		switch k := k.(type) {
+		case float64:
+			if math.IsNaN(k) {
+				return nil, nil
+			}
+			i := int(math.Floor(k))
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return nil, nil
+			}
+			return v[i], nil
+		case map[string]any:
+			return slice(v, k["start"], k["end"])
+		case []any:
+			return indices(v, k)
+		}
+	}
+	if s, ok := k.(string); ok {
+		return nil, errorf("cannot index %s with %q", typeName(v), s)
+	}
+	return nil, errorf("cannot index %s with %s", typeName(v), typeName(k))
+}
+
+// slice returns a slice of an array or string.
+func slice(v any, from, to any) (any, error) {
+	var n int
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		n = len(v)
+	case string:
+		n = len([]rune(v))
+	default:
+		return nil, errorf("cannot slice %s", typeName(v))
+	}
+	start, end, err := sliceBounds(n, from, to)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case []any:
+		return slices.Clone(v[start:end]), nil
+	case string:
+		return string([]rune(v)[start:end]), nil
+	}
+	return nil, nil
+}
+
+// sliceBounds returns the start and end of a slice of a value with length n.
+func sliceBounds(n int, from, to any) (int, int, error) {
+	bound := func(x any, def int) (int, error) {
+		switch x := x.(type) {
+		case nil:
+			return def, nil
+		case float64:
+			i := int(math.Floor(x))
+			if i < 0 {
+				i += n
+			}
+			return max(0, min(n, i)), nil
+		}
+		return 0, errorf("slice indices must be numbers")
+	}
+	start, err := bound(from, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := bound(to, n)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, max(start, end), nil
+}
+
+// indices returns the positions of all occurrences of sub in v.
+// Strings are searched for substrings and arrays for elements or, when sub is an array, for sub-arrays.
+func indices(v, sub any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		s, ok := sub.(string)
+		if !ok {
+			return nil, errorf("cannot index string with %s", typeName(sub))
+		}
+		if s == "" {
+			return nil, nil
+		}
+		r := make([]any, 0)
+		for i := 0; ; {
+			j := strings.Index(v[i:], s)
+			if j < 0 {
+				break
+			}
+			r = append(r, float64(utf8.RuneCountInString(v[:i+j])))
+			i += j + 1
+		}
+		return r, nil
+	case []any:
+		b, ok := sub.([]any)
+		if !ok {
+			b = []any{sub}
+		}
+		if len(b) == 0 {
+			return nil, nil
+		}
+		r := make([]any, 0)
+		for i := 0; i+len(b) <= len(v); i++ {
+			if slices.EqualFunc(v[i:i+len(b)], b, func(x, y any) bool { return compare(x, y) == 0 }) {
+				r = append(r, float64(i))
+			}
+		}
+		return r, nil
+	}
+	return nil, errorf("cannot index %s with %s", typeName(v), typeName(sub))
+}
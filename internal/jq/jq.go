@@ -0,0 +1,83 @@
+// Package jq implements a subset of the jq language for transforming JSON data.
+//
+// Queries operate on decoded JSON values, i.e. nil, bool, float64, string,
+// []any and map[string]any.
+//
+// Supported are paths, pipes, commas, object and array construction, string interpolation,
+// arithmetic and comparison operators, alternatives, assignments, variables,
+// if-then-else, try-catch, reduce and foreach, and most of the jq builtin functions.
+// Not supported are function definitions, modules, labels, destructuring, format strings
+// and SQL-style operators. Compiling a query which uses them returns an error,
+// which wraps [ErrInvalidExpression] and [ErrUnsupported].
+package jq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidExpression = errors.New("invalid jq expression")
+	ErrUnsupported       = errors.New("unsupported") // jq feature not implemented by this package
+)
+
+// Error is an error raised while running a query, e.g. by the error function.
+type Error struct {
+	Value any
+}
+
+func (e *Error) Error() string {
+	if s, ok := e.Value.(string); ok {
+		return s
+	}
+	return toJSON(e.Value) + " (not a string)"
+}
+
+func errorf(format string, args ...any) error {
+	return &Error{Value: fmt.Sprintf(format, args...)}
+}
+
+// Query represents a compiled jq expression.
+type Query struct {
+	root node
+	s    string
+}
+
+// Compile parses a jq expression and returns a query when successful.
+// Returns an error wrapping [ErrInvalidExpression] when the expression is not valid.
+func Compile(s string) (*Query, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseAll()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{root: root, s: s}, nil
+}
+
+// String returns the original expression.
+func (q *Query) String() string {
+	return q.s
+}
+
+// Run runs the query against the input and returns all outputs.
+// Like in jq, nan in outputs becomes null and infinite numbers become the largest finite numbers,
+// so that all outputs are valid JSON.
+// Returns the context's error when the context is canceled.
+func (q *Query) Run(ctx context.Context, input any) ([]any, error) {
+	ev := &evaluator{ctx: ctx}
+	outputs := make([]any, 0)
+	err := ev.eval(q.root, input, nil, func(v any) error {
+		v, _ = jsonValue(v)
+		outputs = append(outputs, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
@@ -0,0 +1,279 @@
+package jq_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ErikKalkoken/janice/internal/jq"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseJSON(s string) any {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestRun(t *testing.T) {
+	const input = `{
+		"orders": [
+			{"id": 1, "status": "failed", "price": 120, "customer": {"name": "Alice", "city": "Paris"}, "tags": ["a", "b"]},
+			{"id": 2, "status": "ok", "price": 80, "customer": {"name": "Bob", "city": "Berlin"}, "tags": []},
+			{"id": 3, "status": "failed", "price": 40, "customer": {"name": "Carol", "city": "Paris"}, "tags": ["b"]}
+		],
+		"meta": {"count": 3, "source": null}
+	}`
+	cases := []struct {
+		query string
+		want  string // JSON array of all outputs
+	}{
+		{`.`, `[` + input + `]`},
+		{``, `[` + input + `]`},
+		{`.meta.count`, `[3]`},
+		{`.meta["count"]`, `[3]`},
+		{`.meta."count"`, `[3]`},
+		{`.missing`, `[null]`},
+		{`.orders[0].id`, `[1]`},
+		{`.orders[-1].id`, `[3]`},
+		{`.orders[5]`, `[null]`},
+		{`[.orders[1:][].id]`, `[[2,3]]`},
+		{`.orders[].id`, `[1,2,3]`},
+		{`.orders | length`, `[3]`},
+		{`.meta | keys`, `[["count","source"]]`},
+		{`[.orders[] | select(.status == "failed") | .id]`, `[[1,3]]`},
+		{`.orders | map(.price * 2)`, `[[240,160,80]]`},
+		{`.orders | map({id, name: .customer.name})`, `[[{"id":1,"name":"Alice"},{"id":2,"name":"Bob"},{"id":3,"name":"Carol"}]]`},
+		{`.orders | group_by(.customer.city) | map({city: .[0].customer.city, ids: map(.id)})`, `[[{"city":"Berlin","ids":[2]},{"city":"Paris","ids":[1,3]}]]`},
+		{`.orders | sort_by(.price) | map(.id)`, `[[3,2,1]]`},
+		{`.orders | sort_by(-.price) | first | .id`, `[1]`},
+		{`.orders | min_by(.price).id, max_by(.price).id`, `[3,1]`},
+		{`.orders | unique_by(.status) | length`, `[2]`},
+		{`.orders[0] | pick(.id, .customer.name)`, `[{"id":1,"customer":{"name":"Alice"}}]`},
+		{`.orders[0] | del(.customer, .tags)`, `[{"id":1,"status":"failed","price":120}]`},
+		{`.orders[0].tags | del(.[0])`, `[["b"]]`},
+		{`.orders | map(.price) | add`, `[240]`},
+		{`.orders | map(.price) | add / length`, `[80]`},
+		{`[.orders[].tags[]] | unique`, `[["a","b"]]`},
+		{`.meta | to_entries`, `[[{"key":"count","value":3},{"key":"source","value":null}]]`},
+		{`.meta | with_entries(.value |= tostring)`, `[{"count":"3","source":"null"}]`},
+		{`[.orders[] | .customer.name | ascii_downcase]`, `[["alice","bob","carol"]]`},
+		{`.orders[0] | "\(.customer.name) paid \(.price)"`, `["Alice paid 120"]`},
+		{`.orders[0].price > 100 and .orders[1].price > 100`, `[false]`},
+		{`.orders[0].price > 100 or .orders[1].price > 100`, `[true]`},
+		{`.meta.source // "none"`, `["none"]`},
+		{`.orders[] | if .price > 100 then "high" elif .price > 50 then "mid" else "low" end`, `["high","mid","low"]`},
+		{`reduce .orders[] as $o (0; . + $o.price)`, `[240]`},
+		{`[foreach .orders[] as $o (0; . + 1)]`, `[[1,2,3]]`},
+		{`.orders[0].price as $p | .orders | map(select(.price < $p)) | length`, `[2]`},
+		{`[.orders[] | .id] | contains([1])`, `[true]`},
+		{`.orders[0].customer | has("name"), has("zip")`, `[true,false]`},
+		{`.orders[0].customer.name | test("^al"; "i")`, `[true]`},
+		{`.orders[0].customer.name | sub("(?<x>l)"; "<\(.x)>")`, `["A<l>ice"]`},
+		{`"a-b-c" | gsub("-"; "+")`, `["a+b+c"]`},
+		{`"a,b" | split(",")`, `[["a","b"]]`},
+		{`"a1b2" | [scan("\\d")]`, `[["1","2"]]`},
+		{`"a1b2" | [scan("([a-z])(\\d)")]`, `[[["a","1"],["b","2"]]]`},
+		{`"äb" | match("b")`, `[{"offset":1,"length":1,"string":"b","captures":[]}]`},
+		{`"ab" | [match("(?<x>a)(c)?"; "g")]`, `[[{"offset":0,"length":1,"string":"a","captures":[{"offset":0,"length":1,"string":"a","name":"x"},{"offset":-1,"length":0,"string":null,"name":null}]}]]`},
+		{`"a, b,c" | split(", *"; null)`, `[["a","b","c"]]`},
+		{`"a1b" | [splits("\\d")]`, `[["a","b"]]`},
+		{`["a",1,null] | join("-")`, `["a-1-"]`},
+		{`[1,[2,[3]]] | flatten`, `[[1,2,3]]`},
+		{`[range(3)]`, `[[0,1,2]]`},
+		{`[range(0; 10; 4)]`, `[[0,4,8]]`},
+		{`[limit(2; .orders[].id)]`, `[[1,2]]`},
+		{`first(.orders[].id)`, `[1]`},
+		{`[.[] | numbers]`, `[[]]`},
+		{`[.. | numbers]`, `[[3,1,120,2,80,3,40]]`},
+		{`[paths(type == "number")] | length`, `[7]`},
+		{`.meta | [paths]`, `[[["count"],["source"]]]`},
+		{`.meta | path(.count)`, `[["count"]]`},
+		{`.meta | getpath(["count"])`, `[3]`},
+		{`.meta | setpath(["a","b"]; 1)`, `[{"count":3,"source":null,"a":{"b":1}}]`},
+		{`.meta.count |= . + 1 | .meta.count`, `[4]`},
+		{`.meta.count += 10 | .meta.count`, `[13]`},
+		{`.meta.source //= "x" | .meta.source`, `["x"]`},
+		{`.orders[].price = 0 | [.orders[].price]`, `[[0,0,0]]`},
+		{`.orders | map_values(.id)`, `[[1,2,3]]`},
+		{`[1,2] | walk(if type == "number" then . * 10 else . end)`, `[[10,20]]`},
+		{`{a: 1} * {a: {b: 2}}`, `[{"a":{"b":2}}]`},
+		{`[1,2,3] - [2]`, `[[1,3]]`},
+		{`"abc" | .[1:]`, `["bc"]`},
+		{`(1,2) + (10,20)`, `[11,12,21,22]`},
+		{`{a: (1,2)}`, `[{"a":1},{"a":2}]`},
+		{`[.meta[]?]`, `[[3,null]]`},
+		{`.meta.count | .[0]?`, `[]`},
+		{`try error("x") catch .`, `["x"]`},
+		{`[.orders[] | try (if .price < 50 then error("cheap") else .id end) catch "err"]`, `[[1,2,"err"]]`},
+		{`.meta | tojson | fromjson`, `[{"count":3,"source":null}]`},
+		{`[3,1,2] | sort`, `[[1,2,3]]`},
+		{`[null,true,false,1,"a",[],{}] | sort`, `[[null,false,true,1,"a",[],{}]]`},
+		{`1 / 3 | . * 3`, `[1]`},
+		{`-(1 + 2)`, `[-3]`},
+		{`[.orders[].id] | any(. > 2), all(. > 2)`, `[true,false]`},
+		{`.orders | INDEX(.id) | keys`, `[["1","2","3"]]`},
+		{`.orders[0].tags | @csv`, `["\"a\",\"b\""]`},
+		{`"x" | @base64 | @base64d`, `["x"]`},
+		{`[.orders[].customer.city] | IN(["Paris"]; .)`, `[false]`},
+		{`$ENV | type`, `["object"]`},
+		{`[splits]?`, ""},
+	}
+	data := parseJSON(input)
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := jq.Compile(tc.query)
+			if tc.want == "" {
+				assert.ErrorIs(t, err, jq.ErrInvalidExpression)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			got, err := q.Run(context.Background(), data)
+			if assert.NoError(t, err) {
+				assert.Equal(t, parseJSON(tc.want), any(got))
+			}
+		})
+	}
+}
+
+func TestRunLikeJQ(t *testing.T) {
+	cases := []struct {
+		query string
+		input string
+		want  string // JSON array of all outputs as printed by jq 1.6
+	}{
+		{`@uri`, `"a b&c=d/é~"`, `["a%20b%26c%3Dd%2F%C3%A9~"]`},
+		{`.[1:] |= map(. * 10)`, `[1,2,3]`, `[[1,20,30]]`},
+		{`.[1:] = ["x"]`, `[1,2,3]`, `[[1,"x"]]`},
+		{`.[:-1] += ["x"]`, `[1,2,3]`, `[[1,2,"x",3]]`},
+		{`.[1:2] = []`, `null`, `[[]]`},
+		{`del(.[1:])`, `[1,2,3]`, `[[1]]`},
+		{`index(2)`, `[1,2,3,2]`, `[1]`},
+		{`rindex(2)`, `[1,2,3,2]`, `[3]`},
+		{`index(5)`, `[1,2]`, `[null]`},
+		{`indices(2)`, `[1,2,3,2]`, `[[1,3]]`},
+		{`indices([1,2])`, `[0,1,2,1,2]`, `[[1,3]]`},
+		{`.[[2]]`, `[1,2,3,2]`, `[[1,3]]`},
+		{`indices("aa")`, `"aaaé aa"`, `[[0,1,5]]`},
+		{`index("é"), rindex("a")`, `"aéa"`, `[1,2]`},
+		{`to_entries`, `["a","b"]`, `[[{"key":0,"value":"a"},{"key":1,"value":"b"}]]`},
+		{`with_entries(.value += 1)`, `[1,2]`, `[{"0":2,"1":3}]`},
+		{`nan < 1, nan > 1, nan == nan`, `null`, `[true,false,false]`},
+		{`[1, nan, 0] | sort`, `null`, `[[null,0,1]]`},
+		{`infinite, -infinite, nan`, `null`, `[1.7976931348623157e+308,-1.7976931348623157e+308,null]`},
+		{`[1, nan, {a: infinite}]`, `null`, `[[1,null,{"a":1.7976931348623157e+308}]]`},
+		{`[nan] | tojson`, `null`, `["[null]"]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := jq.Compile(tc.query)
+			if !assert.NoError(t, err) {
+				return
+			}
+			got, err := q.Run(context.Background(), parseJSON(tc.input))
+			if assert.NoError(t, err) {
+				assert.Equal(t, parseJSON(tc.want), any(got))
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		input string
+		want  string
+	}{
+		{`.a`, `[1]`, `cannot index array with "a"`},
+		{`.[]`, `1`, `cannot iterate over number (1)`},
+		{`1 + "a"`, `null`, `number (1) and string ("a") cannot be added`},
+		{`1 / 0`, `null`, `number (1) and number (0) cannot be divided because the divisor is zero`},
+		{`error("boom")`, `null`, `boom`},
+		{`error({"a": 1})`, `null`, `{"a":1} (not a string)`},
+		{`del(1)`, `{}`, `invalid path expression`},
+		{`$x`, `null`, `$x is not defined`},
+		{`.[1:] = 1`, `[1,2]`, `a slice of an array can only be assigned another array`},
+		{`indices(1)`, `1`, `cannot index number with number`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := jq.Compile(tc.query)
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = q.Run(context.Background(), parseJSON(tc.input))
+			var e *jq.Error
+			if assert.ErrorAs(t, err, &e) {
+				assert.Equal(t, tc.want, e.Error())
+			}
+		})
+	}
+	t.Run("should stop when context is canceled", func(t *testing.T) {
+		q, err := jq.Compile(`[range(1e12)] | length`)
+		if !assert.NoError(t, err) {
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = q.Run(ctx, nil)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestCompile(t *testing.T) {
+	invalid := []string{
+		`.[`,
+		`.a |`,
+		`{a: 1`,
+		`"abc`,
+		`foo`,
+		`map`,
+		`map(.; .)`,
+		`if . then 1`,
+		`def f: 1; f`,
+		`1 == 2 == 3`,
+		`@foo`,
+		`reduce .[] as $x (0)`,
+		`. as x | .`,
+		`{1: 2}`,
+		`"\q"`,
+		`.a ]`,
+	}
+	for _, s := range invalid {
+		t.Run(s, func(t *testing.T) {
+			_, err := jq.Compile(s)
+			assert.ErrorIs(t, err, jq.ErrInvalidExpression)
+		})
+	}
+	unsupported := []struct {
+		query string
+		want  string
+	}{
+		{`def f: .a; f`, "unsupported: function definitions (def) at position 1"},
+		{`.x | def f: 1; f`, "unsupported: function definitions (def) at position 6"},
+		{`. as [$a, $b] | $a`, "unsupported: destructuring (as [...) at position 6"},
+		{`. as {a: $a} | $a`, "unsupported: destructuring (as {...) at position 6"},
+		{`reduce .[] as [$a] (0; . + $a)`, "unsupported: destructuring (as [...) at position 15"},
+		{`@base64 "id: \(.x)"`, "unsupported: format strings (@base64 \"...\") at position 1"},
+		{`label $out | 1`, "unsupported: labels (label) at position 1"},
+		{`import "a" as a; .`, "unsupported: modules (import) at position 1"},
+	}
+	for _, tc := range unsupported {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := jq.Compile(tc.query)
+			assert.ErrorIs(t, err, jq.ErrInvalidExpression)
+			assert.ErrorIs(t, err, jq.ErrUnsupported)
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+	t.Run("should return original expression", func(t *testing.T) {
+		q, err := jq.Compile(` .a | map(.b) `)
+		if assert.NoError(t, err) {
+			assert.Equal(t, " .a | map(.b) ", q.String())
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package jq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind uint8
+
+const (
+	tokenEOF    tokenKind = iota
+	tokenIdent            // name or keyword
+	tokenField            // .name
+	tokenVar              // $name
+	tokenNumber           // 1.5
+	tokenString           // "text \(expr)"
+	tokenFormat           // @base64
+	tokenOp               // punctuation and operators
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	num   float64
+	parts []stringPart // only for strings
+	pos   int
+}
+
+// stringPart is either a literal text or the tokens of an interpolated expression.
+type stringPart struct {
+	text   string
+	tokens []token
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return strconv.FormatFloat(t.num, 'g', -1, 64)
+	case tokenField:
+		return "." + t.text
+	case tokenVar:
+		return "$" + t.text
+	case tokenFormat:
+		return "@" + t.text
+	}
+	return t.text
+}
+
+// Operators sorted by length, so that the longest operator is matched first.
+var operators = []string{
+	"//=",
+	"|=", "+=", "-=", "*=", "/=", "%=", "==", "!=", "<=", ">=", "//", "..",
+	"|", ",", ".", "[", "]", "(", ")", "{", "}", ":", ";", "=", "<", ">", "+", "-", "*", "/", "%", "?",
+}
+
+type lexer struct {
+	s   string
+	pos int
+}
+
+func (l *lexer) errorf(pos int, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidExpression, fmt.Sprintf(format, args...), pos+1)
+}
+
+// tokenize returns all tokens of an expression.
+func tokenize(s string) ([]token, error) {
+	l := &lexer{s: s}
+	var tokens []token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+		if t.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func isIdentChar(c byte, first bool) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+func (l *lexer) skipSpaceAndComments() {
+	for l.pos < len(l.s) {
+		c := l.s[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.s) && l.s[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) ident() string {
+	start := l.pos
+	for l.pos < len(l.s) && isIdentChar(l.s[l.pos], l.pos == start) {
+		l.pos++
+	}
+	return l.s[start:l.pos]
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpaceAndComments()
+	start := l.pos
+	if l.pos >= len(l.s) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+	c := l.s[l.pos]
+	switch {
+	case c == '"':
+		parts, err := l.lexString()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokenString, parts: parts, pos: start}, nil
+	case c >= '0' && c <= '9' || (c == '.' && l.pos+1 < len(l.s) && l.s[l.pos+1] >= '0' && l.s[l.pos+1] <= '9'):
+		return l.lexNumber()
+	case c == '.' && l.pos+1 < len(l.s) && isIdentChar(l.s[l.pos+1], true):
+		l.pos++
+		return token{kind: tokenField, text: l.ident(), pos: start}, nil
+	case c == '$' || c == '@':
+		l.pos++
+		name := l.ident()
+		if name == "" {
+			return token{}, l.errorf(start, "name expected after %c", c)
+		}
+		if c == '$' {
+			return token{kind: tokenVar, text: name, pos: start}, nil
+		}
+		return token{kind: tokenFormat, text: name, pos: start}, nil
+	case isIdentChar(c, true):
+		return token{kind: tokenIdent, text: l.ident(), pos: start}, nil
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.s[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokenOp, text: op, pos: start}, nil
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(l.s[l.pos:])
+	return token{}, l.errorf(start, "unexpected character %q", r)
+}
+
+func (l *lexer) lexNumber() (token, error) {
+	start := l.pos
+	for l.pos < len(l.s) && (l.s[l.pos] >= '0' && l.s[l.pos] <= '9' || l.s[l.pos] == '.') {
+		l.pos++
+	}
+	if l.pos < len(l.s) && (l.s[l.pos] == 'e' || l.s[l.pos] == 'E') {
+		l.pos++
+		if l.pos < len(l.s) && (l.s[l.pos] == '+' || l.s[l.pos] == '-') {
+			l.pos++
+		}
+		for l.pos < len(l.s) && l.s[l.pos] >= '0' && l.s[l.pos] <= '9' {
+			l.pos++
+		}
+	}
+	x, err := strconv.ParseFloat(l.s[start:l.pos], 64)
+	if err != nil {
+		return token{}, l.errorf(start, "invalid number %s", l.s[start:l.pos])
+	}
+	return token{kind: tokenNumber, num: x, pos: start}, nil
+}
+
+// lexString lexes a string literal with interpolations like "\(.name)".
+func (l *lexer) lexString() ([]stringPart, error) {
+	start := l.pos
+	l.pos++ // opening quote
+	var parts []stringPart
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.s) {
+			return nil, l.errorf(start, "unterminated string")
+		}
+		c := l.s[l.pos]
+		if c == '"' {
+			l.pos++
+			if sb.Len() > 0 || len(parts) == 0 {
+				parts = append(parts, stringPart{text: sb.String()})
+			}
+			return parts, nil
+		}
+		if c != '\\' {
+			sb.WriteByte(c)
+			l.pos++
+			continue
+		}
+		l.pos++
+		if l.pos >= len(l.s) {
+			return nil, l.errorf(start, "unterminated string")
+		}
+		c = l.s[l.pos]
+		l.pos++
+		switch c {
+		case '"', '\\', '/':
+			sb.WriteByte(c)
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'u':
+			if l.pos+4 > len(l.s) {
+				return nil, l.errorf(l.pos, "invalid unicode escape")
+			}
+			x, err := strconv.ParseUint(l.s[l.pos:l.pos+4], 16, 32)
+			if err != nil {
+				return nil, l.errorf(l.pos, "invalid unicode escape")
+			}
+			l.pos += 4
+			r := rune(x)
+			if r >= 0xD800 && r <= 0xDBFF && strings.HasPrefix(l.s[l.pos:], `\u`) && l.pos+6 <= len(l.s) {
+				if y, err := strconv.ParseUint(l.s[l.pos+2:l.pos+6], 16, 32); err == nil && y >= 0xDC00 && y <= 0xDFFF {
+					r = 0x10000 + (r-0xD800)<<10 + (rune(y) - 0xDC00)
+					l.pos += 6
+				}
+			}
+			sb.WriteRune(r)
+		case '(':
+			if sb.Len() > 0 {
+				parts = append(parts, stringPart{text: sb.String()})
+				sb.Reset()
+			}
+			tokens, err := l.lexInterpolation()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, stringPart{tokens: tokens})
+		default:
+			return nil, l.errorf(l.pos-2, "invalid escape sequence \\%c", c)
+		}
+	}
+}
+
+// lexInterpolation returns the tokens of an interpolated expression up to the closing parenthesis.
+func (l *lexer) lexInterpolation() ([]token, error) {
+	start := l.pos
+	var tokens []token
+	depth := 0
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.kind == tokenEOF:
+			return nil, l.errorf(start, "unterminated interpolation")
+		case t.kind == tokenOp && t.text == "(":
+			depth++
+		case t.kind == tokenOp && t.text == ")":
+			if depth == 0 {
+				return append(tokens, token{kind: tokenEOF, pos: t.pos}), nil
+			}
+			depth--
+		}
+		tokens = append(tokens, t)
+	}
+}
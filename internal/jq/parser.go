@@ -0,0 +1,728 @@
+package jq
+
+import (
+	"fmt"
+	"slices"
+)
+
+// node is a node of the syntax tree of a query.
+type node any
+
+type (
+	identityNode struct{}
+	recurseNode  struct{}            // ..
+	literalNode  struct{ value any } // 1, "text", null
+	fieldNode    struct {
+		target node
+		name   string
+	} // .name
+	indexNode   struct{ target, index node }    // .[expr]
+	sliceNode   struct{ target, from, to node } // .[from:to], from and to can be nil
+	iterateNode struct{ target node }           // .[]
+	stringNode  struct{ parts []node }          // "text \(expr)"
+	formatNode  struct{ name string }           // @base64
+	arrayNode   struct{ body node }             // [expr], body can be nil
+	objectNode  struct{ entries []objectEntry } // {key: expr}
+	pipeNode    struct{ left, right node }      // a | b
+	commaNode   struct{ left, right node }      // a, b
+	negateNode  struct{ x node }                // -a
+	binaryNode  struct {
+		op          string
+		left, right node
+	} // a + b, a == b, a and b
+	alternateNode struct{ left, right node } // a // b
+	assignNode    struct {
+		op          string
+		left, right node
+	} // a = b, a |= b, a += b
+	ifNode     struct{ cond, then, otherwise node }
+	tryNode    struct{ body, catch node } // catch can be nil
+	reduceNode struct {
+		source       node
+		name         string
+		init, update node
+	}
+	foreachNode struct {
+		source                node
+		name                  string
+		init, update, extract node // extract can be nil
+	}
+	bindNode struct { // source as $name | body
+		source node
+		name   string
+		body   node
+	}
+	varNode  struct{ name string }
+	callNode struct {
+		name string
+		args []node
+	}
+)
+
+type objectEntry struct {
+	key, value node
+}
+
+var keywords = []string{
+	"as", "and", "catch", "def", "elif", "else", "end", "foreach", "if", "import", "include",
+	"label", "or", "reduce", "then", "try",
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokenOp && t.text == op
+}
+
+func (p *parser) isKeyword(name string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && t.text == name
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	pos := p.peek().pos
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidExpression, fmt.Sprintf(format, args...), pos+1)
+}
+
+// unsupportedf returns an error for a jq feature, which is not supported.
+func (p *parser) unsupportedf(format string, args ...any) error {
+	pos := p.peek().pos
+	return fmt.Errorf("%w: %w: %s at position %d", ErrInvalidExpression, ErrUnsupported, fmt.Sprintf(format, args...), pos+1)
+}
+
+func (p *parser) expectOp(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q but found %s", op, p.peek())
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) expectKeyword(name string) error {
+	if !p.isKeyword(name) {
+		return p.errorf("expected %q but found %s", name, p.peek())
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) parseAll() (node, error) {
+	if p.peek().kind == tokenEOF {
+		return identityNode{}, nil
+	}
+	n, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", t)
+	}
+	return n, nil
+}
+
+// parsePipe parses the lowest precedence level: pipes and variable bindings.
+func (p *parser) parsePipe() (node, error) {
+	switch {
+	case p.isKeyword("def"):
+		return nil, p.unsupportedf("function definitions (def)")
+	case p.isKeyword("label"):
+		return nil, p.unsupportedf("labels (label)")
+	case p.isKeyword("import"), p.isKeyword("include"):
+		return nil, p.unsupportedf("modules (%s)", p.peek().text)
+	}
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	if p.isKeyword("as") {
+		p.advance()
+		name, err := p.parseBindingVar()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOp("|"); err != nil {
+			return nil, err
+		}
+		body, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return bindNode{source: left, name: name, body: body}, nil
+	}
+	if p.isOp("|") {
+		p.advance()
+		right, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return pipeNode{left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseComma() (node, error) {
+	left, err := p.parseAlternate()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(",") {
+		p.advance()
+		right, err := p.parseAlternate()
+		if err != nil {
+			return nil, err
+		}
+		left = commaNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAlternate() (node, error) {
+	left, err := p.parseAssign()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("//") {
+		p.advance()
+		right, err := p.parseAlternate()
+		if err != nil {
+			return nil, err
+		}
+		return alternateNode{left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+var assignOps = []string{"=", "|=", "+=", "-=", "*=", "/=", "%=", "//="}
+
+func (p *parser) parseAssign() (node, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokenOp && slices.Contains(assignOps, t.text) {
+		p.advance()
+		right, err := p.parseAlternate()
+		if err != nil {
+			return nil, err
+		}
+		return assignNode{op: t.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.advance()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+var comparisonOps = []string{"==", "!=", "<", "<=", ">", ">="}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokenOp && slices.Contains(comparisonOps, t.text) {
+		p.advance()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t.kind == tokenOp && slices.Contains(comparisonOps, t.text) {
+			return nil, p.errorf("comparisons can not be chained")
+		}
+		return binaryNode{op: t.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.advance().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.advance().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("-") {
+		p.advance()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := x.(literalNode); ok {
+			if f, ok := l.value.(float64); ok {
+				return literalNode{value: -f}, nil
+			}
+		}
+		return negateNode{x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix parses a term followed by suffixes like .name, [expr], [] and ?.
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch t := p.peek(); {
+		case t.kind == tokenField:
+			p.advance()
+			n = fieldNode{target: n, name: t.text}
+		case t.kind == tokenOp && t.text == "." && p.tokens[p.pos+1].kind == tokenString:
+			p.advance()
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			n = indexNode{target: n, index: key}
+		case t.kind == tokenOp && t.text == "." && p.tokens[p.pos+1].kind == tokenOp && p.tokens[p.pos+1].text == "[":
+			p.advance()
+		case t.kind == tokenOp && t.text == "[":
+			n, err = p.parseBracketSuffix(n)
+			if err != nil {
+				return nil, err
+			}
+		case t.kind == tokenOp && t.text == "?":
+			p.advance()
+			n = tryNode{body: n}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// parseBracketSuffix parses the suffixes [], [expr] and [from:to].
+func (p *parser) parseBracketSuffix(target node) (node, error) {
+	p.advance() // [
+	if p.isOp("]") {
+		p.advance()
+		return iterateNode{target: target}, nil
+	}
+	var from node
+	if !p.isOp(":") {
+		var err error
+		from, err = p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if p.isOp("]") {
+			p.advance()
+			return indexNode{target: target, index: from}, nil
+		}
+	}
+	if err := p.expectOp(":"); err != nil {
+		return nil, err
+	}
+	var to node
+	if !p.isOp("]") {
+		var err error
+		to, err = p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+	} else if from == nil {
+		return nil, p.errorf("slice needs at least one bound")
+	}
+	if err := p.expectOp("]"); err != nil {
+		return nil, err
+	}
+	return sliceNode{target: target, from: from, to: to}, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.advance()
+		return literalNode{value: t.num}, nil
+	case tokenString:
+		return p.parseString()
+	case tokenFormat:
+		p.advance()
+		if p.peek().kind == tokenString {
+			p.pos--
+			return nil, p.unsupportedf("format strings (@%s \"...\")", t.text)
+		}
+		if !slices.Contains(formats, t.text) {
+			p.pos--
+			return nil, p.errorf("unknown format @%s", t.text)
+		}
+		return formatNode{name: t.text}, nil
+	case tokenField:
+		p.advance()
+		return fieldNode{target: identityNode{}, name: t.text}, nil
+	case tokenVar:
+		p.advance()
+		return varNode{name: t.text}, nil
+	case tokenIdent:
+		return p.parseIdent()
+	case tokenOp:
+		switch t.text {
+		case ".":
+			p.advance()
+			if p.peek().kind == tokenString {
+				key, err := p.parseString()
+				if err != nil {
+					return nil, err
+				}
+				return indexNode{target: identityNode{}, index: key}, nil
+			}
+			return identityNode{}, nil
+		case "..":
+			p.advance()
+			return recurseNode{}, nil
+		case "(":
+			p.advance()
+			n, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			p.advance()
+			if p.isOp("]") {
+				p.advance()
+				return arrayNode{}, nil
+			}
+			n, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			return arrayNode{body: n}, nil
+		case "{":
+			return p.parseObject()
+		}
+	}
+	return nil, p.errorf("unexpected %s", t)
+}
+
+func (p *parser) parseString() (node, error) {
+	t := p.advance()
+	if len(t.parts) == 1 && t.parts[0].tokens == nil {
+		return literalNode{value: t.parts[0].text}, nil
+	}
+	parts := make([]node, 0, len(t.parts))
+	for _, part := range t.parts {
+		if part.tokens == nil {
+			parts = append(parts, literalNode{value: part.text})
+			continue
+		}
+		sub := &parser{tokens: part.tokens}
+		n, err := sub.parseAll()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, n)
+	}
+	return stringNode{parts: parts}, nil
+}
+
+func (p *parser) parseIdent() (node, error) {
+	t := p.advance()
+	switch t.text {
+	case "true":
+		return literalNode{value: true}, nil
+	case "false":
+		return literalNode{value: false}, nil
+	case "null":
+		return literalNode{value: nil}, nil
+	case "if":
+		return p.parseIf()
+	case "try":
+		body, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isKeyword("catch") {
+			return tryNode{body: body}, nil
+		}
+		p.advance()
+		catch, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return tryNode{body: body, catch: catch}, nil
+	case "reduce", "foreach":
+		return p.parseReduce(t.text)
+	}
+	if slices.Contains(keywords, t.text) {
+		p.pos--
+		return nil, p.errorf("unexpected keyword %q", t.text)
+	}
+	n := callNode{name: t.text}
+	if p.isOp("(") {
+		p.advance()
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, arg)
+			if !p.isOp(";") {
+				break
+			}
+			p.advance()
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+	}
+	if !hasBuiltin(n.name, len(n.args)) {
+		p.pos--
+		return nil, fmt.Errorf("%w: unknown function %s/%d at position %d", ErrInvalidExpression, n.name, len(n.args), t.pos+1)
+	}
+	return n, nil
+}
+
+func (p *parser) parseIf() (node, error) {
+	cond, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	n := ifNode{cond: cond, then: then, otherwise: identityNode{}}
+	switch {
+	case p.isKeyword("elif"):
+		p.advance()
+		n.otherwise, err = p.parseIf()
+		return n, err
+	case p.isKeyword("else"):
+		p.advance()
+		n.otherwise, err = p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectKeyword("end"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// parseReduce parses reduce and foreach expressions, e.g. reduce .[] as $x (0; . + $x).
+func (p *parser) parseReduce(keyword string) (node, error) {
+	source, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("as"); err != nil {
+		return nil, err
+	}
+	name, err := p.parseBindingVar()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectOp("("); err != nil {
+		return nil, err
+	}
+	var args []node
+	for {
+		arg, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.isOp(";") {
+			break
+		}
+		p.advance()
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	if keyword == "reduce" {
+		if len(args) != 2 {
+			return nil, p.errorf("reduce needs 2 arguments")
+		}
+		return reduceNode{source: source, name: name, init: args[0], update: args[1]}, nil
+	}
+	n := foreachNode{source: source, name: name}
+	switch len(args) {
+	case 3:
+		n.extract = args[2]
+		fallthrough
+	case 2:
+		n.init, n.update = args[0], args[1]
+	default:
+		return nil, p.errorf("foreach needs 2 or 3 arguments")
+	}
+	return n, nil
+}
+
+// parseBindingVar parses the variable after "as" and returns its name.
+func (p *parser) parseBindingVar() (string, error) {
+	if p.isOp("[") || p.isOp("{") {
+		return "", p.unsupportedf("destructuring (as %s...)", p.peek().text)
+	}
+	t := p.peek()
+	if t.kind != tokenVar {
+		return "", p.errorf("variable expected after \"as\"")
+	}
+	p.advance()
+	return t.text, nil
+}
+
+// parseObject parses object constructions like {a, "b": 1, (.c): .d, $x}.
+func (p *parser) parseObject() (node, error) {
+	p.advance() // {
+	var n objectNode
+	for !p.isOp("}") {
+		var e objectEntry
+		t := p.peek()
+		switch {
+		case t.kind == tokenVar:
+			p.advance()
+			e = objectEntry{key: literalNode{value: t.text}, value: varNode{name: t.text}}
+		case t.kind == tokenIdent:
+			p.advance()
+			e.key = literalNode{value: t.text}
+		case t.kind == tokenNumber:
+			return nil, p.errorf("object keys must be strings")
+		case t.kind == tokenString:
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			e.key = key
+		case t.kind == tokenFormat:
+			p.advance()
+			e.key = formatNode{name: t.text}
+		case t.kind == tokenOp && t.text == "(":
+			p.advance()
+			key, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			e.key = key
+			if !p.isOp(":") {
+				return nil, p.errorf("expected \":\" after computed key")
+			}
+		default:
+			return nil, p.errorf("unexpected %s in object", t)
+		}
+		switch {
+		case e.value != nil:
+		case p.isOp(":"):
+			p.advance()
+			v, err := p.parseObjectValue()
+			if err != nil {
+				return nil, err
+			}
+			e.value = v
+		default:
+			e.value = indexNode{target: identityNode{}, index: e.key}
+		}
+		n.entries = append(n.entries, e)
+		if !p.isOp(",") {
+			break
+		}
+		p.advance()
+	}
+	if err := p.expectOp("}"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// parseObjectValue parses the value of an object entry, which may contain pipes but no commas.
+func (p *parser) parseObjectValue() (node, error) {
+	left, err := p.parseAlternate()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("|") {
+		p.advance()
+		right, err := p.parseObjectValue()
+		if err != nil {
+			return nil, err
+		}
+		return pipeNode{left: left, right: right}, nil
+	}
+	return left, nil
+}
@@ -0,0 +1,242 @@
+package jq
+
+import "slices"
+
+// pathEmitFunc receives a path and the value at that path.
+type pathEmitFunc func(path []any, v any) error
+
+// paths returns all paths an expression refers to, e.g. [["a", 0]] for .a[0].
+func (ev *evaluator) paths(n node, input any, e *env) ([][]any, error) {
+	var paths [][]any
+	err := ev.evalPaths(n, input, nil, e, func(p []any, _ any) error {
+		paths = append(paths, p)
+		return nil
+	})
+	return paths, err
+}
+
+// evalPaths evaluates a path expression and calls emit for every path.
+// Only expressions which refer to parts of the input are allowed, e.g. .a[0] or .[] | select(.b).
+func (ev *evaluator) evalPaths(n node, input any, path []any, e *env, emit pathEmitFunc) error {
+	if err := ev.checkCanceled(); err != nil {
+		return err
+	}
+	extend := func(k any) []any {
+		return append(slices.Clip(path), k)
+	}
+	switch n := n.(type) {
+	case identityNode:
+		return emit(path, input)
+	case recurseNode:
+		return ev.recursePaths(input, path, emit)
+	case fieldNode:
+		return ev.evalPaths(n.target, input, path, e, func(p []any, v any) error {
+			x, err := index(v, n.name)
+			if err != nil {
+				return err
+			}
+			return emit(append(slices.Clip(p), n.name), x)
+		})
+	case indexNode:
+		return ev.evalPaths(n.target, input, path, e, func(p []any, v any) error {
+			return ev.eval(n.index, input, e, func(k any) error {
+				x, err := index(v, k)
+				if err != nil {
+					return err
+				}
+				return emit(append(slices.Clip(p), k), x)
+			})
+		})
+	case sliceNode:
+		return ev.evalPaths(n.target, input, path, e, func(p []any, v any) error {
+			return ev.evalOptional(n.to, input, e, func(to any) error {
+				return ev.evalOptional(n.from, input, e, func(from any) error {
+					x, err := slice(v, from, to)
+					if err != nil {
+						return err
+					}
+					return emit(append(slices.Clip(p), map[string]any{"start": from, "end": to}), x)
+				})
+			})
+		})
+	case iterateNode:
+		return ev.evalPaths(n.target, input, path, e, func(p []any, v any) error {
+			if v == nil {
+				return nil
+			}
+			return iterate(v, func(k any, x any) error {
+				return emit(append(slices.Clip(p), k), x)
+			})
+		})
+	case pipeNode:
+		return ev.evalPaths(n.left, input, path, e, func(p []any, v any) error {
+			return ev.evalPaths(n.right, v, p, e, emit)
+		})
+	case commaNode:
+		if err := ev.evalPaths(n.left, input, path, e, emit); err != nil {
+			return err
+		}
+		return ev.evalPaths(n.right, input, path, e, emit)
+	case ifNode:
+		return ev.eval(n.cond, input, e, func(v any) error {
+			if isTruthy(v) {
+				return ev.evalPaths(n.then, input, path, e, emit)
+			}
+			return ev.evalPaths(n.otherwise, input, path, e, emit)
+		})
+	case alternateNode:
+		var found bool
+		err := ev.evalPaths(n.left, input, path, e, func(p []any, v any) error {
+			if !isTruthy(v) {
+				return nil
+			}
+			found = true
+			if err := emit(p, v); err != nil {
+				return &passError{err}
+			}
+			return nil
+		})
+		switch err := err.(type) {
+		case *passError:
+			return err.err
+		case *Error, nil:
+		default:
+			return err
+		}
+		if found {
+			return nil
+		}
+		return ev.evalPaths(n.right, input, path, e, emit)
+	case tryNode:
+		err := ev.evalPaths(n.body, input, path, e, func(p []any, v any) error {
+			if err := emit(p, v); err != nil {
+				return &passError{err}
+			}
+			return nil
+		})
+		switch err := err.(type) {
+		case *passError:
+			return err.err
+		case *Error:
+			if n.catch != nil {
+				return errorf("invalid path expression with catch")
+			}
+			return nil
+		}
+		return err
+	case bindNode:
+		return ev.eval(n.source, input, e, func(v any) error {
+			return ev.evalPaths(n.body, input, path, e.bind(n.name, v), emit)
+		})
+	case literalNode:
+		if n.value == nil {
+			return emit(path, nil)
+		}
+	case callNode:
+		return ev.callPaths(n, input, path, e, extend, emit)
+	}
+	return errorf("invalid path expression")
+}
+
+// callPaths evaluates builtin functions which are valid in path expressions.
+func (ev *evaluator) callPaths(n callNode, input any, path []any, e *env, extend func(k any) []any, emit pathEmitFunc) error {
+	switch key(n.name, len(n.args)) {
+	case "empty/0":
+		return nil
+	case "select/1":
+		return ev.eval(n.args[0], input, e, func(v any) error {
+			if isTruthy(v) {
+				return emit(path, input)
+			}
+			return nil
+		})
+	case "recurse/0":
+		return ev.recursePaths(input, path, emit)
+	case "recurse/1":
+		return ev.recursePathsWith(n.args[0], input, path, e, emit)
+	case "first/1":
+		stop := newStop()
+		err := ev.evalPaths(n.args[0], input, path, e, func(p []any, v any) error {
+			if err := emit(p, v); err != nil {
+				return err
+			}
+			return stop
+		})
+		return catchStop(err, stop)
+	case "last/1":
+		var last []any
+		var value any
+		var found bool
+		err := ev.evalPaths(n.args[0], input, path, e, func(p []any, v any) error {
+			last, value, found = p, v, true
+			return nil
+		})
+		if err != nil || !found {
+			return err
+		}
+		return emit(last, value)
+	case "getpath/1":
+		return ev.eval(n.args[0], input, e, func(v any) error {
+			p, ok := v.([]any)
+			if !ok {
+				return errorf("path must be specified as an array")
+			}
+			x, err := getPath(input, p)
+			if err != nil {
+				return err
+			}
+			return emit(slices.Concat(path, p), x)
+		})
+	case "first/0":
+		x, err := index(input, 0.0)
+		if err != nil {
+			return err
+		}
+		return emit(extend(0.0), x)
+	case "last/0":
+		a, ok := input.([]any)
+		if !ok {
+			return errorf("cannot index %s with number", typeName(input))
+		}
+		i := float64(len(a) - 1)
+		x, _ := index(input, i)
+		return emit(extend(i), x)
+	case "values/0", "nulls/0", "booleans/0", "numbers/0", "strings/0",
+		"arrays/0", "objects/0", "iterables/0", "scalars/0":
+		var ok bool
+		err := ev.call(n, input, e, func(any) error {
+			ok = true
+			return nil
+		})
+		if err != nil || !ok {
+			return err
+		}
+		return emit(path, input)
+	}
+	return errorf("invalid path expression with %s/%d", n.name, len(n.args))
+}
+
+func (ev *evaluator) recursePaths(v any, path []any, emit pathEmitFunc) error {
+	if err := ev.checkCanceled(); err != nil {
+		return err
+	}
+	if err := emit(path, v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case []any, map[string]any:
+		return iterate(v, func(k any, x any) error {
+			return ev.recursePaths(x, append(slices.Clip(path), k), emit)
+		})
+	}
+	return nil
+}
+
+func (ev *evaluator) recursePathsWith(f node, v any, path []any, e *env, emit pathEmitFunc) error {
+	if err := emit(path, v); err != nil {
+		return err
+	}
+	return ev.evalPaths(f, v, path, e, func(p []any, x any) error {
+		return ev.recursePathsWith(f, x, p, e, emit)
+	})
+}
@@ -0,0 +1,655 @@
+package jq
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Max length of values shown in error messages
+const maxErrorValueLength = 30
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func isTruthy(v any) bool {
+	return v != nil && v != false
+}
+
+// describe returns a short description of a value for error messages, e.g. "number (42)".
+func describe(v any) string {
+	return fmt.Sprintf("%s (%s)", typeName(v), truncatedJSON(v))
+}
+
+func truncatedJSON(v any) string {
+	s := toJSON(v)
+	if len(s) > maxErrorValueLength {
+		s = s[:maxErrorValueLength-3] + "..."
+	}
+	return s
+}
+
+func toJSON(v any) string {
+	if f, ok := v.(float64); ok {
+		return formatNumber(f)
+	}
+	v, _ = jsonValue(v)
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
+
+// jsonValue returns v with the numbers replaced, which can not be represented in JSON.
+// Like in jq nan becomes null and infinite numbers become the largest finite numbers.
+// Arrays and objects are copied when they contain such numbers and changed is set.
+func jsonValue(v any) (_ any, changed bool) {
+	switch v := v.(type) {
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return nil, true
+		case math.IsInf(v, 1):
+			return math.MaxFloat64, true
+		case math.IsInf(v, -1):
+			return -math.MaxFloat64, true
+		}
+	case []any:
+		var a []any
+		for i, x := range v {
+			y, ok := jsonValue(x)
+			if ok && a == nil {
+				a = slices.Clone(v)
+			}
+			if ok {
+				a[i] = y
+			}
+		}
+		if a != nil {
+			return a, true
+		}
+	case map[string]any:
+		var o map[string]any
+		for k, x := range v {
+			y, ok := jsonValue(x)
+			if ok && o == nil {
+				o = maps.Clone(v)
+			}
+			if ok {
+				o[k] = y
+			}
+		}
+		if o != nil {
+			return o, true
+		}
+	}
+	return v, false
+}
+
+func formatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "null"
+	case math.IsInf(f, 1):
+		return "1.7976931348623157e+308"
+	case math.IsInf(f, -1):
+		return "-1.7976931348623157e+308"
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1e17 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// typeOrder returns the position of a type in the jq sort order.
+func typeOrder(v any) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []any:
+		return 5
+	}
+	return 6
+}
+
+// compare compares two values in jq order and returns -1, 0 or 1.
+// The order is: null, false, true, numbers, strings, arrays, objects.
+func compare(a, b any) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return cmpInt(ta, tb)
+	}
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		// nan is smaller than any number, like in jq
+		switch {
+		case math.IsNaN(a):
+			return -1
+		case math.IsNaN(b):
+			return 1
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case []any:
+		b := b.([]any)
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return cmpInt(len(a), len(b))
+	case map[string]any:
+		b := b.(map[string]any)
+		ka, kb := sortedKeys(a), sortedKeys(b)
+		if c := slices.Compare(ka, kb); c != 0 {
+			return c
+		}
+		for _, k := range ka {
+			if c := compare(a[k], b[k]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func add(l, r any) (any, error) {
+	if l == nil {
+		return r, nil
+	}
+	if r == nil {
+		return l, nil
+	}
+	switch l := l.(type) {
+	case float64:
+		if r, ok := r.(float64); ok {
+			return l + r, nil
+		}
+	case string:
+		if r, ok := r.(string); ok {
+			return l + r, nil
+		}
+	case []any:
+		if r, ok := r.([]any); ok {
+			return slices.Concat(l, r), nil
+		}
+	case map[string]any:
+		if r, ok := r.(map[string]any); ok {
+			o := make(map[string]any, len(l)+len(r))
+			for k, v := range l {
+				o[k] = v
+			}
+			for k, v := range r {
+				o[k] = v
+			}
+			return o, nil
+		}
+	}
+	return nil, errorf("%s and %s cannot be added", describe(l), describe(r))
+}
+
+func subtract(l, r any) (any, error) {
+	switch l := l.(type) {
+	case float64:
+		if r, ok := r.(float64); ok {
+			return l - r, nil
+		}
+	case []any:
+		if r, ok := r.([]any); ok {
+			a := make([]any, 0, len(l))
+			for _, x := range l {
+				if !slices.ContainsFunc(r, func(y any) bool { return compare(x, y) == 0 }) {
+					a = append(a, x)
+				}
+			}
+			return a, nil
+		}
+	}
+	return nil, errorf("%s and %s cannot be subtracted", describe(l), describe(r))
+}
+
+func multiply(l, r any) (any, error) {
+	switch l := l.(type) {
+	case float64:
+		switch r := r.(type) {
+		case float64:
+			return l * r, nil
+		case string:
+			return repeatString(r, l), nil
+		}
+	case string:
+		if r, ok := r.(float64); ok {
+			return repeatString(l, r), nil
+		}
+	case map[string]any:
+		if r, ok := r.(map[string]any); ok {
+			return deepMerge(l, r), nil
+		}
+	}
+	return nil, errorf("%s and %s cannot be multiplied", describe(l), describe(r))
+}
+
+func repeatString(s string, n float64) any {
+	if n <= 0 {
+		return nil
+	}
+	return strings.Repeat(s, max(1, int(math.Ceil(n))))
+}
+
+func deepMerge(a, b map[string]any) map[string]any {
+	o := make(map[string]any, len(a)+len(b))
+	for k, v := range a {
+		o[k] = v
+	}
+	for k, v := range b {
+		x, ok1 := o[k].(map[string]any)
+		y, ok2 := v.(map[string]any)
+		if ok1 && ok2 {
+			o[k] = deepMerge(x, y)
+		} else {
+			o[k] = v
+		}
+	}
+	return o
+}
+
+func divide(l, r any) (any, error) {
+	switch l := l.(type) {
+	case float64:
+		if r, ok := r.(float64); ok {
+			if r == 0 {
+				return nil, errorf("%s and %s cannot be divided because the divisor is zero", describe(l), describe(r))
+			}
+			return l / r, nil
+		}
+	case string:
+		if r, ok := r.(string); ok {
+			return splitString(l, r), nil
+		}
+	}
+	return nil, errorf("%s and %s cannot be divided", describe(l), describe(r))
+}
+
+func modulo(l, r any) (any, error) {
+	a, ok1 := l.(float64)
+	b, ok2 := r.(float64)
+	if !ok1 || !ok2 {
+		return nil, errorf("%s and %s cannot be divided", describe(l), describe(r))
+	}
+	x, y := int64(a), int64(b)
+	if y == 0 {
+		return nil, errorf("%s and %s cannot be divided because the divisor is zero", describe(l), describe(r))
+	}
+	if y < 0 {
+		y = -y
+	}
+	return float64(x % y), nil
+}
+
+func splitString(s, sep string) []any {
+	a := make([]any, 0)
+	if s == "" {
+		return a
+	}
+	for _, x := range strings.Split(s, sep) {
+		a = append(a, x)
+	}
+	return a
+}
+
+// contains reports whether a contains b, following the rules of the jq contains function.
+func contains(a, b any) (bool, error) {
+	if typeName(a) != typeName(b) {
+		return false, errorf("%s and %s cannot have their containment checked", describe(a), describe(b))
+	}
+	switch a := a.(type) {
+	case string:
+		return strings.Contains(a, b.(string)), nil
+	case []any:
+		for _, y := range b.([]any) {
+			var found bool
+			for _, x := range a {
+				if typeName(x) != typeName(y) {
+					continue
+				}
+				if ok, err := contains(x, y); err == nil && ok {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	case map[string]any:
+		for k, y := range b.(map[string]any) {
+			x, ok := a[k]
+			if !ok || typeName(x) != typeName(y) {
+				return false, nil
+			}
+			if ok, err := contains(x, y); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return compare(a, b) == 0, nil
+}
+
+// Names of supported formats like @base64
+var formats = []string{"text", "json", "html", "uri", "csv", "tsv", "sh", "base64", "base64d"}
+
+// format converts a value to a string with a format like @base64.
+func format(name string, v any) (any, error) {
+	text := func(v any) string {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return toJSON(v)
+	}
+	switch name {
+	case "text":
+		return text(v), nil
+	case "json":
+		return toJSON(v), nil
+	case "html":
+		return html.EscapeString(text(v)), nil
+	case "uri":
+		return escapeURI(text(v)), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(text(v))), nil
+	case "base64d":
+		b, err := base64.StdEncoding.DecodeString(text(v))
+		if err != nil {
+			b, err = base64.RawStdEncoding.DecodeString(text(v))
+			if err != nil {
+				return nil, errorf("%s is not valid base64 data", describe(v))
+			}
+		}
+		return string(b), nil
+	case "sh":
+		quote := func(v any) (string, error) {
+			switch v.(type) {
+			case []any, map[string]any:
+				return "", errorf("%s can not be escaped for shell", describe(v))
+			case string:
+				return "'" + strings.ReplaceAll(text(v), "'", `'\''`) + "'", nil
+			}
+			return text(v), nil
+		}
+		a, ok := v.([]any)
+		if !ok {
+			return quote(v)
+		}
+		parts := make([]string, len(a))
+		for i, x := range a {
+			s, err := quote(x)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, " "), nil
+	case "csv", "tsv":
+		a, ok := v.([]any)
+		if !ok {
+			return nil, errorf("%s cannot be %s-formatted, only an array can be", describe(v), name)
+		}
+		parts := make([]string, len(a))
+		for i, x := range a {
+			switch x := x.(type) {
+			case []any, map[string]any:
+				return nil, errorf("%s is not valid in a %s row", describe(x), name)
+			case string:
+				if name == "csv" {
+					parts[i] = `"` + strings.ReplaceAll(x, `"`, `""`) + `"`
+				} else {
+					parts[i] = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(x)
+				}
+			case nil:
+				parts[i] = ""
+			default:
+				parts[i] = toJSON(x)
+			}
+		}
+		if name == "csv" {
+			return strings.Join(parts, ","), nil
+		}
+		return strings.Join(parts, "\t"), nil
+	}
+	return nil, errorf("%s is not a valid format", name)
+}
+
+// escapeURI percent-encodes all bytes of s except the unreserved characters of RFC 3986.
+func escapeURI(s string) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// getPath returns the value at a path.
+func getPath(v any, path []any) (any, error) {
+	for _, k := range path {
+		if v == nil {
+			return nil, nil
+		}
+		var err error
+		v, err = index(v, k)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// setPath returns a copy of v with the value at path set to x.
+// Missing objects and arrays along the path are created.
+func setPath(v any, path []any, x any) (any, error) {
+	if len(path) == 0 {
+		return x, nil
+	}
+	switch k := path[0].(type) {
+	case string:
+		var o map[string]any
+		switch v := v.(type) {
+		case nil:
+			o = make(map[string]any)
+		case map[string]any:
+			o = make(map[string]any, len(v)+1)
+			for k, x := range v {
+				o[k] = x
+			}
+		default:
+			return nil, errorf("cannot index %s with %q", typeName(v), k)
+		}
+		child, err := setPath(o[k], path[1:], x)
+		if err != nil {
+			return nil, err
+		}
+		o[k] = child
+		return o, nil
+	case float64:
+		var a []any
+		switch v := v.(type) {
+		case nil:
+		case []any:
+			a = slices.Clone(v)
+		default:
+			return nil, errorf("cannot index %s with number", typeName(v))
+		}
+		i := int(k)
+		if i < 0 {
+			i += len(a)
+			if i < 0 {
+				return nil, errorf("out of bounds negative array index")
+			}
+		}
+		for len(a) <= i {
+			a = append(a, nil)
+		}
+		child, err := setPath(a[i], path[1:], x)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = child
+		return a, nil
+	case map[string]any:
+		var a []any
+		switch v := v.(type) {
+		case nil:
+		case []any:
+			a = v
+		default:
+			return nil, errorf("cannot update field at object index of %s", typeName(v))
+		}
+		start, end, err := sliceBounds(len(a), k["start"], k["end"])
+		if err != nil {
+			return nil, err
+		}
+		child, err := setPath(slices.Clone(a[start:end]), path[1:], x)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := child.([]any)
+		if !ok {
+			return nil, errorf("a slice of an array can only be assigned another array")
+		}
+		result := make([]any, 0, len(a)-(end-start)+len(r))
+		result = append(result, a[:start]...)
+		result = append(result, r...)
+		return append(result, a[end:]...), nil
+	}
+	return nil, errorf("invalid path component %s", describe(path[0]))
+}
+
+// deletePaths returns a copy of v with the values at all paths removed.
+func deletePaths(v any, paths [][]any) (any, error) {
+	// delete longest and last paths first, so that array indices of the remaining paths stay valid
+	paths = slices.Clone(paths)
+	slices.SortFunc(paths, func(a, b []any) int {
+		return -compare(a, b)
+	})
+	for _, p := range paths {
+		var err error
+		v, err = deletePath(v, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func deletePath(v any, path []any) (any, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	if v == nil {
+		return nil, nil
+	}
+	k := path[0]
+	if len(path) > 1 {
+		child, err := index(v, k)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			return v, nil
+		}
+		child, err = deletePath(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+		return setPath(v, path[:1], child)
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		k, ok := k.(string)
+		if !ok {
+			return nil, errorf("cannot delete field at %s index of object", typeName(path[0]))
+		}
+		o := make(map[string]any, len(v))
+		for k2, x := range v {
+			if k2 != k {
+				o[k2] = x
+			}
+		}
+		return o, nil
+	case []any:
+		if s, ok := k.(map[string]any); ok {
+			start, end, err := sliceBounds(len(v), s["start"], s["end"])
+			if err != nil {
+				return nil, err
+			}
+			return slices.Delete(slices.Clone(v), start, end), nil
+		}
+		i, ok := k.(float64)
+		if !ok {
+			return nil, errorf("cannot delete field at %s index of array", typeName(k))
+		}
+		n := int(i)
+		if n < 0 {
+			n += len(v)
+		}
+		if n < 0 || n >= len(v) {
+			return v, nil
+		}
+		return slices.Delete(slices.Clone(v), n, n+1), nil
+	}
+	return nil, errorf("cannot delete field of %s", typeName(v))
+}
@@ -0,0 +1,410 @@
+package jsondocument
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// condOp is the operator of a condition.
+type condOp uint8
+
+const (
+	opEqual condOp = iota
+	opNotEqual
+	opLess
+	opLessEqual
+	opGreater
+	opGreaterEqual
+	opMatch
+	opNotMatch
+	opContains
+)
+
+var condOps = map[string]condOp{
+	"=":        opEqual,
+	"==":       opEqual,
+	"!=":       opNotEqual,
+	"<":        opLess,
+	"<=":       opLessEqual,
+	">":        opGreater,
+	">=":       opGreaterEqual,
+	"~":        opMatch,
+	"!~":       opNotMatch,
+	"contains": opContains,
+}
+
+// condition is a test on an object member, e.g. price > 100.
+type condition struct {
+	key        *regexp.Regexp
+	op         condOp
+	value      any            // string, float64, bool or nil
+	pattern    *regexp.Regexp // for match operators
+	ignoreCase bool
+}
+
+// conditionExpr is a search condition in disjunctive normal form,
+// i.e. a node matches when all conditions of at least one group match.
+type conditionExpr [][]condition
+
+// parseCondition parses a search condition like `status = "failed" and price > 100`.
+// Keys can contain wildcards and must be quoted when they contain spaces or operators.
+// Values are JSON literals. Unquoted words are treated as strings.
+// Regular expressions for the match operators are written as /pattern/ with optional flags.
+// And binds stronger than or. Grouping with parentheses is not supported.
+func parseCondition(s string, opts SearchOptions) (conditionExpr, error) {
+	tokens, err := scanCondition(s)
+	if err != nil {
+		return nil, err
+	}
+	invalid := func(t condToken, expected string) error {
+		if t.kind == condTokenEOF {
+			return fmt.Errorf("%w: expected %s at end of condition", ErrInvalidPattern, expected)
+		}
+		return fmt.Errorf("%w: expected %s at position %d", ErrInvalidPattern, expected, t.pos+1)
+	}
+	var expr conditionExpr
+	var group []condition
+	for i := 0; ; {
+		// key
+		t := tokens[i]
+		if t.kind != condTokenWord && t.kind != condTokenString {
+			return nil, invalid(t, "key")
+		}
+		key, err := CompilePattern(t.text, SearchOptions{IgnoreCase: opts.IgnoreCase})
+		if err != nil {
+			return nil, err
+		}
+		c := condition{key: key, ignoreCase: opts.IgnoreCase}
+		i++
+		// operator
+		t = tokens[i]
+		op, ok := condOps[strings.ToLower(t.text)]
+		if !ok || (t.kind != condTokenOperator && t.kind != condTokenWord) {
+			return nil, invalid(t, "operator")
+		}
+		c.op = op
+		i++
+		// value
+		t = tokens[i]
+		switch {
+		case op == opMatch || op == opNotMatch:
+			if t.kind != condTokenRegex && t.kind != condTokenString {
+				return nil, invalid(t, "regular expression")
+			}
+			re := t.text
+			if t.kind == condTokenString {
+				re = regexp.QuoteMeta(re)
+			}
+			if opts.IgnoreCase {
+				re = "(?i)" + re
+			}
+			c.pattern, err = regexp.Compile(re)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPattern, err)
+			}
+		case t.kind == condTokenString:
+			c.value = t.text
+		case t.kind == condTokenWord:
+			c.value = parseConditionWord(t.text)
+		default:
+			return nil, invalid(t, "value")
+		}
+		if (op == opLess || op == opLessEqual || op == opGreater || op == opGreaterEqual) && !isOrdered(c.value) {
+			return nil, fmt.Errorf("%w: operator %s needs a number or string at position %d", ErrInvalidPattern, tokens[i-1].text, t.pos+1)
+		}
+		group = append(group, c)
+		i++
+		// conjunction
+		t = tokens[i]
+		if t.kind == condTokenEOF {
+			expr = append(expr, group)
+			return expr, nil
+		}
+		switch strings.ToLower(t.text) {
+		case "and", "&&":
+		case "or", "||":
+			expr = append(expr, group)
+			group = nil
+		default:
+			return nil, invalid(t, "and or or")
+		}
+		i++
+	}
+}
+
+// parseConditionWord returns the value of an unquoted word.
+func parseConditionWord(s string) any {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	// ParseFloat also accepts words like "inf" and hex numbers, which are no JSON numbers
+	if strings.ContainsAny(strings.ToLower(s), "inxp_") {
+		return s
+	}
+	if x, err := strconv.ParseFloat(s, 64); err == nil {
+		return x
+	}
+	return s
+}
+
+func isOrdered(v any) bool {
+	switch v.(type) {
+	case float64, string:
+		return true
+	}
+	return false
+}
+
+// match reports whether a node is an object with members matching the condition.
+func (expr conditionExpr) match(j *JSONDocument, id int32) bool {
+	if j.values[id].Type != Object {
+		return false
+	}
+	for _, group := range expr {
+		ok := true
+		for _, c := range group {
+			if !c.match(j, id) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether an object has a member matching the condition.
+func (c condition) match(j *JSONDocument, id int32) bool {
+	for _, childID := range j.ids[id] {
+		if c.key.MatchString(j.values[childID].Key) && c.test(j, childID) {
+			return true
+		}
+	}
+	return false
+}
+
+// test reports whether the value of a node fulfills the condition.
+func (c condition) test(j *JSONDocument, id int32) bool {
+	n := j.values[id]
+	switch c.op {
+	case opEqual:
+		return c.equal(n)
+	case opNotEqual:
+		return !c.equal(n)
+	case opLess, opLessEqual, opGreater, opGreaterEqual:
+		var r int
+		switch v := c.value.(type) {
+		case float64:
+			if n.Type != Number {
+				return false
+			}
+			x := n.Value.(float64)
+			switch {
+			case x < v:
+				r = -1
+			case x > v:
+				r = 1
+			}
+		case string:
+			if n.Type != String {
+				return false
+			}
+			r = strings.Compare(n.Value.(string), v)
+		default:
+			return false
+		}
+		switch c.op {
+		case opLess:
+			return r < 0
+		case opLessEqual:
+			return r <= 0
+		case opGreater:
+			return r > 0
+		default:
+			return r >= 0
+		}
+	case opMatch, opNotMatch:
+		s, ok := scalarText(n)
+		if !ok {
+			return false
+		}
+		return c.pattern.MatchString(s) == (c.op == opMatch)
+	case opContains:
+		switch n.Type {
+		case Array:
+			for _, childID := range j.ids[id] {
+				if c.equal(j.values[childID]) {
+					return true
+				}
+			}
+		case Object:
+			for _, childID := range j.ids[id] {
+				if c.equal(Node{Type: String, Value: j.values[childID].Key}) {
+					return true
+				}
+			}
+		case String:
+			v, ok := c.value.(string)
+			if !ok {
+				v, _ = scalarText(Node{Type: typeOf(c.value), Value: c.value})
+			}
+			s := n.Value.(string)
+			if c.ignoreCase {
+				s, v = strings.ToLower(s), strings.ToLower(v)
+			}
+			return strings.Contains(s, v)
+		}
+	}
+	return false
+}
+
+// equal reports whether a node has the same type and value as the condition.
+func (c condition) equal(n Node) bool {
+	switch v := c.value.(type) {
+	case string:
+		if n.Type != String {
+			return false
+		}
+		if c.ignoreCase {
+			return strings.EqualFold(n.Value.(string), v)
+		}
+		return n.Value.(string) == v
+	case float64:
+		return n.Type == Number && n.Value.(float64) == v
+	case bool:
+		return n.Type == Boolean && n.Value.(bool) == v
+	case nil:
+		return n.Type == Null
+	}
+	return false
+}
+
+// scalarText returns the text of a scalar value and reports whether the node is a scalar.
+func scalarText(n Node) (string, bool) {
+	switch n.Type {
+	case String:
+		return n.Value.(string), true
+	case Number:
+		return strconv.FormatFloat(n.Value.(float64), 'f', -1, 64), true
+	case Boolean:
+		return strconv.FormatBool(n.Value.(bool)), true
+	case Null:
+		return "null", true
+	}
+	return "", false
+}
+
+func typeOf(v any) JSONType {
+	switch v.(type) {
+	case string:
+		return String
+	case float64:
+		return Number
+	case bool:
+		return Boolean
+	case nil:
+		return Null
+	}
+	return Unknown
+}
+
+type condTokenKind uint8
+
+const (
+	condTokenEOF condTokenKind = iota
+	condTokenWord
+	condTokenString
+	condTokenRegex
+	condTokenOperator
+)
+
+type condToken struct {
+	kind condTokenKind
+	text string
+	pos  int
+}
+
+// scanCondition splits a condition into tokens. The last token is always EOF.
+func scanCondition(s string) ([]condToken, error) {
+	var tokens []condToken
+	i := 0
+	for {
+		for i < len(s) && unicode.IsSpace(rune(s[i])) {
+			i++
+		}
+		if i == len(s) {
+			tokens = append(tokens, condToken{kind: condTokenEOF, pos: i})
+			return tokens, nil
+		}
+		start := i
+		switch c := s[i]; {
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(s) && s[end] != c {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrInvalidPattern, start+1)
+			}
+			text := s[i+1 : end]
+			if c == '"' {
+				var err error
+				text, err = strconv.Unquote(s[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid string at position %d", ErrInvalidPattern, start+1)
+				}
+			} else {
+				text = strings.ReplaceAll(strings.ReplaceAll(text, `\'`, `'`), `\\`, `\`)
+			}
+			tokens = append(tokens, condToken{kind: condTokenString, text: text, pos: start})
+			i = end + 1
+		case c == '/':
+			end := i + 1
+			for end < len(s) && s[end] != '/' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("%w: unterminated regular expression at position %d", ErrInvalidPattern, start+1)
+			}
+			expr := strings.ReplaceAll(s[i+1:end], `\/`, `/`)
+			i = end + 1
+			var flags string
+			for i < len(s) && strings.IndexByte("imsU", s[i]) >= 0 {
+				flags += string(s[i])
+				i++
+			}
+			if flags != "" {
+				expr = "(?" + flags + ")" + expr
+			}
+			tokens = append(tokens, condToken{kind: condTokenRegex, text: expr, pos: start})
+		case c == '(' || c == ')':
+			return nil, fmt.Errorf("%w: parentheses are not supported at position %d, quote values containing them", ErrInvalidPattern, start+1)
+		case strings.IndexByte("=!<>~&|", c) >= 0:
+			for i < len(s) && strings.IndexByte("=!<>~&|", s[i]) >= 0 {
+				i++
+			}
+			tokens = append(tokens, condToken{kind: condTokenOperator, text: s[start:i], pos: start})
+		default:
+			for i < len(s) && !unicode.IsSpace(rune(s[i])) && strings.IndexByte("=!<>~&|\"'()", s[i]) < 0 {
+				i++
+			}
+			tokens = append(tokens, condToken{kind: condTokenWord, text: s[start:i], pos: start})
+		}
+	}
+}
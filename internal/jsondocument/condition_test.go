@@ -0,0 +1,94 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCondition(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{"jobs": [
+		{"name": "foo-build", "status": "failed", "price": 150, "tags": ["x", "y"], "done": false},
+		{"name": "bar", "status": "ok", "price": 50, "tags": ["y"], "done": true, "meta": null},
+		{"name": "Foo test", "status": "Failed", "price": 100.5, "first name": "a=b"}
+	]}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	findAll := func(search string, opts jsondocument.SearchOptions) ([]string, error) {
+		var got []string
+		err := j.SearchAll(ctx, search, jsondocument.SearchCondition, opts, func(uid string) {
+			got = append(got, j.Pointer(uid))
+		}, nil)
+		return got, err
+	}
+	cases := []struct {
+		search string
+		opts   jsondocument.SearchOptions
+		want   []string
+	}{
+		{`status = "failed"`, jsondocument.SearchOptions{}, []string{"/jobs/0"}},
+		{`status == failed`, jsondocument.SearchOptions{}, []string{"/jobs/0"}},
+		{`status = "failed"`, jsondocument.SearchOptions{IgnoreCase: true}, []string{"/jobs/0", "/jobs/2"}},
+		{`status != "failed"`, jsondocument.SearchOptions{}, []string{"/jobs/1", "/jobs/2"}},
+		{`price > 100`, jsondocument.SearchOptions{}, []string{"/jobs/0", "/jobs/2"}},
+		{`price >= 50`, jsondocument.SearchOptions{}, []string{"/jobs/0", "/jobs/1", "/jobs/2"}},
+		{`price < 100`, jsondocument.SearchOptions{}, []string{"/jobs/1"}},
+		{`price <= 100.5`, jsondocument.SearchOptions{}, []string{"/jobs/1", "/jobs/2"}},
+		{`price = 50`, jsondocument.SearchOptions{}, []string{"/jobs/1"}},
+		{`price = "50"`, jsondocument.SearchOptions{}, nil},
+		{`name ~ /^foo/`, jsondocument.SearchOptions{}, []string{"/jobs/0"}},
+		{`name ~ /^foo/i`, jsondocument.SearchOptions{}, []string{"/jobs/0", "/jobs/2"}},
+		{`name !~ /^foo/`, jsondocument.SearchOptions{}, []string{"/jobs/1", "/jobs/2"}},
+		{`price ~ /\.5$/`, jsondocument.SearchOptions{}, []string{"/jobs/2"}},
+		{`tags contains "x"`, jsondocument.SearchOptions{}, []string{"/jobs/0"}},
+		{`tags CONTAINS y`, jsondocument.SearchOptions{}, []string{"/jobs/0", "/jobs/1"}},
+		{`name contains build`, jsondocument.SearchOptions{}, []string{"/jobs/0"}},
+		{`done = true`, jsondocument.SearchOptions{}, []string{"/jobs/1"}},
+		{`meta = null`, jsondocument.SearchOptions{}, []string{"/jobs/1"}},
+		{`"first name" = 'a=b'`, jsondocument.SearchOptions{}, []string{"/jobs/2"}},
+		{`na* = bar`, jsondocument.SearchOptions{}, []string{"/jobs/1"}},
+		{`status = failed and price > 100`, jsondocument.SearchOptions{}, []string{"/jobs/0"}},
+		{`status = failed && price < 100`, jsondocument.SearchOptions{}, nil},
+		{`status = ok or price > 100`, jsondocument.SearchOptions{}, []string{"/jobs/0", "/jobs/1", "/jobs/2"}},
+		{`status = ok || name ~ "test" and price > 100`, jsondocument.SearchOptions{}, []string{"/jobs/1", "/jobs/2"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.search, func(t *testing.T) {
+			got, err := findAll(tc.search, tc.opts)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+	t.Run("should find next matching object", func(t *testing.T) {
+		uid, err := j.SearchWithOptions(ctx, "", `price > 60`, jsondocument.SearchCondition, jsondocument.SearchOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "/jobs/0", j.Pointer(uid))
+		}
+	})
+	t.Run("should report invalid conditions", func(t *testing.T) {
+		for _, s := range []string{
+			`status`,
+			`status =`,
+			`status failed`,
+			`= failed`,
+			`status = "failed`,
+			`name ~ /(foo/`,
+			`name ~ 5`,
+			`price > true`,
+			`status = ok price > 5`,
+			`status = ok and`,
+			`a = 1 and (b = 2)`,
+			`(status = ok)`,
+		} {
+			err := jsondocument.ValidateSearch(s, jsondocument.SearchCondition, jsondocument.SearchOptions{})
+			assert.ErrorIs(t, err, jsondocument.ErrInvalidPattern, s)
+		}
+	})
+}
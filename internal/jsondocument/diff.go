@@ -0,0 +1,329 @@
+package jsondocument
+
+import (
+	"context"
+	"slices"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// ChangeType represents the type of change of a node between two documents.
+type ChangeType uint8
+
+const (
+	Unchanged ChangeType = iota
+	Added
+	Removed
+	Modified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unchanged"
+}
+
+// Change represents a difference between two documents A and B.
+//
+// Nodes which exist only in one document are identified by their parent in the other document:
+// For added nodes A is the UID of the parent in document A
+// and for removed nodes B is the UID of the parent in document B.
+type Change struct {
+	Type ChangeType
+	A    widget.TreeNodeID
+	B    widget.TreeNodeID
+}
+
+// CompareOptions represents the options for comparing two documents.
+type CompareOptions struct {
+	// Key of a field for matching objects in arrays, e.g. "id".
+	// Array elements without this field are matched by their position.
+	// When empty all array elements are matched by their index.
+	ArrayKey string
+}
+
+// Diff represents the structural differences between two documents A and B.
+type Diff struct {
+	// Changes in order of document B
+	Changes []Change
+
+	a       *JSONDocument
+	b       *JSONDocument
+	n       int
+	opts    CompareOptions
+	pairsA  map[int32]int32 // matched containers from A to B
+	pairsB  map[int32]int32 // matched containers from B to A
+	statusA map[int32]ChangeType
+	statusB map[int32]ChangeType
+}
+
+// Compare returns the structural differences between the documents a and b.
+// Object members are matched by their keys and array elements by their index
+// or by a key field as defined in opts.
+func Compare(ctx context.Context, a, b *JSONDocument, opts CompareOptions) (*Diff, error) {
+	d := &Diff{
+		a:       a,
+		b:       b,
+		opts:    opts,
+		pairsA:  make(map[int32]int32),
+		pairsB:  make(map[int32]int32),
+		statusA: make(map[int32]ChangeType),
+		statusB: make(map[int32]ChangeType),
+	}
+	if a.Size() == 0 || b.Size() == 0 {
+		return d, nil
+	}
+	if err := d.compareNodes(ctx, 0, 0); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Count returns the number of changes of a type.
+func (d *Diff) Count(typ ChangeType) int {
+	var c int
+	for _, x := range d.Changes {
+		if x.Type == typ {
+			c++
+		}
+	}
+	return c
+}
+
+// StatusA returns how a node in document A has changed.
+// Containers with removed elements are reported as modified.
+func (d *Diff) StatusA(uid widget.TreeNodeID) ChangeType {
+	return d.statusA[uid2id(uid)]
+}
+
+// StatusB returns how a node in document B has changed.
+// Containers with removed elements are reported as modified.
+func (d *Diff) StatusB(uid widget.TreeNodeID) ChangeType {
+	return d.statusB[uid2id(uid)]
+}
+
+func (d *Diff) compareNodes(ctx context.Context, idA, idB int32) error {
+	d.n++
+	if d.n%progressUpdateTick == 0 {
+		select {
+		case <-ctx.Done():
+			return ErrCallerCanceled
+		default:
+		}
+	}
+	na, nb := d.a.values[idA], d.b.values[idB]
+	if na.Type != nb.Type {
+		d.addChange(Modified, idA, idB)
+		return nil
+	}
+	switch na.Type {
+	case Object:
+		d.pairsA[idA], d.pairsB[idB] = idB, idA
+		return d.compareObjects(ctx, idA, idB)
+	case Array:
+		d.pairsA[idA], d.pairsB[idB] = idB, idA
+		if d.opts.ArrayKey != "" {
+			return d.compareArraysByKey(ctx, idA, idB)
+		}
+		return d.compareArrays(ctx, idA, idB)
+	}
+	if na.Value != nb.Value {
+		d.addChange(Modified, idA, idB)
+	}
+	return nil
+}
+
+func (d *Diff) compareObjects(ctx context.Context, idA, idB int32) error {
+	childrenA := d.a.ids[idA]
+	keysA := make(map[string]int32, len(childrenA))
+	for _, id := range childrenA {
+		keysA[d.a.values[id].Key] = id
+	}
+	matched := make(map[int32]bool, len(childrenA))
+	for _, childB := range d.b.ids[idB] {
+		childA, found := keysA[d.b.values[childB].Key]
+		if !found {
+			d.addChange(Added, idA, childB)
+			continue
+		}
+		matched[childA] = true
+		if err := d.compareNodes(ctx, childA, childB); err != nil {
+			return err
+		}
+	}
+	for _, childA := range childrenA {
+		if !matched[childA] {
+			d.addChange(Removed, childA, idB)
+		}
+	}
+	return nil
+}
+
+func (d *Diff) compareArrays(ctx context.Context, idA, idB int32) error {
+	childrenA, childrenB := d.a.ids[idA], d.b.ids[idB]
+	for i, childB := range childrenB {
+		if i >= len(childrenA) {
+			d.addChange(Added, idA, childB)
+			continue
+		}
+		if err := d.compareNodes(ctx, childrenA[i], childB); err != nil {
+			return err
+		}
+	}
+	for _, childA := range childrenA[min(len(childrenA), len(childrenB)):] {
+		d.addChange(Removed, childA, idB)
+	}
+	return nil
+}
+
+// compareArraysByKey compares arrays and matches their elements by a key field.
+// Elements without a key are matched by their position among those elements.
+func (d *Diff) compareArraysByKey(ctx context.Context, idA, idB int32) error {
+	keyedA := make(map[any]int32)
+	var unkeyedA []int32
+	for _, childA := range d.a.ids[idA] {
+		if k, ok := d.a.fieldValue(childA, d.opts.ArrayKey); ok {
+			if _, found := keyedA[k]; !found {
+				keyedA[k] = childA
+				continue
+			}
+		}
+		unkeyedA = append(unkeyedA, childA)
+	}
+	matched := make(map[int32]bool)
+	var i int
+	for _, childB := range d.b.ids[idB] {
+		var childA int32
+		var found bool
+		if k, ok := d.b.fieldValue(childB, d.opts.ArrayKey); ok {
+			childA, found = keyedA[k]
+			if found && matched[childA] {
+				found = false // duplicate key
+			}
+		}
+		if !found && i < len(unkeyedA) {
+			if _, ok := d.b.fieldValue(childB, d.opts.ArrayKey); !ok {
+				childA, found = unkeyedA[i], true
+				i++
+			}
+		}
+		if !found {
+			d.addChange(Added, idA, childB)
+			continue
+		}
+		matched[childA] = true
+		if err := d.compareNodes(ctx, childA, childB); err != nil {
+			return err
+		}
+	}
+	for _, childA := range d.a.ids[idA] {
+		if !matched[childA] {
+			d.addChange(Removed, childA, idB)
+		}
+	}
+	return nil
+}
+
+// fieldValue returns the value of a scalar field in an object
+// and reports whether it was found.
+func (j *JSONDocument) fieldValue(id int32, key string) (any, bool) {
+	if j.values[id].Type != Object {
+		return nil, false
+	}
+	for _, childID := range j.ids[id] {
+		n := j.values[childID]
+		if n.Key != key {
+			continue
+		}
+		if n.Type == Object || n.Type == Array {
+			return nil, false
+		}
+		return n.Value, true
+	}
+	return nil, false
+}
+
+func (d *Diff) addChange(typ ChangeType, idA, idB int32) {
+	d.Changes = append(d.Changes, Change{Type: typ, A: id2uid(idA), B: id2uid(idB)})
+	switch typ {
+	case Added:
+		d.statusB[idB] = Added
+		if d.statusA[idA] == Unchanged {
+			d.statusA[idA] = Modified
+		}
+	case Removed:
+		d.statusA[idA] = Removed
+		if d.statusB[idB] == Unchanged {
+			d.statusB[idB] = Modified
+		}
+	case Modified:
+		d.statusA[idA] = Modified
+		d.statusB[idB] = Modified
+	}
+}
+
+// ChangesB returns the UIDs of all changed nodes in document B in document order.
+// Removed nodes are represented by their parents.
+func (d *Diff) ChangesB() []widget.TreeNodeID {
+	ids := make([]int32, 0, len(d.statusB))
+	for id := range d.statusB {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids2uids(ids)
+}
+
+// ChangesA returns the UIDs of all changed nodes in document A in document order.
+// Containers with added nodes are reported as changed.
+func (d *Diff) ChangesA() []widget.TreeNodeID {
+	ids := make([]int32, 0, len(d.statusA))
+	for id := range d.statusA {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids2uids(ids)
+}
+
+// CounterpartA returns the UID of the node in document A, which corresponds to a node in document B
+// and reports whether it was found.
+func (d *Diff) CounterpartA(uid widget.TreeNodeID) (widget.TreeNodeID, bool) {
+	return counterpart(uid2id(uid), d.b, d.a, d.pairsB)
+}
+
+// CounterpartB returns the UID of the node in document B, which corresponds to a node in document A
+// and reports whether it was found.
+func (d *Diff) CounterpartB(uid widget.TreeNodeID) (widget.TreeNodeID, bool) {
+	return counterpart(uid2id(uid), d.a, d.b, d.pairsA)
+}
+
+// counterpart returns the node in the other document corresponding to a node.
+// Matched containers are looked up directly.
+// Other nodes are found by their key in the counterpart of their parent.
+func counterpart(id int32, this, other *JSONDocument, pairs map[int32]int32) (widget.TreeNodeID, bool) {
+	if id2, found := pairs[id]; found {
+		return id2uid(id2), true
+	}
+	if id == 0 {
+		return "", false
+	}
+	parentID, found := pairs[this.parents[id]]
+	if !found {
+		return "", false
+	}
+	key := this.values[id].Key
+	for _, childID := range other.ids[parentID] {
+		if other.values[childID].Key == key {
+			if _, found := pairs[childID]; found {
+				return "", false // a container matched with a different node
+			}
+			return id2uid(childID), true
+		}
+	}
+	return "", false
+}
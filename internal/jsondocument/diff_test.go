@@ -0,0 +1,128 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	ctx := context.TODO()
+	var dummy = binding.NewUntyped()
+	a := jsondocument.New()
+	dataA := map[string]any{
+		"alpha":   1,
+		"bravo":   "abc",
+		"charlie": []any{1, 2, 3},
+		"delta":   map[string]any{"echo": true},
+		"golf":    5,
+	}
+	if err := a.Load(ctx, makeDataReader(dataA), dummy); err != nil {
+		t.Fatal(err)
+	}
+	b := jsondocument.New()
+	dataB := map[string]any{
+		"alpha":   1,
+		"bravo":   "xyz",
+		"charlie": []any{1, 2},
+		"delta":   map[string]any{"echo": true, "foxtrot": nil},
+		"golf":    "5",
+	}
+	if err := b.Load(ctx, makeDataReader(dataB), dummy); err != nil {
+		t.Fatal(err)
+	}
+	idsA := a.ChildUIDs("")
+	alphaA, bravoA, charlieA, deltaA, golfA := idsA[0], idsA[1], idsA[2], idsA[3], idsA[4]
+	idsB := b.ChildUIDs("")
+	alphaB, bravoB, charlieB, deltaB, golfB := idsB[0], idsB[1], idsB[2], idsB[3], idsB[4]
+	charlie3A := a.ChildUIDs(charlieA)[2]
+	foxtrotB := b.ChildUIDs(deltaB)[1]
+	t.Run("should report all changes", func(t *testing.T) {
+		d, err := jsondocument.Compare(ctx, a, b, jsondocument.CompareOptions{})
+		if assert.NoError(t, err) {
+			want := []jsondocument.Change{
+				{Type: jsondocument.Modified, A: bravoA, B: bravoB},
+				{Type: jsondocument.Removed, A: charlie3A, B: charlieB},
+				{Type: jsondocument.Added, A: deltaA, B: foxtrotB},
+				{Type: jsondocument.Modified, A: golfA, B: golfB},
+			}
+			assert.Equal(t, want, d.Changes)
+			assert.Equal(t, 1, d.Count(jsondocument.Added))
+			assert.Equal(t, 1, d.Count(jsondocument.Removed))
+			assert.Equal(t, 2, d.Count(jsondocument.Modified))
+		}
+	})
+	t.Run("should report status of nodes", func(t *testing.T) {
+		d, err := jsondocument.Compare(ctx, a, b, jsondocument.CompareOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, jsondocument.Unchanged, d.StatusB(alphaB))
+			assert.Equal(t, jsondocument.Modified, d.StatusB(bravoB))
+			assert.Equal(t, jsondocument.Modified, d.StatusB(charlieB))
+			assert.Equal(t, jsondocument.Added, d.StatusB(foxtrotB))
+			assert.Equal(t, jsondocument.Unchanged, d.StatusA(alphaA))
+			assert.Equal(t, jsondocument.Removed, d.StatusA(charlie3A))
+			assert.Equal(t, jsondocument.Modified, d.StatusA(deltaA))
+			assert.Equal(t, []string{bravoB, charlieB, foxtrotB, golfB}, d.ChangesB())
+		}
+	})
+	t.Run("should report no changes for identical documents", func(t *testing.T) {
+		d, err := jsondocument.Compare(ctx, a, a, jsondocument.CompareOptions{})
+		if assert.NoError(t, err) {
+			assert.Len(t, d.Changes, 0)
+		}
+	})
+}
+
+func TestCompareWithArrayKey(t *testing.T) {
+	ctx := context.TODO()
+	var dummy = binding.NewUntyped()
+	a := jsondocument.New()
+	dataA := []any{
+		map[string]any{"id": 1, "name": "alpha"},
+		map[string]any{"id": 2, "name": "bravo"},
+	}
+	if err := a.Load(ctx, makeDataReader(dataA), dummy); err != nil {
+		t.Fatal(err)
+	}
+	b := jsondocument.New()
+	dataB := []any{
+		map[string]any{"id": 2, "name": "bravo"},
+		map[string]any{"id": 1, "name": "charlie"},
+	}
+	if err := b.Load(ctx, makeDataReader(dataB), dummy); err != nil {
+		t.Fatal(err)
+	}
+	alphaA := a.ChildUIDs("")[0]
+	nameA := a.ChildUIDs(alphaA)[1]
+	charlieB := b.ChildUIDs("")[1]
+	nameB := b.ChildUIDs(charlieB)[1]
+	t.Run("should match array elements by key", func(t *testing.T) {
+		d, err := jsondocument.Compare(ctx, a, b, jsondocument.CompareOptions{ArrayKey: "id"})
+		if assert.NoError(t, err) {
+			want := []jsondocument.Change{{Type: jsondocument.Modified, A: nameA, B: nameB}}
+			assert.Equal(t, want, d.Changes)
+		}
+	})
+	t.Run("should match array elements by index without key", func(t *testing.T) {
+		d, err := jsondocument.Compare(ctx, a, b, jsondocument.CompareOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 4, d.Count(jsondocument.Modified))
+		}
+	})
+	t.Run("should return counterparts of nodes", func(t *testing.T) {
+		d, err := jsondocument.Compare(ctx, a, b, jsondocument.CompareOptions{ArrayKey: "id"})
+		if assert.NoError(t, err) {
+			got, found := d.CounterpartB(alphaA)
+			if assert.True(t, found) {
+				assert.Equal(t, charlieB, got)
+			}
+			got, found = d.CounterpartA(nameB)
+			if assert.True(t, found) {
+				assert.Equal(t, nameA, got)
+			}
+		}
+	})
+}
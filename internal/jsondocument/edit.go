@@ -0,0 +1,399 @@
+package jsondocument
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+var ErrInvalidEdit = errors.New("invalid edit")
+var ErrKeyExists = errors.New("key already exists")
+
+// Parent ID of nodes, which were removed from the document
+const detachedParentID = -2
+
+// Exists reports whether a node is part of the document. Nodes removed by an edit are not.
+func (j *JSONDocument) Exists(uid widget.TreeNodeID) bool {
+	_, err := j.editableID(uid)
+	return err == nil
+}
+
+// SetValue replaces the value of a node. This can also change the type of a node.
+//
+// The value can be any value produced by un-marshalling JSON, i.e. a string, a float64, a bool, nil,
+// a map[string]any or a []any. The children of a replaced object or array are removed
+// and an object or array value adds new children.
+func (j *JSONDocument) SetValue(uid widget.TreeNodeID, v any) error {
+	id, err := j.editableID(uid)
+	if err != nil {
+		return err
+	}
+	if err := checkValue(v); err != nil {
+		return err
+	}
+	if _, ok := v.(map[string]any); !ok && id == rootNodeID {
+		if _, ok := v.([]any); !ok {
+			return fmt.Errorf("root must be an object or array: %w", ErrInvalidEdit)
+		}
+	}
+	j.startChange()
+	defer j.indexMu.Unlock()
+	sizer := JSONTreeSizer{}
+	node := Node{Key: j.values[id].Key}
+	switch v.(type) {
+	case map[string]any:
+		node.Type, node.Value = Object, Empty
+	case []any:
+		node.Type, node.Value = Array, Empty
+	default:
+		node.Type, node.Value = scalarType(v), v
+	}
+	oldNode, oldChildren := j.swapNode(id, node, nil)
+	switch x := v.(type) {
+	case map[string]any:
+		j.grow(sizer.calculateValue(v) - 1)
+		err = j.addObject(context.Background(), id, x)
+	case []any:
+		j.grow(sizer.calculateValue(v) - 1)
+		err = j.addArray(context.Background(), id, x)
+	}
+	if err != nil {
+		return err
+	}
+	children := slices.Clone(j.ids[id])
+	j.record("Change value of "+j.describe(id), func() {
+		j.swapNode(id, oldNode, oldChildren)
+	}, func() {
+		j.swapNode(id, node, children)
+	})
+	return nil
+}
+
+// RenameKey changes the key of an object member.
+// The member is moved to keep the members of the object in alphabetical order.
+// It returns [ErrKeyExists] when the object already has a member with that key.
+func (j *JSONDocument) RenameKey(uid widget.TreeNodeID, key string) error {
+	id, err := j.editableID(uid)
+	if err != nil {
+		return err
+	}
+	if id == rootNodeID {
+		return fmt.Errorf("root has no key: %w", ErrInvalidEdit)
+	}
+	parentID := j.parents[id]
+	if j.values[parentID].Type != Object {
+		return fmt.Errorf("can not rename array element: %w", ErrInvalidEdit)
+	}
+	if j.values[id].Key == key {
+		return nil
+	}
+	if j.hasMember(parentID, key) {
+		return fmt.Errorf("%q: %w", key, ErrKeyExists)
+	}
+	j.startChange()
+	defer j.indexMu.Unlock()
+	name := fmt.Sprintf("Rename %s to %q", j.describe(id), key)
+	oldKey, oldIndex := j.values[id].Key, slices.Index(j.ids[parentID], id)
+	j.detach(id)
+	index := j.memberIndex(parentID, key)
+	j.values[id].Key = key
+	j.attach(id, parentID, index)
+	j.record(name, func() {
+		j.move(id, oldKey, oldIndex)
+	}, func() {
+		j.move(id, key, index)
+	})
+	return nil
+}
+
+// InsertElement inserts a value into an array at position index and returns the UID of the new element.
+// The index can be equal to the length of the array, which appends the value.
+func (j *JSONDocument) InsertElement(uid widget.TreeNodeID, index int, v any) (widget.TreeNodeID, error) {
+	id, err := j.editableID(uid)
+	if err != nil {
+		return "", err
+	}
+	if j.values[id].Type != Array {
+		return "", fmt.Errorf("not an array: %w", ErrInvalidEdit)
+	}
+	if index < 0 || index > len(j.ids[id]) {
+		return "", fmt.Errorf("index %d out of range: %w", index, ErrInvalidEdit)
+	}
+	if err := checkValue(v); err != nil {
+		return "", err
+	}
+	j.startChange()
+	defer j.indexMu.Unlock()
+	newID, err := j.insertValue(id, index, arrayKey(index), v)
+	if err != nil {
+		return "", err
+	}
+	return id2uid(newID), nil
+}
+
+// InsertMember adds a member to an object and returns the UID of the new member.
+// It returns [ErrKeyExists] when the object already has a member with that key.
+func (j *JSONDocument) InsertMember(uid widget.TreeNodeID, key string, v any) (widget.TreeNodeID, error) {
+	id, err := j.editableID(uid)
+	if err != nil {
+		return "", err
+	}
+	if j.values[id].Type != Object {
+		return "", fmt.Errorf("not an object: %w", ErrInvalidEdit)
+	}
+	if j.hasMember(id, key) {
+		return "", fmt.Errorf("%q: %w", key, ErrKeyExists)
+	}
+	if err := checkValue(v); err != nil {
+		return "", err
+	}
+	j.startChange()
+	defer j.indexMu.Unlock()
+	newID, err := j.insertValue(id, j.memberIndex(id, key), key, v)
+	if err != nil {
+		return "", err
+	}
+	return id2uid(newID), nil
+}
+
+// Delete removes a node and all its descendants from the document.
+// The following elements of an array move up.
+func (j *JSONDocument) Delete(uid widget.TreeNodeID) error {
+	id, err := j.editableID(uid)
+	if err != nil {
+		return err
+	}
+	if id == rootNodeID {
+		return fmt.Errorf("can not delete root: %w", ErrInvalidEdit)
+	}
+	j.startChange()
+	defer j.indexMu.Unlock()
+	name := "Delete " + j.describe(id)
+	parentID, index := j.parents[id], slices.Index(j.ids[j.parents[id]], id)
+	j.detach(id)
+	j.record(name, func() {
+		j.attach(id, parentID, index)
+	}, func() {
+		j.detach(id)
+	})
+	return nil
+}
+
+// Duplicate adds a copy of a node and all its descendants to its parent and returns the UID of the copy.
+// The copy of an array element is inserted after the element.
+// The copy of an object member gets a new key, e.g. "name copy".
+func (j *JSONDocument) Duplicate(uid widget.TreeNodeID) (widget.TreeNodeID, error) {
+	id, err := j.editableID(uid)
+	if err != nil {
+		return "", err
+	}
+	if id == rootNodeID {
+		return "", fmt.Errorf("can not duplicate root: %w", ErrInvalidEdit)
+	}
+	parentID := j.parents[id]
+	var newUID widget.TreeNodeID
+	err = j.Group("Duplicate "+j.describe(id), func() error {
+		var err error
+		if j.values[parentID].Type == Array {
+			newUID, err = j.InsertElement(id2uid(parentID), slices.Index(j.ids[parentID], id)+1, j.extractValue(id))
+			return err
+		}
+		key := j.values[id].Key + " copy"
+		for i := 2; j.hasMember(parentID, key); i++ {
+			key = j.values[id].Key + " copy " + strconv.Itoa(i)
+		}
+		newUID, err = j.InsertMember(id2uid(parentID), key, j.extractValue(id))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return newUID, nil
+}
+
+// editableID returns the ID for a node, which can be edited.
+// Returns [ErrNotFound] when the node does not exist or was removed.
+func (j *JSONDocument) editableID(uid widget.TreeNodeID) (int32, error) {
+	if uid == "" {
+		return rootNodeID, nil
+	}
+	x, err := strconv.Atoi(uid)
+	if err != nil || x <= 0 || x >= int(j.n) {
+		return 0, fmt.Errorf("node %s: %w", uid, ErrNotFound)
+	}
+	id := int32(x)
+	for p := id; p != rootNodeID; p = j.parents[p] {
+		if j.parents[p] == detachedParentID {
+			return 0, fmt.Errorf("node %s: %w", uid, ErrNotFound)
+		}
+	}
+	return id, nil
+}
+
+// startChange drops the search index before the document is changed by an edit,
+// because node IDs are no longer in document order afterwards.
+// It locks the index, which must be unlocked by the caller.
+func (j *JSONDocument) startChange() {
+	j.indexMu.Lock()
+	j.index = nil
+	j.edits++
+}
+
+// describe returns a short description of a node for naming edits.
+func (j *JSONDocument) describe(id int32) string {
+	if id == rootNodeID {
+		return "root"
+	}
+	return j.Pointer(id2uid(id))
+}
+
+// insertValue adds a value with all descendants as child of a parent at position index.
+func (j *JSONDocument) insertValue(parentID int32, index int, key string, v any) (int32, error) {
+	sizer := JSONTreeSizer{}
+	j.grow(sizer.calculateValue(v))
+	if err := j.addValue(context.Background(), parentID, key, v); err != nil {
+		return 0, err
+	}
+	children := j.ids[parentID]
+	id := children[len(children)-1]
+	j.detach(id)
+	j.attach(id, parentID, index)
+	j.record("Add "+j.describe(id), func() {
+		j.detach(id)
+	}, func() {
+		j.attach(id, parentID, index)
+	})
+	return id, nil
+}
+
+// swapNode replaces a node and its children and returns the previous node and children.
+// The previous children are removed from the document, but keep their descendants.
+func (j *JSONDocument) swapNode(id int32, node Node, children []int32) (Node, []int32) {
+	oldNode, oldChildren := j.values[id], j.ids[id]
+	for _, childID := range oldChildren {
+		j.parents[childID] = detachedParentID
+		j.detached += j.subtreeSize(childID)
+	}
+	j.values[id] = node
+	if len(children) > 0 {
+		j.ids[id] = slices.Clone(children)
+		for _, childID := range children {
+			j.parents[childID] = id
+			j.detached -= j.subtreeSize(childID)
+		}
+	} else {
+		delete(j.ids, id)
+	}
+	return oldNode, oldChildren
+}
+
+// move changes the key and position of a node within its parent.
+func (j *JSONDocument) move(id int32, key string, index int) {
+	parentID := j.parents[id]
+	j.detach(id)
+	j.values[id].Key = key
+	j.attach(id, parentID, index)
+}
+
+// detach removes a node from its parent. The node keeps its descendants.
+func (j *JSONDocument) detach(id int32) {
+	parentID := j.parents[id]
+	children := j.ids[parentID]
+	index := slices.Index(children, id)
+	children = slices.Delete(children, index, index+1)
+	if len(children) == 0 {
+		delete(j.ids, parentID)
+	} else {
+		j.ids[parentID] = children
+	}
+	j.parents[id] = detachedParentID
+	j.detached += j.subtreeSize(id)
+	j.renumber(parentID, index)
+}
+
+// attach adds a detached node as child to a parent at position index.
+func (j *JSONDocument) attach(id, parentID int32, index int) {
+	j.ids[parentID] = slices.Insert(j.ids[parentID], index, id)
+	j.parents[id] = parentID
+	j.detached -= j.subtreeSize(id)
+	j.renumber(parentID, index)
+}
+
+// renumber updates the keys of array elements starting at position index.
+func (j *JSONDocument) renumber(parentID int32, index int) {
+	if j.values[parentID].Type != Array {
+		return
+	}
+	children := j.ids[parentID]
+	for i := index; i < len(children); i++ {
+		j.values[children[i]].Key = arrayKey(i)
+	}
+}
+
+// subtreeSize returns the number of nodes in a subtree.
+func (j *JSONDocument) subtreeSize(id int32) int32 {
+	n := int32(1)
+	for _, childID := range j.ids[id] {
+		n += j.subtreeSize(childID)
+	}
+	return n
+}
+
+// hasMember reports whether an object has a member with a key.
+func (j *JSONDocument) hasMember(id int32, key string) bool {
+	return slices.ContainsFunc(j.ids[id], func(childID int32) bool {
+		return j.values[childID].Key == key
+	})
+}
+
+// memberIndex returns the position for a new member of an object, which keeps the keys in order.
+func (j *JSONDocument) memberIndex(id int32, key string) int {
+	i := slices.IndexFunc(j.ids[id], func(childID int32) bool {
+		return j.values[childID].Key > key
+	})
+	if i == -1 {
+		return len(j.ids[id])
+	}
+	return i
+}
+
+// checkValue returns an error when a value can not be added to a document.
+func checkValue(v any) error {
+	switch x := v.(type) {
+	case map[string]any:
+		for _, v2 := range x {
+			if err := checkValue(v2); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, v2 := range x {
+			if err := checkValue(v2); err != nil {
+				return err
+			}
+		}
+	case string, float64, bool, nil:
+	default:
+		return fmt.Errorf("unsupported type %T: %w", v, ErrInvalidEdit)
+	}
+	return nil
+}
+
+// scalarType returns the JSON type of a scalar value.
+func scalarType(v any) JSONType {
+	switch v.(type) {
+	case string:
+		return String
+	case float64:
+		return Number
+	case bool:
+		return Boolean
+	case nil:
+		return Null
+	}
+	return Unknown
+}
@@ -0,0 +1,180 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/widget"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdit(t *testing.T) {
+	ctx := context.TODO()
+	const doc = `{"alpha": {"id": 1, "name": "a"}, "bravo": [1, 2, 3], "charlie": "c"}`
+	load := func(t *testing.T) *jsondocument.JSONDocument {
+		j := jsondocument.New()
+		if err := j.LoadData(ctx, parseJSON(doc)); err != nil {
+			t.Fatal(err)
+		}
+		return j
+	}
+	find := func(t *testing.T, j *jsondocument.JSONDocument, path string) widget.TreeNodeID {
+		uid, err := j.ResolvePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	t.Run("should change scalar value and type", func(t *testing.T) {
+		j := load(t)
+		uid := find(t, j, "/charlie")
+		err := j.SetValue(uid, 42.0)
+		require.NoError(t, err)
+		assert.Equal(t, jsondocument.Node{Key: "charlie", Value: 42.0, Type: jsondocument.Number}, j.Value(uid))
+		assert.True(t, j.IsModified())
+		err = j.SetValue(uid, nil)
+		require.NoError(t, err)
+		assert.Equal(t, jsondocument.Null, j.Value(uid).Type)
+	})
+	t.Run("should replace object with scalar and scalar with object", func(t *testing.T) {
+		j := load(t)
+		alpha := find(t, j, "/alpha")
+		require.NoError(t, j.SetValue(alpha, "x"))
+		assert.False(t, j.IsBranch(alpha))
+		assert.Equal(t, 7, j.Size())
+		charlie := find(t, j, "/charlie")
+		require.NoError(t, j.SetValue(charlie, parseJSON(`{"delta": [true]}`)))
+		assert.Equal(t, parseJSON(`{"alpha": "x", "bravo": [1, 2, 3], "charlie": {"delta": [true]}}`), j.ExtractValue(""))
+		assert.Equal(t, 9, j.Size())
+	})
+	t.Run("should rename key and keep order", func(t *testing.T) {
+		j := load(t)
+		uid := find(t, j, "/alpha")
+		require.NoError(t, j.RenameKey(uid, "delta"))
+		assert.Equal(t, "delta", j.Value(uid).Key)
+		assert.Equal(t, []widget.TreeNodeID{find(t, j, "/bravo"), find(t, j, "/charlie"), uid}, j.ChildUIDs(""))
+		assert.Equal(t, "/delta/id", j.Pointer(find(t, j, "/delta/id")))
+	})
+	t.Run("should not rename to existing key", func(t *testing.T) {
+		j := load(t)
+		err := j.RenameKey(find(t, j, "/alpha"), "bravo")
+		assert.ErrorIs(t, err, jsondocument.ErrKeyExists)
+		assert.False(t, j.IsModified())
+	})
+	t.Run("should not rename array element", func(t *testing.T) {
+		j := load(t)
+		err := j.RenameKey(find(t, j, "/bravo/0"), "x")
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidEdit)
+	})
+	t.Run("should insert array elements", func(t *testing.T) {
+		j := load(t)
+		bravo := find(t, j, "/bravo")
+		second := find(t, j, "/bravo/1")
+		uid, err := j.InsertElement(bravo, 1, "x")
+		require.NoError(t, err)
+		assert.Equal(t, "/bravo/1", j.Pointer(uid))
+		assert.Equal(t, "/bravo/2", j.Pointer(second))
+		assert.Equal(t, "[2]", j.Value(second).Key)
+		_, err = j.InsertElement(bravo, 4, parseJSON(`{"a": 1}`))
+		require.NoError(t, err)
+		assert.Equal(t, parseJSON(`[1, "x", 2, 3, {"a": 1}]`), j.ExtractValue(bravo))
+		assert.Equal(t, 12, j.Size())
+	})
+	t.Run("should not insert element out of range or into object", func(t *testing.T) {
+		j := load(t)
+		_, err := j.InsertElement(find(t, j, "/bravo"), 4, 1.0)
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidEdit)
+		_, err = j.InsertElement(find(t, j, "/alpha"), 0, 1.0)
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidEdit)
+	})
+	t.Run("should insert object members in order", func(t *testing.T) {
+		j := load(t)
+		uid, err := j.InsertMember("", "beta", true)
+		require.NoError(t, err)
+		assert.Equal(t, []widget.TreeNodeID{find(t, j, "/alpha"), uid, find(t, j, "/bravo"), find(t, j, "/charlie")}, j.ChildUIDs(""))
+		_, err = j.InsertMember("", "beta", true)
+		assert.ErrorIs(t, err, jsondocument.ErrKeyExists)
+	})
+	t.Run("should insert into empty containers", func(t *testing.T) {
+		j := jsondocument.New()
+		require.NoError(t, j.LoadData(ctx, parseJSON(`{"a": [], "o": {}}`)))
+		a := find(t, j, "/a")
+		o := find(t, j, "/o")
+		assert.False(t, j.IsBranch(a))
+		_, err := j.InsertElement(a, 0, 1.0)
+		require.NoError(t, err)
+		_, err = j.InsertMember(o, "k", "v")
+		require.NoError(t, err)
+		assert.True(t, j.IsBranch(a))
+		assert.Equal(t, parseJSON(`{"a": [1], "o": {"k": "v"}}`), j.ExtractValue(""))
+	})
+	t.Run("should not insert unsupported values", func(t *testing.T) {
+		j := load(t)
+		_, err := j.InsertMember("", "x", []any{1})
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidEdit)
+		assert.Equal(t, 9, j.Size())
+	})
+	t.Run("should delete nodes", func(t *testing.T) {
+		j := load(t)
+		last := find(t, j, "/bravo/2")
+		require.NoError(t, j.Delete(find(t, j, "/bravo/0")))
+		assert.Equal(t, "[1]", j.Value(last).Key)
+		require.NoError(t, j.Delete(find(t, j, "/alpha")))
+		assert.Equal(t, parseJSON(`{"bravo": [2, 3], "charlie": "c"}`), j.ExtractValue(""))
+		assert.Equal(t, 5, j.Size())
+		err := j.Delete(find(t, j, "/bravo/0"))
+		require.NoError(t, err)
+		err = j.Delete(last)
+		require.NoError(t, err)
+		assert.False(t, j.IsBranch(find(t, j, "/bravo")))
+	})
+	t.Run("should not edit deleted nodes", func(t *testing.T) {
+		j := load(t)
+		id := find(t, j, "/alpha/id")
+		require.NoError(t, j.Delete(find(t, j, "/alpha")))
+		assert.ErrorIs(t, j.SetValue(id, 1.0), jsondocument.ErrNotFound)
+		assert.False(t, j.Exists(id))
+		assert.True(t, j.Exists(find(t, j, "/bravo")))
+		assert.ErrorIs(t, j.Delete("999"), jsondocument.ErrNotFound)
+	})
+	t.Run("should not delete root", func(t *testing.T) {
+		j := load(t)
+		assert.ErrorIs(t, j.Delete(""), jsondocument.ErrInvalidEdit)
+		assert.ErrorIs(t, j.SetValue("", 1.0), jsondocument.ErrInvalidEdit)
+	})
+	t.Run("should duplicate subtrees", func(t *testing.T) {
+		j := load(t)
+		uid, err := j.Duplicate(find(t, j, "/alpha"))
+		require.NoError(t, err)
+		assert.Equal(t, "alpha copy", j.Value(uid).Key)
+		_, err = j.Duplicate(find(t, j, "/alpha"))
+		require.NoError(t, err)
+		_, err = j.Duplicate(find(t, j, "/bravo/0"))
+		require.NoError(t, err)
+		want := parseJSON(`{
+			"alpha": {"id": 1, "name": "a"},
+			"alpha copy": {"id": 1, "name": "a"},
+			"alpha copy 2": {"id": 1, "name": "a"},
+			"bravo": [1, 1, 2, 3],
+			"charlie": "c"
+		}`)
+		assert.Equal(t, want, j.ExtractValue(""))
+	})
+	t.Run("should search edited document in document order", func(t *testing.T) {
+		j := load(t)
+		require.NoError(t, j.BuildIndex(ctx))
+		_, err := j.InsertMember(find(t, j, "/alpha"), "extra", "a")
+		require.NoError(t, err)
+		assert.False(t, j.HasIndex())
+		require.NoError(t, j.BuildIndex(ctx))
+		assert.False(t, j.HasIndex())
+		var got []string
+		err = j.SearchAll(ctx, "a", jsondocument.SearchString, jsondocument.SearchOptions{}, func(uid widget.TreeNodeID) {
+			got = append(got, j.Pointer(uid))
+		}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/alpha/extra", "/alpha/name"}, got)
+	})
+}
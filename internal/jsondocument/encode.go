@@ -0,0 +1,261 @@
+package jsondocument
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2/widget"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Size of the buffer, after which encoded data is written to the output
+const encodeFlushSize = 1 << 16
+
+// KeyOrder defines the order in which the members of objects are encoded.
+type KeyOrder uint8
+
+const (
+	KeyOrderDocument   KeyOrder = iota // order of the document, i.e. sorted by key
+	KeyOrderIgnoreCase                 // sorted by key ignoring case
+	KeyOrderNatural                    // sorted by key with numbers compared by value, e.g. "item2" before "item10"
+)
+
+// EncodeOptions define how a document is encoded.
+type EncodeOptions struct {
+	// Indent is used for each level of nested values, e.g. two spaces or a tab.
+	// Values are encoded without whitespace when it is empty.
+	Indent   string
+	KeyOrder KeyOrder
+}
+
+// Encode writes a node and all its descendants as JSON to w.
+// Nodes are written directly, so that the document does not need to be converted into Go values first.
+//
+// The values of a stream are written as one minified value per line when encoding the root node.
+// The document must not be edited while it is encoded.
+func (j *JSONDocument) Encode(ctx context.Context, w io.Writer, uid widget.TreeNodeID, opts EncodeOptions) error {
+	if !j.Exists(uid) {
+		return ErrNotFound
+	}
+	id := uid2id(uid)
+	e := &encoder{
+		ctx:    ctx,
+		j:      j,
+		opts:   opts,
+		stream: jsoniter.NewStream(json, w, encodeFlushSize),
+	}
+	if id == rootNodeID && j.isStream {
+		e.opts.Indent = ""
+		for _, childID := range j.ids[id] {
+			if err := e.encode(childID, 0); err != nil {
+				return err
+			}
+			e.stream.WriteRaw("\n")
+		}
+	} else {
+		if err := e.encode(id, 0); err != nil {
+			return err
+		}
+		if e.opts.Indent != "" {
+			e.stream.WriteRaw("\n")
+		}
+	}
+	return e.flush()
+}
+
+// Save writes the document as JSON to a file.
+// The file is replaced atomically, so that it is never left half-written:
+// The document is first written to a temporary file in the same folder, which is then renamed.
+//
+// Save only reads the document, so it can run in the background.
+// Callers should mark the document as saved with [JSONDocument.MarkSaved] afterwards.
+func (j *JSONDocument) Save(ctx context.Context, path string, opts EncodeOptions) error {
+	perm := fs.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = func() error {
+		defer f.Close()
+		w := bufio.NewWriter(f)
+		if err := j.Encode(ctx, w, "", opts); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := f.Chmod(perm); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		return f.Close()
+	}()
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// encoder writes nodes of a document as JSON.
+type encoder struct {
+	ctx    context.Context
+	j      *JSONDocument
+	n      int // number of encoded nodes
+	opts   EncodeOptions
+	stream *jsoniter.Stream
+}
+
+func (e *encoder) encode(id int32, depth int) error {
+	e.n++
+	if e.n%searchCancelTick == 0 {
+		select {
+		case <-e.ctx.Done():
+			return ErrCallerCanceled
+		default:
+		}
+	}
+	s := e.stream
+	node := e.j.values[id]
+	switch node.Type {
+	case Object, Array:
+		open, close := "[", "]"
+		children := e.j.ids[id]
+		if node.Type == Object {
+			open, close = "{", "}"
+			children = e.sortMembers(children)
+		}
+		s.WriteRaw(open)
+		for i, childID := range children {
+			if i > 0 {
+				s.WriteRaw(",")
+			}
+			e.newline(depth + 1)
+			if node.Type == Object {
+				s.WriteString(e.j.values[childID].Key)
+				s.WriteRaw(":")
+				if e.opts.Indent != "" {
+					s.WriteRaw(" ")
+				}
+			}
+			if err := e.encode(childID, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(children) > 0 {
+			e.newline(depth)
+		}
+		s.WriteRaw(close)
+	case String:
+		s.WriteString(node.Value.(string))
+	case Number:
+		s.WriteFloat64(node.Value.(float64))
+	case Boolean:
+		s.WriteBool(node.Value.(bool))
+	case Null:
+		s.WriteNil()
+	default:
+		return fmt.Errorf("unsupported type %s for node %d", node.Type, id)
+	}
+	if s.Buffered() >= encodeFlushSize {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *encoder) newline(depth int) {
+	if e.opts.Indent == "" {
+		return
+	}
+	e.stream.WriteRaw("\n")
+	for range depth {
+		e.stream.WriteRaw(e.opts.Indent)
+	}
+}
+
+func (e *encoder) flush() error {
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.stream.Error
+}
+
+// sortMembers returns the members of an object in the order of the encoder's options.
+func (e *encoder) sortMembers(ids []int32) []int32 {
+	var cmp func(a, b string) int
+	switch e.opts.KeyOrder {
+	case KeyOrderIgnoreCase:
+		cmp = func(a, b string) int {
+			if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+				return c
+			}
+			return strings.Compare(a, b)
+		}
+	case KeyOrderNatural:
+		cmp = compareNatural
+	default:
+		return ids
+	}
+	ids = slices.Clone(ids)
+	slices.SortStableFunc(ids, func(a, b int32) int {
+		return cmp(e.j.values[a].Key, e.j.values[b].Key)
+	})
+	return ids
+}
+
+// compareNatural compares two strings like [strings.Compare],
+// but compares sequences of digits by their numeric value.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if c := len(na) - len(nb); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			if c := len(da) - len(db); c != 0 {
+				return c
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			return int(ra) - int(rb)
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return len(a) - len(b)
+}
+
+// leadingDigits returns the ASCII digits at the start of a string.
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,126 @@
+package jsondocument_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	ctx := context.TODO()
+	const doc = `{"b": [1, 2.5, "<a&b>"], "A": {"item10": true, "item2": null}, "c": {}, "d": []}`
+	load := func(t *testing.T) *jsondocument.JSONDocument {
+		j := jsondocument.New()
+		if err := j.LoadData(ctx, parseJSON(doc)); err != nil {
+			t.Fatal(err)
+		}
+		return j
+	}
+	encode := func(t *testing.T, j *jsondocument.JSONDocument, uid string, opts jsondocument.EncodeOptions) string {
+		var buf bytes.Buffer
+		err := j.Encode(ctx, &buf, uid, opts)
+		require.NoError(t, err)
+		return buf.String()
+	}
+	t.Run("should encode minified", func(t *testing.T) {
+		j := load(t)
+		got := encode(t, j, "", jsondocument.EncodeOptions{})
+		assert.Equal(t, `{"A":{"item10":true,"item2":null},"b":[1,2.5,"<a&b>"],"c":{},"d":[]}`, got)
+		assert.Equal(t, parseJSON(doc), parseJSON(got))
+	})
+	t.Run("should encode with indentation", func(t *testing.T) {
+		j := load(t)
+		got := encode(t, j, "", jsondocument.EncodeOptions{Indent: "\t"})
+		want := "{\n\t\"A\": {\n\t\t\"item10\": true,\n\t\t\"item2\": null\n\t},\n" +
+			"\t\"b\": [\n\t\t1,\n\t\t2.5,\n\t\t\"<a&b>\"\n\t],\n\t\"c\": {},\n\t\"d\": []\n}\n"
+		assert.Equal(t, want, got)
+	})
+	t.Run("should encode keys in natural order", func(t *testing.T) {
+		j := load(t)
+		uid, err := j.ResolvePath("/A")
+		require.NoError(t, err)
+		got := encode(t, j, uid, jsondocument.EncodeOptions{KeyOrder: jsondocument.KeyOrderNatural})
+		assert.Equal(t, `{"item2":null,"item10":true}`, got)
+	})
+	t.Run("should encode keys ignoring case", func(t *testing.T) {
+		j := load(t)
+		got := encode(t, j, "", jsondocument.EncodeOptions{KeyOrder: jsondocument.KeyOrderIgnoreCase})
+		assert.True(t, strings.HasPrefix(got, `{"A":`))
+	})
+	t.Run("should encode edited document", func(t *testing.T) {
+		j := load(t)
+		uid, err := j.ResolvePath("/b/0")
+		require.NoError(t, err)
+		require.NoError(t, j.Delete(uid))
+		_, err = j.InsertMember("", "e", parseJSON(`{"x": 1e21}`))
+		require.NoError(t, err)
+		got := encode(t, j, "", jsondocument.EncodeOptions{})
+		assert.Equal(t, parseJSON(`{"A": {"item10": true, "item2": null}, "b": [2.5, "<a&b>"], "c": {}, "d": [], "e": {"x": 1e21}}`), parseJSON(got))
+		assert.ErrorIs(t, j.Encode(ctx, &bytes.Buffer{}, uid, jsondocument.EncodeOptions{}), jsondocument.ErrNotFound)
+	})
+	t.Run("should encode stream as lines", func(t *testing.T) {
+		j := jsondocument.New()
+		r := jsondocument.MakeURIReadCloser(strings.NewReader("{\"a\": 1}\n{\"a\": 2}\n"), "data.ndjson")
+		require.NoError(t, j.Load(ctx, r, binding.NewUntyped()))
+		got := encode(t, j, "", jsondocument.EncodeOptions{Indent: "  "})
+		assert.Equal(t, "{\"a\":1}\n{\"a\":2}\n", got)
+	})
+}
+
+func TestSave(t *testing.T) {
+	ctx := context.TODO()
+	t.Run("should save document and mark it as saved", func(t *testing.T) {
+		j := jsondocument.New()
+		require.NoError(t, j.LoadData(ctx, parseJSON(`{"a": 1}`)))
+		_, err := j.InsertMember("", "b", "x")
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "data.json")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+		err = j.Save(ctx, path, jsondocument.EncodeOptions{Indent: "  "})
+		require.NoError(t, err)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"a\": 1,\n  \"b\": \"x\"\n}\n", string(data))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		assert.True(t, j.IsModified())
+		j.MarkSaved()
+		assert.False(t, j.IsModified())
+		entries, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+		_, err = j.Undo()
+		require.NoError(t, err)
+		assert.True(t, j.IsModified())
+	})
+	t.Run("should keep original file when saving fails", func(t *testing.T) {
+		j := jsondocument.New()
+		require.NoError(t, j.LoadData(ctx, parseJSON(`{"a": 1}`)))
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "data.json")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+		big := make([]any, 5000)
+		_, err := j.InsertMember("", "big", big)
+		require.NoError(t, err)
+		err = j.Save(cancelled, path, jsondocument.EncodeOptions{})
+		assert.ErrorIs(t, err, jsondocument.ErrCallerCanceled)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(data))
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.True(t, j.IsModified())
+	})
+}
@@ -0,0 +1,110 @@
+package jsondocument
+
+import (
+	"fmt"
+	"strconv"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// Filter is a view of a JSON document, which contains only matching nodes and their ancestors.
+// Descendants of a matching node are part of the view too,
+// so that the content of a matching object or array can be inspected.
+//
+// It provides the same ChildUIDs and IsBranch methods as a JSONDocument,
+// so that it can be used as projection of a document in a tree widget.
+type Filter struct {
+	j        *JSONDocument
+	children map[int32][]int32 // visible children of ancestors of matches
+	matched  map[int32]bool
+}
+
+// NewFilter returns a filter, which shows the nodes with the given UIDs and their ancestors.
+// Returns ErrNotFound when a UID does not exist in the document.
+func (j *JSONDocument) NewFilter(uids []widget.TreeNodeID) (*Filter, error) {
+	f := &Filter{
+		j:        j,
+		children: make(map[int32][]int32),
+		matched:  make(map[int32]bool),
+	}
+	for _, uid := range uids {
+		id := rootNodeID
+		if uid != "" {
+			x, err := strconv.Atoi(uid)
+			if err != nil || x <= 0 || x >= int(j.n) {
+				return nil, fmt.Errorf("filter node %s: %w", uid, ErrNotFound)
+			}
+			id = x
+		}
+		f.matched[int32(id)] = true
+	}
+	visible := make(map[int32]bool)
+	for id := range f.matched {
+		for id != rootNodeID && !visible[id] {
+			visible[id] = true
+			id = j.parents[id]
+			f.children[id] = nil
+		}
+	}
+	for parentID := range f.children {
+		for _, id := range j.ids[parentID] {
+			if visible[id] {
+				f.children[parentID] = append(f.children[parentID], id)
+			}
+		}
+	}
+	return f, nil
+}
+
+// ChildUIDs returns the visible child UIDs of a node.
+func (f *Filter) ChildUIDs(uid widget.TreeNodeID) []widget.TreeNodeID {
+	id := uid2id(uid)
+	if f.isExpanded(id) {
+		return ids2uids(f.j.ids[id])
+	}
+	return ids2uids(f.children[id])
+}
+
+// IsBranch reports whether a node is a branch in the filtered view.
+func (f *Filter) IsBranch(uid widget.TreeNodeID) bool {
+	id := uid2id(uid)
+	if f.isExpanded(id) {
+		_, found := f.j.ids[id]
+		return found
+	}
+	_, found := f.children[id]
+	return found
+}
+
+// IsMatch reports whether a node is one of the nodes the filter was created with.
+func (f *Filter) IsMatch(uid widget.TreeNodeID) bool {
+	return f.matched[uid2id(uid)]
+}
+
+// MatchCount returns the number of nodes the filter was created with.
+func (f *Filter) MatchCount() int {
+	return len(f.matched)
+}
+
+// HiddenCount returns the number of children of a node, which are hidden by the filter.
+func (f *Filter) HiddenCount(uid widget.TreeNodeID) int {
+	id := uid2id(uid)
+	if f.isExpanded(id) {
+		return 0
+	}
+	return len(f.j.ids[id]) - len(f.children[id])
+}
+
+// isExpanded reports whether a node is a match or a descendant of one,
+// which means all its children are visible.
+func (f *Filter) isExpanded(id int32) bool {
+	for {
+		if f.matched[id] {
+			return true
+		}
+		if id == rootNodeID {
+			return false
+		}
+		id = f.j.parents[id]
+	}
+}
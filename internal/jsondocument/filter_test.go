@@ -0,0 +1,73 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/widget"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	err := j.LoadData(ctx, parseJSON(`{"a": {"b": 1, "c": 2, "d": {"e": 3}}, "f": [4, 5, 6], "g": 7}`))
+	require.NoError(t, err)
+	uid := func(keys ...string) widget.TreeNodeID {
+		uid, err := j.FindKeyPath(keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	keys := func(f *jsondocument.Filter, uid widget.TreeNodeID) []string {
+		var s []string
+		for _, uid2 := range f.ChildUIDs(uid) {
+			s = append(s, j.Value(uid2).Key)
+		}
+		return s
+	}
+	t.Run("should show matches and their ancestors only", func(t *testing.T) {
+		f, err := j.NewFilter([]widget.TreeNodeID{uid("f", "[2]"), uid("a", "c")})
+		require.NoError(t, err)
+		assert.Equal(t, 2, f.MatchCount())
+		assert.Equal(t, []string{"a", "f"}, keys(f, ""))
+		assert.Equal(t, []string{"c"}, keys(f, uid("a")))
+		assert.Equal(t, []string{"[2]"}, keys(f, uid("f")))
+		assert.True(t, f.IsBranch(uid("a")))
+		assert.False(t, f.IsBranch(uid("a", "c")))
+		assert.True(t, f.IsMatch(uid("a", "c")))
+		assert.False(t, f.IsMatch(uid("a")))
+	})
+	t.Run("should report hidden children", func(t *testing.T) {
+		f, err := j.NewFilter([]widget.TreeNodeID{uid("f", "[2]"), uid("a", "c")})
+		require.NoError(t, err)
+		assert.Equal(t, 1, f.HiddenCount(""))
+		assert.Equal(t, 2, f.HiddenCount(uid("a")))
+		assert.Equal(t, 2, f.HiddenCount(uid("f")))
+		assert.Equal(t, 0, f.HiddenCount(uid("a", "c")))
+	})
+	t.Run("should show all descendants of matches", func(t *testing.T) {
+		f, err := j.NewFilter([]widget.TreeNodeID{uid("a")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, keys(f, ""))
+		assert.Equal(t, []string{"b", "c", "d"}, keys(f, uid("a")))
+		assert.Equal(t, []string{"e"}, keys(f, uid("a", "d")))
+		assert.True(t, f.IsBranch(uid("a", "d")))
+		assert.Equal(t, 0, f.HiddenCount(uid("a")))
+		assert.Equal(t, 2, f.HiddenCount(""))
+	})
+	t.Run("should show nothing when there are no matches", func(t *testing.T) {
+		f, err := j.NewFilter(nil)
+		require.NoError(t, err)
+		assert.Empty(t, f.ChildUIDs(""))
+		assert.False(t, f.IsBranch(""))
+		assert.Equal(t, 3, f.HiddenCount(""))
+	})
+	t.Run("should return error for unknown nodes", func(t *testing.T) {
+		_, err := j.NewFilter([]widget.TreeNodeID{"999"})
+		assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+	})
+}
@@ -0,0 +1,269 @@
+package jsondocument
+
+import (
+	"context"
+	"slices"
+	"unicode"
+)
+
+// Scores for fuzzy matching
+const (
+	fuzzyScoreMatch       = 16 // for each matched character
+	fuzzyBonusConsecutive = 8  // character directly follows the previous match
+	fuzzyBonusBoundary    = 8  // character starts a word
+	fuzzyBonusExact       = 32 // text equals the pattern
+	fuzzyPenaltyGap       = 1  // for each skipped character between matches
+	fuzzyPenaltyTypo      = 24 // for each edit needed to turn the text into the pattern
+)
+
+// FuzzyMatch is the result of matching a pattern fuzzy against a text.
+type FuzzyMatch struct {
+	Score     int   // higher is better
+	Positions []int // indexes of the matched runes in the text
+}
+
+// MatchFuzzy matches a pattern fuzzy against a text and reports whether it matched.
+//
+// A text matches when it contains all characters of the pattern in the same order,
+// e.g. "rcvdat" matches "receivedAt", or when it differs from the pattern only by a few typos,
+// e.g. "recievedAt" matches "receivedAt". Letter case is ignored.
+// Consecutive characters and characters at the start of words score higher.
+func MatchFuzzy(pattern, text string) (FuzzyMatch, bool) {
+	p := lowerRunes(pattern)
+	if len(p) == 0 {
+		return FuzzyMatch{}, false
+	}
+	orig := []rune(text)
+	t := lowerRunes(text)
+	if positions, ok := fuzzySubsequence(p, t); ok {
+		score := fuzzyScore(orig, positions)
+		if len(t) == len(p) {
+			score += fuzzyBonusExact
+		}
+		return FuzzyMatch{Score: score, Positions: positions}, true
+	}
+	if len(p) < 3 {
+		return FuzzyMatch{}, false
+	}
+	maxTypos := 1 + (len(p)-3)/4
+	if d := len(t) - len(p); d > maxTypos || -d > maxTypos {
+		return FuzzyMatch{}, false
+	}
+	typos, positions := editDistance(p, t)
+	if typos > maxTypos {
+		return FuzzyMatch{}, false
+	}
+	return FuzzyMatch{Score: fuzzyScore(orig, positions) - typos*fuzzyPenaltyTypo, Positions: positions}, true
+}
+
+func lowerRunes(s string) []rune {
+	r := []rune(s)
+	for i, c := range r {
+		r[i] = unicode.ToLower(c)
+	}
+	return r
+}
+
+// fuzzySubsequence returns the positions of the shortest occurrence of p as subsequence in t
+// and reports whether there is one.
+func fuzzySubsequence(p, t []rune) ([]int, bool) {
+	// find the end of the first occurrence
+	end := -1
+	k := 0
+	for i, r := range t {
+		if r == p[k] {
+			k++
+			if k == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return nil, false
+	}
+	// find the latest start of an occurrence ending there
+	start := end
+	k = len(p) - 1
+	for i := end; i >= 0; i-- {
+		if t[i] == p[k] {
+			k--
+			if k < 0 {
+				start = i
+				break
+			}
+		}
+	}
+	positions := make([]int, 0, len(p))
+	k = 0
+	for i := start; k < len(p); i++ {
+		if t[i] == p[k] {
+			positions = append(positions, i)
+			k++
+		}
+	}
+	return positions, true
+}
+
+// fuzzyScore returns the score for matched positions in a text.
+func fuzzyScore(t []rune, positions []int) int {
+	var score int
+	for k, i := range positions {
+		score += fuzzyScoreMatch
+		if k > 0 {
+			if gap := i - positions[k-1] - 1; gap == 0 {
+				score += fuzzyBonusConsecutive
+			} else {
+				score -= gap * fuzzyPenaltyGap
+			}
+		}
+		if isWordStart(t, i) {
+			score += fuzzyBonusBoundary
+		}
+	}
+	return score
+}
+
+// isWordStart reports whether the rune at position i starts a word, e.g. "b" in "a_b" or "aB".
+func isWordStart(t []rune, i int) bool {
+	if !isAlphaNumeric(t[i]) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	return !isAlphaNumeric(t[i-1]) || unicode.IsLower(t[i-1]) && unicode.IsUpper(t[i])
+}
+
+func isAlphaNumeric(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// editDistance returns the optimal string alignment distance between p and t,
+// i.e. the number of inserted, removed, substituted or swapped characters,
+// and the positions of all characters in t which are kept or swapped.
+func editDistance(p, t []rune) (int, []int) {
+	m, n := len(p), len(t)
+	d := make([][]int, m+1)
+	for i := range d {
+		d[i] = make([]int, n+1)
+		d[i][0] = i
+	}
+	for j := range n + 1 {
+		d[0][j] = j
+	}
+	for i := 1; i <= m; i++ {
+		for j := 1; j <= n; j++ {
+			cost := 1
+			if p[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && p[i-1] == t[j-2] && p[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	var positions []int
+	i, j := m, n
+	for i > 0 && j > 0 {
+		switch {
+		case p[i-1] == t[j-1] && d[i][j] == d[i-1][j-1]:
+			positions = append(positions, j-1)
+			i, j = i-1, j-1
+		case i > 1 && j > 1 && p[i-1] == t[j-2] && p[i-2] == t[j-1] && d[i][j] == d[i-2][j-2]+1:
+			positions = append(positions, j-1, j-2)
+			i, j = i-2, j-2
+		case d[i][j] == d[i-1][j-1]+1:
+			i, j = i-1, j-1
+		case d[i][j] == d[i-1][j]+1:
+			i--
+		default:
+			j--
+		}
+	}
+	slices.Reverse(positions)
+	return d[m][n], positions
+}
+
+// FuzzyResult is a node matching a fuzzy search.
+// The positions refer to the key when the key matched and otherwise to the text of the value.
+type FuzzyResult struct {
+	SearchMatch
+	FuzzyMatch
+}
+
+// SearchFuzzy searches the whole document or the scope defined by the options for nodes matching
+// a search fuzzy as defined by [MatchFuzzy]. The search mode of the options is ignored.
+// It returns the results ranked by score, best first. Results with the same score are in document order.
+// It reports the number of searched nodes to progress, which can be nil.
+// It returns [ErrCallerCanceled] when the context is canceled.
+func (j *JSONDocument) SearchFuzzy(ctx context.Context, search string, typ SearchType, opts SearchOptions, progress func(searched int)) ([]FuzzyResult, error) {
+	if search == "" {
+		return nil, nil
+	}
+	if err := ValidateSearch(search, typ, SearchOptions{Mode: SearchFuzzy}); err != nil {
+		return nil, err
+	}
+	c, err := j.newScopedCursor(opts.Scope, opts.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	var results []FuzzyResult
+	var searched int
+	for c.next() {
+		searched++
+		if searched%searchCancelTick == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ErrCallerCanceled
+			default:
+			}
+		}
+		if r, ok := j.matchFuzzy(c.id(), search, typ); ok {
+			results = append(results, r)
+		}
+		if progress != nil && searched%int(j.ProgressUpdateTick) == 0 {
+			progress(searched)
+		}
+	}
+	if progress != nil && searched%int(j.ProgressUpdateTick) != 0 {
+		progress(searched)
+	}
+	slices.SortStableFunc(results, func(a, b FuzzyResult) int {
+		return b.Score - a.Score
+	})
+	return results, nil
+}
+
+// matchFuzzy matches the key or value of a node fuzzy as needed for a search type
+// and returns the best match.
+func (j *JSONDocument) matchFuzzy(id int32, search string, typ SearchType) (FuzzyResult, bool) {
+	n := j.values[id]
+	var best FuzzyResult
+	var found bool
+	try := func(text string, part MatchPart) {
+		m, ok := MatchFuzzy(search, text)
+		if ok && (!found || m.Score > best.Score) {
+			best = FuzzyResult{SearchMatch: SearchMatch{UID: id2uid(id), Part: part}, FuzzyMatch: m}
+			found = true
+		}
+	}
+	if (typ == SearchKey || typ == SearchAnything) && id != rootNodeID && j.values[j.parents[id]].Type != Array {
+		try(n.Key, MatchKey)
+	}
+	var isValue bool
+	switch n.Type {
+	case String:
+		isValue = typ == SearchString || typ == SearchAnything
+	case Number:
+		isValue = typ == SearchNumber || typ == SearchAnything
+	case Boolean, Null:
+		isValue = typ == SearchKeyword || typ == SearchAnything
+	}
+	if isValue {
+		s, _ := scalarText(n)
+		try(s, MatchValue)
+	}
+	return best, found
+}
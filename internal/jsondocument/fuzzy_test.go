@@ -0,0 +1,102 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchFuzzy(t *testing.T) {
+	cases := []struct {
+		pattern       string
+		text          string
+		wantOk        bool
+		wantPositions []int
+	}{
+		{"receivedAt", "receivedAt", true, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"rcvdat", "receivedAt", true, []int{0, 2, 5, 7, 8, 9}},
+		{"RECEIVED", "receivedAt", true, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{"recievedAt", "receivedAt", true, []int{0, 1, 2, 4, 3, 5, 6, 7, 8, 9}},
+		{"recevedAt", "receivedAt", true, []int{0, 1, 2, 3, 5, 6, 7, 8, 9}},
+		{"ab", "xaxb", true, []int{1, 3}},
+		{"ab", "ba", false, nil},
+		{"xyz", "receivedAt", false, nil},
+		{"recievdAtt", "receivedAt", false, nil},
+		{"", "alpha", false, nil},
+		{"ä", "Ärger", true, []int{0}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.pattern+" "+tc.text, func(t *testing.T) {
+			got, ok := jsondocument.MatchFuzzy(tc.pattern, tc.text)
+			if assert.Equal(t, tc.wantOk, ok) && ok {
+				assert.ElementsMatch(t, tc.wantPositions, got.Positions)
+			}
+		})
+	}
+	t.Run("should rank better matches higher", func(t *testing.T) {
+		score := func(pattern, text string) int {
+			m, ok := jsondocument.MatchFuzzy(pattern, text)
+			if !ok {
+				t.Fatalf("%s does not match %s", pattern, text)
+			}
+			return m.Score
+		}
+		assert.Greater(t, score("name", "name"), score("name", "firstName"))
+		assert.Greater(t, score("name", "firstName"), score("name", "nxaxmxe"))
+		assert.Greater(t, score("fn", "firstName"), score("fn", "often"))
+		assert.Greater(t, score("receivedAt", "receivedAt"), score("recievedAt", "receivedAt"))
+	})
+}
+
+func TestSearchFuzzy(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{"receivedAt": 1, "items": [{"recipient": "Bob"}, {"createdAt": "received"}], "sentAt": 2}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("should rank keys by score", func(t *testing.T) {
+		got, err := j.SearchFuzzy(ctx, "recievedAt", jsondocument.SearchKey, jsondocument.SearchOptions{}, nil)
+		if assert.NoError(t, err) && assert.Len(t, got, 1) {
+			assert.Equal(t, "/receivedAt", j.Pointer(got[0].UID))
+			assert.Equal(t, jsondocument.MatchKey, got[0].Part)
+		}
+		got, err = j.SearchFuzzy(ctx, "rec", jsondocument.SearchKey, jsondocument.SearchOptions{}, nil)
+		if assert.NoError(t, err) {
+			var paths []string
+			for _, r := range got {
+				paths = append(paths, j.Pointer(r.UID))
+			}
+			assert.Equal(t, []string{"/items/0/recipient", "/receivedAt"}, paths)
+		}
+	})
+	t.Run("should match keys and values", func(t *testing.T) {
+		got, err := j.SearchFuzzy(ctx, "received", jsondocument.SearchAnything, jsondocument.SearchOptions{}, nil)
+		if assert.NoError(t, err) && assert.Len(t, got, 2) {
+			assert.Equal(t, "/items/1/createdAt", j.Pointer(got[0].UID))
+			assert.Equal(t, jsondocument.MatchValue, got[0].Part)
+			assert.Equal(t, "/receivedAt", j.Pointer(got[1].UID))
+			assert.Equal(t, jsondocument.MatchKey, got[1].Part)
+		}
+	})
+	t.Run("should search within scope", func(t *testing.T) {
+		items, _ := j.ResolvePath("/items")
+		got, err := j.SearchFuzzy(ctx, "rec", jsondocument.SearchKey, jsondocument.SearchOptions{Scope: items}, nil)
+		if assert.NoError(t, err) && assert.Len(t, got, 1) {
+			assert.Equal(t, "/items/0/recipient", j.Pointer(got[0].UID))
+		}
+	})
+	t.Run("should find next fuzzy match", func(t *testing.T) {
+		uid, err := j.SearchWithOptions(ctx, "", "snt", jsondocument.SearchKey, jsondocument.SearchOptions{Mode: jsondocument.SearchFuzzy})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "/sentAt", j.Pointer(uid))
+		}
+	})
+	t.Run("should not allow fuzzy conditions", func(t *testing.T) {
+		_, err := j.SearchFuzzy(ctx, "a = 1", jsondocument.SearchCondition, jsondocument.SearchOptions{}, nil)
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidPattern)
+	})
+}
@@ -0,0 +1,168 @@
+package jsondocument
+
+import (
+	"errors"
+)
+
+var ErrNoHistory = errors.New("nothing to undo or redo")
+
+// history contains the edits of a document, which can be undone and redone.
+//
+// Edits are recorded as steps, which change only the affected nodes.
+// Removed nodes are kept in the document, so that steps can restore them without copying.
+type history struct {
+	group *operation // collects the steps of a running group
+	redo  []operation
+	saved int // sequence number of the current operation when the document was loaded or saved
+	seq   int // sequence number of the last recorded operation
+	undo  []operation
+}
+
+// operation is a named edit, which consists of one or more steps.
+type operation struct {
+	name  string
+	seq   int
+	steps []editStep
+}
+
+// editStep is a change of a document and its inverse.
+type editStep struct {
+	undo func()
+	redo func()
+}
+
+// current returns the sequence number of the last applied operation or 0 if there is none.
+func (h *history) current() int {
+	if len(h.undo) == 0 {
+		return 0
+	}
+	return h.undo[len(h.undo)-1].seq
+}
+
+// IsModified reports whether the document was edited since it was loaded.
+// A document is no longer modified when all its edits were undone.
+func (j *JSONDocument) IsModified() bool {
+	return j.history.current() != j.history.saved
+}
+
+// MarkSaved marks the current state of the document as saved,
+// so that it is no longer reported as modified.
+func (j *JSONDocument) MarkSaved() {
+	j.history.saved = j.history.current()
+}
+
+// Edits returns the number of changes made to the document by edits, including undo and redo.
+// Comparing it with an earlier result tells whether the document was changed in between.
+func (j *JSONDocument) Edits() int {
+	return j.edits
+}
+
+// CanUndo reports whether there is an edit which can be undone.
+func (j *JSONDocument) CanUndo() bool {
+	return len(j.history.undo) > 0
+}
+
+// CanRedo reports whether there is an undone edit which can be redone.
+func (j *JSONDocument) CanRedo() bool {
+	return len(j.history.redo) > 0
+}
+
+// UndoHistory returns the names of the edits which can be undone, starting with the most recent one.
+func (j *JSONDocument) UndoHistory() []string {
+	names := make([]string, len(j.history.undo))
+	for i, op := range j.history.undo {
+		names[len(names)-1-i] = op.name
+	}
+	return names
+}
+
+// RedoHistory returns the names of the edits which can be redone, starting with the next one.
+func (j *JSONDocument) RedoHistory() []string {
+	names := make([]string, len(j.history.redo))
+	for i, op := range j.history.redo {
+		names[len(names)-1-i] = op.name
+	}
+	return names
+}
+
+// Undo reverts the last edit and returns its name.
+// It returns [ErrNoHistory] when there is nothing to undo.
+func (j *JSONDocument) Undo() (string, error) {
+	h := &j.history
+	if len(h.undo) == 0 {
+		return "", ErrNoHistory
+	}
+	op := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	j.startChange()
+	defer j.indexMu.Unlock()
+	for i := len(op.steps) - 1; i >= 0; i-- {
+		op.steps[i].undo()
+	}
+	h.redo = append(h.redo, op)
+	return op.name, nil
+}
+
+// Redo applies the last undone edit again and returns its name.
+// It returns [ErrNoHistory] when there is nothing to redo.
+func (j *JSONDocument) Redo() (string, error) {
+	h := &j.history
+	if len(h.redo) == 0 {
+		return "", ErrNoHistory
+	}
+	op := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	j.startChange()
+	defer j.indexMu.Unlock()
+	for _, s := range op.steps {
+		s.redo()
+	}
+	h.undo = append(h.undo, op)
+	return op.name, nil
+}
+
+// Group runs f and records all edits made by f as one operation with the given name,
+// so that they are undone and redone together.
+// When f returns an error, all its edits are reverted and the error is returned.
+// Groups can be nested, in which case the edits belong to the outermost group.
+func (j *JSONDocument) Group(name string, f func() error) error {
+	h := &j.history
+	if h.group != nil {
+		return f()
+	}
+	op := &operation{name: name}
+	h.group = op
+	err := f()
+	h.group = nil
+	if err != nil {
+		j.startChange()
+		defer j.indexMu.Unlock()
+		for i := len(op.steps) - 1; i >= 0; i-- {
+			op.steps[i].undo()
+		}
+		return err
+	}
+	if len(op.steps) > 0 {
+		h.push(*op)
+	}
+	return nil
+}
+
+// record adds an edit to the history.
+func (j *JSONDocument) record(name string, undo, redo func()) {
+	h := &j.history
+	s := editStep{undo: undo, redo: redo}
+	if h.group != nil {
+		h.group.steps = append(h.group.steps, s)
+		return
+	}
+	h.push(operation{name: name, steps: []editStep{s}})
+}
+
+// push adds an operation to the history and clears the operations which could be redone.
+func (h *history) push(op operation) {
+	h.seq++
+	op.seq = h.seq
+	h.undo = append(h.undo, op)
+	h.redo = nil
+}
@@ -0,0 +1,170 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/widget"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := context.TODO()
+	const doc = `{"alpha": {"id": 1, "name": "a"}, "bravo": [1, 2, 3], "charlie": "c"}`
+	load := func(t *testing.T) *jsondocument.JSONDocument {
+		j := jsondocument.New()
+		if err := j.LoadData(ctx, parseJSON(doc)); err != nil {
+			t.Fatal(err)
+		}
+		return j
+	}
+	find := func(t *testing.T, j *jsondocument.JSONDocument, path string) widget.TreeNodeID {
+		uid, err := j.ResolvePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	cases := []struct {
+		name string
+		edit func(j *jsondocument.JSONDocument) error
+	}{
+		{"set scalar", func(j *jsondocument.JSONDocument) error {
+			return j.SetValue(find(t, j, "/charlie"), 5.0)
+		}},
+		{"replace object", func(j *jsondocument.JSONDocument) error {
+			return j.SetValue(find(t, j, "/alpha"), parseJSON(`[true, {"x": null}]`))
+		}},
+		{"rename", func(j *jsondocument.JSONDocument) error {
+			return j.RenameKey(find(t, j, "/alpha"), "delta")
+		}},
+		{"insert element", func(j *jsondocument.JSONDocument) error {
+			_, err := j.InsertElement(find(t, j, "/bravo"), 0, parseJSON(`{"a": [1]}`))
+			return err
+		}},
+		{"insert member", func(j *jsondocument.JSONDocument) error {
+			_, err := j.InsertMember("", "beta", "b")
+			return err
+		}},
+		{"delete", func(j *jsondocument.JSONDocument) error {
+			return j.Delete(find(t, j, "/bravo/1"))
+		}},
+		{"duplicate", func(j *jsondocument.JSONDocument) error {
+			_, err := j.Duplicate(find(t, j, "/alpha"))
+			return err
+		}},
+	}
+	for _, tc := range cases {
+		t.Run("should undo and redo: "+tc.name, func(t *testing.T) {
+			j := load(t)
+			original, size := j.ExtractValue(""), j.Size()
+			require.NoError(t, tc.edit(j))
+			edited, editedSize := j.ExtractValue(""), j.Size()
+			assert.True(t, j.IsModified())
+			assert.Len(t, j.UndoHistory(), 1)
+			_, err := j.Undo()
+			require.NoError(t, err)
+			assert.Equal(t, original, j.ExtractValue(""))
+			assert.Equal(t, size, j.Size())
+			assert.False(t, j.IsModified())
+			_, err = j.Redo()
+			require.NoError(t, err)
+			assert.Equal(t, edited, j.ExtractValue(""))
+			assert.Equal(t, editedSize, j.Size())
+			assert.True(t, j.IsModified())
+		})
+	}
+	t.Run("should undo and redo many edits in order", func(t *testing.T) {
+		j := load(t)
+		original := j.ExtractValue("")
+		require.NoError(t, j.SetValue(find(t, j, "/charlie"), parseJSON(`{"x": [1, 2]}`)))
+		_, err := j.InsertElement(find(t, j, "/charlie/x"), 1, "y")
+		require.NoError(t, err)
+		require.NoError(t, j.RenameKey(find(t, j, "/alpha"), "delta"))
+		require.NoError(t, j.Delete(find(t, j, "/bravo/0")))
+		_, err = j.Duplicate(find(t, j, "/delta"))
+		require.NoError(t, err)
+		require.NoError(t, j.Delete(find(t, j, "/charlie/x/0")))
+		final, size := j.ExtractValue(""), j.Size()
+		for j.CanUndo() {
+			_, err := j.Undo()
+			require.NoError(t, err)
+		}
+		assert.Equal(t, original, j.ExtractValue(""))
+		assert.Equal(t, 9, j.Size())
+		for j.CanRedo() {
+			_, err := j.Redo()
+			require.NoError(t, err)
+		}
+		assert.Equal(t, final, j.ExtractValue(""))
+		assert.Equal(t, size, j.Size())
+		assert.Equal(t, "/charlie/x/0", j.Pointer(find(t, j, "/charlie/x/0")))
+	})
+	t.Run("should name operations", func(t *testing.T) {
+		j := load(t)
+		require.NoError(t, j.Delete(find(t, j, "/bravo/0")))
+		require.NoError(t, j.RenameKey(find(t, j, "/alpha"), "delta"))
+		_, err := j.Duplicate(find(t, j, "/charlie"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Duplicate /charlie", `Rename /alpha to "delta"`, "Delete /bravo/0"}, j.UndoHistory())
+		name, err := j.Undo()
+		require.NoError(t, err)
+		assert.Equal(t, "Duplicate /charlie", name)
+		_, err = j.Undo()
+		require.NoError(t, err)
+		assert.Equal(t, []string{`Rename /alpha to "delta"`, "Duplicate /charlie"}, j.RedoHistory())
+	})
+	t.Run("should clear redo after new edit", func(t *testing.T) {
+		j := load(t)
+		require.NoError(t, j.Delete(find(t, j, "/bravo/0")))
+		_, err := j.Undo()
+		require.NoError(t, err)
+		require.NoError(t, j.Delete(find(t, j, "/charlie")))
+		assert.False(t, j.CanRedo())
+		_, err = j.Redo()
+		assert.ErrorIs(t, err, jsondocument.ErrNoHistory)
+	})
+	t.Run("should return error when nothing to undo", func(t *testing.T) {
+		j := load(t)
+		_, err := j.Undo()
+		assert.ErrorIs(t, err, jsondocument.ErrNoHistory)
+	})
+	t.Run("should undo group as one operation", func(t *testing.T) {
+		j := load(t)
+		original := j.ExtractValue("")
+		err := j.Group("Replace", func() error {
+			if err := j.SetValue(find(t, j, "/charlie"), "x"); err != nil {
+				return err
+			}
+			return j.SetValue(find(t, j, "/alpha/name"), "x")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Replace"}, j.UndoHistory())
+		_, err = j.Undo()
+		require.NoError(t, err)
+		assert.Equal(t, original, j.ExtractValue(""))
+	})
+	t.Run("should revert group on error", func(t *testing.T) {
+		j := load(t)
+		original := j.ExtractValue("")
+		err := j.Group("Replace", func() error {
+			if err := j.Delete(find(t, j, "/alpha")); err != nil {
+				return err
+			}
+			return j.RenameKey(find(t, j, "/bravo"), "charlie")
+		})
+		assert.ErrorIs(t, err, jsondocument.ErrKeyExists)
+		assert.Equal(t, original, j.ExtractValue(""))
+		assert.False(t, j.CanUndo())
+		assert.False(t, j.IsModified())
+	})
+	t.Run("should reset history when loading", func(t *testing.T) {
+		j := load(t)
+		require.NoError(t, j.Delete(find(t, j, "/alpha")))
+		require.NoError(t, j.LoadData(ctx, parseJSON(doc)))
+		assert.False(t, j.CanUndo())
+		assert.False(t, j.IsModified())
+	})
+}
@@ -0,0 +1,333 @@
+package jsondocument
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// searchIndex is an inverted index of a document for fast lookups of keys and values.
+// All lists contain node IDs in ascending order, which is also the document order.
+type searchIndex struct {
+	size     int32              // number of indexed nodes
+	keys     map[string][]int32 // nodes by lower case key, except array elements
+	tokens   map[string][]int32 // string nodes by lower case word
+	numbers  []int32            // number nodes
+	byValue  []indexedNumber    // number nodes sorted by value
+	keywords []int32            // boolean and null nodes
+}
+
+// indexedNumber is the value of a number node.
+type indexedNumber struct {
+	value float64
+	id    int32
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		keys:   make(map[string][]int32),
+		tokens: make(map[string][]int32),
+	}
+}
+
+// add adds the nodes from the size of the index up to size to the index.
+// Node IDs must be greater than all IDs already in the index.
+func (idx *searchIndex) add(ctx context.Context, values []Node, parents []int32, size int32) error {
+	var numbers []indexedNumber
+	for id := max(idx.size, 1); id < size; id++ {
+		if id%searchCancelTick == 0 {
+			select {
+			case <-ctx.Done():
+				return ErrCallerCanceled
+			default:
+			}
+		}
+		n := values[id]
+		if values[parents[id]].Type != Array {
+			k := strings.ToLower(n.Key)
+			idx.keys[k] = append(idx.keys[k], id)
+		}
+		switch n.Type {
+		case String:
+			for _, t := range tokenize(n.Value.(string)) {
+				ids := idx.tokens[t]
+				if len(ids) > 0 && ids[len(ids)-1] == id {
+					continue // word occurs more than once
+				}
+				idx.tokens[t] = append(ids, id)
+			}
+		case Number:
+			idx.numbers = append(idx.numbers, id)
+			numbers = append(numbers, indexedNumber{value: n.Value.(float64), id: id})
+		case Boolean, Null:
+			idx.keywords = append(idx.keywords, id)
+		}
+	}
+	slices.SortFunc(numbers, compareIndexedNumbers)
+	if len(idx.byValue) == 0 {
+		idx.byValue = numbers
+	} else if len(numbers) > 0 {
+		idx.byValue = mergeIndexedNumbers(idx.byValue, numbers)
+	}
+	idx.size = size
+	return nil
+}
+
+func compareIndexedNumbers(a, b indexedNumber) int {
+	if c := cmp.Compare(a.value, b.value); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.id, b.id)
+}
+
+// mergeIndexedNumbers returns the sorted numbers of two sorted lists.
+func mergeIndexedNumbers(a, b []indexedNumber) []indexedNumber {
+	r := make([]indexedNumber, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if compareIndexedNumbers(a[0], b[0]) <= 0 {
+			r = append(r, a[0])
+			a = a[1:]
+		} else {
+			r = append(r, b[0])
+			b = b[1:]
+		}
+	}
+	r = append(r, a...)
+	return append(r, b...)
+}
+
+// numbersBetween returns the number nodes with values from lo to hi in ascending order of their IDs.
+func (idx *searchIndex) numbersBetween(lo, hi float64) []int32 {
+	if len(idx.byValue) == 0 {
+		return nil
+	}
+	if lo <= idx.byValue[0].value && hi >= idx.byValue[len(idx.byValue)-1].value {
+		return idx.numbers
+	}
+	from, _ := slices.BinarySearchFunc(idx.byValue, lo, func(n indexedNumber, x float64) int {
+		return cmp.Compare(n.value, x)
+	})
+	to, _ := slices.BinarySearchFunc(idx.byValue[from:], hi, func(n indexedNumber, x float64) int {
+		if n.value <= x {
+			return -1
+		}
+		return 1
+	})
+	ids := make([]int32, 0, to)
+	for _, n := range idx.byValue[from : from+to] {
+		ids = append(ids, n.id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// BuildIndex builds a search index for the document, which speeds up searches
+// for keys, words in strings, numbers and keywords. Searches which can not use the index
+// still search the whole document.
+//
+// It is safe to search the document while the index is being built.
+// The index is extended with the nodes which are appended to a stream
+// and is not stored when the document changed while it was built.
+// Documents which were edited are not indexed,
+// because their node IDs are no longer in document order.
+// It returns [ErrCallerCanceled] when the context is canceled.
+func (j *JSONDocument) BuildIndex(ctx context.Context) error {
+	j.indexMu.Lock()
+	values, parents, size, edits := j.values, j.parents, j.n, j.edits
+	j.indexMu.Unlock()
+	if edits > 0 {
+		return nil
+	}
+	idx := newSearchIndex()
+	if err := idx.add(ctx, values, parents, size); err != nil {
+		return err
+	}
+	j.indexMu.Lock()
+	defer j.indexMu.Unlock()
+	if j.n != size || j.edits != edits {
+		return nil // document has changed
+	}
+	j.index = idx
+	return nil
+}
+
+// HasIndex reports whether the document has a search index.
+func (j *JSONDocument) HasIndex() bool {
+	return j.searchIndex() != nil
+}
+
+// DropIndex removes the search index and frees its memory.
+func (j *JSONDocument) DropIndex() {
+	j.indexMu.Lock()
+	defer j.indexMu.Unlock()
+	j.index = nil
+}
+
+func (j *JSONDocument) searchIndex() *searchIndex {
+	j.indexMu.Lock()
+	defer j.indexMu.Unlock()
+	return j.index
+}
+
+// tokenize returns the lower case words of a text.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// candidates returns the IDs of all nodes which can match a search in ascending order
+// and reports whether the index can be used for the search.
+// The candidates still need to be checked with a matcher.
+func (idx *searchIndex) candidates(search string, typ SearchType, opts SearchOptions) ([]int32, bool) {
+	isExact := opts.Mode == SearchWildcard && !strings.Contains(search, "*")
+	switch typ {
+	case SearchKey:
+		if !isExact {
+			return nil, false
+		}
+		return idx.keys[strings.ToLower(search)], true
+	case SearchString:
+		if !isExact {
+			return nil, false
+		}
+		return idx.words(search)
+	case SearchNumber:
+		if opts.Mode != SearchRegex {
+			if q, ok, err := parseNumberQuery(search); err == nil && ok {
+				return idx.numbersBetween(q.lo, q.hi), true
+			}
+		}
+		if x, err := strconv.ParseFloat(search, 64); err == nil && isExact {
+			return idx.numbersBetween(x, x), true
+		}
+		return idx.numbers, true
+	case SearchKeyword:
+		return idx.keywords, true
+	case SearchAnything:
+		if !isExact {
+			return nil, false
+		}
+		words, ok := idx.words(search)
+		if !ok {
+			return nil, false // strings without words can not be looked up
+		}
+		ids := slices.Clone(idx.keys[strings.ToLower(search)])
+		ids = append(ids, words...)
+		if x, err := strconv.ParseFloat(search, 64); err == nil {
+			ids = append(ids, idx.numbersBetween(x, x)...)
+		}
+		switch strings.ToLower(search) {
+		case "true", "false", "null":
+			ids = append(ids, idx.keywords...)
+		}
+		slices.Sort(ids)
+		return slices.Compact(ids), true
+	}
+	return nil, false
+}
+
+// words returns the string nodes containing all words of a text
+// and reports whether the text has any words.
+func (idx *searchIndex) words(s string) ([]int32, bool) {
+	tokens := tokenize(s)
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	lists := make([][]int32, len(tokens))
+	for i, t := range tokens {
+		lists[i] = idx.tokens[t]
+	}
+	slices.SortFunc(lists, func(a, b []int32) int {
+		return len(a) - len(b)
+	})
+	var ids []int32
+	for _, id := range lists[0] {
+		found := true
+		for _, l := range lists[1:] {
+			if _, ok := slices.BinarySearch(l, id); !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			ids = append(ids, id)
+		}
+	}
+	return ids, true
+}
+
+// indexCandidates returns the candidates for a search from the search index
+// and reports whether the index can be used.
+func (j *JSONDocument) indexCandidates(search string, typ SearchType, opts SearchOptions) ([]int32, bool) {
+	idx := j.searchIndex()
+	if idx == nil {
+		return nil, false
+	}
+	return idx.candidates(search, typ, opts)
+}
+
+// searchCandidates works like [JSONDocument.SearchWithOptions], but only checks the candidates.
+// The cursor is positioned at the starting node.
+func (j *JSONDocument) searchCandidates(ctx context.Context, ids []int32, c *cursor, startID int32, match matcher, opts SearchOptions, progress func(scanned int)) (widget.TreeNodeID, error) {
+	i, found := slices.BinarySearch(ids, startID)
+	after := i
+	if found {
+		after++
+	}
+	var checked int
+	if progress != nil {
+		defer func() {
+			if checked%int(j.ProgressUpdateTick) != 0 {
+				progress(checked)
+			}
+		}()
+	}
+	check := func(id int32) (bool, error) {
+		checked++
+		if checked%searchCancelTick == 0 {
+			select {
+			case <-ctx.Done():
+				return false, ErrCallerCanceled
+			default:
+			}
+		}
+		if progress != nil && checked%int(j.ProgressUpdateTick) == 0 {
+			progress(checked)
+		}
+		return c.contains(id) && match(j, id) != 0, nil
+	}
+	var ranges [][2]int // ranges of candidates to check in order
+	if opts.Backward {
+		ranges = append(ranges, [2]int{i - 1, -1})
+		if opts.Wrap {
+			ranges = append(ranges, [2]int{len(ids) - 1, after - 1})
+		}
+	} else {
+		ranges = append(ranges, [2]int{after, len(ids)})
+		if opts.Wrap {
+			ranges = append(ranges, [2]int{0, i})
+		}
+	}
+	for _, r := range ranges {
+		step := 1
+		if r[0] > r[1] {
+			step = -1
+		}
+		for k := r[0]; k != r[1]; k += step {
+			ok, err := check(ids[k])
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return id2uid(ids[k]), nil
+			}
+		}
+	}
+	return "", ErrNotFound
+}
@@ -0,0 +1,170 @@
+package jsondocument_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildIndex(t *testing.T) {
+	ctx := context.TODO()
+	load := func() *jsondocument.JSONDocument {
+		j := jsondocument.New()
+		data := parseJSON(`{
+			"alpha": {"Name": "Johnny Walker", "id": 1, "tags": ["whisky", "Scotch whisky"]},
+			"bravo": {"name": "jim beam", "id": 2.5, "active": true},
+			"charlie": [{"name": "Jim-Beam", "id": 42, "meta": null}, 42, "42", "true"],
+			"42": "jim",
+			"delta": "$$"
+		}`)
+		if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+			t.Fatal(err)
+		}
+		return j
+	}
+	plain := load()
+	indexed := load()
+	if err := indexed.BuildIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, plain.HasIndex())
+	assert.True(t, indexed.HasIndex())
+	find := func(j *jsondocument.JSONDocument, path string) string {
+		uid, err := j.ResolvePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	cases := []struct {
+		search string
+		typ    jsondocument.SearchType
+		opts   jsondocument.SearchOptions
+	}{
+		{"name", jsondocument.SearchKey, jsondocument.SearchOptions{}},
+		{"name", jsondocument.SearchKey, jsondocument.SearchOptions{IgnoreCase: true}},
+		{"n*", jsondocument.SearchKey, jsondocument.SearchOptions{}},
+		{"jim beam", jsondocument.SearchString, jsondocument.SearchOptions{}},
+		{"jim beam", jsondocument.SearchString, jsondocument.SearchOptions{IgnoreCase: true}},
+		{"whisky", jsondocument.SearchString, jsondocument.SearchOptions{}},
+		{"whisky", jsondocument.SearchString, jsondocument.SearchOptions{Mode: jsondocument.SearchContains}},
+		{"-", jsondocument.SearchString, jsondocument.SearchOptions{}},
+		{"42", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"> 2", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"<= 2.5", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"= 42", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"!= 42", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"2..42", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"int", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"2.5", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"4*", jsondocument.SearchNumber, jsondocument.SearchOptions{}},
+		{"true", jsondocument.SearchKeyword, jsondocument.SearchOptions{}},
+		{"null", jsondocument.SearchKeyword, jsondocument.SearchOptions{}},
+		{"42", jsondocument.SearchAnything, jsondocument.SearchOptions{}},
+		{"TRUE", jsondocument.SearchAnything, jsondocument.SearchOptions{IgnoreCase: true}},
+		{"jim", jsondocument.SearchAnything, jsondocument.SearchOptions{}},
+		{"2.5", jsondocument.SearchAnything, jsondocument.SearchOptions{}},
+		{"$$", jsondocument.SearchAnything, jsondocument.SearchOptions{}},
+		{"-", jsondocument.SearchAnything, jsondocument.SearchOptions{}},
+		{`id > 1`, jsondocument.SearchCondition, jsondocument.SearchOptions{}},
+	}
+	scopes := []struct {
+		scope    string
+		maxDepth int
+	}{
+		{"", 0},
+		{"", 1},
+		{"/charlie", 0},
+		{"/charlie", 1},
+	}
+	for _, tc := range cases {
+		for _, sc := range scopes {
+			t.Run(fmt.Sprintf("%s %d %s %d", tc.search, tc.typ, sc.scope, sc.maxDepth), func(t *testing.T) {
+				findAll := func(j *jsondocument.JSONDocument) []string {
+					opts := tc.opts
+					opts.Scope = find(j, sc.scope)
+					opts.MaxDepth = sc.maxDepth
+					var got []string
+					err := j.SearchAllMatches(ctx, tc.search, tc.typ, opts, func(m jsondocument.SearchMatch) {
+						got = append(got, fmt.Sprintf("%s %s", j.Pointer(m.UID), m.Part))
+					}, nil)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return got
+				}
+				assert.Equal(t, findAll(plain), findAll(indexed))
+				for _, start := range []string{"", "/alpha/id", "/charlie/0/name", "/charlie/3"} {
+					for _, dir := range []jsondocument.SearchOptions{{}, {Backward: true}, {Wrap: true}, {Backward: true, Wrap: true}} {
+						search := func(j *jsondocument.JSONDocument) string {
+							opts := tc.opts
+							opts.Scope = find(j, sc.scope)
+							opts.MaxDepth = sc.maxDepth
+							opts.Backward = dir.Backward
+							opts.Wrap = dir.Wrap
+							uid, err := j.SearchWithOptions(ctx, find(j, start), tc.search, tc.typ, opts)
+							if err != nil {
+								return err.Error()
+							}
+							return j.Pointer(uid)
+						}
+						assert.Equal(t, search(plain), search(indexed), "start: %s backward: %v wrap: %v", start, dir.Backward, dir.Wrap)
+					}
+				}
+			})
+		}
+	}
+	t.Run("should report number of candidates as search size", func(t *testing.T) {
+		assert.Equal(t, 2, indexed.SearchSize("42", jsondocument.SearchNumber, jsondocument.SearchOptions{}))
+		assert.Equal(t, plain.Size()-1, plain.SearchSize("42", jsondocument.SearchNumber, jsondocument.SearchOptions{}))
+		assert.Equal(t, indexed.Size()-1, indexed.SearchSize("n*", jsondocument.SearchKey, jsondocument.SearchOptions{}))
+	})
+	t.Run("should return error when canceled", func(t *testing.T) {
+		j := jsondocument.New()
+		a := make([]any, 5000)
+		for i := range a {
+			a[i] = float64(i)
+		}
+		if err := j.LoadData(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := j.BuildIndex(ctx)
+		assert.ErrorIs(t, err, jsondocument.ErrCallerCanceled)
+		assert.False(t, j.HasIndex())
+	})
+	t.Run("should add lines appended to a stream to the index", func(t *testing.T) {
+		j := jsondocument.New()
+		if err := j.Load(ctx, jsondocument.MakeURIReadCloser(strings.NewReader("3\n{\"id\": 1}\n"), "test.ndjson"), binding.NewUntyped()); err != nil {
+			t.Fatal(err)
+		}
+		if err := j.BuildIndex(ctx); err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, j.HasIndex())
+		if _, err := j.Append(ctx, strings.NewReader("{\"id\": 2}\n1\n")); err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, j.HasIndex())
+		search := func(search string, typ jsondocument.SearchType) []string {
+			var got []string
+			err := j.SearchAll(ctx, search, typ, jsondocument.SearchOptions{}, func(uid string) {
+				got = append(got, j.Pointer(uid))
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return got
+		}
+		assert.Equal(t, []string{"/1/id", "/2/id"}, search("id", jsondocument.SearchKey))
+		assert.Equal(t, []string{"/0", "/1/id", "/2/id", "/3"}, search("int", jsondocument.SearchNumber))
+		assert.Equal(t, []string{"/1/id", "/3"}, search("1", jsondocument.SearchNumber))
+		assert.Equal(t, []string{"/0", "/2/id"}, search("2..3", jsondocument.SearchNumber))
+	})
+}
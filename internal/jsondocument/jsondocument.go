@@ -2,15 +2,17 @@
 package jsondocument
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/data/binding"
@@ -23,16 +25,21 @@ var json = jsoniter.ConfigCompatibleWithStandardLibrary
 const (
 	// Update progress after x added nodes
 	progressUpdateTick = 10_000
+	// Update progress after x bytes read
+	progressUpdateBytes = 1 << 20
 	// Total number of load steps
 	totalLoadSteps = 3
+	// ID of root node
+	rootNodeID = 0
 	// Parent ID of root node
 	rootNodeParentID = -1
-	// Search target not found
-	notFound = -1
+	// Check for cancellation after x searched nodes
+	searchCancelTick = 1_000
 )
 
 var ErrCallerCanceled = errors.New("process canceled by caller")
 var ErrNotFound = errors.New("not found")
+var ErrNotStream = errors.New("not a stream of JSON values")
 
 // JSONType represents the type of a JSON value.
 type JSONType uint8
@@ -67,16 +74,6 @@ func (t JSONType) String() string {
 	return s
 }
 
-// SearchType represents the type of search to perform.
-type SearchType uint
-
-const (
-	SearchKey SearchType = iota
-	SearchString
-	SearchNumber
-	SearchKeyword
-)
-
 // ProgressInfo represents the current progress while loading a document
 // and is used to communicate the the UI.
 type ProgressInfo struct {
@@ -103,12 +100,23 @@ type JSONDocument struct {
 
 	progressInfo  binding.Untyped
 	elementsCount int
+	isStream      bool
+	loadedBytes   int64
 
 	// ids are stored as int32 to save memory. The API converts them to and from UID strings.
 	ids     map[int32][]int32
 	values  []Node  // using a slice here instead of a map for better load time
 	parents []int32 // ditto
 	n       int32
+
+	// Edits
+	detached int32 // number of nodes, which were removed by edits
+	edits    int   // number of changes by edits, including undo and redo
+	history  history
+
+	// Search index, which protects also the size of the document while it is built
+	indexMu sync.Mutex
+	index   *searchIndex
 }
 
 // Returns a new JSONDocument object.
@@ -144,6 +152,8 @@ func (j *JSONDocument) Value(uid widget.TreeNodeID) Node {
 
 // Load loads JSON data from a reader and builds a new JSON document from it.
 // It reports it's current progress to the caller via updates to progressInfo.
+// When the reader reports the length of it's content, e.g. a HTTP response,
+// the progress of reading the data is reported too.
 // Closes the reader.
 func (j *JSONDocument) Load(ctx context.Context, reader fyne.URIReadCloser, progressInfo binding.Untyped) error {
 	j.progressInfo = progressInfo
@@ -185,9 +195,147 @@ func (j *JSONDocument) Load(ctx context.Context, reader fyne.URIReadCloser, prog
 	return nil
 }
 
+// LoadData builds a new JSON document from a Go value,
+// e.g. one returned by ExtractValue. The value must be an object or an array.
+func (j *JSONDocument) LoadData(ctx context.Context, data any) error {
+	sizer := JSONTreeSizer{}
+	size, err := sizer.Calculate(data)
+	if err != nil {
+		return err
+	}
+	j.elementsCount = size
+	j.isStream = false
+	j.loadedBytes = 0
+	return j.render(ctx, data, int32(size))
+}
+
 // Size returns the number of nodes.
 func (j *JSONDocument) Reset() {
 	j.initialize(0)
+	j.isStream = false
+	j.loadedBytes = 0
+}
+
+// IsStream reports whether the document was loaded from a stream of JSON values,
+// e.g. a NDJSON file. The values of a stream are the elements of the root array.
+func (j *JSONDocument) IsStream() bool {
+	return j.isStream
+}
+
+// LoadedBytes returns the number of bytes the document was loaded from.
+func (j *JSONDocument) LoadedBytes() int64 {
+	return j.loadedBytes
+}
+
+// Append parses new lines from a stream of JSON values, e.g. a NDJSON file,
+// and appends them as elements to the root array.
+// Only complete lines are parsed. Returns the number of bytes consumed.
+// The new elements are added to the search index.
+func (j *JSONDocument) Append(ctx context.Context, r io.Reader) (int64, error) {
+	if !j.isStream {
+		return 0, ErrNotStream
+	}
+	values, consumed, err := readLines(newReaderContext(ctx, io.NopCloser(r)), false)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return consumed, nil
+	}
+	size := 0
+	for _, v := range values {
+		sizer := JSONTreeSizer{}
+		size += sizer.calculateValue(v)
+	}
+	j.indexMu.Lock()
+	defer j.indexMu.Unlock()
+	j.grow(size)
+	n, offset := j.n, len(j.ids[0])
+	for i, v := range values {
+		if err := j.addValue(ctx, 0, arrayKey(offset+i), v); err != nil {
+			// remove the elements added so far, so that the document stays unchanged
+			for id := n; id < j.n; id++ {
+				j.values[id] = Node{}
+				delete(j.ids, id)
+			}
+			j.ids[0] = j.ids[0][:offset]
+			j.n = n
+			return 0, err
+		}
+	}
+	if j.index != nil {
+		// the new nodes have the highest IDs, so the lists of the index stay in document order
+		if err := j.index.add(ctx, j.values, j.parents, j.n); err != nil {
+			j.index = nil
+		}
+	}
+	j.loadedBytes += consumed
+	return consumed, nil
+}
+
+// readLines parses the lines of a stream of JSON values
+// and returns the values together with the number of bytes consumed.
+// A last line without a line break is only parsed when all is set.
+// Otherwise it is not consumed, so that it can be parsed when it is complete.
+func readLines(r io.Reader, all bool) ([]any, int64, error) {
+	values := []any{}
+	var consumed int64
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && (!all || len(line) == 0) {
+			break // incomplete lines are parsed later
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil, 0, ErrCallerCanceled
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, err
+		}
+		consumed += int64(len(line))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(line, &v); err != nil {
+			return nil, 0, err
+		}
+		values = append(values, v)
+	}
+	return values, consumed, nil
+}
+
+// KeyPath returns the keys of all nodes from the top to the given node.
+func (j *JSONDocument) KeyPath(uid widget.TreeNodeID) []string {
+	if uid == "" {
+		return []string{}
+	}
+	var keys []string
+	for _, uid2 := range j.Path(uid) {
+		keys = append(keys, j.Value(uid2).Key)
+	}
+	keys = append(keys, j.Value(uid).Key)
+	return keys
+}
+
+// FindKeyPath returns the UID of the node at the end of a path of keys
+// or ErrNotFound if no node exists for that path.
+func (j *JSONDocument) FindKeyPath(keys []string) (widget.TreeNodeID, error) {
+	var id int32
+	for _, k := range keys {
+		found := false
+		for _, childID := range j.ids[id] {
+			if j.values[childID].Key == k {
+				id = childID
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", ErrNotFound
+		}
+	}
+	return id2uid(id), nil
 }
 
 // Parent returns the UID of the parent node.
@@ -213,7 +361,7 @@ func (j *JSONDocument) Path(uid widget.TreeNodeID) []widget.TreeNodeID {
 
 // Size returns the number of nodes.
 func (j *JSONDocument) Size() int {
-	return int(j.n)
+	return int(j.n - j.detached)
 }
 
 // readCloserCtx adds context to a ReadCloser and allows a stream to be canceled.
@@ -238,20 +386,80 @@ func newReaderContext(ctx context.Context, r io.ReadCloser) io.ReadCloser {
 	return &readCloserCtx{ctx: ctx, r: r}
 }
 
+// contentLengther is implemented by readers which know the length of their content.
+type contentLengther interface {
+	ContentLength() int64
+}
+
+// progressReader is a reader which counts the bytes read
+// and reports it's progress when the total is known.
+type progressReader struct {
+	r         io.Reader
+	n         int64
+	next      int64
+	total     int64
+	setUpdate func(p float64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.setUpdate != nil && r.n >= r.next {
+		r.next = r.n + progressUpdateBytes
+		r.setUpdate(min(float64(r.n)/float64(r.total), 1))
+	}
+	return n, err
+}
+
 func (j *JSONDocument) load(ctx context.Context, reader io.ReadCloser) (any, error) {
 	defer reader.Close()
+	j.isStream = false
+	j.loadedBytes = 0
 	if err := j.setProgressInfo(ProgressInfo{CurrentStep: 1}); err != nil {
 		return nil, err
 	}
 	var data any
-	reader2 := newReaderContext(ctx, reader)
+	reader2 := &progressReader{r: newReaderContext(ctx, reader)}
+	if x, ok := reader.(contentLengther); ok && x.ContentLength() > 0 {
+		reader2.total = x.ContentLength()
+		reader2.setUpdate = func(p float64) {
+			if err := j.setProgressInfo(ProgressInfo{CurrentStep: 1, Progress: p}); err != nil {
+				slog.Warn("Failed to set progress", "err", err)
+			}
+		}
+	}
+	if isStreamFile(reader) {
+		// A stream of JSON values, e.g. NDJSON, is loaded as array
+		values, consumed, err := readLines(reader2, true)
+		if err != nil {
+			return nil, err
+		}
+		j.isStream = true
+		j.loadedBytes = consumed
+		return values, nil
+	}
 	dec := json.NewDecoder(reader2)
 	if err := dec.Decode(&data); err != nil {
 		return nil, err
 	}
+	j.loadedBytes = reader2.n
 	return data, nil
 }
 
+// isStreamFile reports whether a reader is for a file with a stream of JSON values,
+// e.g. NDJSON. This is detected from it's file extension.
+func isStreamFile(reader io.ReadCloser) bool {
+	r, ok := reader.(fyne.URIReadCloser)
+	if !ok || r.URI() == nil {
+		return false
+	}
+	switch strings.ToLower(r.URI().Extension()) {
+	case ".ndjson", ".jsonl", ".ldjson":
+		return true
+	}
+	return false
+}
+
 // render is the main method for rendering the JSON data into a tree.
 func (j *JSONDocument) render(ctx context.Context, data any, size int32) error {
 	j.initialize(size)
@@ -291,19 +499,23 @@ func (j *JSONDocument) addObject(ctx context.Context, parentID int32, data map[s
 
 // addArray adds a JSON array to the tree.
 func (j *JSONDocument) addArray(ctx context.Context, parentID int32, a []any) error {
-	var sb strings.Builder
 	for i, v := range a {
-		sb.Reset()
-		sb.WriteByte('[')
-		sb.WriteString(strconv.Itoa(i))
-		sb.WriteByte(']')
-		if err := j.addValue(ctx, parentID, sb.String(), v); err != nil {
+		if err := j.addValue(ctx, parentID, arrayKey(i), v); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// arrayKey returns the key of an array element, e.g. "[3]".
+func arrayKey(i int) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	sb.WriteString(strconv.Itoa(i))
+	sb.WriteByte(']')
+	return sb.String()
+}
+
 // addValue adds a JSON value to the tree.
 func (j *JSONDocument) addValue(ctx context.Context, parentID int32, k string, v any) error {
 	switch v2 := v.(type) {
@@ -389,10 +601,23 @@ func (j *JSONDocument) addNode(ctx context.Context, parentID int32, key string,
 //
 // A valid tree includes a root node (ID=0) and at least one normal node.
 func (j *JSONDocument) initialize(size int32) {
+	j.indexMu.Lock()
+	defer j.indexMu.Unlock()
+	j.index = nil
 	j.ids = make(map[int32][]int32)
 	j.values = make([]Node, size)
 	j.parents = make([]int32, size)
 	j.n = 0
+	j.detached = 0
+	j.edits = 0
+	j.history = history{}
+}
+
+// grow allocates memory for adding n more nodes to an existing tree.
+func (j *JSONDocument) grow(n int) {
+	j.values = append(j.values[:j.n], make([]Node, n)...)
+	j.parents = append(j.parents[:j.n], make([]int32, n)...)
+	j.elementsCount = int(j.n) + n
 }
 
 func (j *JSONDocument) setProgressInfo(info ProgressInfo) error {
@@ -404,190 +629,88 @@ func (j *JSONDocument) setProgressInfo(info ProgressInfo) error {
 	return nil
 }
 
-// Search returns the next node with a matching key or an error if not found or canceled.
-// The starting node will be ignored, so that is is possible to find successive nodes with the same key.
-// The search direction is from top to bottom.
-func (j *JSONDocument) Search(ctx context.Context, uid widget.TreeNodeID, search string, typ SearchType) (widget.TreeNodeID, error) {
-	if search == "" {
-		return "", ErrNotFound
-	}
-	id := uid2id(uid)
-	startID := id
-	pattern, err := regexp.Compile(wildCardToRegexp(search))
-	if err != nil {
-		return "", err
-	}
-
-	for {
-		foundID, err := j.searchNode(ctx, id, pattern, typ)
-		if err != nil {
-			return "", err
-		}
-		if foundID != startID && foundID != notFound {
-			return id2uid(foundID), nil
-		}
-		for {
-			parentID := j.parents[id]
-			childIDs := j.ids[parentID]
-			idx := slices.Index(childIDs, id)
-			if idx < len(childIDs)-1 {
-				id = childIDs[idx+1]
-				break
-			}
-			if parentID == rootNodeParentID || j.parents[parentID] == rootNodeParentID {
-				return "", ErrNotFound
-			}
-			id = parentID
-			select {
-			case <-ctx.Done():
-				return "", ErrCallerCanceled
-			default:
-			}
-		}
+// Extract returns a segment of the JSON document, with the given UID as new root container.
+// Note that only arrays and objects can be extracted
+func (j *JSONDocument) Extract(uid widget.TreeNodeID) ([]byte, error) {
+	n := j.Value(uid)
+	if n.Type != Array && n.Type != Object {
+		return nil, fmt.Errorf("can only extract objects and arrays")
 	}
+	return json.Marshal(j.ExtractValue(uid))
 }
 
-func (j *JSONDocument) searchNode(ctx context.Context, id int32, pattern *regexp.Regexp, typ SearchType) (int32, error) {
-	n := j.values[id]
-	if n.Type == Array || n.Type == Object {
-		foundID, err := j.searchContainer(ctx, id, pattern, typ)
-		if err != nil {
-			return 0, err
-		}
-		if foundID != notFound {
-			return foundID, nil
-		}
-	}
-	switch typ {
-	case SearchKey:
-		if pattern.MatchString(n.Key) {
-			return id, nil
-		}
-	case SearchKeyword:
-		switch n.Type {
-		case Boolean:
-			if pattern.MatchString(fmt.Sprint(n.Value)) {
-				return id, nil
-			}
-		case Null:
-			if pattern.MatchString("null") {
-				return id, nil
-			}
-		default:
-			return notFound, nil
-		}
-	case SearchNumber:
-		if n.Type != Number {
-			return notFound, nil
-		}
-		v := n.Value.(float64)
-		if pattern.MatchString(strconv.FormatFloat(v, 'f', -1, 64)) {
-			return id, nil
-		}
-	case SearchString:
-		if n.Type != String {
-			return notFound, nil
-		}
-		v := n.Value.(string)
-		if pattern.MatchString(v) {
-			return id, nil
-		}
-	default:
-		panic("Undefined search type")
-	}
-	return notFound, nil
+// MarshalIndent returns the indented JSON encoding of a Go value like [encoding/json.MarshalIndent].
+func MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(v, prefix, indent)
 }
 
-func (j *JSONDocument) searchContainer(ctx context.Context, id int32, pattern *regexp.Regexp, typ SearchType) (int32, error) {
-	select {
-	case <-ctx.Done():
-		return 0, ErrCallerCanceled
-	default:
-	}
-	for _, childID := range j.ids[id] {
-		foundID, err := j.searchNode(ctx, childID, pattern, typ)
-		if err != nil {
-			return 0, err
-		}
-		if foundID != notFound {
-			return foundID, nil
-		}
-	}
-	return notFound, nil
+// ExtractValue returns the value of a node as Go value,
+// i.e. objects as map[string]any, arrays as []any and numbers as float64.
+func (j *JSONDocument) ExtractValue(uid widget.TreeNodeID) any {
+	return j.extractValue(uid2id(uid))
 }
 
-func wildCardToRegexp(pattern string) string {
-	components := strings.Split(pattern, "*")
-	if len(components) == 1 {
-		// if len is 1, there are no *'s, return exact match pattern
-		return "^" + pattern + "$"
-	}
-	var result strings.Builder
-	for i, literal := range components {
+// ExtractValueContext returns the value of a node like [JSONDocument.ExtractValue].
+// It returns [ErrCallerCanceled] when the context is canceled.
+func (j *JSONDocument) ExtractValueContext(ctx context.Context, uid widget.TreeNodeID) (any, error) {
+	e := extractor{ctx: ctx, j: j}
+	return e.extractValue(uid2id(uid))
+}
 
-		// Replace * with .*
-		if i > 0 {
-			result.WriteString(".*")
-		}
+func (j *JSONDocument) extractValue(id int32) any {
+	e := extractor{ctx: context.Background(), j: j}
+	v, _ := e.extractValue(id)
+	return v
+}
 
-		// Quote any regular expression meta characters in the
-		// literal text.
-		result.WriteString(regexp.QuoteMeta(literal))
-	}
-	return "^" + result.String() + "$"
+// extractor extracts values from a document and checks for cancellation while doing so.
+type extractor struct {
+	ctx context.Context
+	j   *JSONDocument
+	n   int // number of extracted nodes
 }
 
-// Extract returns a segment of the JSON document, with the given UID as new root container.
-// Note that only arrays and objects can be extracted
-func (j *JSONDocument) Extract(uid widget.TreeNodeID) ([]byte, error) {
-	var data any
-	id := uid2id(uid)
-	n := j.values[id]
+func (e *extractor) extractValue(id int32) (any, error) {
+	e.n++
+	if e.n%searchCancelTick == 0 {
+		select {
+		case <-e.ctx.Done():
+			return nil, ErrCallerCanceled
+		default:
+		}
+	}
+	n := e.j.values[id]
 	switch n.Type {
 	case Array:
-		data = j.extractArray(id)
+		return e.extractArray(id)
 	case Object:
-		data = j.extractObject(id)
-	default:
-		return nil, fmt.Errorf("can only extract objects and arrays")
+		return e.extractObject(id)
 	}
-	return json.Marshal(data)
+	return n.Value, nil
 }
 
-func (j *JSONDocument) extractArray(id int32) []any {
-	data := make([]any, len(j.ids[id]))
-	for i, childID := range j.ids[id] {
-		n := j.values[childID]
-		var v any
-		switch n.Type {
-		case Array:
-			v = j.extractArray(childID)
-		case Object:
-			v = j.extractObject(childID)
-		default:
-			v = n.Value
+func (e *extractor) extractArray(id int32) ([]any, error) {
+	data := make([]any, len(e.j.ids[id]))
+	for i, childID := range e.j.ids[id] {
+		v, err := e.extractValue(childID)
+		if err != nil {
+			return nil, err
 		}
 		data[i] = v
 	}
-	return data
+	return data, nil
 }
 
-func (j *JSONDocument) extractObject(id int32) map[string]any {
+func (e *extractor) extractObject(id int32) (map[string]any, error) {
 	data := make(map[string]any)
-	for _, childID := range j.ids[id] {
-		n := j.values[childID]
-		var v any
-		switch n.Type {
-		case Array:
-			v = j.extractArray(childID)
-		case Object:
-			v = j.extractObject(childID)
-		default:
-			v = n.Value
+	for _, childID := range e.j.ids[id] {
+		v, err := e.extractValue(childID)
+		if err != nil {
+			return nil, err
 		}
-		data[n.Key] = v
+		data[e.j.values[childID].Key] = v
 	}
-	return data
+	return data, nil
 }
 
 func uid2id(uid widget.TreeNodeID) int32 {
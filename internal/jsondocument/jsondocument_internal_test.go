@@ -41,6 +41,32 @@ func TestLoadFile(t *testing.T) {
 		// then
 		assert.Error(t, err)
 	})
+	t.Run("should report progress when content length is known", func(t *testing.T) {
+		// given
+		dat := []byte(`{"alpha": "two"}`)
+		r := sizedReadCloser{MakeURIReadCloser(bytes.NewReader(dat), "test"), int64(len(dat))}
+		j := New()
+		// when
+		_, err := j.load(ctx, r)
+		// then
+		if assert.NoError(t, err) {
+			x, err := j.progressInfo.Get()
+			if assert.NoError(t, err) {
+				p := x.(ProgressInfo)
+				assert.Equal(t, 1, p.CurrentStep)
+				assert.Equal(t, 1.0, p.Progress)
+			}
+		}
+	})
+}
+
+type sizedReadCloser struct {
+	uriReadCloser
+	size int64
+}
+
+func (r sizedReadCloser) ContentLength() int64 {
+	return r.size
 }
 
 func TestAddNode(t *testing.T) {
@@ -99,6 +125,9 @@ func TestWildcard2Regex(t *testing.T) {
 		got := wildCardToRegexp(tc.in)
 		assert.Equal(t, tc.want, got)
 	}
+	t.Run("should quote meta characters of patterns without wildcards", func(t *testing.T) {
+		assert.Equal(t, `^a\.b$`, wildCardToRegexp("a.b"))
+	})
 }
 
 // func TestMemoryUsage(t *testing.T) {
@@ -129,3 +158,71 @@ func PrintMemUsage() {
 func bToMb(b uint64) uint64 {
 	return b / 1024 / 1024
 }
+
+func TestCompareNatural(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"item2", "item10", -1},
+		{"item10", "item2", 1},
+		{"a", "b", -1},
+		{"a1b", "a1b", 0},
+		{"a01", "a1", 1},
+		{"x", "x1", -1},
+		{"10", "9a", 1},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s %s", tc.a, tc.b), func(t *testing.T) {
+			got := compareNatural(tc.a, tc.b)
+			switch {
+			case tc.want < 0:
+				assert.Less(t, got, 0)
+			case tc.want > 0:
+				assert.Greater(t, got, 0)
+			default:
+				assert.Equal(t, 0, got)
+			}
+		})
+	}
+}
+
+func TestSearchIndexNumbers(t *testing.T) {
+	ctx := context.TODO()
+	j := New()
+	if err := j.LoadData(ctx, []any{5.0, -1.0, 2.5, 5.0, 100.0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.BuildIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	idx := j.searchIndex()
+	cases := []struct {
+		lo, hi float64
+		want   []int32
+	}{
+		{-1, 100, []int32{1, 2, 3, 4, 5}},
+		{5, 5, []int32{1, 4}},
+		{0, 10, []int32{1, 3, 4}},
+		{6, 99, []int32{}},
+		{101, 200, []int32{}},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%v..%v", tc.lo, tc.hi), func(t *testing.T) {
+			assert.Equal(t, tc.want, idx.numbersBetween(tc.lo, tc.hi))
+		})
+	}
+	t.Run("should keep numbers sorted when nodes are added", func(t *testing.T) {
+		idx := newSearchIndex()
+		values := []Node{{Type: Array}, {Type: Number, Value: 3.0}, {Type: Number, Value: 1.0}, {Type: Number, Value: 2.0}}
+		parents := []int32{0, 0, 0, 0}
+		if err := idx.add(ctx, values, parents, 3); err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.add(ctx, values, parents, 4); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []indexedNumber{{1, 2}, {2, 3}, {3, 1}}, idx.byValue)
+		assert.Equal(t, []int32{1, 2, 3}, idx.numbers)
+	})
+}
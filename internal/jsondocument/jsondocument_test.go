@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"fyne.io/fyne/v2"
@@ -163,6 +164,111 @@ func TestJsonDocumentLoad(t *testing.T) {
 	})
 }
 
+func TestJsonDocumentStream(t *testing.T) {
+	ctx := context.TODO()
+	var dummy = binding.NewUntyped()
+	t.Run("can load stream of JSON values as array", func(t *testing.T) {
+		// given
+		j := jsondocument.New()
+		s := "{\"alpha\": 1}\n{\"bravo\": 2}\n{\"charlie\": 3}"
+		// when
+		err := j.Load(ctx, jsondocument.MakeURIReadCloser(strings.NewReader(s), "test.ndjson"), dummy)
+		// then
+		if assert.NoError(t, err) {
+			assert.True(t, j.IsStream())
+			assert.Equal(t, int64(len(s)), j.LoadedBytes())
+			assert.Equal(t, 7, j.Size())
+			ids := j.ChildUIDs("")
+			assert.Equal(t, "[1]", j.Value(ids[1]).Key)
+			assert.Equal(t, []string{"[2]", "charlie"}, j.KeyPath(j.ChildUIDs(ids[2])[0]))
+		}
+	})
+	t.Run("should not treat normal document as stream", func(t *testing.T) {
+		// given
+		j := jsondocument.New()
+		// when
+		err := j.Load(ctx, makeDataReader([]any{1, 2}), dummy)
+		// then
+		if assert.NoError(t, err) {
+			assert.False(t, j.IsStream())
+		}
+	})
+	t.Run("should not treat JSON file with several values as stream", func(t *testing.T) {
+		// given
+		j := jsondocument.New()
+		s := "{\"alpha\": 1}\n{\"bravo\": 2}\n"
+		// when
+		err := j.Load(ctx, jsondocument.MakeURIReadCloser(strings.NewReader(s), "test.json"), dummy)
+		// then
+		if assert.NoError(t, err) {
+			assert.False(t, j.IsStream())
+			assert.Equal(t, 2, j.Size())
+		}
+	})
+	t.Run("can append complete lines to stream", func(t *testing.T) {
+		// given
+		j := jsondocument.New()
+		s := "{\"alpha\": 1}\n"
+		if err := j.Load(ctx, jsondocument.MakeURIReadCloser(strings.NewReader(s), "test.ndjson"), dummy); err != nil {
+			t.Fatal(err)
+		}
+		// when
+		n, err := j.Append(ctx, strings.NewReader("{\"bravo\": [1, 2]}\n\n3\n{\"char"))
+		// then
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(21), n)
+			assert.Equal(t, 8, j.Size())
+			ids := j.ChildUIDs("")
+			if assert.Len(t, ids, 3) {
+				assert.Equal(t, jsondocument.Node{Key: "[1]", Value: jsondocument.Empty, Type: jsondocument.Object}, j.Value(ids[1]))
+				assert.Equal(t, jsondocument.Node{Key: "[2]", Value: float64(3), Type: jsondocument.Number}, j.Value(ids[2]))
+				assert.Len(t, j.ChildUIDs(j.ChildUIDs(ids[1])[0]), 2)
+			}
+		}
+	})
+	t.Run("should return error when appending to normal document", func(t *testing.T) {
+		// given
+		j := jsondocument.New()
+		if err := j.Load(ctx, makeDataReader([]any{1, 2}), dummy); err != nil {
+			t.Fatal(err)
+		}
+		// when
+		_, err := j.Append(ctx, strings.NewReader("3\n"))
+		// then
+		assert.ErrorIs(t, err, jsondocument.ErrNotStream)
+	})
+}
+
+func TestJsonDocumentKeyPath(t *testing.T) {
+	ctx := context.TODO()
+	var dummy = binding.NewUntyped()
+	j := jsondocument.New()
+	data := map[string]any{
+		"alpha": map[string]any{"charlie": []any{1, 2}},
+		"bravo": 2,
+	}
+	if err := j.Load(ctx, makeDataReader(data), dummy); err != nil {
+		t.Fatal(err)
+	}
+	alphaID := j.ChildUIDs("")[0]
+	charlieID := j.ChildUIDs(alphaID)[0]
+	elementID := j.ChildUIDs(charlieID)[1]
+	t.Run("can return key path of a node", func(t *testing.T) {
+		got := j.KeyPath(elementID)
+		assert.Equal(t, []string{"alpha", "charlie", "[1]"}, got)
+	})
+	t.Run("can find node from key path", func(t *testing.T) {
+		got, err := j.FindKeyPath([]string{"alpha", "charlie", "[1]"})
+		if assert.NoError(t, err) {
+			assert.Equal(t, elementID, got)
+		}
+	})
+	t.Run("should return error when key path does not exist", func(t *testing.T) {
+		_, err := j.FindKeyPath([]string{"alpha", "delta"})
+		assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+	})
+}
+
 func TestJsonDocumentExtract(t *testing.T) {
 	ctx := context.TODO()
 	var dummy = binding.NewUntyped()
@@ -199,6 +305,27 @@ func TestJsonDocumentExtract(t *testing.T) {
 		_, err := j.Extract(deltaID)
 		assert.Error(t, err)
 	})
+	t.Run("can extract value of any node", func(t *testing.T) {
+		assert.Equal(t, map[string]any{"delta": float64(1)}, j.ExtractValue(charlieID))
+		assert.Equal(t, float64(1), j.ExtractValue(deltaID))
+	})
+	t.Run("should stop extracting value when canceled", func(t *testing.T) {
+		j := jsondocument.New()
+		if err := j.LoadData(ctx, make([]any, 2000)); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := j.ExtractValueContext(ctx, "")
+		assert.ErrorIs(t, err, jsondocument.ErrCallerCanceled)
+	})
+	t.Run("can load document from extracted value", func(t *testing.T) {
+		j2 := jsondocument.New()
+		if assert.NoError(t, j2.LoadData(ctx, j.ExtractValue(""))) {
+			assert.Equal(t, j.Size(), j2.Size())
+			assert.Equal(t, j.ExtractValue(""), j2.ExtractValue(""))
+		}
+	})
 }
 
 func TestJSONType(t *testing.T) {
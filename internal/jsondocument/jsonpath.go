@@ -0,0 +1,963 @@
+package jsondocument
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+var ErrInvalidJSONPath = errors.New("invalid JSONPath")
+
+// JSONPath represents a compiled JSONPath query as defined in RFC 9535.
+type JSONPath struct {
+	query *pathQuery
+	s     string
+}
+
+// CompileJSONPath parses a JSONPath query, e.g. "$.items[?@.price > 10].name".
+// Leading and trailing white space is ignored.
+func CompileJSONPath(s string) (*JSONPath, error) {
+	s = strings.TrimSpace(s)
+	p := &pathParser{s: s}
+	if !p.consume("$") {
+		return nil, p.errorf("query must start with $")
+	}
+	q := &pathQuery{absolute: true}
+	var err error
+	q.segments, err = p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected character %q", p.s[p.pos])
+	}
+	return &JSONPath{query: q, s: s}, nil
+}
+
+func (p *JSONPath) String() string {
+	return p.s
+}
+
+// QueryJSONPath returns the UIDs of all nodes selected by a JSONPath query.
+// Nodes are returned in the order defined by RFC 9535 and can appear more than once.
+func (j *JSONDocument) QueryJSONPath(ctx context.Context, path *JSONPath) ([]widget.TreeNodeID, error) {
+	if j.Size() == 0 {
+		return []widget.TreeNodeID{}, nil
+	}
+	e := &pathEvaluator{ctx: ctx, j: j, regexps: make(map[string]*regexp.Regexp)}
+	ids := e.query(path.query, 0)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return ids2uids(ids), nil
+}
+
+// pathQuery represents an absolute ($) or relative (@) query.
+type pathQuery struct {
+	absolute bool
+	segments []pathSegment
+}
+
+// isSingular reports whether a query can select at most one node.
+func (q *pathQuery) isSingular() bool {
+	for _, s := range q.segments {
+		if s.descendant || len(s.selectors) != 1 {
+			return false
+		}
+		if k := s.selectors[0].kind; k != selectName && k != selectIndex {
+			return false
+		}
+	}
+	return true
+}
+
+type pathSegment struct {
+	descendant bool
+	selectors  []pathSelector
+}
+
+type selectorKind uint8
+
+const (
+	selectName selectorKind = iota
+	selectWildcard
+	selectIndex
+	selectSlice
+	selectFilter
+)
+
+type pathSelector struct {
+	kind   selectorKind
+	name   string
+	index  int
+	start  *int
+	end    *int
+	step   int
+	filter any
+}
+
+// Filter expressions
+type (
+	logicalOr  []any
+	logicalAnd []any
+	logicalNot struct{ expr any }
+	testQuery  struct{ query *pathQuery }
+	testFunc   struct{ call *funcCall }
+	comparison struct {
+		op          string
+		left, right any // literal, *pathQuery or *funcCall
+	}
+	literal  struct{ value any }
+	funcCall struct {
+		name string
+		args []any // literal, *pathQuery, *funcCall or logical expression
+	}
+)
+
+type funcType uint8
+
+const (
+	valueType funcType = iota
+	logicalType
+	nodesType
+)
+
+var pathFunctions = map[string]struct {
+	result funcType
+	args   []funcType
+}{
+	"count":  {valueType, []funcType{nodesType}},
+	"length": {valueType, []funcType{valueType}},
+	"match":  {logicalType, []funcType{valueType, valueType}},
+	"search": {logicalType, []funcType{valueType, valueType}},
+	"value":  {valueType, []funcType{nodesType}},
+}
+
+// pathNothing represents the absence of a value in filter expressions.
+type pathNothing struct{}
+
+var nothing = pathNothing{}
+
+type pathParser struct {
+	s   string
+	pos int
+}
+
+func (p *pathParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidJSONPath, fmt.Sprintf(format, args...), p.pos+1)
+}
+
+// peek returns the current character or 0 at the end.
+func (p *pathParser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *pathParser) consume(s string) bool {
+	if strings.HasPrefix(p.s[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) skipSpace() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *pathParser) parseSegments() ([]pathSegment, error) {
+	segments := make([]pathSegment, 0)
+	for {
+		start := p.pos
+		p.skipSpace()
+		var seg pathSegment
+		var err error
+		switch {
+		case p.consume(".."):
+			seg.descendant = true
+			if p.peek() == '[' {
+				seg.selectors, err = p.parseBracketed()
+			} else {
+				seg.selectors, err = p.parseShorthand()
+			}
+		case p.consume("."):
+			seg.selectors, err = p.parseShorthand()
+		case p.peek() == '[':
+			seg.selectors, err = p.parseBracketed()
+		default:
+			p.pos = start
+			return segments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+}
+
+// parseShorthand parses the selector of a segment in dot notation, e.g. ".name" or ".*".
+func (p *pathParser) parseShorthand() ([]pathSelector, error) {
+	if p.consume("*") {
+		return []pathSelector{{kind: selectWildcard}}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		if !isNameChar(r, p.pos == start) {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return nil, p.errorf("member name expected")
+	}
+	return []pathSelector{{kind: selectName, name: p.s[start:p.pos]}}, nil
+}
+
+func isNameChar(r rune, first bool) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r >= 0x80:
+		return true
+	case r >= '0' && r <= '9':
+		return !first
+	}
+	return false
+}
+
+func (p *pathParser) parseBracketed() ([]pathSelector, error) {
+	p.pos++ // [
+	var selectors []pathSelector
+	for {
+		p.skipSpace()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+		p.skipSpace()
+		if p.consume(",") {
+			continue
+		}
+		if p.consume("]") {
+			return selectors, nil
+		}
+		return nil, p.errorf("expected , or ]")
+	}
+}
+
+func (p *pathParser) parseSelector() (pathSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return pathSelector{}, err
+		}
+		return pathSelector{kind: selectName, name: s}, nil
+	case c == '*':
+		p.pos++
+		return pathSelector{kind: selectWildcard}, nil
+	case c == '?':
+		p.pos++
+		p.skipSpace()
+		expr, err := p.parseLogicalOr()
+		if err != nil {
+			return pathSelector{}, err
+		}
+		return pathSelector{kind: selectFilter, filter: expr}, nil
+	}
+	start, err := p.parseOptionalInt()
+	if err != nil {
+		return pathSelector{}, err
+	}
+	p.skipSpace()
+	if !p.consume(":") {
+		if start == nil {
+			return pathSelector{}, p.errorf("selector expected")
+		}
+		return pathSelector{kind: selectIndex, index: *start}, nil
+	}
+	sel := pathSelector{kind: selectSlice, start: start, step: 1}
+	p.skipSpace()
+	if sel.end, err = p.parseOptionalInt(); err != nil {
+		return pathSelector{}, err
+	}
+	p.skipSpace()
+	if p.consume(":") {
+		p.skipSpace()
+		step, err := p.parseOptionalInt()
+		if err != nil {
+			return pathSelector{}, err
+		}
+		if step != nil {
+			sel.step = *step
+		}
+	}
+	return sel, nil
+}
+
+// parseOptionalInt parses an integer as defined by RFC 9535 or returns nil if there is none.
+func (p *pathParser) parseOptionalInt() (*int, error) {
+	start := p.pos
+	p.consume("-")
+	digits := p.pos
+	for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+		p.pos++
+	}
+	s := p.s[start:p.pos]
+	if p.pos == digits {
+		if s == "-" {
+			return nil, p.errorf("digit expected")
+		}
+		return nil, nil
+	}
+	if (p.s[digits] == '0' && p.pos-digits > 1) || s == "-0" {
+		return nil, p.errorf("invalid integer %s", s)
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || i > 1<<53-1 || i < -(1<<53-1) {
+		return nil, p.errorf("integer out of range %s", s)
+	}
+	x := int(i)
+	return &x, nil
+}
+
+// parseString parses a string literal in single or double quotes.
+func (p *pathParser) parseString() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.s) {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.s[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c < 0x20:
+			return "", p.errorf("invalid character in string")
+		case c != '\\':
+			sb.WriteByte(c)
+			p.pos++
+			continue
+		}
+		p.pos++ // backslash
+		c = p.peek()
+		p.pos++
+		switch c {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '/', '\\':
+			sb.WriteByte(c)
+		case '\'', '"':
+			if c != quote {
+				return "", p.errorf("invalid escape sequence")
+			}
+			sb.WriteByte(c)
+		case 'u':
+			r, err := p.parseHex()
+			if err != nil {
+				return "", err
+			}
+			if r >= 0xD800 && r <= 0xDBFF {
+				if !p.consume(`\u`) {
+					return "", p.errorf("invalid surrogate pair")
+				}
+				r2, err := p.parseHex()
+				if err != nil {
+					return "", err
+				}
+				if r2 < 0xDC00 || r2 > 0xDFFF {
+					return "", p.errorf("invalid surrogate pair")
+				}
+				r = 0x10000 + (r-0xD800)<<10 + (r2 - 0xDC00)
+			} else if r >= 0xDC00 && r <= 0xDFFF {
+				return "", p.errorf("invalid surrogate pair")
+			}
+			sb.WriteRune(r)
+		default:
+			p.pos--
+			return "", p.errorf("invalid escape sequence")
+		}
+	}
+}
+
+func (p *pathParser) parseHex() (rune, error) {
+	if p.pos+4 > len(p.s) {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	x, err := strconv.ParseUint(p.s[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	p.pos += 4
+	return rune(x), nil
+}
+
+func (p *pathParser) parseLogicalOr() (any, error) {
+	var ops logicalOr
+	for {
+		expr, err := p.parseLogicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, expr)
+		p.skipSpace()
+		if !p.consume("||") {
+			break
+		}
+		p.skipSpace()
+	}
+	if len(ops) == 1 {
+		return ops[0], nil
+	}
+	return ops, nil
+}
+
+func (p *pathParser) parseLogicalAnd() (any, error) {
+	var ops logicalAnd
+	for {
+		expr, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, expr)
+		p.skipSpace()
+		if !p.consume("&&") {
+			break
+		}
+		p.skipSpace()
+	}
+	if len(ops) == 1 {
+		return ops[0], nil
+	}
+	return ops, nil
+}
+
+// parseBasic parses a parenthesized expression, a comparison or a test expression.
+func (p *pathParser) parseBasic() (any, error) {
+	p.skipSpace()
+	if p.peek() == '!' && !strings.HasPrefix(p.s[p.pos:], "!=") {
+		p.pos++
+		p.skipSpace()
+		isParen := p.peek() == '('
+		expr, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := expr.(comparison); ok && !isParen {
+			return nil, p.errorf("comparison must be in parentheses to be negated")
+		}
+		return logicalNot{expr}, nil
+	}
+	if p.consume("(") {
+		expr, err := p.parseLogicalOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return expr, nil
+	}
+	start := p.pos
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	op := p.parseComparisonOperator()
+	if op == "" {
+		switch x := left.(type) {
+		case *pathQuery:
+			return testQuery{x}, nil
+		case *funcCall:
+			if pathFunctions[x.name].result == valueType {
+				p.pos = start
+				return nil, p.errorf("result of %s() must be compared", x.name)
+			}
+			return testFunc{x}, nil
+		}
+		return nil, p.errorf("comparison operator expected")
+	}
+	p.skipSpace()
+	rightPos := p.pos
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkComparable(left, start); err != nil {
+		return nil, err
+	}
+	if err := p.checkComparable(right, rightPos); err != nil {
+		return nil, err
+	}
+	return comparison{op: op, left: left, right: right}, nil
+}
+
+func (p *pathParser) parseComparisonOperator() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// checkComparable reports an error if an operand can not be used in a comparison.
+func (p *pathParser) checkComparable(operand any, pos int) error {
+	switch x := operand.(type) {
+	case *pathQuery:
+		if !x.isSingular() {
+			p.pos = pos
+			return p.errorf("only singular queries can be compared")
+		}
+	case *funcCall:
+		if pathFunctions[x.name].result != valueType {
+			p.pos = pos
+			return p.errorf("result of %s() can not be compared", x.name)
+		}
+	}
+	return nil
+}
+
+// parseOperand parses a literal, a query or a function call.
+func (p *pathParser) parseOperand() (any, error) {
+	c := p.peek()
+	switch {
+	case c == '@' || c == '$':
+		p.pos++
+		q := &pathQuery{absolute: c == '$'}
+		var err error
+		q.segments, err = p.parseSegments()
+		if err != nil {
+			return nil, err
+		}
+		return q, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return literal{s}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case c >= 'a' && c <= 'z':
+		start := p.pos
+		for c := p.peek(); (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_'; c = p.peek() {
+			p.pos++
+		}
+		name := p.s[start:p.pos]
+		if p.peek() != '(' {
+			switch name {
+			case "true":
+				return literal{true}, nil
+			case "false":
+				return literal{false}, nil
+			case "null":
+				return literal{nil}, nil
+			}
+			p.pos = start
+			return nil, p.errorf("unknown literal %q", name)
+		}
+		return p.parseFuncCall(name, start)
+	}
+	return nil, p.errorf("value expected")
+}
+
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?`)
+
+func (p *pathParser) parseNumber() (any, error) {
+	s := numberPattern.FindString(p.s[p.pos:])
+	if s == "" {
+		return nil, p.errorf("invalid number")
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(x, 0) {
+		return nil, p.errorf("invalid number %s", s)
+	}
+	p.pos += len(s)
+	return literal{x}, nil
+}
+
+func (p *pathParser) parseFuncCall(name string, start int) (any, error) {
+	def, found := pathFunctions[name]
+	if !found {
+		p.pos = start
+		return nil, p.errorf("unknown function %s()", name)
+	}
+	p.pos++ // (
+	f := &funcCall{name: name}
+	p.skipSpace()
+	if !p.consume(")") {
+		for {
+			p.skipSpace()
+			argPos := p.pos
+			arg, err := p.parseFuncArg()
+			if err != nil {
+				return nil, err
+			}
+			if len(f.args) < len(def.args) {
+				if err := p.checkArg(def.args[len(f.args)], arg, argPos, name); err != nil {
+					return nil, err
+				}
+			}
+			f.args = append(f.args, arg)
+			p.skipSpace()
+			if p.consume(",") {
+				continue
+			}
+			if p.consume(")") {
+				break
+			}
+			return nil, p.errorf("expected , or )")
+		}
+	}
+	if len(f.args) != len(def.args) {
+		p.pos = start
+		return nil, p.errorf("%s() expects %d arguments", name, len(def.args))
+	}
+	return f, nil
+}
+
+// parseFuncArg parses a function argument,
+// which is either a literal, query, function call or logical expression.
+func (p *pathParser) parseFuncArg() (any, error) {
+	start := p.pos
+	if c := p.peek(); c != '!' && c != '(' {
+		arg, err := p.parseOperand()
+		if err == nil {
+			p.skipSpace()
+			if c := p.peek(); c == ',' || c == ')' {
+				return arg, nil
+			}
+		}
+	}
+	p.pos = start
+	return p.parseLogicalOr()
+}
+
+// checkArg reports an error if an argument does not have the type required by a function.
+func (p *pathParser) checkArg(typ funcType, arg any, pos int, name string) error {
+	ok := true
+	switch typ {
+	case valueType:
+		switch x := arg.(type) {
+		case literal:
+		case *pathQuery:
+			ok = x.isSingular()
+		case *funcCall:
+			ok = pathFunctions[x.name].result == valueType
+		default:
+			ok = false
+		}
+	case nodesType:
+		_, ok = arg.(*pathQuery)
+	}
+	if !ok {
+		p.pos = pos
+		return p.errorf("invalid argument for %s()", name)
+	}
+	return nil
+}
+
+type pathEvaluator struct {
+	ctx     context.Context
+	err     error
+	j       *JSONDocument
+	n       int
+	regexps map[string]*regexp.Regexp
+}
+
+// canceled reports whether the evaluation was canceled.
+func (e *pathEvaluator) canceled() bool {
+	e.n++
+	if e.err == nil && e.n%progressUpdateTick == 0 {
+		select {
+		case <-e.ctx.Done():
+			e.err = ErrCallerCanceled
+		default:
+		}
+	}
+	return e.err != nil
+}
+
+func (e *pathEvaluator) query(q *pathQuery, current int32) []int32 {
+	nodes := []int32{current}
+	if q.absolute {
+		nodes = []int32{0}
+	}
+	for _, seg := range q.segments {
+		var next []int32
+		for _, id := range nodes {
+			if seg.descendant {
+				next = e.selectDescendants(seg.selectors, id, next)
+			} else {
+				next = e.selectChildren(seg.selectors, id, next)
+			}
+			if e.err != nil {
+				return nil
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// selectDescendants applies selectors to a node and all it's descendants in document order.
+func (e *pathEvaluator) selectDescendants(selectors []pathSelector, id int32, result []int32) []int32 {
+	if e.canceled() {
+		return result
+	}
+	result = e.selectChildren(selectors, id, result)
+	for _, childID := range e.j.ids[id] {
+		result = e.selectDescendants(selectors, childID, result)
+	}
+	return result
+}
+
+func (e *pathEvaluator) selectChildren(selectors []pathSelector, id int32, result []int32) []int32 {
+	typ := e.j.values[id].Type
+	children := e.j.ids[id]
+	for _, sel := range selectors {
+		switch sel.kind {
+		case selectName:
+			if typ != Object {
+				continue
+			}
+			for _, childID := range children {
+				if e.j.values[childID].Key == sel.name {
+					result = append(result, childID)
+					break
+				}
+			}
+		case selectWildcard:
+			result = append(result, children...)
+		case selectIndex:
+			if typ != Array {
+				continue
+			}
+			i := sel.index
+			if i < 0 {
+				i += len(children)
+			}
+			if i >= 0 && i < len(children) {
+				result = append(result, children[i])
+			}
+		case selectSlice:
+			if typ != Array {
+				continue
+			}
+			result = appendSlice(result, children, sel)
+		case selectFilter:
+			for _, childID := range children {
+				if e.canceled() {
+					return result
+				}
+				if e.logical(sel.filter, childID) {
+					result = append(result, childID)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// appendSlice appends the elements selected by a slice selector as defined in RFC 9535.
+func appendSlice(result, elements []int32, sel pathSelector) []int32 {
+	n := len(elements)
+	if sel.step == 0 {
+		return result
+	}
+	normalize := func(i int) int {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+	if sel.step > 0 {
+		start, end := 0, n
+		if sel.start != nil {
+			start = normalize(*sel.start)
+		}
+		if sel.end != nil {
+			end = normalize(*sel.end)
+		}
+		lower, upper := min(max(start, 0), n), min(max(end, 0), n)
+		for i := lower; i < upper; i += sel.step {
+			result = append(result, elements[i])
+		}
+		return result
+	}
+	start, end := n-1, -n-1
+	if sel.start != nil {
+		start = normalize(*sel.start)
+	}
+	if sel.end != nil {
+		end = normalize(*sel.end)
+	}
+	upper, lower := min(max(start, -1), n-1), min(max(end, -1), n-1)
+	for i := upper; lower < i; i += sel.step {
+		result = append(result, elements[i])
+	}
+	return result
+}
+
+// logical returns the result of a logical expression for the current node.
+func (e *pathEvaluator) logical(expr any, current int32) bool {
+	switch x := expr.(type) {
+	case logicalOr:
+		for _, op := range x {
+			if e.logical(op, current) {
+				return true
+			}
+		}
+		return false
+	case logicalAnd:
+		for _, op := range x {
+			if !e.logical(op, current) {
+				return false
+			}
+		}
+		return true
+	case logicalNot:
+		return !e.logical(x.expr, current)
+	case testQuery:
+		return len(e.query(x.query, current)) > 0
+	case testFunc:
+		v, _ := e.call(x.call, current).(bool)
+		return v
+	case comparison:
+		return compareFilterValues(x.op, e.value(x.left, current), e.value(x.right, current))
+	}
+	return false
+}
+
+// value returns the value of a literal, singular query or function call.
+func (e *pathEvaluator) value(operand any, current int32) any {
+	switch x := operand.(type) {
+	case literal:
+		return x.value
+	case *pathQuery:
+		nodes := e.query(x, current)
+		if len(nodes) != 1 {
+			return nothing
+		}
+		return e.j.extractValue(nodes[0])
+	case *funcCall:
+		return e.call(x, current)
+	}
+	return nothing
+}
+
+func (e *pathEvaluator) call(f *funcCall, current int32) any {
+	switch f.name {
+	case "count":
+		return float64(len(e.query(f.args[0].(*pathQuery), current)))
+	case "value":
+		nodes := e.query(f.args[0].(*pathQuery), current)
+		if len(nodes) != 1 {
+			return nothing
+		}
+		return e.j.extractValue(nodes[0])
+	case "length":
+		if q, ok := f.args[0].(*pathQuery); ok {
+			// avoid extracting containers only for counting their elements
+			nodes := e.query(q, current)
+			if len(nodes) != 1 {
+				return nothing
+			}
+			if n := e.j.values[nodes[0]]; n.Type == Object || n.Type == Array {
+				return float64(len(e.j.ids[nodes[0]]))
+			}
+		}
+		switch v := e.value(f.args[0], current).(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v))
+		case []any:
+			return float64(len(v))
+		case map[string]any:
+			return float64(len(v))
+		}
+		return nothing
+	case "match", "search":
+		s, ok1 := e.value(f.args[0], current).(string)
+		pattern, ok2 := e.value(f.args[1], current).(string)
+		if !ok1 || !ok2 {
+			return false
+		}
+		if f.name == "match" {
+			pattern = "^(?:" + pattern + ")$"
+		}
+		r, found := e.regexps[pattern]
+		if !found {
+			r, _ = regexp.Compile(pattern) // invalid patterns never match
+			e.regexps[pattern] = r
+		}
+		return r != nil && r.MatchString(s)
+	}
+	return nothing
+}
+
+func compareFilterValues(op string, a, b any) bool {
+	switch op {
+	case "==":
+		return equalFilterValues(a, b)
+	case "!=":
+		return !equalFilterValues(a, b)
+	case "<":
+		return lessFilterValues(a, b)
+	case "<=":
+		return lessFilterValues(a, b) || equalFilterValues(a, b)
+	case ">":
+		return lessFilterValues(b, a)
+	case ">=":
+		return lessFilterValues(b, a) || equalFilterValues(a, b)
+	}
+	return false
+}
+
+func equalFilterValues(a, b any) bool {
+	if a == nothing || b == nothing {
+		return a == b
+	}
+	return equalValues(a, b)
+}
+
+func lessFilterValues(a, b any) bool {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		return ok && x < y
+	case string:
+		y, ok := b.(string)
+		return ok && x < y
+	}
+	return false
+}
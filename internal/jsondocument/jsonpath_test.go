@@ -0,0 +1,146 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryJSONPath(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{
+		"store": {
+			"book": [
+				{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+				{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+				{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+				{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+			],
+			"bicycle": {"color": "red", "price": 399}
+		},
+		"a/b": 1,
+		"empty": [],
+		"o": {"j": 1, "k": "abc"}
+	}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		query string
+		want  []any
+	}{
+		{`$.store.book[*].author`, []any{"Nigel Rees", "Evelyn Waugh", "Herman Melville", "J. R. R. Tolkien"}},
+		{`$..author`, []any{"Nigel Rees", "Evelyn Waugh", "Herman Melville", "J. R. R. Tolkien"}},
+		{`$.store.*.color`, []any{"red"}},
+		{`$.store..price`, []any{399.0, 8.95, 12.99, 8.99, 22.99}},
+		{`$..book[2].author`, []any{"Herman Melville"}},
+		{`$..book[-1].title`, []any{"The Lord of the Rings"}},
+		{`$..book[0,1].price`, []any{8.95, 12.99}},
+		{`$..book[:2].price`, []any{8.95, 12.99}},
+		{`$..book[::-2].price`, []any{22.99, 12.99}},
+		{`$..book[?@.isbn].title`, []any{"Moby Dick", "The Lord of the Rings"}},
+		{`$..book[?@.price < 10].title`, []any{"Sayings of the Century", "Moby Dick"}},
+		{`$.store.book[?@.price > 10 && @.category == 'fiction'].price`, []any{12.99, 22.99}},
+		{`$.store.book[?!@.isbn].price`, []any{8.95, 12.99}},
+		{`$.store.book[?(@.price < 9 || @.price > 20)].price`, []any{8.95, 8.99, 22.99}},
+		{`$.store.book[?@.price > $.store.bicycle.price].title`, []any{}},
+		{`$.store.book[?length(@.title) == 9].title`, []any{"Moby Dick"}},
+		{`$.store.book[?match(@.author, 'J.*')].price`, []any{22.99}},
+		{`$.store.book[?search(@.title, 'of')].price`, []any{8.95, 12.99, 22.99}},
+		{`$[?count(@.*) == 2 && @.k]`, []any{map[string]any{"j": 1.0, "k": "abc"}}},
+		{`$.o[?value(@) == 'abc']`, []any{"abc"}},
+		{`$['a/b']`, []any{1.0}},
+		{`$["o"]['k']`, []any{"abc"}},
+		{`$.empty[0]`, []any{}},
+		{`$.o[?@ == {"j": 1}]`, nil}, // object literals are not allowed
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			p, err := jsondocument.CompileJSONPath(tc.query)
+			if tc.want == nil {
+				assert.ErrorIs(t, err, jsondocument.ErrInvalidJSONPath)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			uids, err := j.QueryJSONPath(ctx, p)
+			if assert.NoError(t, err) {
+				got := make([]any, len(uids))
+				for i, uid := range uids {
+					got[i] = j.ExtractValue(uid)
+				}
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+	t.Run("can select root", func(t *testing.T) {
+		p, err := jsondocument.CompileJSONPath("$")
+		if assert.NoError(t, err) {
+			uids, err := j.QueryJSONPath(ctx, p)
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{""}, uids)
+			}
+		}
+	})
+	t.Run("should return nodes of the document", func(t *testing.T) {
+		p, err := jsondocument.CompileJSONPath("$.o.k")
+		if assert.NoError(t, err) {
+			uids, err := j.QueryJSONPath(ctx, p)
+			if assert.NoError(t, err) {
+				want, _ := j.FindKeyPath([]string{"o", "k"})
+				assert.Equal(t, []string{want}, uids)
+			}
+		}
+	})
+}
+
+func TestCompileJSONPath(t *testing.T) {
+	invalid := []string{
+		``,
+		`store`,
+		`$.`,
+		`$[`,
+		`$[01]`,
+		`$[-0]`,
+		`$.a[?@.b]x`,
+		`$[?@.* == 1]`,
+		`$[?length(@.*) == 1]`,
+		`$[?length(@.a)]`,
+		`$[?count(1) == 1]`,
+		`$[?foo(@)]`,
+		`$[?!@.a == 1]`,
+		`$['abc]`,
+		`$["\q"]`,
+		`$[?@.a == tru]`,
+	}
+	for _, s := range invalid {
+		t.Run(s, func(t *testing.T) {
+			_, err := jsondocument.CompileJSONPath(s)
+			assert.ErrorIs(t, err, jsondocument.ErrInvalidJSONPath)
+		})
+	}
+	valid := []string{
+		`$`,
+		` $.a `,
+		`$ .a ['b'] [ 1 , 2 ]`,
+		`$..*`,
+		`$..[0]`,
+		`$.ä`,
+		`$["ä\"'"]`,
+		`$[?@.a == -1.5e3]`,
+		`$[?!(@.a == 1)]`,
+		`$[?match(@.a, "x") && @.b != null]`,
+		`$[1:-1:2]`,
+	}
+	for _, s := range valid {
+		t.Run(s, func(t *testing.T) {
+			_, err := jsondocument.CompileJSONPath(s)
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,530 @@
+package jsondocument
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPatch = errors.New("invalid patch")
+var ErrTestFailed = errors.New("test failed")
+
+// PatchKind represents the format of a patch.
+type PatchKind uint8
+
+const (
+	JSONPatch  PatchKind = iota // JSON Patch as defined in RFC 6902
+	MergePatch                  // JSON Merge Patch as defined in RFC 7386
+)
+
+func (k PatchKind) String() string {
+	if k == MergePatch {
+		return "JSON Merge Patch"
+	}
+	return "JSON Patch"
+}
+
+// PatchOperation represents an operation of a JSON Patch.
+type PatchOperation struct {
+	Op    string
+	Path  string
+	From  string
+	Value any
+
+	hasValue bool
+}
+
+// NewPatchOperation returns a new operation, which has a value.
+func NewPatchOperation(op, path string, value any) PatchOperation {
+	return PatchOperation{Op: op, Path: path, Value: value, hasValue: true}
+}
+
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	m := map[string]any{"op": o.Op, "path": o.Path}
+	switch o.Op {
+	case "add", "replace", "test":
+		m["value"] = o.Value
+	case "move", "copy":
+		m["from"] = o.From
+	}
+	return json.Marshal(m)
+}
+
+func (o *PatchOperation) UnmarshalJSON(data []byte) error {
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*o = PatchOperation{}
+	for k, target := range map[string]*string{"op": &o.Op, "path": &o.Path, "from": &o.From} {
+		v, found := m[k]
+		if !found {
+			if k != "from" {
+				return fmt.Errorf("operation without %s: %w", k, ErrInvalidPatch)
+			}
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string: %w", k, ErrInvalidPatch)
+		}
+		*target = s
+	}
+	o.Value, o.hasValue = m["value"]
+	return nil
+}
+
+// Patch represents a patch, which can be applied to a JSON value.
+type Patch struct {
+	Kind PatchKind
+	// Operations of a JSON Patch
+	Operations []PatchOperation
+	// Document of a JSON Merge Patch
+	Merge any
+}
+
+// ReadPatch reads a patch from r.
+// An array is read as JSON Patch and any other value as JSON Merge Patch.
+func ReadPatch(r io.Reader) (*Patch, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	if _, ok := v.([]any); !ok {
+		return &Patch{Kind: MergePatch, Merge: v}, nil
+	}
+	var ops []PatchOperation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		if errors.Is(err, ErrInvalidPatch) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	return &Patch{Kind: JSONPatch, Operations: ops}, nil
+}
+
+// MarshalJSON returns the patch in it's JSON representation.
+func (p *Patch) MarshalJSON() ([]byte, error) {
+	if p.Kind == MergePatch {
+		return json.Marshal(p.Merge)
+	}
+	if p.Operations == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(p.Operations)
+}
+
+// PatchError represents an operation of a JSON Patch which could not be applied.
+type PatchError struct {
+	Index     int // zero based
+	Operation PatchOperation
+	Err       error
+}
+
+func (e PatchError) Error() string {
+	return fmt.Sprintf("operation %d (%s %s): %s", e.Index+1, e.Operation.Op, e.Operation.Path, e.Err)
+}
+
+func (e PatchError) Unwrap() error {
+	return e.Err
+}
+
+// Apply applies the patch to a copy of doc and returns the result.
+//
+// As required by RFC 6902 a JSON Patch with a failed operation is not applied
+// and no result is returned. The remaining operations are still tried without the failed ones,
+// so that the errors of all failed operations can be reported at once.
+func (p *Patch) Apply(doc any) (any, []PatchError) {
+	if p.Kind == MergePatch {
+		return applyMergePatch(copyValue(doc), p.Merge), nil
+	}
+	doc = copyValue(doc)
+	var errs []PatchError
+	for i, op := range p.Operations {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			errs = append(errs, PatchError{Index: i, Operation: op, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return doc, nil
+}
+
+// NewPatch returns a patch of the given kind, which transforms value a into value b.
+//
+// Array elements are compared by their index.
+// Note that a JSON Merge Patch can not set values to null
+// and replaces arrays completely, as defined by RFC 7386.
+func NewPatch(kind PatchKind, a, b any) *Patch {
+	if kind == MergePatch {
+		return &Patch{Kind: MergePatch, Merge: newMergePatch(a, b)}
+	}
+	ops := make([]PatchOperation, 0)
+	diffValues(&ops, []string{}, a, b)
+	return &Patch{Kind: JSONPatch, Operations: ops}
+}
+
+func diffValues(ops *[]PatchOperation, tokens []string, a, b any) {
+	switch va := a.(type) {
+	case map[string]any:
+		vb, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		for _, k := range slices.Sorted(maps.Keys(va)) {
+			if _, found := vb[k]; !found {
+				path := formatPointer(append(slices.Clone(tokens), k))
+				*ops = append(*ops, PatchOperation{Op: "remove", Path: path})
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(vb)) {
+			path := append(slices.Clone(tokens), k)
+			x, found := va[k]
+			if !found {
+				*ops = append(*ops, NewPatchOperation("add", formatPointer(path), copyValue(vb[k])))
+				continue
+			}
+			diffValues(ops, path, x, vb[k])
+		}
+		return
+	case []any:
+		vb, ok := b.([]any)
+		if !ok {
+			break
+		}
+		n := min(len(va), len(vb))
+		for i := range n {
+			diffValues(ops, append(slices.Clone(tokens), strconv.Itoa(i)), va[i], vb[i])
+		}
+		for i := n; i < len(vb); i++ {
+			path := formatPointer(append(slices.Clone(tokens), strconv.Itoa(i)))
+			*ops = append(*ops, NewPatchOperation("add", path, copyValue(vb[i])))
+		}
+		for i := len(va) - 1; i >= n; i-- {
+			path := formatPointer(append(slices.Clone(tokens), strconv.Itoa(i)))
+			*ops = append(*ops, PatchOperation{Op: "remove", Path: path})
+		}
+		return
+	}
+	if !equalValues(a, b) {
+		*ops = append(*ops, NewPatchOperation("replace", formatPointer(tokens), copyValue(b)))
+	}
+}
+
+func newMergePatch(a, b any) any {
+	va, ok1 := a.(map[string]any)
+	vb, ok2 := b.(map[string]any)
+	if !ok1 || !ok2 {
+		return copyValue(b)
+	}
+	patch := make(map[string]any)
+	for k := range va {
+		if _, found := vb[k]; !found {
+			patch[k] = nil
+		}
+	}
+	for k, v := range vb {
+		old, found := va[k]
+		if found && equalValues(old, v) {
+			continue
+		}
+		if found {
+			patch[k] = newMergePatch(old, v)
+		} else {
+			patch[k] = newMergePatch(nil, v)
+		}
+	}
+	return patch
+}
+
+// applyMergePatch applies a merge patch to target as defined in RFC 7386.
+func applyMergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return copyValue(patch)
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any)
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = applyMergePatch(t[k], v)
+	}
+	return t
+}
+
+func applyOperation(doc any, op PatchOperation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return doc, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if !op.hasValue {
+			return doc, fmt.Errorf("missing value: %w", ErrInvalidPatch)
+		}
+	}
+	switch op.Op {
+	case "add":
+		return addPointerValue(doc, path, copyValue(op.Value))
+	case "remove":
+		doc, _, err := removePointerValue(doc, path)
+		return doc, err
+	case "replace":
+		if _, err := getPointerValue(doc, path); err != nil {
+			return doc, err
+		}
+		return setPointerValue(doc, path, copyValue(op.Value))
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return doc, err
+		}
+		v, err := getPointerValue(doc, from)
+		if err != nil {
+			return doc, fmt.Errorf("from: %w", err)
+		}
+		if op.Op == "copy" {
+			return addPointerValue(doc, path, copyValue(v))
+		}
+		if len(from) < len(path) && slices.Equal(from, path[:len(from)]) {
+			return doc, fmt.Errorf("can not move a value into itself: %w", ErrInvalidPatch)
+		}
+		doc2, _, err := removePointerValue(doc, from)
+		if err != nil {
+			return doc, err
+		}
+		doc2, err = addPointerValue(doc2, path, v)
+		if err != nil {
+			// restore the removed value so the failed operation has no effect
+			doc, _ = addPointerValue(doc2, from, v)
+			return doc, err
+		}
+		return doc2, nil
+	case "test":
+		v, err := getPointerValue(doc, path)
+		if err != nil {
+			return doc, err
+		}
+		if !equalValues(v, op.Value) {
+			return doc, ErrTestFailed
+		}
+		return doc, nil
+	}
+	return doc, fmt.Errorf("unknown operation %q: %w", op.Op, ErrInvalidPatch)
+}
+
+// parsePointer returns the reference tokens of a JSON Pointer as defined in RFC 6901.
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("pointer %q must start with /: %w", s, ErrInvalidPatch)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// formatPointer returns a JSON Pointer from reference tokens.
+func formatPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, t := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// arrayIndex returns the index of an array element from a reference token.
+// A valid index has no leading zeros and must be less than max.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i >= max {
+		return 0, fmt.Errorf("array index %s: %w", token, ErrNotFound)
+	}
+	return i, nil
+}
+
+func getPointerValue(doc any, tokens []string) (any, error) {
+	v := doc
+	for _, t := range tokens {
+		switch x := v.(type) {
+		case map[string]any:
+			child, found := x[t]
+			if !found {
+				return nil, fmt.Errorf("member %q: %w", t, ErrNotFound)
+			}
+			v = child
+		case []any:
+			i, err := arrayIndex(t, len(x))
+			if err != nil {
+				return nil, err
+			}
+			v = x[i]
+		default:
+			return nil, fmt.Errorf("%q: %w", t, ErrNotFound)
+		}
+	}
+	return v, nil
+}
+
+// updateContainer applies f to the container of the last token and returns the updated document.
+func updateContainer(doc any, tokens []string, f func(container any, token string) (any, error)) (any, error) {
+	parent, err := getPointerValue(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return doc, err
+	}
+	updated, err := f(parent, tokens[len(tokens)-1])
+	if err != nil {
+		return doc, err
+	}
+	if len(tokens) == 1 {
+		return updated, nil
+	}
+	// slices may have been reallocated and must be stored in their parent again
+	return setPointerValue(doc, tokens[:len(tokens)-1], updated)
+}
+
+func addPointerValue(doc any, tokens []string, v any) (any, error) {
+	if len(tokens) == 0 {
+		return v, nil
+	}
+	return updateContainer(doc, tokens, func(container any, t string) (any, error) {
+		switch x := container.(type) {
+		case map[string]any:
+			x[t] = v
+			return x, nil
+		case []any:
+			i := len(x)
+			if t != "-" {
+				var err error
+				i, err = arrayIndex(t, len(x)+1)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return slices.Insert(x, i, v), nil
+		}
+		return nil, fmt.Errorf("%q: parent is not a container: %w", t, ErrNotFound)
+	})
+}
+
+func setPointerValue(doc any, tokens []string, v any) (any, error) {
+	if len(tokens) == 0 {
+		return v, nil
+	}
+	return updateContainer(doc, tokens, func(container any, t string) (any, error) {
+		switch x := container.(type) {
+		case map[string]any:
+			x[t] = v
+			return x, nil
+		case []any:
+			i, err := arrayIndex(t, len(x))
+			if err != nil {
+				return nil, err
+			}
+			x[i] = v
+			return x, nil
+		}
+		return nil, fmt.Errorf("%q: parent is not a container: %w", t, ErrNotFound)
+	})
+}
+
+func removePointerValue(doc any, tokens []string) (any, any, error) {
+	if len(tokens) == 0 {
+		return doc, nil, fmt.Errorf("can not remove the root: %w", ErrInvalidPatch)
+	}
+	var removed any
+	doc, err := updateContainer(doc, tokens, func(container any, t string) (any, error) {
+		switch x := container.(type) {
+		case map[string]any:
+			v, found := x[t]
+			if !found {
+				return nil, fmt.Errorf("member %q: %w", t, ErrNotFound)
+			}
+			removed = v
+			delete(x, t)
+			return x, nil
+		case []any:
+			i, err := arrayIndex(t, len(x))
+			if err != nil {
+				return nil, err
+			}
+			removed = x[i]
+			return slices.Delete(x, i, i+1), nil
+		}
+		return nil, fmt.Errorf("%q: %w", t, ErrNotFound)
+	})
+	return doc, removed, err
+}
+
+// copyValue returns a deep copy of a JSON value.
+func copyValue(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for k, v2 := range x {
+			m[k] = copyValue(v2)
+		}
+		return m
+	case []any:
+		s := make([]any, len(x))
+		for i, v2 := range x {
+			s[i] = copyValue(v2)
+		}
+		return s
+	}
+	return v
+}
+
+// equalValues reports whether two JSON values are equal.
+func equalValues(a, b any) bool {
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			v2, found := y[k]
+			if !found || !equalValues(v, v2) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalValues(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
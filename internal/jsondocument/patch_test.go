@@ -0,0 +1,166 @@
+package jsondocument_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseJSON(s string) any {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestJSONPatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"add to end of array", `{"foo":[1]}`, `[{"op":"add","path":"/foo/-","value":2}]`, `{"foo":[1,2]}`},
+		{"add null", `{}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`},
+		{"test", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`},
+		{"replace root", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := jsondocument.ReadPatch(strings.NewReader(tc.patch))
+			if assert.NoError(t, err) {
+				assert.Equal(t, jsondocument.JSONPatch, p.Kind)
+				got, errs := p.Apply(parseJSON(tc.doc))
+				assert.Empty(t, errs)
+				assert.Equal(t, parseJSON(tc.want), got)
+			}
+		})
+	}
+	t.Run("should report all failed operations and return no result", func(t *testing.T) {
+		p, err := jsondocument.ReadPatch(strings.NewReader(`[
+			{"op":"test","path":"/a","value":2},
+			{"op":"add","path":"/b","value":2},
+			{"op":"remove","path":"/c"},
+			{"op":"add","path":"/d/5","value":1}
+		]`))
+		if assert.NoError(t, err) {
+			got, errs := p.Apply(parseJSON(`{"a":1,"d":[]}`))
+			assert.Nil(t, got)
+			if assert.Len(t, errs, 3) {
+				assert.Equal(t, 0, errs[0].Index)
+				assert.ErrorIs(t, errs[0], jsondocument.ErrTestFailed)
+				assert.Equal(t, 2, errs[1].Index)
+				assert.ErrorIs(t, errs[1], jsondocument.ErrNotFound)
+				assert.Equal(t, 3, errs[2].Index)
+			}
+		}
+	})
+	t.Run("should not modify the original document", func(t *testing.T) {
+		doc := parseJSON(`{"a":{"b":1}}`)
+		p, err := jsondocument.ReadPatch(strings.NewReader(`[{"op":"remove","path":"/a/b"}]`))
+		if assert.NoError(t, err) {
+			p.Apply(doc)
+			assert.Equal(t, parseJSON(`{"a":{"b":1}}`), doc)
+		}
+	})
+	t.Run("should read objects as merge patch", func(t *testing.T) {
+		p, err := jsondocument.ReadPatch(strings.NewReader(`{"a":null}`))
+		if assert.NoError(t, err) {
+			assert.Equal(t, jsondocument.MergePatch, p.Kind)
+		}
+	})
+	t.Run("should reject operations without path", func(t *testing.T) {
+		_, err := jsondocument.ReadPatch(strings.NewReader(`[{"op":"remove"}]`))
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidPatch)
+	})
+	t.Run("should reject invalid JSON", func(t *testing.T) {
+		_, err := jsondocument.ReadPatch(strings.NewReader(`[{`))
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidPatch)
+	})
+}
+
+func TestMergePatch(t *testing.T) {
+	cases := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.patch, func(t *testing.T) {
+			p := &jsondocument.Patch{Kind: jsondocument.MergePatch, Merge: parseJSON(tc.patch)}
+			got, errs := p.Apply(parseJSON(tc.doc))
+			assert.Empty(t, errs)
+			assert.Equal(t, parseJSON(tc.want), got)
+		})
+	}
+}
+
+func TestNewPatch(t *testing.T) {
+	a := parseJSON(`{"alpha":1,"bravo":[1,2,3],"charlie":{"delta":true},"echo":"x"}`)
+	b := parseJSON(`{"alpha":2,"bravo":[1,5],"charlie":{"delta":true,"foxtrot":null},"golf":[]}`)
+	t.Run("should create JSON patch", func(t *testing.T) {
+		p := jsondocument.NewPatch(jsondocument.JSONPatch, a, b)
+		got, err := json.Marshal(p)
+		if assert.NoError(t, err) {
+			want := `[
+				{"op":"remove","path":"/echo"},
+				{"op":"replace","path":"/alpha","value":2},
+				{"op":"replace","path":"/bravo/1","value":5},
+				{"op":"remove","path":"/bravo/2"},
+				{"op":"add","path":"/charlie/foxtrot","value":null},
+				{"op":"add","path":"/golf","value":[]}
+			]`
+			assert.JSONEq(t, want, string(got))
+		}
+	})
+	t.Run("should create JSON patch which transforms a into b", func(t *testing.T) {
+		p := jsondocument.NewPatch(jsondocument.JSONPatch, a, b)
+		got, errs := p.Apply(a)
+		assert.Empty(t, errs)
+		assert.Equal(t, b, got)
+	})
+	t.Run("should create empty JSON patch for equal values", func(t *testing.T) {
+		p := jsondocument.NewPatch(jsondocument.JSONPatch, a, a)
+		got, err := json.Marshal(p)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "[]", string(got))
+		}
+	})
+	t.Run("should create merge patch", func(t *testing.T) {
+		b := parseJSON(`{"alpha":2,"bravo":[1,5],"charlie":{"delta":false},"golf":[]}`)
+		p := jsondocument.NewPatch(jsondocument.MergePatch, a, b)
+		got, err := json.Marshal(p)
+		if assert.NoError(t, err) {
+			want := `{"alpha":2,"bravo":[1,5],"charlie":{"delta":false},"echo":null,"golf":[]}`
+			assert.JSONEq(t, want, string(got))
+		}
+		got2, errs := p.Apply(a)
+		assert.Empty(t, errs)
+		assert.Equal(t, b, got2)
+	})
+}
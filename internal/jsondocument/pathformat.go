@@ -0,0 +1,214 @@
+package jsondocument
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// PathSyntax is the syntax for formatting the path to a node.
+type PathSyntax uint
+
+const (
+	SyntaxJSONPointer PathSyntax = iota
+	SyntaxJSONPath
+	SyntaxJQ
+	SyntaxJavaScript
+	SyntaxPython
+	SyntaxGo
+)
+
+// PathSyntaxes contains all supported path syntaxes.
+var PathSyntaxes = []PathSyntax{SyntaxJSONPointer, SyntaxJSONPath, SyntaxJQ, SyntaxJavaScript, SyntaxPython, SyntaxGo}
+
+func (s PathSyntax) String() string {
+	switch s {
+	case SyntaxJSONPointer:
+		return "JSON Pointer"
+	case SyntaxJSONPath:
+		return "JSONPath"
+	case SyntaxJQ:
+		return "jq"
+	case SyntaxJavaScript:
+		return "JavaScript"
+	case SyntaxPython:
+		return "Python"
+	case SyntaxGo:
+		return "Go"
+	}
+	return "?"
+}
+
+// Name of the variable for the root of the document in program code
+const pathRootVariable = "data"
+
+// pathStep is a step of the path to a node, which is either an object key or an array index.
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// FormatPath returns the path of a node in the given syntax, e.g. `.items[0]."first name"` for jq.
+// Keys are quoted and escaped as needed by the syntax.
+// Paths for program code assume the document is in a variable called "data".
+func (j *JSONDocument) FormatPath(uid widget.TreeNodeID, syntax PathSyntax) string {
+	if syntax == SyntaxJSONPointer {
+		return j.Pointer(uid)
+	}
+	var segments []pathStep
+	tokens := j.pointerTokens(uid2id(uid))
+	id := int32(0)
+	for _, t := range tokens {
+		var s pathStep
+		if j.values[id].Type == Array {
+			i, _ := strconv.Atoi(t)
+			s = pathStep{index: i, isIndex: true}
+		} else {
+			s = pathStep{key: t}
+		}
+		segments = append(segments, s)
+		id, _ = j.child(id, t)
+	}
+	return formatPath(segments, syntax)
+}
+
+func formatPath(segments []pathStep, syntax PathSyntax) string {
+	var sb strings.Builder
+	switch syntax {
+	case SyntaxJSONPath:
+		sb.WriteString("$")
+	case SyntaxJavaScript, SyntaxPython, SyntaxGo:
+		sb.WriteString(pathRootVariable)
+	}
+	for i, s := range segments {
+		switch syntax {
+		case SyntaxJSONPath:
+			switch {
+			case s.isIndex:
+				fmt.Fprintf(&sb, "[%d]", s.index)
+			case isJSONPathName(s.key):
+				sb.WriteString("." + s.key)
+			default:
+				sb.WriteString("[" + quoteJSONPathString(s.key) + "]")
+			}
+		case SyntaxJQ:
+			switch {
+			case s.isIndex && i == 0:
+				fmt.Fprintf(&sb, ".[%d]", s.index)
+			case s.isIndex:
+				fmt.Fprintf(&sb, "[%d]", s.index)
+			case isIdentifier(s.key, false):
+				sb.WriteString("." + s.key)
+			default:
+				sb.WriteString("." + quoteJSONString(s.key))
+			}
+		case SyntaxJavaScript:
+			switch {
+			case s.isIndex:
+				fmt.Fprintf(&sb, "[%d]", s.index)
+			case isIdentifier(s.key, true):
+				sb.WriteString("." + s.key)
+			default:
+				sb.WriteString("[" + quoteJSONString(s.key) + "]")
+			}
+		case SyntaxPython:
+			if s.isIndex {
+				fmt.Fprintf(&sb, "[%d]", s.index)
+			} else {
+				sb.WriteString("[" + quoteJSONString(s.key) + "]")
+			}
+		case SyntaxGo:
+			if s.isIndex {
+				fmt.Fprintf(&sb, ".([]any)[%d]", s.index)
+			} else {
+				sb.WriteString(".(map[string]any)[" + strconv.Quote(s.key) + "]")
+			}
+		}
+	}
+	if syntax == SyntaxJQ && len(segments) == 0 {
+		return "."
+	}
+	return sb.String()
+}
+
+// isIdentifier reports whether s is an ASCII identifier, which can be used with dot notation.
+// Dollar signs are allowed for JavaScript.
+func isIdentifier(s string, allowDollar bool) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c == '$' && allowDollar:
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isJSONPathName reports whether s can be used in the member name shorthand of JSONPath.
+func isJSONPathName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c >= 0x80 && unicode.IsPrint(c):
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// quoteJSONString returns s as JSON string literal, which is also valid in JavaScript, Python and jq.
+func quoteJSONString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return strconv.Quote(s)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// quoteJSONPathString returns s as single quoted string literal as defined in RFC 9535.
+func quoteJSONPathString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+	for _, c := range s {
+		switch c {
+		case '\'':
+			sb.WriteString(`\'`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				fmt.Fprintf(&sb, `\u%04x`, c)
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatPath(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{
+		"items": [{"name": "alpha"}],
+		"first name": {"a'b\"c": 1},
+		"$id": 2,
+		"a/b": {"m~n": 3},
+		"1st": 4
+	}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	find := func(path string) string {
+		uid, err := j.ResolvePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	cases := []struct {
+		path   string
+		syntax jsondocument.PathSyntax
+		want   string
+	}{
+		{"/items/0/name", jsondocument.SyntaxJSONPointer, "/items/0/name"},
+		{"/items/0/name", jsondocument.SyntaxJSONPath, "$.items[0].name"},
+		{"/items/0/name", jsondocument.SyntaxJQ, ".items[0].name"},
+		{"/items/0/name", jsondocument.SyntaxJavaScript, "data.items[0].name"},
+		{"/items/0/name", jsondocument.SyntaxPython, `data["items"][0]["name"]`},
+		{"/items/0/name", jsondocument.SyntaxGo, `data.(map[string]any)["items"].([]any)[0].(map[string]any)["name"]`},
+		{`/first name/a'b"c`, jsondocument.SyntaxJSONPointer, `/first name/a'b"c`},
+		{`/first name/a'b"c`, jsondocument.SyntaxJSONPath, `$['first name']['a\'b"c']`},
+		{`/first name/a'b"c`, jsondocument.SyntaxJQ, `."first name"."a'b\"c"`},
+		{`/first name/a'b"c`, jsondocument.SyntaxJavaScript, `data["first name"]["a'b\"c"]`},
+		{`/first name/a'b"c`, jsondocument.SyntaxPython, `data["first name"]["a'b\"c"]`},
+		{`/first name/a'b"c`, jsondocument.SyntaxGo, `data.(map[string]any)["first name"].(map[string]any)["a'b\"c"]`},
+		{"/$id", jsondocument.SyntaxJSONPath, "$['$id']"},
+		{"/$id", jsondocument.SyntaxJQ, `."$id"`},
+		{"/$id", jsondocument.SyntaxJavaScript, "data.$id"},
+		{"/a~1b/m~0n", jsondocument.SyntaxJSONPointer, "/a~1b/m~0n"},
+		{"/a~1b/m~0n", jsondocument.SyntaxJQ, `."a/b"."m~n"`},
+		{"/1st", jsondocument.SyntaxJSONPath, "$['1st']"},
+		{"/1st", jsondocument.SyntaxJavaScript, `data["1st"]`},
+		{"", jsondocument.SyntaxJSONPointer, ""},
+		{"", jsondocument.SyntaxJSONPath, "$"},
+		{"", jsondocument.SyntaxJQ, "."},
+		{"", jsondocument.SyntaxPython, "data"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.syntax.String()+" "+tc.path, func(t *testing.T) {
+			got := j.FormatPath(find(tc.path), tc.syntax)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
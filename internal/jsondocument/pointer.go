@@ -0,0 +1,198 @@
+package jsondocument
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+var ErrInvalidPointer = errors.New("invalid JSON pointer")
+
+// PathError is returned when a segment of a path does not match a node in the document.
+type PathError struct {
+	Segment int    // position of the segment, starting with 1
+	Token   string // segment which did not match
+	Parent  string // JSON pointer of the last node which did match
+}
+
+func (e *PathError) Error() string {
+	parent := e.Parent
+	if parent == "" {
+		parent = "the root"
+	}
+	return fmt.Sprintf("segment %d %q not found in %s", e.Segment, e.Token, parent)
+}
+
+func (e *PathError) Unwrap() error {
+	return ErrNotFound
+}
+
+// Pointer returns the JSON pointer of a node as defined in RFC 6901, e.g. "/spec/containers/0".
+// The pointer of the root node is the empty string.
+func (j *JSONDocument) Pointer(uid widget.TreeNodeID) string {
+	return formatPointer(j.pointerTokens(uid2id(uid)))
+}
+
+// pointerTokens returns the reference tokens for a node.
+func (j *JSONDocument) pointerTokens(id int32) []string {
+	tokens := make([]string, 0)
+	for id != 0 {
+		parent := j.parents[id]
+		key := j.values[id].Key
+		if j.values[parent].Type == Array {
+			key = strings.Trim(key, "[]")
+		}
+		tokens = append(tokens, key)
+		id = parent
+	}
+	for i, k := 0, len(tokens)-1; i < k; i, k = i+1, k-1 {
+		tokens[i], tokens[k] = tokens[k], tokens[i]
+	}
+	return tokens
+}
+
+// Resolve returns the UID of the node a JSON pointer refers to.
+// It returns an error wrapping [ErrInvalidPointer] for malformed pointers
+// and a [PathError] when a segment does not match.
+func (j *JSONDocument) Resolve(pointer string) (widget.TreeNodeID, error) {
+	if pointer != "" && pointer[0] != '/' {
+		return "", fmt.Errorf("%w: %q must start with /", ErrInvalidPointer, pointer)
+	}
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return "", err
+	}
+	return j.resolveTokens(tokens)
+}
+
+// ResolvePath returns the UID of the node a path refers to.
+// The path can be a JSON pointer like "/spec/containers/0/image"
+// or a dotted path like "spec.containers[0].image" or "spec.containers.0.image".
+func (j *JSONDocument) ResolvePath(path string) (widget.TreeNodeID, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path[0] == '/' {
+		return j.Resolve(path)
+	}
+	tokens, err := parseDottedPath(path)
+	if err != nil {
+		return "", err
+	}
+	return j.resolveTokens(tokens)
+}
+
+func (j *JSONDocument) resolveTokens(tokens []string) (widget.TreeNodeID, error) {
+	var id int32
+	for i, t := range tokens {
+		childID, ok := j.child(id, t)
+		if !ok {
+			return "", &PathError{Segment: i + 1, Token: t, Parent: formatPointer(tokens[:i])}
+		}
+		id = childID
+	}
+	return id2uid(id), nil
+}
+
+// child returns the ID of the child node matching a reference token.
+func (j *JSONDocument) child(id int32, token string) (int32, bool) {
+	var key string
+	switch j.values[id].Type {
+	case Array:
+		i, err := arrayIndex(token, len(j.ids[id]))
+		if err != nil {
+			return 0, false
+		}
+		return j.ids[id][i], true
+	case Object:
+		key = token
+	default:
+		return 0, false
+	}
+	for _, childID := range j.ids[id] {
+		if j.values[childID].Key == key {
+			return childID, true
+		}
+	}
+	return 0, false
+}
+
+// parseDottedPath returns the reference tokens of a dotted path like "a.b[0]['c.d']".
+// A leading "$" or "." is ignored.
+func parseDottedPath(s string) ([]string, error) {
+	s = strings.TrimPrefix(s, "$")
+	tokens := make([]string, 0)
+	invalid := func(pos int) error {
+		return fmt.Errorf("%w: unexpected character at position %d in %q", ErrInvalidPointer, pos+1, s)
+	}
+	i := 0
+	for i < len(s) {
+		switch s[i] {
+		case '.':
+			i++
+			start := i
+			for i < len(s) && s[i] != '.' && s[i] != '[' {
+				i++
+			}
+			if i == start {
+				if i < len(s) && s[i] == '[' {
+					continue // allow ".[0]"
+				}
+				return nil, invalid(start)
+			}
+			tokens = append(tokens, s[start:i])
+		case '[':
+			start := i
+			i++
+			var token string
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := i + 1
+				for end < len(s) && s[end] != q {
+					if s[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				if end >= len(s) {
+					return nil, invalid(start)
+				}
+				token = s[i+1 : end]
+				if q == '"' {
+					k, err := strconv.Unquote(s[i : end+1])
+					if err != nil {
+						return nil, invalid(i)
+					}
+					token = k
+				}
+				i = end + 1
+			} else {
+				end := strings.IndexByte(s[i:], ']')
+				if end < 0 {
+					return nil, invalid(start)
+				}
+				token = s[i : i+end]
+				if _, err := strconv.Atoi(token); err != nil {
+					return nil, invalid(i)
+				}
+				i += end
+			}
+			if i >= len(s) || s[i] != ']' {
+				return nil, invalid(i)
+			}
+			i++
+			tokens = append(tokens, token)
+		default:
+			if i > 0 {
+				return nil, invalid(i)
+			}
+			start := i
+			for i < len(s) && s[i] != '.' && s[i] != '[' {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		}
+	}
+	return tokens, nil
+}
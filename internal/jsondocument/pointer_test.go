@@ -0,0 +1,108 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointer(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{
+		"spec": {"template": {"containers": [{"image": "nginx"}, {"image": "redis"}]}},
+		"a/b": {"m~n": 1},
+		"": 2,
+		"x.y": 3
+	}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("can resolve pointers", func(t *testing.T) {
+		cases := []struct {
+			pointer string
+			want    any
+		}{
+			{"/spec/template/containers/1/image", "redis"},
+			{"/spec/template/containers/0", map[string]any{"image": "nginx"}},
+			{"/a~1b/m~0n", 1.0},
+			{"/", 2.0},
+			{"/x.y", 3.0},
+		}
+		for _, tc := range cases {
+			uid, err := j.Resolve(tc.pointer)
+			if assert.NoError(t, err, tc.pointer) {
+				assert.Equal(t, tc.want, j.ExtractValue(uid), tc.pointer)
+			}
+		}
+	})
+	t.Run("can resolve root", func(t *testing.T) {
+		uid, err := j.Resolve("")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "", uid)
+		}
+	})
+	t.Run("should report segment which did not match", func(t *testing.T) {
+		_, err := j.Resolve("/spec/template/pods/0")
+		assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+		var pe *jsondocument.PathError
+		if assert.ErrorAs(t, err, &pe) {
+			assert.Equal(t, 3, pe.Segment)
+			assert.Equal(t, "pods", pe.Token)
+			assert.Equal(t, "/spec/template", pe.Parent)
+		}
+		assert.Equal(t, `segment 3 "pods" not found in /spec/template`, err.Error())
+	})
+	t.Run("should not resolve invalid array indexes", func(t *testing.T) {
+		for _, p := range []string{"/spec/template/containers/2", "/spec/template/containers/01", "/spec/template/containers/-"} {
+			_, err := j.Resolve(p)
+			assert.ErrorIs(t, err, jsondocument.ErrNotFound, p)
+		}
+	})
+	t.Run("should return error for malformed pointer", func(t *testing.T) {
+		_, err := j.Resolve("spec")
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidPointer)
+	})
+	t.Run("can create pointers which resolve to the same node", func(t *testing.T) {
+		for _, p := range []string{"/spec/template/containers/1/image", "/a~1b/m~0n", "/", ""} {
+			uid, err := j.Resolve(p)
+			if assert.NoError(t, err) {
+				assert.Equal(t, p, j.Pointer(uid))
+			}
+		}
+	})
+	t.Run("can resolve dotted paths and pointers", func(t *testing.T) {
+		cases := []struct {
+			path string
+			want any
+		}{
+			{"spec.template.containers[1].image", "redis"},
+			{"spec.template.containers.1.image", "redis"},
+			{"$.spec.template.containers[0].image", "nginx"},
+			{".spec.template.containers.[0].image", "nginx"},
+			{`["x.y"]`, 3.0},
+			{`['a/b']["m~n"]`, 1.0},
+			{" /spec/template/containers/0/image ", "nginx"},
+		}
+		for _, tc := range cases {
+			uid, err := j.ResolvePath(tc.path)
+			if assert.NoError(t, err, tc.path) {
+				assert.Equal(t, tc.want, j.ExtractValue(uid), tc.path)
+			}
+		}
+	})
+	t.Run("should report invalid dotted paths", func(t *testing.T) {
+		for _, p := range []string{"spec..template", "spec[x]", "spec['a", "spec.", "spec[0"} {
+			_, err := j.ResolvePath(p)
+			assert.ErrorIs(t, err, jsondocument.ErrInvalidPointer, p)
+		}
+		_, err := j.ResolvePath("spec.pods")
+		var pe *jsondocument.PathError
+		if assert.ErrorAs(t, err, &pe) {
+			assert.Equal(t, 2, pe.Segment)
+		}
+	})
+}
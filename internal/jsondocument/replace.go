@@ -0,0 +1,213 @@
+package jsondocument
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// ReplaceTarget defines which parts of nodes are replaced. Targets can be combined.
+type ReplaceTarget uint8
+
+const (
+	ReplaceKeys    ReplaceTarget = 1 << iota // keys of object members
+	ReplaceStrings                           // string values
+	ReplaceNumbers                           // number values
+)
+
+// ReplaceOptions define how text is found and replaced.
+type ReplaceOptions struct {
+	// Regex enables matching with a regular expression.
+	// The replacement can then refer to capture groups, e.g. $1 or ${name}.
+	Regex      bool
+	IgnoreCase bool
+	Targets    ReplaceTarget
+}
+
+// Replacement is a change of the key or the value of a node found by [JSONDocument.FindReplacements].
+type Replacement struct {
+	UID widget.TreeNodeID
+	Key bool // whether the key is replaced, otherwise the value
+	Old string
+	New string
+	Err error // set when the replacement is invalid, e.g. not a valid number
+}
+
+// FindReplacements returns the changes for replacing all matches of pattern in the node uid
+// and its descendants. Values are returned in document order.
+//
+// Numbers are matched as text and must still be a valid number after the replacement.
+// Otherwise the replacement is returned with an error instead of failing the whole search.
+func (j *JSONDocument) FindReplacements(ctx context.Context, uid widget.TreeNodeID, pattern, replacement string, opts ReplaceOptions) ([]Replacement, error) {
+	id, err := j.editableID(uid)
+	if err != nil {
+		return nil, err
+	}
+	if pattern == "" {
+		return nil, errors.New("nothing to find")
+	}
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	replace := func(s string) (string, bool) {
+		if !re.MatchString(s) {
+			return "", false
+		}
+		var r string
+		if opts.Regex {
+			r = re.ReplaceAllString(s, replacement)
+		} else {
+			r = re.ReplaceAllLiteralString(s, replacement)
+		}
+		return r, r != s
+	}
+	var rs []Replacement
+	var n int
+	stack := []int32{id}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n++
+		if n%searchCancelTick == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ErrCallerCanceled
+			default:
+			}
+		}
+		node := j.values[id]
+		uid := id2uid(id)
+		if opts.Targets&ReplaceKeys != 0 && id != rootNodeID && j.values[j.parents[id]].Type == Object {
+			if s, ok := replace(node.Key); ok {
+				rs = append(rs, Replacement{UID: uid, Key: true, Old: node.Key, New: s})
+			}
+		}
+		switch {
+		case node.Type == String && opts.Targets&ReplaceStrings != 0:
+			if s, ok := replace(node.Value.(string)); ok {
+				rs = append(rs, Replacement{UID: uid, Old: node.Value.(string), New: s})
+			}
+		case node.Type == Number && opts.Targets&ReplaceNumbers != 0:
+			old := formatNumber(node.Value.(float64))
+			if s, ok := replace(old); ok {
+				_, err := parseNumber(s)
+				rs = append(rs, Replacement{UID: uid, Old: old, New: s, Err: err})
+			}
+		}
+		children := j.ids[id]
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
+	}
+	return rs, nil
+}
+
+// ApplyReplacements applies replacements to the document as one operation with the given name,
+// which can be undone at once. Either all or none of the replacements are applied.
+// Invalid replacements, which have an error, are skipped.
+//
+// Keys, which would collide with another key of the same object, are renamed
+// after the other key was renamed too.
+//
+// It returns [ErrInvalidEdit] when the key or value of a node no longer equals the old text of its replacement,
+// e.g. because the document was changed after the replacements were found.
+func (j *JSONDocument) ApplyReplacements(name string, rs []Replacement) error {
+	for _, r := range rs {
+		if r.Err != nil {
+			continue
+		}
+		if err := j.checkReplacement(r); err != nil {
+			return err
+		}
+	}
+	return j.Group(name, func() error {
+		var keys []Replacement
+		for _, r := range rs {
+			if r.Err != nil {
+				continue
+			}
+			if r.Key {
+				keys = append(keys, r)
+				continue
+			}
+			var v any = r.New
+			if j.Value(r.UID).Type == Number {
+				x, err := parseNumber(r.New)
+				if err != nil {
+					return err
+				}
+				v = x
+			}
+			if err := j.SetValue(r.UID, v); err != nil {
+				return err
+			}
+		}
+		for len(keys) > 0 {
+			var pending []Replacement
+			var err error
+			for _, r := range keys {
+				err = j.RenameKey(r.UID, r.New)
+				if errors.Is(err, ErrKeyExists) {
+					pending = append(pending, r)
+				} else if err != nil {
+					return err
+				}
+			}
+			if len(pending) == len(keys) {
+				return err
+			}
+			keys = pending
+		}
+		return nil
+	})
+}
+
+// checkReplacement returns an error when the key or value of the node of a replacement
+// is no longer the replaced text.
+func (j *JSONDocument) checkReplacement(r Replacement) error {
+	id, err := j.editableID(r.UID)
+	if err != nil {
+		return err
+	}
+	node := j.values[id]
+	var current string
+	var ok bool
+	switch {
+	case r.Key:
+		current, ok = node.Key, id != rootNodeID && j.values[j.parents[id]].Type == Object
+	case node.Type == String:
+		current, ok = node.Value.(string), true
+	case node.Type == Number:
+		current, ok = formatNumber(node.Value.(float64)), true
+	}
+	if !ok || current != r.Old {
+		return fmt.Errorf("%s no longer contains %q: %w", j.describe(id), r.Old, ErrInvalidEdit)
+	}
+	return nil
+}
+
+// formatNumber returns the text of a number as matched by replacements.
+func formatNumber(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// parseNumber returns the number of a text or an error when it is not a valid JSON number.
+func parseNumber(s string) (float64, error) {
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+		return 0, fmt.Errorf("not a valid number: %q: %w", s, ErrInvalidEdit)
+	}
+	return x, nil
+}
@@ -0,0 +1,144 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplace(t *testing.T) {
+	ctx := context.TODO()
+	const doc = `{
+		"hosts": [{"url": "http://old.example.com/a", "port": 8080}, {"url": "http://OLD.example.com/b", "port": 80}],
+		"old_name": "old",
+		"other": {"old_name": 1.5}
+	}`
+	load := func(t *testing.T) *jsondocument.JSONDocument {
+		j := jsondocument.New()
+		if err := j.LoadData(ctx, parseJSON(doc)); err != nil {
+			t.Fatal(err)
+		}
+		return j
+	}
+	pointers := func(j *jsondocument.JSONDocument, rs []jsondocument.Replacement) []string {
+		var s []string
+		for _, r := range rs {
+			s = append(s, j.Pointer(r.UID))
+		}
+		return s
+	}
+	t.Run("should find literal matches in strings in document order", func(t *testing.T) {
+		j := load(t)
+		rs, err := j.FindReplacements(ctx, "", "old", "new", jsondocument.ReplaceOptions{Targets: jsondocument.ReplaceStrings})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/hosts/0/url", "/old_name"}, pointers(j, rs))
+		assert.Equal(t, "http://new.example.com/a", rs[0].New)
+		assert.False(t, rs[0].Key)
+	})
+	t.Run("should find matches ignoring case", func(t *testing.T) {
+		j := load(t)
+		rs, err := j.FindReplacements(ctx, "", "old.", "new.", jsondocument.ReplaceOptions{
+			IgnoreCase: true,
+			Targets:    jsondocument.ReplaceStrings,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/hosts/0/url", "/hosts/1/url"}, pointers(j, rs))
+	})
+	t.Run("should replace with capture groups", func(t *testing.T) {
+		j := load(t)
+		rs, err := j.FindReplacements(ctx, "", `^http://(\w+)\.example\.com/(.*)$`, "https://$1.example.org/${2}", jsondocument.ReplaceOptions{
+			Regex:   true,
+			Targets: jsondocument.ReplaceStrings,
+		})
+		require.NoError(t, err)
+		require.Len(t, rs, 2)
+		assert.Equal(t, "https://OLD.example.org/b", rs[1].New)
+	})
+	t.Run("should find keys and numbers within subtree", func(t *testing.T) {
+		j := load(t)
+		other, err := j.ResolvePath("/other")
+		require.NoError(t, err)
+		rs, err := j.FindReplacements(ctx, other, "old", "new", jsondocument.ReplaceOptions{
+			Targets: jsondocument.ReplaceKeys | jsondocument.ReplaceNumbers,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []jsondocument.Replacement{{UID: rs[0].UID, Key: true, Old: "old_name", New: "new_name"}}, rs)
+		rs, err = j.FindReplacements(ctx, "", "80", "90", jsondocument.ReplaceOptions{Targets: jsondocument.ReplaceNumbers})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/hosts/0/port", "/hosts/1/port"}, pointers(j, rs))
+		assert.Equal(t, "9090", rs[0].New)
+	})
+	t.Run("should report replacements with invalid numbers and skip them", func(t *testing.T) {
+		j := load(t)
+		rs, err := j.FindReplacements(ctx, "", `^(80|1\.5)$`, "${1}5.0", jsondocument.ReplaceOptions{Regex: true, Targets: jsondocument.ReplaceNumbers})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/hosts/1/port", "/other/old_name"}, pointers(j, rs))
+		assert.NoError(t, rs[0].Err)
+		assert.ErrorIs(t, rs[1].Err, jsondocument.ErrInvalidEdit)
+		require.NoError(t, j.ApplyReplacements("Replace", rs))
+		v := j.ExtractValue("").(map[string]any)
+		assert.Equal(t, 805.0, v["hosts"].([]any)[1].(map[string]any)["port"])
+		assert.Equal(t, 1.5, v["other"].(map[string]any)["old_name"])
+	})
+	t.Run("should return error for invalid pattern", func(t *testing.T) {
+		j := load(t)
+		_, err := j.FindReplacements(ctx, "", "(", "", jsondocument.ReplaceOptions{Regex: true, Targets: jsondocument.ReplaceStrings})
+		assert.Error(t, err)
+	})
+	t.Run("should apply replacements as one operation", func(t *testing.T) {
+		j := load(t)
+		original := j.ExtractValue("")
+		rs, err := j.FindReplacements(ctx, "", "old", "new", jsondocument.ReplaceOptions{
+			Targets: jsondocument.ReplaceKeys | jsondocument.ReplaceStrings | jsondocument.ReplaceNumbers,
+		})
+		require.NoError(t, err)
+		require.NoError(t, j.ApplyReplacements("Replace old", rs))
+		want := parseJSON(`{
+			"hosts": [{"url": "http://new.example.com/a", "port": 8080}, {"url": "http://OLD.example.com/b", "port": 80}],
+			"new_name": "new",
+			"other": {"new_name": 1.5}
+		}`)
+		assert.Equal(t, want, j.ExtractValue(""))
+		assert.Equal(t, []string{"Replace old"}, j.UndoHistory())
+		_, err = j.Undo()
+		require.NoError(t, err)
+		assert.Equal(t, original, j.ExtractValue(""))
+	})
+	t.Run("should rename keys, which collide before all keys are renamed", func(t *testing.T) {
+		j := jsondocument.New()
+		require.NoError(t, j.LoadData(ctx, parseJSON(`{"a": 1, "b": 2}`)))
+		a, err := j.ResolvePath("/a")
+		require.NoError(t, err)
+		b, err := j.ResolvePath("/b")
+		require.NoError(t, err)
+		rs := []jsondocument.Replacement{
+			{UID: a, Key: true, Old: "a", New: "b"},
+			{UID: b, Key: true, Old: "b", New: "c"},
+		}
+		require.NoError(t, j.ApplyReplacements("Replace", rs))
+		assert.Equal(t, parseJSON(`{"b": 1, "c": 2}`), j.ExtractValue(""))
+	})
+	t.Run("should not apply replacements when keys collide", func(t *testing.T) {
+		j := jsondocument.New()
+		require.NoError(t, j.LoadData(ctx, parseJSON(`{"a1": 1, "a2": 2}`)))
+		rs, err := j.FindReplacements(ctx, "", `a\d`, "a", jsondocument.ReplaceOptions{Regex: true, Targets: jsondocument.ReplaceKeys})
+		require.NoError(t, err)
+		assert.ErrorIs(t, j.ApplyReplacements("Replace", rs), jsondocument.ErrKeyExists)
+		assert.Equal(t, parseJSON(`{"a1": 1, "a2": 2}`), j.ExtractValue(""))
+		assert.False(t, j.CanUndo())
+	})
+	t.Run("should not apply replacements when a node has changed", func(t *testing.T) {
+		j := load(t)
+		rs, err := j.FindReplacements(ctx, "", "old", "new", jsondocument.ReplaceOptions{Targets: jsondocument.ReplaceStrings})
+		require.NoError(t, err)
+		edits := j.Edits()
+		require.NoError(t, j.SetValue(rs[1].UID, "changed"))
+		assert.NotEqual(t, edits, j.Edits())
+		assert.ErrorIs(t, j.ApplyReplacements("Replace", rs), jsondocument.ErrInvalidEdit)
+		assert.Equal(t, "http://old.example.com/a", j.Value(rs[0].UID).Value)
+		assert.Equal(t, []string{"Change value of /old_name"}, j.UndoHistory())
+	})
+}
@@ -0,0 +1,693 @@
+package jsondocument
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+var ErrInvalidPattern = errors.New("invalid search pattern")
+
+// SearchType represents the type of search to perform.
+type SearchType uint
+
+const (
+	SearchKey SearchType = iota
+	SearchString
+	SearchNumber
+	SearchKeyword
+	SearchCondition // objects with members matching a condition like price > 100
+	SearchAnything  // keys and all scalar values
+)
+
+// MatchPart tells which parts of a node matched a search.
+type MatchPart uint8
+
+const (
+	MatchKey MatchPart = 1 << iota
+	MatchValue
+)
+
+func (p MatchPart) String() string {
+	switch p {
+	case MatchKey:
+		return "key"
+	case MatchValue:
+		return "value"
+	case MatchKey | MatchValue:
+		return "key and value"
+	}
+	return "none"
+}
+
+// SearchMatch is a node matching a search.
+type SearchMatch struct {
+	UID  widget.TreeNodeID
+	Part MatchPart
+}
+
+// SearchMode defines how a search pattern is matched against keys and values.
+type SearchMode uint
+
+const (
+	// The pattern must match the whole text. An asterisk matches any characters.
+	SearchWildcard SearchMode = iota
+	// The pattern must match a part of the text. An asterisk matches any characters.
+	SearchContains
+	// The pattern is a regular expression in RE2 syntax, which must match a part of the text.
+	SearchRegex
+	// The text must match the pattern fuzzy as defined by [MatchFuzzy].
+	SearchFuzzy
+)
+
+// SearchOptions represents options for a search.
+// The zero value is a case-sensitive wildcard search.
+type SearchOptions struct {
+	Mode       SearchMode
+	IgnoreCase bool
+	Backward   bool // search from bottom to top
+	Wrap       bool // continue at the other end of the document or scope
+	// Scope is the node whose descendants are searched. The default is the whole document.
+	Scope widget.TreeNodeID
+	// MaxDepth limits the search to descendants up to this many levels below the scope. Zero means no limit.
+	MaxDepth int
+}
+
+// CompilePattern returns the regular expression for matching a search pattern with the given options.
+// It returns an error wrapping [ErrInvalidPattern] when the pattern is not a valid regular expression.
+func CompilePattern(search string, opts SearchOptions) (*regexp.Regexp, error) {
+	var expr string
+	switch opts.Mode {
+	case SearchWildcard:
+		expr = wildCardToRegexp(search)
+	case SearchContains:
+		parts := strings.Split(search, "*")
+		for i, p := range parts {
+			parts[i] = regexp.QuoteMeta(p)
+		}
+		expr = strings.Join(parts, ".*")
+	case SearchRegex:
+		expr = search
+	case SearchFuzzy:
+		// approximation, which matches the characters of the pattern in order, but no typos
+		parts := make([]string, 0, len(search))
+		for _, r := range search {
+			parts = append(parts, regexp.QuoteMeta(string(r)))
+		}
+		expr = "(?i)" + strings.Join(parts, ".*")
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		var re *syntax.Error
+		if errors.As(err, &re) {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidPattern, re.Code, re.Expr)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPattern, err)
+	}
+	return pattern, nil
+}
+
+// matcher returns the parts of a node matching a search. Zero means no match.
+type matcher func(j *JSONDocument, id int32) MatchPart
+
+// compileMatcher returns a matcher for a search.
+func compileMatcher(search string, typ SearchType, opts SearchOptions) (matcher, error) {
+	if typ == SearchCondition && opts.Mode == SearchFuzzy {
+		return nil, fmt.Errorf("%w: conditions can not be matched fuzzy", ErrInvalidPattern)
+	}
+	if typ == SearchCondition {
+		expr, err := parseCondition(search, opts)
+		if err != nil {
+			return nil, err
+		}
+		return func(j *JSONDocument, id int32) MatchPart {
+			if expr.match(j, id) {
+				return MatchValue
+			}
+			return 0
+		}, nil
+	}
+	if opts.Mode == SearchFuzzy {
+		return func(j *JSONDocument, id int32) MatchPart {
+			r, ok := j.matchFuzzy(id, search, typ)
+			if !ok {
+				return 0
+			}
+			return r.Part
+		}, nil
+	}
+	if typ == SearchNumber && opts.Mode != SearchRegex {
+		q, ok, err := parseNumberQuery(search)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return func(j *JSONDocument, id int32) MatchPart {
+				if n := j.values[id]; n.Type == Number && q.match(n.Value.(float64)) {
+					return MatchValue
+				}
+				return 0
+			}, nil
+		}
+	}
+	pattern, err := CompilePattern(search, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(j *JSONDocument, id int32) MatchPart {
+		return j.matchNode(id, pattern, typ)
+	}, nil
+}
+
+// Largest integer which can be represented exactly as float64 and in JavaScript
+const maxSafeInteger = 1<<53 - 1
+
+// numberQuery is a query for numbers.
+type numberQuery struct {
+	lo, hi float64              // range of all matching numbers, which is used for looking them up in the search index
+	match  func(x float64) bool // reports whether a number matches
+}
+
+// parseNumberQuery returns a query for a comparison like ">= 10", an inclusive range like "0.5..0.9"
+// or a property, which is one of:
+//   - int: integers
+//   - nonint: numbers which are not integers
+//   - unsafe: integers outside of the range which can be represented exactly, e.g. IDs which lost precision
+//
+// It reports false when s is neither and should be treated as pattern.
+func parseNumberQuery(s string) (numberQuery, bool, error) {
+	s = strings.TrimSpace(s)
+	parse := func(t string) (float64, error) {
+		x, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: not a number: %q", ErrInvalidPattern, strings.TrimSpace(t))
+		}
+		return x, nil
+	}
+	all := numberQuery{lo: math.Inf(-1), hi: math.Inf(1)}
+	switch strings.ToLower(s) {
+	case "int":
+		all.match = func(x float64) bool {
+			return x == math.Trunc(x) && !math.IsInf(x, 0)
+		}
+		return all, true, nil
+	case "nonint":
+		all.match = func(x float64) bool {
+			return x != math.Trunc(x)
+		}
+		return all, true, nil
+	case "unsafe":
+		all.match = func(x float64) bool {
+			return math.Abs(x) > maxSafeInteger && !math.IsInf(x, 0)
+		}
+		return all, true, nil
+	}
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		t, found := strings.CutPrefix(s, op)
+		if !found {
+			continue
+		}
+		v, err := parse(t)
+		if err != nil {
+			return numberQuery{}, false, err
+		}
+		q := all
+		switch op {
+		case ">=":
+			q.lo, q.match = v, func(x float64) bool { return x >= v }
+		case "<=":
+			q.hi, q.match = v, func(x float64) bool { return x <= v }
+		case "!=":
+			q.match = func(x float64) bool { return x != v }
+		case ">":
+			q.lo, q.match = v, func(x float64) bool { return x > v }
+		case "<":
+			q.hi, q.match = v, func(x float64) bool { return x < v }
+		default:
+			q.lo, q.hi, q.match = v, v, func(x float64) bool { return x == v }
+		}
+		return q, true, nil
+	}
+	lower, upper, found := strings.Cut(s, "..")
+	if !found {
+		return numberQuery{}, false, nil
+	}
+	lo, hi := math.Inf(-1), math.Inf(1)
+	var err error
+	if strings.TrimSpace(lower) != "" {
+		if lo, err = parse(lower); err != nil {
+			return numberQuery{}, false, err
+		}
+	}
+	if strings.TrimSpace(upper) != "" {
+		if hi, err = parse(upper); err != nil {
+			return numberQuery{}, false, err
+		}
+	}
+	if lo > hi {
+		return numberQuery{}, false, fmt.Errorf("%w: lower bound %v is greater than upper bound %v", ErrInvalidPattern, lo, hi)
+	}
+	q := numberQuery{lo: lo, hi: hi, match: func(x float64) bool {
+		return x >= lo && x <= hi
+	}}
+	return q, true, nil
+}
+
+// ValidateSearch returns an error wrapping [ErrInvalidPattern] when a search is not valid.
+func ValidateSearch(search string, typ SearchType, opts SearchOptions) error {
+	_, err := compileMatcher(search, typ, opts)
+	return err
+}
+
+// Search returns the next node with a matching key or an error if not found or canceled.
+// The starting node will be ignored, so that is is possible to find successive nodes with the same key.
+// The search direction is from top to bottom.
+func (j *JSONDocument) Search(ctx context.Context, uid widget.TreeNodeID, search string, typ SearchType) (widget.TreeNodeID, error) {
+	return j.SearchWithOptions(ctx, uid, search, typ, SearchOptions{})
+}
+
+// SearchWithOptions works like [JSONDocument.Search], but matches the pattern as defined by the options.
+// The search direction is from bottom to top when Backward is set. When Wrap is set, the search continues
+// at the other end of the document and ends at the starting node.
+//
+// When the options define a scope, only the descendants of the scope are searched.
+// A starting node outside of the scope starts the search at the beginning or end of the scope.
+func (j *JSONDocument) SearchWithOptions(ctx context.Context, uid widget.TreeNodeID, search string, typ SearchType, opts SearchOptions) (widget.TreeNodeID, error) {
+	return j.SearchWithProgress(ctx, uid, search, typ, opts, nil)
+}
+
+// SearchWithProgress works like [JSONDocument.SearchWithOptions],
+// but reports the number of scanned nodes to progress, which can be nil.
+// The number of scanned nodes does not exceed [JSONDocument.SearchSize].
+//
+// When the search can use the search index, only the nodes found in the index are scanned.
+func (j *JSONDocument) SearchWithProgress(ctx context.Context, uid widget.TreeNodeID, search string, typ SearchType, opts SearchOptions, progress func(scanned int)) (widget.TreeNodeID, error) {
+	if search == "" {
+		return "", ErrNotFound
+	}
+	match, err := compileMatcher(search, typ, opts)
+	if err != nil {
+		return "", err
+	}
+	c, err := j.newScopedCursor(opts.Scope, opts.MaxDepth)
+	if err != nil {
+		return "", err
+	}
+	startID := uid2id(uid)
+	// checkStart is true when the cursor is already at the first node to check
+	var checkStart bool
+	inScope, truncated := c.moveTo(startID)
+	switch {
+	case !inScope && opts.Backward:
+		c.last()
+		checkStart = true
+	case truncated && opts.Backward:
+		checkStart = true // ancestor of the starting node comes before it
+	}
+	if ids, ok := j.indexCandidates(search, typ, opts); ok {
+		if !inScope {
+			if opts.Backward {
+				startID = math.MaxInt32 // after all nodes of the scope
+			} else {
+				startID = c.scopeID
+			}
+		}
+		return j.searchCandidates(ctx, ids, c, startID, match, opts, progress)
+	}
+	var hasWrapped bool
+	var scanned int
+	if progress != nil {
+		defer func() {
+			if scanned%int(j.ProgressUpdateTick) != 0 {
+				progress(scanned)
+			}
+		}()
+	}
+	for i := 0; ; i++ {
+		if i%searchCancelTick == 0 {
+			select {
+			case <-ctx.Done():
+				return "", ErrCallerCanceled
+			default:
+			}
+		}
+		var ok bool
+		switch {
+		case checkStart:
+			ok = true
+			checkStart = false
+		case opts.Backward:
+			ok = c.prev()
+		default:
+			ok = c.next()
+		}
+		if !ok {
+			if !opts.Wrap || hasWrapped {
+				return "", ErrNotFound
+			}
+			hasWrapped = true
+			if opts.Backward {
+				c.last()
+			} else {
+				c.first()
+			}
+		}
+		id := c.id()
+		if hasWrapped && id == startID {
+			return "", ErrNotFound
+		}
+		scanned++
+		if progress != nil && scanned%int(j.ProgressUpdateTick) == 0 {
+			progress(scanned)
+		}
+		if id != c.scopeID && match(j, id) != 0 {
+			return id2uid(id), nil
+		}
+	}
+}
+
+// SearchAll searches the whole document or the scope defined by the options
+// and calls found for each matching node in document order.
+// It reports the number of searched nodes to progress, which can be nil.
+// It returns [ErrCallerCanceled] when the context is canceled.
+func (j *JSONDocument) SearchAll(ctx context.Context, search string, typ SearchType, opts SearchOptions, found func(uid widget.TreeNodeID), progress func(searched int)) error {
+	return j.SearchAllMatches(ctx, search, typ, opts, func(m SearchMatch) {
+		found(m.UID)
+	}, progress)
+}
+
+// SearchAllMatches works like [JSONDocument.SearchAll], but also reports which parts of a node matched.
+//
+// When the search can use the search index, only the nodes found in the index are searched
+// and reported to progress.
+func (j *JSONDocument) SearchAllMatches(ctx context.Context, search string, typ SearchType, opts SearchOptions, found func(m SearchMatch), progress func(searched int)) error {
+	if search == "" {
+		return nil
+	}
+	match, err := compileMatcher(search, typ, opts)
+	if err != nil {
+		return err
+	}
+	c, err := j.newScopedCursor(opts.Scope, opts.MaxDepth)
+	if err != nil {
+		return err
+	}
+	if ids, ok := j.indexCandidates(search, typ, opts); ok {
+		for i, id := range ids {
+			if (i+1)%searchCancelTick == 0 {
+				select {
+				case <-ctx.Done():
+					return ErrCallerCanceled
+				default:
+				}
+			}
+			if c.contains(id) {
+				if part := match(j, id); part != 0 {
+					found(SearchMatch{UID: id2uid(id), Part: part})
+				}
+			}
+			if progress != nil && (i+1)%int(j.ProgressUpdateTick) == 0 {
+				progress(i + 1)
+			}
+		}
+		if progress != nil && len(ids)%int(j.ProgressUpdateTick) != 0 {
+			progress(len(ids))
+		}
+		return nil
+	}
+	var searched int
+	for c.next() {
+		searched++
+		if searched%searchCancelTick == 0 {
+			select {
+			case <-ctx.Done():
+				return ErrCallerCanceled
+			default:
+			}
+		}
+		id := c.id()
+		if part := match(j, id); part != 0 {
+			found(SearchMatch{UID: id2uid(id), Part: part})
+		}
+		if progress != nil && searched%int(j.ProgressUpdateTick) == 0 {
+			progress(searched)
+		}
+	}
+	if progress != nil && searched%int(j.ProgressUpdateTick) != 0 {
+		progress(searched)
+	}
+	return nil
+}
+
+// SearchSize returns the number of nodes searched by [JSONDocument.SearchAll] for a search,
+// which is the number of candidates when the search can use the search index.
+func (j *JSONDocument) SearchSize(search string, typ SearchType, opts SearchOptions) int {
+	if opts.Mode != SearchFuzzy {
+		if ids, ok := j.indexCandidates(search, typ, opts); ok {
+			return len(ids)
+		}
+	}
+	if opts.Scope == "" && opts.MaxDepth <= 0 {
+		return max(j.Size()-1, 0)
+	}
+	c, err := j.newScopedCursor(opts.Scope, opts.MaxDepth)
+	if err != nil {
+		return 0
+	}
+	var n int
+	for c.next() {
+		n++
+	}
+	return n
+}
+
+// matchNode returns the parts of a node matching the pattern for a search type.
+func (j *JSONDocument) matchNode(id int32, pattern *regexp.Regexp, typ SearchType) MatchPart {
+	n := j.values[id]
+	var ok bool
+	switch typ {
+	case SearchKey:
+		if pattern.MatchString(n.Key) {
+			return MatchKey
+		}
+		return 0
+	case SearchKeyword:
+		switch n.Type {
+		case Boolean, Null:
+			s, _ := scalarText(n)
+			ok = pattern.MatchString(s)
+		}
+	case SearchNumber:
+		if n.Type == Number {
+			s, _ := scalarText(n)
+			ok = pattern.MatchString(s)
+		}
+	case SearchString:
+		if n.Type == String {
+			ok = pattern.MatchString(n.Value.(string))
+		}
+	case SearchAnything:
+		var part MatchPart
+		// array elements have no real keys
+		if id != rootNodeID && j.values[j.parents[id]].Type != Array && pattern.MatchString(n.Key) {
+			part |= MatchKey
+		}
+		if s, isScalar := scalarText(n); isScalar && pattern.MatchString(s) {
+			part |= MatchValue
+		}
+		return part
+	default:
+		panic("Undefined search type")
+	}
+	if ok {
+		return MatchValue
+	}
+	return 0
+}
+
+// cursor moves through the nodes of a document in document order,
+// i.e. the order in which they are shown in a fully expanded tree.
+// A cursor can be limited to the descendants of a scope node.
+type cursor struct {
+	j *JSONDocument
+	// Position of all nodes from the top down to the current node as index in their parent's children.
+	// The current node is the root when empty.
+	stack []cursorLevel
+	// Scope of the cursor and its length of the stack
+	scopeID int32
+	base    int
+	// Maximum number of levels below the scope or zero for no limit
+	maxDepth int
+}
+
+type cursorLevel struct {
+	parentID int32
+	index    int
+}
+
+// newCursor returns a new cursor for the whole document positioned at the root node.
+func (j *JSONDocument) newCursor() *cursor {
+	return &cursor{j: j, scopeID: rootNodeID}
+}
+
+// newScopedCursor returns a new cursor for the descendants of a scope node
+// positioned at the scope node.
+// It returns [ErrNotFound] when the scope does not exist.
+func (j *JSONDocument) newScopedCursor(scope widget.TreeNodeID, maxDepth int) (*cursor, error) {
+	c := j.newCursor()
+	c.maxDepth = max(maxDepth, 0)
+	if scope == "" {
+		return c, nil
+	}
+	id, err := strconv.Atoi(scope)
+	if err != nil || id <= 0 || id >= len(j.values) {
+		return nil, fmt.Errorf("search scope %s: %w", scope, ErrNotFound)
+	}
+	c.moveTo(int32(id))
+	c.scopeID = int32(id)
+	c.base = len(c.stack)
+	return c, nil
+}
+
+// moveTo moves the cursor to a node and reports whether it is within the scope.
+// When the node is within the scope, but deeper than the maximum depth,
+// the cursor is moved to its ancestor at the maximum depth and truncated is reported.
+// When the node is outside the scope the cursor is moved to the scope node.
+func (c *cursor) moveTo(id int32) (inScope bool, truncated bool) {
+	var stack []cursorLevel
+	for id != rootNodeID && id != c.scopeID {
+		parentID := c.j.parents[id]
+		stack = append(stack, cursorLevel{parentID: parentID, index: slices.Index(c.j.ids[parentID], id)})
+		id = parentID
+	}
+	if id != c.scopeID {
+		c.first()
+		return false, false
+	}
+	slices.Reverse(stack)
+	if c.maxDepth > 0 && len(stack) > c.maxDepth {
+		stack = stack[:c.maxDepth]
+		truncated = true
+	}
+	c.stack = append(c.stack[:c.base], stack...)
+	return true, truncated
+}
+
+// contains reports whether a node is within the scope and maximum depth of the cursor.
+func (c *cursor) contains(id int32) bool {
+	var depth int
+	for id != c.scopeID {
+		if id == rootNodeID {
+			return false
+		}
+		id = c.j.parents[id]
+		depth++
+		if c.maxDepth > 0 && depth > c.maxDepth {
+			return false
+		}
+	}
+	return depth > 0
+}
+
+// id returns the ID of the current node.
+func (c *cursor) id() int32 {
+	if len(c.stack) == 0 {
+		return rootNodeID
+	}
+	l := c.stack[len(c.stack)-1]
+	return c.j.ids[l.parentID][l.index]
+}
+
+// canDescend reports whether the cursor can move to the children of the current node.
+func (c *cursor) canDescend() bool {
+	return c.maxDepth == 0 || len(c.stack)-c.base < c.maxDepth
+}
+
+// first moves the cursor to the scope node.
+func (c *cursor) first() {
+	c.stack = c.stack[:c.base]
+}
+
+// last moves the cursor to the last node of the scope.
+func (c *cursor) last() {
+	c.first()
+	c.descendLast()
+}
+
+// descendLast moves the cursor to the last descendant of the current node.
+func (c *cursor) descendLast() {
+	for c.canDescend() {
+		id := c.id()
+		n := len(c.j.ids[id])
+		if n == 0 {
+			return
+		}
+		c.stack = append(c.stack, cursorLevel{parentID: id, index: n - 1})
+	}
+}
+
+// next moves the cursor to the next node and reports whether there was one.
+func (c *cursor) next() bool {
+	if id := c.id(); c.canDescend() && len(c.j.ids[id]) > 0 {
+		c.stack = append(c.stack, cursorLevel{parentID: id})
+		return true
+	}
+	for len(c.stack) > c.base {
+		l := &c.stack[len(c.stack)-1]
+		if l.index+1 < len(c.j.ids[l.parentID]) {
+			l.index++
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	c.last() // stay at the last node
+	return false
+}
+
+// prev moves the cursor to the previous node and reports whether there was one.
+// The scope node is not regarded as previous node.
+func (c *cursor) prev() bool {
+	if len(c.stack) == c.base {
+		return false
+	}
+	l := &c.stack[len(c.stack)-1]
+	if l.index > 0 {
+		l.index--
+		c.descendLast()
+		return true
+	}
+	c.stack = c.stack[:len(c.stack)-1]
+	return len(c.stack) > c.base
+}
+
+func wildCardToRegexp(pattern string) string {
+	components := strings.Split(pattern, "*")
+	if len(components) == 1 {
+		// if len is 1, there are no *'s, return exact match pattern
+		return "^" + regexp.QuoteMeta(pattern) + "$"
+	}
+	var result strings.Builder
+	for i, literal := range components {
+
+		// Replace * with .*
+		if i > 0 {
+			result.WriteString(".*")
+		}
+
+		// Quote any regular expression meta characters in the
+		// literal text.
+		result.WriteString(regexp.QuoteMeta(literal))
+	}
+	return "^" + result.String() + "$"
+}
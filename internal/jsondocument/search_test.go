@@ -0,0 +1,416 @@
+package jsondocument_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompilePattern(t *testing.T) {
+	cases := []struct {
+		search    string
+		opts      jsondocument.SearchOptions
+		text      string
+		wantMatch bool
+	}{
+		{"alpha", jsondocument.SearchOptions{}, "alpha", true},
+		{"alpha", jsondocument.SearchOptions{}, "Alpha", false},
+		{"alpha", jsondocument.SearchOptions{}, "alphabet", false},
+		{"alpha", jsondocument.SearchOptions{IgnoreCase: true}, "ALPHA", true},
+		{"al*", jsondocument.SearchOptions{IgnoreCase: true}, "ALPHA", true},
+		{"pha", jsondocument.SearchOptions{Mode: jsondocument.SearchContains}, "alphabet", true},
+		{"p*a", jsondocument.SearchOptions{Mode: jsondocument.SearchContains}, "alphabet", true},
+		{"a.b", jsondocument.SearchOptions{Mode: jsondocument.SearchContains}, "xa.by", true},
+		{"a.b", jsondocument.SearchOptions{Mode: jsondocument.SearchContains}, "axb", false},
+		{"PHA", jsondocument.SearchOptions{Mode: jsondocument.SearchContains, IgnoreCase: true}, "alphabet", true},
+		{`^a\d+$`, jsondocument.SearchOptions{Mode: jsondocument.SearchRegex}, "a42", true},
+		{`^a\d+$`, jsondocument.SearchOptions{Mode: jsondocument.SearchRegex}, "a42b", false},
+		{`\d+`, jsondocument.SearchOptions{Mode: jsondocument.SearchRegex}, "a42b", true},
+		{`^A`, jsondocument.SearchOptions{Mode: jsondocument.SearchRegex, IgnoreCase: true}, "alpha", true},
+		{"rcv.", jsondocument.SearchOptions{Mode: jsondocument.SearchFuzzy}, "Received.", true},
+		{"rcv.", jsondocument.SearchOptions{Mode: jsondocument.SearchFuzzy}, "received", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.search+" "+tc.text, func(t *testing.T) {
+			p, err := jsondocument.CompilePattern(tc.search, tc.opts)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.wantMatch, p.MatchString(tc.text))
+			}
+		})
+	}
+	t.Run("should return error for invalid regular expression", func(t *testing.T) {
+		_, err := jsondocument.CompilePattern("(a", jsondocument.SearchOptions{Mode: jsondocument.SearchRegex})
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidPattern)
+		assert.Equal(t, "invalid search pattern: missing closing ): (a", err.Error())
+	})
+}
+
+func TestSearchWithOptions(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{"alpha": {"Name": "Johnny Walker"}, "bravo": {"name": "jim beam"}, "charlie": 1042}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	find := func(path string) string {
+		uid, err := j.ResolvePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	cases := []struct {
+		search string
+		typ    jsondocument.SearchType
+		opts   jsondocument.SearchOptions
+		want   string
+	}{
+		{"name", jsondocument.SearchKey, jsondocument.SearchOptions{}, "/bravo/name"},
+		{"name", jsondocument.SearchKey, jsondocument.SearchOptions{IgnoreCase: true}, "/alpha/Name"},
+		{"walk", jsondocument.SearchString, jsondocument.SearchOptions{Mode: jsondocument.SearchContains, IgnoreCase: true}, "/alpha/Name"},
+		{`^j\w+ b`, jsondocument.SearchString, jsondocument.SearchOptions{Mode: jsondocument.SearchRegex}, "/bravo/name"},
+		{"04", jsondocument.SearchNumber, jsondocument.SearchOptions{Mode: jsondocument.SearchContains}, "/charlie"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.search, func(t *testing.T) {
+			got, err := j.SearchWithOptions(ctx, "", tc.search, tc.typ, tc.opts)
+			if assert.NoError(t, err) {
+				assert.Equal(t, find(tc.want), got)
+			}
+		})
+	}
+	t.Run("should return error for invalid pattern", func(t *testing.T) {
+		_, err := j.SearchWithOptions(ctx, "", "[a", jsondocument.SearchKey, jsondocument.SearchOptions{Mode: jsondocument.SearchRegex})
+		assert.ErrorIs(t, err, jsondocument.ErrInvalidPattern)
+	})
+}
+
+func TestSearchDirection(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{"alpha": {"id": 1, "bravo": {"id": 2}}, "charlie": [{"id": 3}], "delta": 4}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	find := func(path string) string {
+		uid, err := j.ResolvePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	backward := jsondocument.SearchOptions{Backward: true}
+	cases := []struct {
+		name  string
+		start string
+		opts  jsondocument.SearchOptions
+		want  string
+	}{
+		{"forward from top", "", jsondocument.SearchOptions{}, "/alpha/bravo/id"},
+		{"forward into next branch", "/alpha/bravo/id", jsondocument.SearchOptions{}, "/alpha/id"},
+		{"forward into array", "/alpha/id", jsondocument.SearchOptions{}, "/charlie/0/id"},
+		{"backward from bottom", "/delta", backward, "/charlie/0/id"},
+		{"backward into previous branch", "/charlie/0/id", backward, "/alpha/id"},
+		{"backward to ancestor's sibling", "/alpha/id", backward, "/alpha/bravo/id"},
+		{"wrap forward", "/charlie/0/id", jsondocument.SearchOptions{Wrap: true}, "/alpha/bravo/id"},
+		{"wrap backward", "/alpha/bravo/id", jsondocument.SearchOptions{Backward: true, Wrap: true}, "/charlie/0/id"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := j.SearchWithOptions(ctx, find(tc.start), "id", jsondocument.SearchKey, tc.opts)
+			if assert.NoError(t, err) {
+				assert.Equal(t, find(tc.want), got)
+			}
+		})
+	}
+	t.Run("should not find beyond the end without wrap", func(t *testing.T) {
+		_, err := j.SearchWithOptions(ctx, find("/charlie/0/id"), "id", jsondocument.SearchKey, jsondocument.SearchOptions{})
+		assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+		_, err = j.SearchWithOptions(ctx, find("/alpha/bravo/id"), "id", jsondocument.SearchKey, backward)
+		assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+	})
+	t.Run("should not report starting node when it is the only match", func(t *testing.T) {
+		for _, opts := range []jsondocument.SearchOptions{{Wrap: true}, {Wrap: true, Backward: true}} {
+			_, err := j.SearchWithOptions(ctx, find("/delta"), "delta", jsondocument.SearchKey, opts)
+			assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+		}
+	})
+	t.Run("should find a node above when searching backward", func(t *testing.T) {
+		got, err := j.SearchWithOptions(ctx, find("/alpha/bravo"), "alpha", jsondocument.SearchKey, backward)
+		if assert.NoError(t, err) {
+			assert.Equal(t, find("/alpha"), got)
+		}
+	})
+}
+
+func TestSearchAll(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	j.ProgressUpdateTick = 2
+	data := parseJSON(`{"alpha": {"id": 1, "bravo": {"id": 2}}, "charlie": [{"id": 3}], "delta": 4}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("should find all matches in document order", func(t *testing.T) {
+		var got [][]string
+		var searched []int
+		err := j.SearchAll(ctx, "id", jsondocument.SearchKey, jsondocument.SearchOptions{}, func(uid string) {
+			got = append(got, j.KeyPath(uid))
+		}, func(n int) {
+			searched = append(searched, n)
+		})
+		if assert.NoError(t, err) {
+			want := [][]string{{"alpha", "bravo", "id"}, {"alpha", "id"}, {"charlie", "[0]", "id"}}
+			assert.Equal(t, want, got)
+			assert.Equal(t, []int{2, 4, 6, 8}, searched)
+		}
+	})
+	t.Run("should stop when canceled", func(t *testing.T) {
+		j := jsondocument.New()
+		a := make([]any, 5000)
+		for i := range a {
+			a[i] = float64(i)
+		}
+		if err := j.LoadData(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := j.SearchAll(ctx, "*", jsondocument.SearchNumber, jsondocument.SearchOptions{}, func(string) {}, nil)
+		assert.ErrorIs(t, err, jsondocument.ErrCallerCanceled)
+	})
+}
+
+func TestSearchNumberQuery(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := []any{1.0, 0.5, 0.9, 1000.0, 1500.0, -3.0, 9007199254740993.0, 0.95}
+	if err := j.LoadData(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		search string
+		want   []string
+	}{
+		{"> 1000", []string{"/4", "/6"}},
+		{">=1000", []string{"/3", "/4", "/6"}},
+		{"< 0", []string{"/5"}},
+		{"<= 0.5", []string{"/1", "/5"}},
+		{"= 0.9", []string{"/2"}},
+		{"!= 1", []string{"/1", "/2", "/3", "/4", "/5", "/6", "/7"}},
+		{"0.5..0.9", []string{"/1", "/2"}},
+		{"1000..", []string{"/3", "/4", "/6"}},
+		{"..0", []string{"/5"}},
+		{"int", []string{"/0", "/3", "/4", "/5", "/6"}},
+		{"nonint", []string{"/1", "/2", "/7"}},
+		{"unsafe", []string{"/6"}},
+		{"nan", nil},
+		{"1*", []string{"/0", "/3", "/4"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.search, func(t *testing.T) {
+			var got []string
+			err := j.SearchAll(ctx, tc.search, jsondocument.SearchNumber, jsondocument.SearchOptions{}, func(uid string) {
+				got = append(got, j.Pointer(uid))
+			}, nil)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+	t.Run("should report invalid queries", func(t *testing.T) {
+		for _, s := range []string{"> abc", "5..1", "1..x", ">"} {
+			err := jsondocument.ValidateSearch(s, jsondocument.SearchNumber, jsondocument.SearchOptions{})
+			assert.ErrorIs(t, err, jsondocument.ErrInvalidPattern, s)
+		}
+	})
+}
+
+func TestSearchScope(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{"alpha": {"id": 1, "bravo": {"id": 2, "echo": {"id": 3}}}, "charlie": [{"id": 4}], "delta": {"id": 5}}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	find := func(path string) string {
+		uid, err := j.ResolvePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	alpha := find("/alpha")
+	t.Run("should find all matches within scope", func(t *testing.T) {
+		cases := []struct {
+			scope    string
+			maxDepth int
+			want     []string
+			size     int
+		}{
+			{"", 0, []string{"/alpha/bravo/echo/id", "/alpha/bravo/id", "/alpha/id", "/charlie/0/id", "/delta/id"}, 11},
+			{"/alpha", 0, []string{"/alpha/bravo/echo/id", "/alpha/bravo/id", "/alpha/id"}, 5},
+			{"/alpha", 1, []string{"/alpha/id"}, 2},
+			{"/alpha", 2, []string{"/alpha/bravo/id", "/alpha/id"}, 4},
+			{"/alpha/bravo/echo", 0, []string{"/alpha/bravo/echo/id"}, 1},
+			{"/delta/id", 0, nil, 0},
+			{"", 1, nil, 3},
+		}
+		for _, tc := range cases {
+			t.Run(fmt.Sprintf("%s %d", tc.scope, tc.maxDepth), func(t *testing.T) {
+				opts := jsondocument.SearchOptions{Scope: find(tc.scope), MaxDepth: tc.maxDepth}
+				var got []string
+				err := j.SearchAll(ctx, "id", jsondocument.SearchKey, opts, func(uid string) {
+					got = append(got, j.Pointer(uid))
+				}, nil)
+				if assert.NoError(t, err) {
+					assert.Equal(t, tc.want, got)
+					assert.Equal(t, tc.size, j.SearchSize("id", jsondocument.SearchKey, opts))
+				}
+			})
+		}
+	})
+	t.Run("should step through matches within scope", func(t *testing.T) {
+		cases := []struct {
+			name  string
+			start string
+			opts  jsondocument.SearchOptions
+			want  string
+		}{
+			{"forward from scope", "/alpha", jsondocument.SearchOptions{Scope: alpha}, "/alpha/bravo/echo/id"},
+			{"forward from outside", "/delta", jsondocument.SearchOptions{Scope: alpha}, "/alpha/bravo/echo/id"},
+			{"backward from outside", "/delta", jsondocument.SearchOptions{Scope: alpha, Backward: true}, "/alpha/id"},
+			{"wrap forward", "/alpha/id", jsondocument.SearchOptions{Scope: alpha, Wrap: true}, "/alpha/bravo/echo/id"},
+			{"wrap backward", "/alpha/bravo/echo/id", jsondocument.SearchOptions{Scope: alpha, Wrap: true, Backward: true}, "/alpha/id"},
+			{"forward from too deep", "/alpha/bravo/echo/id", jsondocument.SearchOptions{Scope: alpha, MaxDepth: 2}, "/alpha/bravo/id"},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				got, err := j.SearchWithOptions(ctx, find(tc.start), "id", jsondocument.SearchKey, tc.opts)
+				if assert.NoError(t, err) {
+					assert.Equal(t, tc.want, j.Pointer(got))
+				}
+			})
+		}
+		got, err := j.SearchWithOptions(ctx, find("/alpha/bravo/echo/id"), "bravo", jsondocument.SearchKey, jsondocument.SearchOptions{Scope: alpha, MaxDepth: 1, Backward: true})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "/alpha/bravo", j.Pointer(got))
+		}
+		_, err = j.SearchWithOptions(ctx, find("/alpha/id"), "id", jsondocument.SearchKey, jsondocument.SearchOptions{Scope: alpha})
+		assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+	})
+	t.Run("should return error for unknown scope", func(t *testing.T) {
+		err := j.SearchAll(ctx, "id", jsondocument.SearchKey, jsondocument.SearchOptions{Scope: "999"}, func(string) {}, nil)
+		assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+	})
+}
+
+func TestSearchAnything(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{"42": "x", "alpha": 42, "bravo": "42", "charlie": [42, true], "delta": {"true": null}, "echo": "echo"}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	type match struct {
+		path string
+		part jsondocument.MatchPart
+	}
+	findAll := func(search string, opts jsondocument.SearchOptions) []match {
+		var got []match
+		err := j.SearchAllMatches(ctx, search, jsondocument.SearchAnything, opts, func(m jsondocument.SearchMatch) {
+			got = append(got, match{j.Pointer(m.UID), m.Part})
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	t.Run("should match keys and all kinds of values", func(t *testing.T) {
+		got := findAll("42", jsondocument.SearchOptions{})
+		want := []match{
+			{"/42", jsondocument.MatchKey},
+			{"/alpha", jsondocument.MatchValue},
+			{"/bravo", jsondocument.MatchValue},
+			{"/charlie/0", jsondocument.MatchValue},
+		}
+		assert.Equal(t, want, got)
+	})
+	t.Run("should report when key and value match", func(t *testing.T) {
+		got := findAll("*a*", jsondocument.SearchOptions{})
+		want := []match{
+			{"/alpha", jsondocument.MatchKey},
+			{"/bravo", jsondocument.MatchKey},
+			{"/charlie", jsondocument.MatchKey},
+			{"/delta", jsondocument.MatchKey},
+		}
+		assert.Equal(t, want, got)
+		got = findAll("TRUE", jsondocument.SearchOptions{IgnoreCase: true})
+		want = []match{
+			{"/charlie/1", jsondocument.MatchValue},
+			{"/delta/true", jsondocument.MatchKey},
+		}
+		assert.Equal(t, want, got)
+		got = findAll("null", jsondocument.SearchOptions{Mode: jsondocument.SearchContains})
+		assert.Equal(t, []match{{"/delta/true", jsondocument.MatchValue}}, got)
+		got = findAll("echo", jsondocument.SearchOptions{})
+		assert.Equal(t, []match{{"/echo", jsondocument.MatchKey | jsondocument.MatchValue}}, got)
+	})
+	t.Run("should find next node matching anything", func(t *testing.T) {
+		uid, err := j.SearchWithOptions(ctx, "", "x", jsondocument.SearchAnything, jsondocument.SearchOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "/42", j.Pointer(uid))
+		}
+	})
+	t.Run("should describe match parts", func(t *testing.T) {
+		assert.Equal(t, "key", jsondocument.MatchKey.String())
+		assert.Equal(t, "value", jsondocument.MatchValue.String())
+		assert.Equal(t, "key and value", (jsondocument.MatchKey | jsondocument.MatchValue).String())
+	})
+}
+
+func TestSearchWithProgress(t *testing.T) {
+	ctx := context.TODO()
+	j := jsondocument.New()
+	data := parseJSON(`{"alpha": {"id": 1, "bravo": {"id": 2}}, "charlie": [{"id": 3}], "delta": {"id": 4}}`)
+	if err := j.Load(ctx, makeDataReader(data), binding.NewUntyped()); err != nil {
+		t.Fatal(err)
+	}
+	j.ProgressUpdateTick = 1
+	cases := []struct {
+		name string
+		opts jsondocument.SearchOptions
+	}{
+		{"forward", jsondocument.SearchOptions{}},
+		{"backward", jsondocument.SearchOptions{Backward: true, Wrap: true}},
+		{"wrap", jsondocument.SearchOptions{Wrap: true}},
+		{"scope", jsondocument.SearchOptions{Scope: "1"}},
+	}
+	for _, tc := range cases {
+		t.Run("should report scanned nodes when not found: "+tc.name, func(t *testing.T) {
+			var got []int
+			_, err := j.SearchWithProgress(ctx, "", "unknown", jsondocument.SearchKey, tc.opts, func(scanned int) {
+				got = append(got, scanned)
+			})
+			assert.ErrorIs(t, err, jsondocument.ErrNotFound)
+			if assert.NotEmpty(t, got) {
+				assert.IsIncreasing(t, got)
+				assert.LessOrEqual(t, got[len(got)-1], j.SearchSize("unknown", jsondocument.SearchKey, tc.opts))
+			}
+		})
+	}
+	t.Run("should report scanned nodes until found", func(t *testing.T) {
+		var got []int
+		uid, err := j.SearchWithProgress(ctx, "", "charlie", jsondocument.SearchKey, jsondocument.SearchOptions{}, func(scanned int) {
+			got = append(got, scanned)
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "charlie", j.Value(uid).Key)
+			assert.Equal(t, []int{1, 2, 3, 4, 5}, got)
+		}
+	})
+}
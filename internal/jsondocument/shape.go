@@ -0,0 +1,52 @@
+package jsondocument
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+)
+
+const (
+	shapeDepth        = 2   // levels of object members, which define the shape
+	shapeArraySamples = 100 // number of array elements inspected for the shape
+)
+
+// Shape returns a fingerprint of the structure of a document, which allows recognizing documents of the same kind,
+// e.g. daily exports from the same system.
+// It is based on the keys and types of the first two levels of object members.
+// Array elements do not count as level and are regarded as one.
+// Returns an empty string for an empty document.
+func (j *JSONDocument) Shape() string {
+	if j.Size() == 0 {
+		return ""
+	}
+	paths := make(map[string]bool)
+	var walk func(id int32, path string, depth int)
+	walk = func(id int32, path string, depth int) {
+		n := j.values[id]
+		paths[path+":"+n.Type.String()] = true
+		if depth == shapeDepth {
+			return
+		}
+		for i, childID := range j.ids[id] {
+			if n.Type == Array {
+				if i == shapeArraySamples {
+					break
+				}
+				walk(childID, path+"[]", depth)
+			} else {
+				walk(childID, path+"."+j.values[childID].Key, depth+1)
+			}
+		}
+	}
+	walk(rootNodeID, "", 0)
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(keys, "\n")))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,36 @@
+package jsondocument_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShape(t *testing.T) {
+	ctx := context.TODO()
+	shape := func(s string) string {
+		j := jsondocument.New()
+		if err := j.LoadData(ctx, parseJSON(s)); err != nil {
+			t.Fatal(err)
+		}
+		return j.Shape()
+	}
+	base := shape(`{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}], "total": 2}`)
+	assert.NotEmpty(t, base)
+	t.Run("should be equal for documents of the same kind", func(t *testing.T) {
+		assert.Equal(t, base, shape(`{"total": 5, "items": [{"name": "x", "id": 7}]}`))
+		assert.Equal(t, base, shape(`{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 3, "name": "c"}], "total": 3}`))
+		assert.Equal(t, base, shape(`{"items": [{"id": 1, "name": "a"}], "total": 2}`))
+	})
+	t.Run("should differ for documents of another kind", func(t *testing.T) {
+		assert.NotEqual(t, base, shape(`{"items": [{"id": 1}], "total": 2}`))
+		assert.NotEqual(t, base, shape(`{"items": [{"id": "1", "name": "a"}], "total": 2}`))
+		assert.NotEqual(t, base, shape(`{"items": [{"id": 1, "name": "a"}], "count": 2}`))
+		assert.NotEqual(t, base, shape(`[{"items": [{"id": 1, "name": "a"}], "total": 2}]`))
+	})
+	t.Run("should be empty for empty document", func(t *testing.T) {
+		assert.Equal(t, "", jsondocument.New().Shape())
+	})
+}
@@ -26,6 +26,13 @@ func (t *JSONTreeSizer) Calculate(data any) (int, error) {
 	return t.count, nil
 }
 
+// calculateValue returns the number of nodes for any JSON value.
+func (t *JSONTreeSizer) calculateValue(v any) int {
+	t.count = 0
+	t.parseValue(v)
+	return t.count
+}
+
 // parseObject parses an object in a JSON tree.
 func (t *JSONTreeSizer) parseObject(data map[string]any) {
 	for _, v := range data {
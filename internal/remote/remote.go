@@ -0,0 +1,167 @@
+// Package remote contains features for fetching JSON documents from web servers.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/storage"
+
+	"github.com/ErikKalkoken/janice/internal/github"
+)
+
+// httpClient is used for all requests.
+// It times out when a server does not respond, but not while the body of a large document is read,
+// which can be canceled through the request's context instead.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+	},
+}
+
+// Methods returns the supported HTTP methods.
+func Methods() []string {
+	return []string{
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	}
+}
+
+// Request represents a HTTP request for fetching a JSON document.
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+}
+
+// Validate reports whether a request is valid.
+func (r Request) Validate() error {
+	uri, err := storage.ParseURI(r.URL)
+	if err != nil {
+		return err
+	}
+	if s := uri.Scheme(); s != "http" && s != "https" {
+		return fmt.Errorf("not a HTTP(S) URL: %s", r.URL)
+	}
+	if r.Method != "" && !slices.Contains(Methods(), r.Method) {
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+	return nil
+}
+
+// Reader represents the body of a HTTP response and can be used for loading documents.
+// It implements [fyne.URIReadCloser].
+type Reader struct {
+	io.ReadCloser
+	contentLength int64
+	uri           fyne.URI
+}
+
+// ContentLength returns the length of the body or -1 if it is unknown.
+func (r *Reader) ContentLength() int64 {
+	return r.contentLength
+}
+
+// URI returns the URI of the request.
+func (r *Reader) URI() fyne.URI {
+	return r.uri
+}
+
+// Open sends a request and returns a reader for the body of the response.
+// The caller must close the reader.
+func Open(ctx context.Context, r Request) (*Reader, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	uri, err := storage.ParseURI(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.Header {
+		req.Header[k] = slices.Clone(v)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", resp.Status, github.ErrHttpError)
+	}
+	x := &Reader{
+		ReadCloser:    resp.Body,
+		contentLength: resp.ContentLength,
+		uri:           uri,
+	}
+	return x, nil
+}
+
+// ParseHeader parses HTTP headers from a text with one "Name: Value" pair per line.
+// Empty lines are ignored.
+func ParseHeader(s string) (http.Header, error) {
+	h := make(http.Header)
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		k, v, found := strings.Cut(line, ":")
+		k = strings.TrimSpace(k)
+		if !found || k == "" || strings.ContainsAny(k, " \t") {
+			return nil, fmt.Errorf("invalid header in line %d: %s", i+1, line)
+		}
+		h.Add(textproto.CanonicalMIMEHeaderKey(k), strings.TrimSpace(v))
+	}
+	return h, nil
+}
+
+// FormatHeader returns HTTP headers as text with one "Name: Value" pair per line.
+func FormatHeader(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var lines []string
+	for _, k := range keys {
+		for _, v := range h[k] {
+			lines = append(lines, fmt.Sprintf("%s: %s", k, v))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,97 @@
+package remote_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ErikKalkoken/janice/internal/github"
+	"github.com/ErikKalkoken/janice/internal/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	var gotMethod, gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotAuth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		switch r.URL.Path {
+		case "/data.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"alpha": 1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Run("should return body of response", func(t *testing.T) {
+		r, err := remote.Open(ctx, remote.Request{URL: srv.URL + "/data.json"})
+		if assert.NoError(t, err) {
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if assert.NoError(t, err) {
+				assert.Equal(t, `{"alpha": 1}`, string(got))
+			}
+			assert.Equal(t, int64(12), r.ContentLength())
+			assert.Equal(t, "data.json", r.URI().Name())
+			assert.Equal(t, "GET", gotMethod)
+		}
+	})
+	t.Run("should send method, headers and body", func(t *testing.T) {
+		req := remote.Request{
+			Method: "POST",
+			URL:    srv.URL + "/data.json",
+			Header: http.Header{"Authorization": []string{"Bearer abc"}},
+			Body:   `{"query": "x"}`,
+		}
+		r, err := remote.Open(ctx, req)
+		if assert.NoError(t, err) {
+			r.Close()
+			assert.Equal(t, "POST", gotMethod)
+			assert.Equal(t, "Bearer abc", gotAuth)
+			assert.Equal(t, `{"query": "x"}`, gotBody)
+		}
+	})
+	t.Run("should return error when server responds with error status", func(t *testing.T) {
+		_, err := remote.Open(ctx, remote.Request{URL: srv.URL + "/missing.json"})
+		assert.ErrorIs(t, err, github.ErrHttpError)
+	})
+	t.Run("should return error when URL is not HTTP", func(t *testing.T) {
+		_, err := remote.Open(ctx, remote.Request{URL: "file:///tmp/data.json"})
+		assert.Error(t, err)
+	})
+	t.Run("should return error when method is not supported", func(t *testing.T) {
+		_, err := remote.Open(ctx, remote.Request{Method: "TRACE", URL: srv.URL + "/data.json"})
+		assert.Error(t, err)
+	})
+}
+
+func TestParseHeader(t *testing.T) {
+	t.Run("can parse headers", func(t *testing.T) {
+		got, err := remote.ParseHeader("authorization: Bearer abc\n\nX-Custom:  one \nX-Custom: two")
+		if assert.NoError(t, err) {
+			want := http.Header{
+				"Authorization": []string{"Bearer abc"},
+				"X-Custom":      []string{"one", "two"},
+			}
+			assert.Equal(t, want, got)
+		}
+	})
+	t.Run("should return error for invalid line", func(t *testing.T) {
+		_, err := remote.ParseHeader("Authorization Bearer abc")
+		assert.Error(t, err)
+	})
+	t.Run("can format headers", func(t *testing.T) {
+		h := http.Header{
+			"X-Custom":      []string{"one", "two"},
+			"Authorization": []string{"Bearer abc"},
+		}
+		got := remote.FormatHeader(h)
+		assert.Equal(t, "Authorization: Bearer abc\nX-Custom: one\nX-Custom: two", got)
+	})
+}
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"context"
+	"image/color"
+	"log/slog"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// changeColor returns the highlight color for a change type or nil if it has none.
+func changeColor(typ jsondocument.ChangeType) color.Color {
+	var name fyne.ThemeColorName
+	switch typ {
+	case jsondocument.Added:
+		name = theme.ColorNameSuccess
+	case jsondocument.Removed:
+		name = theme.ColorNameError
+	case jsondocument.Modified:
+		name = theme.ColorNameWarning
+	default:
+		return nil
+	}
+	return withAlpha(theme.Color(name), 0x50)
+}
+
+// withAlpha returns a color with a new alpha value.
+func withAlpha(c color.Color, alpha uint8) color.Color {
+	r, g, b, _ := c.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: alpha}
+}
+
+// showChanges compares the current document with a previous version of it
+// and highlights the changes in the tree.
+// completed is called after the changes are shown, also when comparing failed, and can be nil.
+func (u *UI) showChanges(previous *jsondocument.JSONDocument, completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	current := u.document
+	ctx, job := u.jobs.start(context.Background(), completed)
+	go func() {
+		d, err := jsondocument.Compare(ctx, previous, current, jsondocument.CompareOptions{})
+		job.readDone()
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			if err != nil {
+				slog.Error("Failed to compare documents", "err", err)
+				return
+			}
+			if u.document != current {
+				return // document was changed in the meantime
+			}
+			u.setDiff(d)
+		})
+	}()
+}
+
+// setDiff highlights the changes of a diff in the tree. Nil clears the highlights.
+func (u *UI) setDiff(d *jsondocument.Diff) {
+	u.diff = d
+	u.diffUIDs = nil
+	u.diffIndex = -1
+	if d != nil {
+		u.diffUIDs = d.ChangesB()
+	}
+	hasChanges := len(u.diffUIDs) > 0
+	u.goNextChange.Disabled = !hasChanges
+	u.goPrevChange.Disabled = !hasChanges
+	u.window.MainMenu().Refresh()
+	u.statusBar.setChanges(d)
+	u.tree.Refresh()
+}
+
+// gotoChange selects the next or previous change in the tree.
+// A change of the root node scrolls to the top, because the root node can not be selected.
+func (u *UI) gotoChange(forward bool) {
+	n := len(u.diffUIDs)
+	if n == 0 {
+		return
+	}
+	if forward {
+		u.diffIndex = (u.diffIndex + 1) % n
+	} else if u.diffIndex <= 0 {
+		u.diffIndex = n - 1
+	} else {
+		u.diffIndex--
+	}
+	u.statusBar.setChangePosition(u.diffIndex+1, n)
+	u.gotoNode(u.diffUIDs[u.diffIndex])
+}
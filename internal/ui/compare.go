@@ -0,0 +1,458 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+	fynetooltip "github.com/dweymouth/fyne-tooltip"
+	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// compareWindow shows two documents side by side and highlights their differences.
+// The left document is A and the right document is B.
+type compareWindow struct {
+	arrayKey   *widget.Entry
+	diff       *jsondocument.Diff
+	docs       [2]*jsondocument.JSONDocument
+	index      int
+	jobs       backgroundJobs // for comparing the documents
+	names      [2]*widget.Label
+	nextChange *ttwidget.Button
+	prevChange *ttwidget.Button
+	selected   [2]widget.TreeNodeID
+	summary    *widget.Label
+	syncing    bool // true while the trees are synchronized
+	trees      [2]*compareTree
+	u          *UI
+	window     fyne.Window
+}
+
+// showCompareWindow opens a new window for comparing two documents.
+// A snapshot of the current document is pre-loaded as left document,
+// so that later edits of the current document do not affect the comparison.
+// completed is called after the differences have been computed and can be nil.
+func (u *UI) showCompareWindow(completed func()) *compareWindow {
+	if completed == nil {
+		completed = func() {}
+	}
+	w := &compareWindow{
+		arrayKey: widget.NewEntry(),
+		docs:     [2]*jsondocument.JSONDocument{jsondocument.New(), jsondocument.New()},
+		index:    -1,
+		names:    [2]*widget.Label{widget.NewLabel("No document"), widget.NewLabel("No document")},
+		summary:  widget.NewLabel(""),
+		u:        u,
+		window:   u.app.NewWindow(fmt.Sprintf("Compare - %s", u.app.Metadata().Name)),
+	}
+	for i := range 2 {
+		w.trees[i] = w.makeTree(i)
+		w.names[i].Truncation = fyne.TextTruncateEllipsis
+	}
+	w.arrayKey.SetPlaceHolder("Match array elements by index")
+	w.arrayKey.OnSubmitted = func(string) {
+		w.compare(nil)
+	}
+	w.prevChange = ttwidget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+		w.gotoChange(false)
+	})
+	w.prevChange.SetToolTip("Previous difference")
+	w.nextChange = ttwidget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
+		w.gotoChange(true)
+	})
+	w.nextChange.SetToolTip("Next difference")
+	w.updateSummary()
+
+	makeSide := func(i int) fyne.CanvasObject {
+		b := ttwidget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+			w.openFile(i)
+		})
+		b.SetToolTip("Open file")
+		return container.NewBorder(
+			container.NewBorder(nil, nil, nil, b, w.names[i]),
+			nil,
+			nil,
+			nil,
+			w.trees[i],
+		)
+	}
+	compare := widget.NewButtonWithIcon("Compare", theme.ViewRefreshIcon(), func() {
+		w.compare(nil)
+	})
+	export := widget.NewButtonWithIcon("Export Patch...", theme.DocumentSaveIcon(), func() {
+		w.showExportPatchDialog()
+	})
+	top := container.NewBorder(
+		nil,
+		nil,
+		widget.NewLabel("Array key"),
+		container.NewHBox(compare, export, layout.NewSpacer(), w.summary, w.prevChange, w.nextChange),
+		w.arrayKey,
+	)
+	split := container.NewHSplit(makeSide(0), makeSide(1))
+	c := container.NewBorder(
+		container.NewVBox(top, widget.NewSeparator()),
+		nil,
+		nil,
+		nil,
+		split,
+	)
+	w.window.SetContent(fynetooltip.AddWindowToolTipLayer(c, w.window.Canvas()))
+	w.window.Resize(fyne.NewSize(1000, 700))
+	w.window.SetOnClosed(func() {
+		w.jobs.stopAll()
+	})
+	w.window.Show()
+	if u.document.Size() == 0 || u.currentFile == nil {
+		w.compare(completed)
+		return w
+	}
+	name := u.currentFile.Name()
+	doc := u.document
+	w.summary.SetText("Loading...")
+	ctx, job := u.jobs.start(context.Background(), nil)
+	go func() {
+		data, err := doc.ExtractValueContext(ctx, "")
+		job.readDone()
+		snapshot := jsondocument.New()
+		if err == nil {
+			err = snapshot.LoadData(context.Background(), data)
+		}
+		fyne.Do(func() {
+			job.finish() // the snapshot stays valid when the document is edited afterwards
+			if errors.Is(err, jsondocument.ErrCallerCanceled) {
+				w.compare(completed) // the document was edited before the snapshot was taken
+				return
+			}
+			if err != nil {
+				showErrorDialog(w.window, "Failed to load document", err)
+				w.compare(completed)
+				return
+			}
+			w.docs[0] = snapshot
+			w.names[0].SetText(name)
+			w.trees[0].Refresh()
+			w.compare(completed)
+		})
+	}()
+	return w
+}
+
+// makeTree returns a tree for showing the document on side i.
+// Scrolling the tree scrolls the other tree to the same offset.
+// Opening, closing and selecting a node also scrolls the other tree to its counterpart.
+func (w *compareWindow) makeTree(i int) *compareTree {
+	t := newCompareTree(
+		func() *jsondocument.JSONDocument {
+			return w.docs[i]
+		},
+		func(uid widget.TreeNodeID) jsondocument.ChangeType {
+			return w.status(i, uid)
+		},
+	)
+	t.OnBranchOpened = func(uid widget.TreeNodeID) {
+		w.syncTree(i, uid, func(t *compareTree, uid widget.TreeNodeID) {
+			t.OpenBranch(uid)
+			t.ScrollTo(uid)
+		})
+	}
+	t.OnBranchClosed = func(uid widget.TreeNodeID) {
+		w.syncTree(i, uid, func(t *compareTree, uid widget.TreeNodeID) {
+			t.CloseBranch(uid)
+			t.ScrollTo(uid)
+		})
+	}
+	t.OnSelected = func(uid widget.TreeNodeID) {
+		w.selected[i] = uid
+		w.syncTree(i, uid, func(t *compareTree, uid widget.TreeNodeID) {
+			w.reveal(1-i, uid)
+		})
+	}
+	t.OnUnselected = func(uid widget.TreeNodeID) {
+		w.selected[i] = ""
+	}
+	t.onScrolled = func(offset float32) {
+		if w.syncing {
+			return
+		}
+		w.syncing = true
+		defer func() {
+			w.syncing = false
+		}()
+		w.trees[1-i].ScrollToOffset(offset)
+	}
+	return t
+}
+
+// status returns the change status of a node on side i.
+func (w *compareWindow) status(i int, uid widget.TreeNodeID) jsondocument.ChangeType {
+	if w.diff == nil {
+		return jsondocument.Unchanged
+	}
+	if i == 0 {
+		return w.diff.StatusA(uid)
+	}
+	return w.diff.StatusB(uid)
+}
+
+// syncTree applies an action to the counterpart of a node in the other tree.
+func (w *compareWindow) syncTree(i int, uid widget.TreeNodeID, action func(t *compareTree, uid widget.TreeNodeID)) {
+	if w.syncing || w.diff == nil {
+		return
+	}
+	other, found := w.counterpart(i, uid)
+	if !found {
+		return
+	}
+	w.syncing = true
+	defer func() {
+		w.syncing = false
+	}()
+	action(w.trees[1-i], other)
+}
+
+// counterpart returns the node in the other tree, which corresponds to a node on side i,
+// and reports whether it was found.
+func (w *compareWindow) counterpart(i int, uid widget.TreeNodeID) (widget.TreeNodeID, bool) {
+	var other widget.TreeNodeID
+	var found bool
+	if i == 0 {
+		other, found = w.diff.CounterpartB(uid)
+	} else {
+		other, found = w.diff.CounterpartA(uid)
+	}
+	return other, found && other != ""
+}
+
+// reveal opens all branches to a node on side i, scrolls to it and selects it.
+func (w *compareWindow) reveal(i int, uid widget.TreeNodeID) {
+	if uid == "" {
+		return
+	}
+	for _, uid2 := range w.docs[i].Path(uid) {
+		w.trees[i].OpenBranch(uid2)
+	}
+	w.trees[i].ScrollTo(uid)
+	w.trees[i].Select(uid)
+}
+
+// openFile shows a file dialog and loads the selected file on side i.
+func (w *compareWindow) openFile(i int) {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			showErrorDialog(w.window, "Failed to read folder", err)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		uri := reader.URI()
+		open := func(context.Context) (fyne.URIReadCloser, error) {
+			return reader, nil
+		}
+		showLoader(w.window, uri, open, func(doc *jsondocument.JSONDocument) {
+			w.docs[i] = doc
+			w.names[i].SetText(uri.Name())
+			w.trees[i].UnselectAll()
+			w.selected[i] = ""
+			w.trees[i].CloseAllBranches()
+			w.compare(nil)
+		}, nil)
+	}, w.window)
+	kxdialog.AddDialogKeyHandler(d, w.window)
+	if w.u.app.Preferences().BoolWithFallback(settingExtensionFilter, settingExtensionDefault) {
+		d.SetFilter(storage.NewExtensionFileFilter([]string{".json", ".ndjson", ".jsonl"}))
+	}
+	d.Show()
+}
+
+// compare computes the differences between both documents.
+// completed is called after the differences have been computed and can be nil.
+func (w *compareWindow) compare(completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	w.jobs.stopAll()
+	a, b := w.docs[0], w.docs[1]
+	if a.Size() == 0 || b.Size() == 0 {
+		w.setDiff(nil)
+		completed()
+		return
+	}
+	opts := jsondocument.CompareOptions{ArrayKey: strings.TrimSpace(w.arrayKey.Text)}
+	w.summary.SetText("Comparing...")
+	ctx, job := w.jobs.start(context.Background(), nil)
+	go func() {
+		d, err := jsondocument.Compare(ctx, a, b, opts)
+		job.readDone()
+		fyne.Do(func() {
+			defer completed()
+			if !job.finish() {
+				return // documents were changed or the window was closed in the meantime
+			}
+			if err != nil {
+				showErrorDialog(w.window, "Failed to compare documents", err)
+				w.setDiff(nil)
+				return
+			}
+			w.setDiff(d)
+		})
+	}()
+}
+
+func (w *compareWindow) setDiff(d *jsondocument.Diff) {
+	w.diff = d
+	w.index = -1
+	w.updateSummary()
+	for _, t := range w.trees {
+		t.Refresh()
+	}
+}
+
+func (w *compareWindow) updateSummary() {
+	hasChanges := w.diff != nil && len(w.diff.Changes) > 0
+	if hasChanges {
+		w.prevChange.Enable()
+		w.nextChange.Enable()
+	} else {
+		w.prevChange.Disable()
+		w.nextChange.Disable()
+	}
+	if w.diff == nil {
+		w.summary.SetText("Open two documents to compare them")
+		return
+	}
+	if !hasChanges {
+		w.summary.SetText("Documents are identical")
+		return
+	}
+	p := message.NewPrinter(language.English)
+	var s string
+	if w.index >= 0 {
+		s = p.Sprintf("%d of %d: ", w.index+1, len(w.diff.Changes))
+	}
+	s += p.Sprintf(
+		"%d added, %d removed, %d modified",
+		w.diff.Count(jsondocument.Added),
+		w.diff.Count(jsondocument.Removed),
+		w.diff.Count(jsondocument.Modified),
+	)
+	w.summary.SetText(s)
+}
+
+// gotoChange selects the next or previous difference in both trees.
+func (w *compareWindow) gotoChange(forward bool) {
+	if w.diff == nil || len(w.diff.Changes) == 0 {
+		return
+	}
+	n := len(w.diff.Changes)
+	if forward {
+		w.index = (w.index + 1) % n
+	} else if w.index <= 0 {
+		w.index = n - 1
+	} else {
+		w.index--
+	}
+	c := w.diff.Changes[w.index]
+	w.syncing = true
+	w.reveal(0, c.A)
+	w.reveal(1, c.B)
+	w.syncing = false
+	w.updateSummary()
+}
+
+// showExportPatchDialog shows a dialog for exporting the differences as patch,
+// which transforms the left document into the right document.
+func (w *compareWindow) showExportPatchDialog() {
+	a, b := w.docs[0], w.docs[1]
+	if a.Size() == 0 || b.Size() == 0 {
+		showErrorDialog(w.window, "Open two documents to export their differences", nil)
+		return
+	}
+	kinds := []jsondocument.PatchKind{jsondocument.JSONPatch, jsondocument.MergePatch}
+	options := make([]string, len(kinds))
+	for i, k := range kinds {
+		options[i] = patchKindLabels[k]
+	}
+	format := widget.NewRadioGroup(options, nil)
+	format.Required = true
+	format.SetSelected(options[0])
+	const scopeDocuments, scopeSelection = "Whole documents", "Selected nodes"
+	scope := widget.NewRadioGroup([]string{scopeDocuments, scopeSelection}, nil)
+	scope.Required = true
+	scope.SetSelected(scopeDocuments)
+	if w.selected[0] == "" || w.selected[1] == "" {
+		scope.Disable()
+	}
+	items := []*widget.FormItem{
+		widget.NewFormItem("Format", format),
+		widget.NewFormItem("Compare", scope),
+	}
+	d := dialog.NewForm("Export Patch", "Export", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		var uidA, uidB widget.TreeNodeID
+		if scope.Selected == scopeSelection {
+			uidA, uidB = w.selected[0], w.selected[1]
+		}
+		kind := kinds[slices.Index(options, format.Selected)]
+		p := jsondocument.NewPatch(kind, a.ExtractValue(uidA), b.ExtractValue(uidB))
+		saveJSONFile(w.window, p, "patch.json")
+	}, w.window)
+	kxdialog.AddDialogKeyHandler(d, w.window)
+	d.Show()
+}
+
+// compareTree is a tree for showing a document in the compare window,
+// which reports when it is scrolled by the user.
+type compareTree struct {
+	widget.Tree
+
+	onScrolled func(offset float32)
+	scroller   *container.Scroll
+}
+
+func newCompareTree(document func() *jsondocument.JSONDocument, status func(uid widget.TreeNodeID) jsondocument.ChangeType) *compareTree {
+	t := &compareTree{}
+	t.ExtendBaseWidget(t)
+	initDocumentTree(&t.Tree, document, status)
+	return t
+}
+
+// CreateRenderer hooks into the scroll container of the tree,
+// because the tree itself does not report scrolling.
+func (t *compareTree) CreateRenderer() fyne.WidgetRenderer {
+	r := t.Tree.CreateRenderer()
+	for _, o := range r.Objects() {
+		s, ok := o.(*container.Scroll)
+		if !ok {
+			continue
+		}
+		t.scroller = s
+		onScrolled := s.OnScrolled
+		s.OnScrolled = func(p fyne.Position) {
+			if onScrolled != nil {
+				onScrolled(p)
+			}
+			if t.onScrolled != nil {
+				t.onScrolled(p.Y)
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+	fynetooltip "github.com/dweymouth/fyne-tooltip"
+	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// showDocumentWindow shows a JSON value as read-only document in a new window,
+// e.g. the result of applying a patch.
+// An optional header is shown above the tree.
+// completed is called after the document is shown in the tree and can be nil.
+func (u *UI) showDocumentWindow(title string, data any, header fyne.CanvasObject, completed func()) fyne.Window {
+	w := u.newDocumentWindow(title)
+	w.window.Show()
+	w.set(data, header, completed)
+	return w.window
+}
+
+// documentWindow is a window, which shows a JSON value as read-only document.
+// The selected node or the whole value can be exported to a file or the clipboard.
+// The value can be replaced, so that the same window can show the latest output of a query.
+// Values which are not an object or array are shown as the single element of an array.
+type documentWindow struct {
+	data        any
+	doc         *jsondocument.JSONDocument
+	header      *fyne.Container
+	loadCount   int // incremented for each value, so that outdated loads are ignored
+	selectedUID widget.TreeNodeID
+	tree        *widget.Tree
+	u           *UI
+	window      fyne.Window
+}
+
+// newDocumentWindow returns a new document window, which is not yet shown.
+func (u *UI) newDocumentWindow(title string) *documentWindow {
+	w := &documentWindow{
+		doc:    jsondocument.New(),
+		header: container.NewStack(),
+		u:      u,
+		window: u.app.NewWindow(fmt.Sprintf("%s - %s", title, u.app.Metadata().Name)),
+	}
+	w.tree = newDocumentTree(func() *jsondocument.JSONDocument {
+		return w.doc
+	}, nil)
+	w.tree.OnSelected = func(uid widget.TreeNodeID) {
+		w.selectedUID = uid
+	}
+	w.tree.OnUnselected = func(uid widget.TreeNodeID) {
+		w.selectedUID = ""
+	}
+	save := ttwidget.NewButtonWithIcon("Save As...", theme.DocumentSaveIcon(), func() {
+		saveJSONFile(w.window, w.selection(), "")
+	})
+	save.SetToolTip("Save selected node or whole document to a file")
+	clipboard := ttwidget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), func() {
+		byt, err := jsondocument.MarshalIndent(w.selection(), "", "  ")
+		if err != nil {
+			showErrorDialog(w.window, "Failed to encode JSON", err)
+			return
+		}
+		u.app.Clipboard().SetContent(string(byt))
+	})
+	clipboard.SetToolTip("Copy selected node or whole document to the clipboard")
+	bottom := container.NewHBox(layout.NewSpacer(), clipboard, save)
+	c := container.NewBorder(w.header, bottom, nil, nil, w.tree)
+	w.window.SetContent(fynetooltip.AddWindowToolTipLayer(c, w.window.Canvas()))
+	w.window.Resize(fyne.NewSize(600, 700))
+	return w
+}
+
+// set replaces the shown value and header. The header can be nil.
+// completed is called after the document is shown in the tree, also when it failed, and can be nil.
+func (w *documentWindow) set(data any, header fyne.CanvasObject, completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	w.loadCount++
+	count := w.loadCount
+	w.data = data
+	w.tree.UnselectAll()
+	if header != nil {
+		w.header.Objects = []fyne.CanvasObject{header}
+	} else {
+		w.header.Objects = nil
+	}
+	w.header.Refresh()
+	go func() {
+		doc := jsondocument.New()
+		var tree any
+		switch data.(type) {
+		case map[string]any, []any:
+			tree = data
+		default:
+			tree = []any{data}
+		}
+		err := doc.LoadData(context.Background(), tree)
+		fyne.Do(func() {
+			defer completed()
+			if count != w.loadCount {
+				return // outdated
+			}
+			if err != nil {
+				showErrorDialog(w.window, "Failed to show document", err)
+				return
+			}
+			w.doc = doc
+			w.tree.Refresh()
+			w.tree.ScrollToTop()
+		})
+	}()
+}
+
+// selection returns the value of the selected node or the whole value when no node is selected.
+func (w *documentWindow) selection() any {
+	if w.selectedUID == "" {
+		return w.data
+	}
+	return w.doc.ExtractValue(w.selectedUID)
+}
+
+// saveJSONFile shows a file dialog and saves a value as indented JSON to the selected file.
+func saveJSONFile(w fyne.Window, data any, fileName string) {
+	byt, err := jsondocument.MarshalIndent(data, "", "  ")
+	if err != nil {
+		showErrorDialog(w, "Failed to encode JSON", err)
+		return
+	}
+	d := dialog.NewFileSave(func(f fyne.URIWriteCloser, err error) {
+		if err != nil {
+			showErrorDialog(w, "Failed to open save dialog", err)
+			return
+		}
+		if f == nil {
+			return
+		}
+		defer f.Close()
+		if _, err := f.Write(byt); err != nil {
+			showErrorDialog(w, "Failed to write file", err)
+			return
+		}
+	}, w)
+	kxdialog.AddDialogKeyHandler(d, w)
+	if fileName != "" {
+		d.SetFileName(fileName)
+	}
+	d.Show()
+}
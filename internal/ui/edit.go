@@ -0,0 +1,375 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// editTypes are the types which can be selected for a value.
+var editTypes = []jsondocument.JSONType{
+	jsondocument.String,
+	jsondocument.Number,
+	jsondocument.Boolean,
+	jsondocument.Null,
+	jsondocument.Object,
+	jsondocument.Array,
+}
+
+// editMenuItems returns the menu items for editing a node.
+func (u *UI) editMenuItems(uid widget.TreeNodeID) []*fyne.MenuItem {
+	doc := u.document
+	node := doc.Value(uid)
+	parent := doc.Value(doc.Parent(uid))
+	var items []*fyne.MenuItem
+	if node.Type != jsondocument.Object && node.Type != jsondocument.Array {
+		items = append(items, newMenuItemWithIcon("Edit value...", theme.DocumentCreateIcon(), func() {
+			u.showEditValueDialog(uid)
+		}))
+	}
+	if parent.Type == jsondocument.Object {
+		items = append(items, fyne.NewMenuItem("Rename key...", func() {
+			u.showRenameKeyDialog(uid)
+		}))
+	}
+	switch node.Type {
+	case jsondocument.Object:
+		items = append(items, newMenuItemWithIcon("Add member...", theme.ContentAddIcon(), func() {
+			u.showInsertDialog("Add member", true, func(key string, v any) (widget.TreeNodeID, error) {
+				return doc.InsertMember(uid, key, v)
+			})
+		}))
+	case jsondocument.Array:
+		items = append(items, newMenuItemWithIcon("Add element...", theme.ContentAddIcon(), func() {
+			u.showInsertDialog("Add element", false, func(_ string, v any) (widget.TreeNodeID, error) {
+				return doc.InsertElement(uid, len(doc.ChildUIDs(uid)), v)
+			})
+		}))
+	}
+	switch parent.Type {
+	case jsondocument.Object:
+		items = append(items, fyne.NewMenuItem("Add sibling member...", func() {
+			u.showInsertDialog("Add member", true, func(key string, v any) (widget.TreeNodeID, error) {
+				return doc.InsertMember(doc.Parent(uid), key, v)
+			})
+		}))
+	case jsondocument.Array:
+		for _, after := range []bool{false, true} {
+			label := "Insert element before..."
+			if after {
+				label = "Insert element after..."
+			}
+			items = append(items, fyne.NewMenuItem(label, func() {
+				u.showInsertDialog("Insert element", false, func(_ string, v any) (widget.TreeNodeID, error) {
+					parentUID := doc.Parent(uid)
+					index := slices.Index(doc.ChildUIDs(parentUID), uid)
+					if after {
+						index++
+					}
+					return doc.InsertElement(parentUID, index, v)
+				})
+			}))
+		}
+	}
+	items = append(items, newMenuItemWithIcon("Duplicate", theme.ContentCopyIcon(), func() {
+		u.jobs.stopAll()
+		newUID, err := doc.Duplicate(uid)
+		if err != nil {
+			u.showErrorDialog("Failed to duplicate", err)
+			return
+		}
+		u.branchEdited(newUID, doc.Parent(uid))
+	}))
+	items = append(items, newMenuItemWithIcon("Delete", theme.DeleteIcon(), func() {
+		u.jobs.stopAll()
+		parentUID := doc.Parent(uid)
+		if err := doc.Delete(uid); err != nil {
+			u.showErrorDialog("Failed to delete", err)
+			return
+		}
+		u.branchEdited("", parentUID)
+	}))
+	return items
+}
+
+// newMenuItemWithIcon returns a new menu item with an icon.
+func newMenuItemWithIcon(label string, icon fyne.Resource, action func()) *fyne.MenuItem {
+	it := fyne.NewMenuItem(label, action)
+	it.Icon = icon
+	return it
+}
+
+// showEditValueDialog shows a dialog for changing the value and type of a node.
+func (u *UI) showEditValueDialog(uid widget.TreeNodeID) {
+	node := u.document.Value(uid)
+	input := newValueInput(node.Type, nodeEditText(node))
+	d := dialog.NewForm("Edit value", "Save", "Cancel", input.formItems(), func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		v, err := input.value()
+		if err != nil {
+			u.showErrorDialog("Invalid value", err)
+			return
+		}
+		wasBranch := u.document.IsBranch(uid)
+		u.jobs.stopAll()
+		if err := u.document.SetValue(uid, v); err != nil {
+			u.showErrorDialog("Failed to change value", err)
+			return
+		}
+		switch {
+		case uid == "":
+			u.documentEdited(uid, true) // the root was replaced
+		case wasBranch || u.document.IsBranch(uid):
+			u.branchEdited(uid, uid)
+		default:
+			u.documentEdited(uid, false)
+		}
+	}, u.window)
+	kxdialog.AddDialogKeyHandler(d, u.window)
+	d.Resize(fyne.NewSize(500, 250))
+	d.Show()
+	u.window.Canvas().Focus(input.text)
+}
+
+// showRenameKeyDialog shows a dialog for changing the key of an object member.
+func (u *UI) showRenameKeyDialog(uid widget.TreeNodeID) {
+	entry := widget.NewEntry()
+	entry.SetText(u.document.Value(uid).Key)
+	items := []*widget.FormItem{{Text: "Key", Widget: entry}}
+	d := dialog.NewForm("Rename key", "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		u.jobs.stopAll()
+		if err := u.document.RenameKey(uid, entry.Text); err != nil {
+			u.showErrorDialog("Failed to rename key", err)
+			return
+		}
+		u.branchEdited(uid, u.document.Parent(uid))
+	}, u.window)
+	kxdialog.AddDialogKeyHandler(d, u.window)
+	d.Resize(fyne.NewSize(500, 200))
+	d.Show()
+	u.window.Canvas().Focus(entry)
+}
+
+// showInsertDialog shows a dialog for entering a new value, which is added by insert.
+// The dialog asks for a key when withKey is set.
+func (u *UI) showInsertDialog(title string, withKey bool, insert func(key string, v any) (widget.TreeNodeID, error)) {
+	input := newValueInput(jsondocument.String, "")
+	key := widget.NewEntry()
+	var items []*widget.FormItem
+	if withKey {
+		items = append(items, &widget.FormItem{Text: "Key", Widget: key})
+	}
+	items = append(items, input.formItems()...)
+	d := dialog.NewForm(title, "Add", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		v, err := input.value()
+		if err != nil {
+			u.showErrorDialog("Invalid value", err)
+			return
+		}
+		u.jobs.stopAll()
+		uid, err := insert(key.Text, v)
+		if err != nil {
+			u.showErrorDialog("Failed to add value", err)
+			return
+		}
+		u.branchEdited(uid, u.document.Parent(uid))
+	}, u.window)
+	kxdialog.AddDialogKeyHandler(d, u.window)
+	d.Resize(fyne.NewSize(500, 250))
+	d.Show()
+	if withKey {
+		u.window.Canvas().Focus(key)
+	} else {
+		u.window.Canvas().Focus(input.text)
+	}
+}
+
+// documentEdited updates the UI after the document was edited.
+// Changes to the structure of the document refresh the whole tree, otherwise only the node at uid is refreshed.
+// Use [UI.branchEdited] for structural changes within one branch.
+// The node at uid is selected afterwards. When uid is empty the current selection is kept if it still exists.
+func (u *UI) documentEdited(uid widget.TreeNodeID, structural bool) {
+	u.editApplied(structural)
+	if structural {
+		u.tree.Refresh()
+	} else {
+		u.tree.RefreshItem(uid)
+		u.results.list.Refresh()
+	}
+	u.selectEdited(uid)
+}
+
+// branchEdited updates the UI after the children of a branch were edited.
+// Only the branch and its children are refreshed in the tree.
+// The node at uid is selected afterwards. When uid is empty the current selection is kept if it still exists.
+func (u *UI) branchEdited(uid, branch widget.TreeNodeID) {
+	u.editApplied(true)
+	u.tree.refreshBranch(branch)
+	u.selectEdited(uid)
+}
+
+// editApplied updates the parts of the UI, which depend on the document as a whole.
+func (u *UI) editApplied(structural bool) {
+	u.updateTitle()
+	u.updateEditMenu()
+	u.statusBar.set(u.document.Size())
+	u.cancelSearchIndex()
+	if u.diff != nil {
+		u.setDiff(nil)
+	}
+	if structural {
+		u.results.clear()
+		u.searchBar.filter.SetOn(false)
+	}
+}
+
+// selectEdited selects the node at uid after an edit.
+// When uid is empty the current selection is kept if it still exists.
+func (u *UI) selectEdited(uid widget.TreeNodeID) {
+	selected := uid
+	if selected == "" {
+		selected = u.selection.selectedUID
+	}
+	if selected == "" || !u.document.Exists(selected) {
+		u.tree.UnselectAll()
+		u.selection.reset()
+		u.detail.reset()
+		return
+	}
+	u.tree.scrollTo(selected)
+	u.selectElement(selected)
+}
+
+// valueInput is a form input for entering a value of any JSON type.
+type valueInput struct {
+	text *widget.Entry
+	typ  *widget.Select
+}
+
+// newValueInput returns a new value input with a type and text.
+func newValueInput(typ jsondocument.JSONType, text string) *valueInput {
+	w := &valueInput{text: widget.NewEntry()}
+	options := make([]string, len(editTypes))
+	for i, t := range editTypes {
+		options[i] = t.String()
+	}
+	w.typ = widget.NewSelect(options, func(string) {
+		w.update()
+	})
+	w.text.SetText(text)
+	w.text.Validator = func(s string) error {
+		_, err := parseEditValue(w.typ.Selected, s)
+		return err
+	}
+	w.typ.SetSelected(typ.String())
+	return w
+}
+
+func (w *valueInput) formItems() []*widget.FormItem {
+	return []*widget.FormItem{
+		{Text: "Type", Widget: w.typ},
+		{Text: "Value", Widget: w.text, HintText: "Objects and arrays are entered as JSON"},
+	}
+}
+
+// update adjusts the value entry to the selected type.
+func (w *valueInput) update() {
+	switch w.typ.Selected {
+	case jsondocument.Null.String():
+		w.text.SetText("")
+		w.text.Disable()
+	case jsondocument.Boolean.String():
+		w.text.Enable()
+		if w.text.Text != "true" && w.text.Text != "false" {
+			w.text.SetText("true")
+		}
+	case jsondocument.Object.String():
+		w.text.Enable()
+		if !strings.HasPrefix(strings.TrimSpace(w.text.Text), "{") {
+			w.text.SetText("{}")
+		}
+	case jsondocument.Array.String():
+		w.text.Enable()
+		if !strings.HasPrefix(strings.TrimSpace(w.text.Text), "[") {
+			w.text.SetText("[]")
+		}
+	default:
+		w.text.Enable()
+	}
+	w.text.Validate()
+}
+
+// value returns the entered value.
+func (w *valueInput) value() (any, error) {
+	return parseEditValue(w.typ.Selected, w.text.Text)
+}
+
+// parseEditValue returns the value of a type from a text.
+func parseEditValue(typ string, text string) (any, error) {
+	switch typ {
+	case jsondocument.String.String():
+		return text, nil
+	case jsondocument.Number.String():
+		x, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("not a valid number: %s", text)
+		}
+		return x, nil
+	case jsondocument.Boolean.String():
+		switch strings.TrimSpace(text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, errors.New("must be true or false")
+	case jsondocument.Null.String():
+		return nil, nil
+	case jsondocument.Object.String():
+		var v map[string]any
+		if err := json.Unmarshal([]byte(text), &v); err != nil || v == nil {
+			return nil, errors.New("not a valid JSON object")
+		}
+		return v, nil
+	case jsondocument.Array.String():
+		var v []any
+		if err := json.Unmarshal([]byte(text), &v); err != nil || v == nil {
+			return nil, errors.New("not a valid JSON array")
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown type: %s", typ)
+}
+
+// nodeEditText returns the text for editing the value of a scalar node.
+func nodeEditText(node jsondocument.Node) string {
+	switch node.Type {
+	case jsondocument.String:
+		return node.Value.(string)
+	case jsondocument.Number:
+		return strconv.FormatFloat(node.Value.(float64), 'f', -1, 64)
+	case jsondocument.Boolean:
+		return fmt.Sprint(node.Value)
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"errors"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+)
+
+// showGoToPathDialog shows a dialog for jumping to a node by it's JSON pointer or dotted path.
+// Paths which do not match are reported while typing.
+func (u *UI) showGoToPathDialog() {
+	if u.document.Size() == 0 {
+		return
+	}
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("/spec/template/containers/0/image")
+	if u.selection.selectedUID != "" {
+		entry.SetText(u.document.Pointer(u.selection.selectedUID))
+	}
+	entry.Validator = func(s string) error {
+		if s == "" {
+			return errors.New("path is required")
+		}
+		_, err := u.document.ResolvePath(s)
+		return err
+	}
+	items := []*widget.FormItem{
+		{
+			Text: "Path", Widget: entry,
+			HintText: "JSON pointer or dotted path, e.g. spec.template.containers[0].image",
+		},
+	}
+	d := dialog.NewForm("Go to path", "Go", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		uid, err := u.document.ResolvePath(entry.Text)
+		if err != nil {
+			u.showErrorDialog("Path not found", err)
+			return
+		}
+		u.gotoNode(uid)
+	}, u.window)
+	kxdialog.AddDialogKeyHandler(d, u.window)
+	d.Resize(fyne.NewSize(500, 200))
+	d.Show()
+	u.window.Canvas().Focus(entry)
+}
+
+// gotoNode expands all branches along the path to a node and selects it.
+// The root node can not be selected and scrolls to the top instead.
+func (u *UI) gotoNode(uid string) {
+	if uid == "" {
+		u.tree.ScrollToTop()
+		return
+	}
+	u.tree.scrollTo(uid)
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+)
+
+// Maximum number of edits shown in the recent changes menu
+const recentChangesMax = 20
+
+// undo reverts the last edit of the current document.
+func (u *UI) undo() {
+	u.jobs.stopAll()
+	if _, err := u.document.Undo(); err != nil {
+		u.showErrorDialog("Failed to undo", err)
+		return
+	}
+	u.documentEdited("", true)
+}
+
+// redo applies the last undone edit of the current document again.
+func (u *UI) redo() {
+	u.jobs.stopAll()
+	if _, err := u.document.Redo(); err != nil {
+		u.showErrorDialog("Failed to redo", err)
+		return
+	}
+	u.documentEdited("", true)
+}
+
+// undoTo reverts the last n edits of the current document.
+func (u *UI) undoTo(n int) {
+	u.jobs.stopAll()
+	for range n {
+		if _, err := u.document.Undo(); err != nil {
+			u.showErrorDialog("Failed to undo", err)
+			break
+		}
+	}
+	u.documentEdited("", true)
+}
+
+// updateEditMenu updates the undo and redo menu items for the history of the current document.
+func (u *UI) updateEditMenu() {
+	doc := u.document
+	undo := doc.UndoHistory()
+	redo := doc.RedoHistory()
+	u.editUndo.Label = "Undo"
+	if len(undo) > 0 {
+		u.editUndo.Label = fmt.Sprintf("Undo %s", undo[0])
+	}
+	u.editUndo.Disabled = len(undo) == 0
+	u.editRedo.Label = "Redo"
+	if len(redo) > 0 {
+		u.editRedo.Label = fmt.Sprintf("Redo %s", redo[0])
+	}
+	u.editRedo.Disabled = len(redo) == 0
+	u.editRecent.Disabled = len(undo) == 0
+	var items []*fyne.MenuItem
+	for i, name := range undo[:min(len(undo), recentChangesMax)] {
+		items = append(items, fyne.NewMenuItem(name, func() {
+			u.undoTo(i + 1)
+		}))
+	}
+	u.editRecent.ChildMenu.Items = items
+	u.window.MainMenu().Refresh()
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"context"
+	"sync"
+)
+
+// backgroundJobs keeps track of goroutines which read the current document,
+// e.g. searches and queries.
+//
+// The document must not be changed while it is read.
+// Therefore all jobs must be stopped with stopAll before the document is edited.
+//
+// Jobs are started, finished and stopped on the UI goroutine.
+type backgroundJobs struct {
+	jobs map[*backgroundJob]struct{}
+	wg   sync.WaitGroup
+}
+
+// backgroundJob is a job started with backgroundJobs.
+type backgroundJob struct {
+	cancel  context.CancelFunc
+	done    func()
+	jobs    *backgroundJobs
+	onStop  func()
+	stopped bool
+}
+
+// start starts a new job and returns it with a context, which is canceled when the job is stopped.
+// The optional onStop is called on the UI goroutine when the job is stopped before it was finished.
+func (b *backgroundJobs) start(ctx context.Context, onStop func()) (context.Context, *backgroundJob) {
+	ctx, cancel := context.WithCancel(ctx)
+	b.wg.Add(1)
+	j := &backgroundJob{cancel: cancel, done: sync.OnceFunc(b.wg.Done), jobs: b, onStop: onStop}
+	if b.jobs == nil {
+		b.jobs = make(map[*backgroundJob]struct{})
+	}
+	b.jobs[j] = struct{}{}
+	return ctx, j
+}
+
+// stopAll cancels all unfinished jobs and waits until they no longer read the document.
+func (b *backgroundJobs) stopAll() {
+	jobs := b.jobs
+	b.jobs = nil
+	for j := range jobs {
+		j.stopped = true
+		j.cancel()
+	}
+	b.wg.Wait()
+	for j := range jobs {
+		if j.onStop != nil {
+			j.onStop()
+		}
+	}
+}
+
+// readDone reports that the job no longer reads the document.
+// It must be called on the job's goroutine and must not wait for the UI goroutine.
+func (j *backgroundJob) readDone() {
+	j.done()
+}
+
+// finish finishes the job on the UI goroutine and releases its context.
+// It reports whether the results of the job are still valid,
+// which is not the case when the job was stopped.
+func (j *backgroundJob) finish() bool {
+	j.cancel()
+	if j.stopped {
+		return false
+	}
+	delete(j.jobs.jobs, j)
+	return true
+}
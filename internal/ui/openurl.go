@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+
+	"github.com/ErikKalkoken/janice/internal/remote"
+)
+
+// showOpenURLDialog shows a dialog for opening a document from a HTTP(S) URL.
+// The dialog is pre-filled with the given request.
+func (u *UI) showOpenURLDialog(req remote.Request) {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://example.com/data.json")
+	urlEntry.SetText(req.URL)
+	urlEntry.Validator = func(s string) error {
+		if s == "" {
+			return errors.New("URL is required")
+		}
+		return remote.Request{URL: s}.Validate()
+	}
+	method := widget.NewSelect(remote.Methods(), nil)
+	if req.Method != "" {
+		method.SetSelected(req.Method)
+	} else {
+		method.SetSelectedIndex(0)
+	}
+	headerEntry := widget.NewMultiLineEntry()
+	headerEntry.SetPlaceHolder("Authorization: Bearer ...")
+	headerEntry.SetMinRowsVisible(3)
+	headerEntry.SetText(remote.FormatHeader(req.Header))
+	headerEntry.Validator = func(s string) error {
+		_, err := remote.ParseHeader(s)
+		return err
+	}
+	bodyEntry := widget.NewMultiLineEntry()
+	bodyEntry.SetMinRowsVisible(3)
+	bodyEntry.SetText(req.Body)
+	items := []*widget.FormItem{
+		{Text: "URL", Widget: urlEntry},
+		{Text: "Method", Widget: method},
+		{
+			Text: "Headers", Widget: headerEntry,
+			HintText: "One header per line, e.g. Authorization: Bearer ...",
+		},
+		{Text: "Body", Widget: bodyEntry, HintText: "Request body, e.g. for POST"},
+	}
+	d := dialog.NewForm("Open URL", "Open", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		header, err := remote.ParseHeader(headerEntry.Text)
+		if err != nil {
+			u.showErrorDialog("Invalid headers", err)
+			return
+		}
+		req := remote.Request{
+			Method: method.Selected,
+			URL:    strings.TrimSpace(urlEntry.Text),
+			Header: header,
+			Body:   bodyEntry.Text,
+		}
+		u.loadURL(req, nil)
+	}, u.window)
+	kxdialog.AddDialogKeyHandler(d, u.window)
+	d.Resize(fyne.NewSize(600, 400))
+	d.Show()
+	u.window.Canvas().Focus(urlEntry)
+}
+
+// isRemoteURI reports whether an URI points to a HTTP(S) resource.
+func isRemoteURI(uri fyne.URI) bool {
+	s := uri.Scheme()
+	return s == "http" || s == "https"
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+var patchKindLabels = map[jsondocument.PatchKind]string{
+	jsondocument.JSONPatch:  "JSON Patch (RFC 6902)",
+	jsondocument.MergePatch: "JSON Merge Patch (RFC 7386)",
+}
+
+// showApplyPatchDialog lets the user select a patch file
+// and shows the result of applying it to the current document.
+func (u *UI) showApplyPatchDialog() {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			u.showErrorDialog("Failed to read folder", err)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+		p, err := jsondocument.ReadPatch(reader)
+		if err != nil {
+			u.showErrorDialog(fmt.Sprintf("Failed to read patch: %s", err), err)
+			return
+		}
+		u.applyPatch(p, reader.URI().Name(), nil)
+	}, u.window)
+	kxdialog.AddDialogKeyHandler(d, u.window)
+	if u.app.Preferences().BoolWithFallback(settingExtensionFilter, settingExtensionDefault) {
+		d.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	}
+	d.Show()
+}
+
+// applyPatch applies a patch to the current document and shows the result in a new window.
+// The current document is not changed. When operations of the patch failed,
+// the errors are shown instead of a result.
+// completed is called after the result or the errors are shown and can be nil.
+func (u *UI) applyPatch(p *jsondocument.Patch, name string, completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	doc := u.document
+	// The patch is applied to a copy, so only the extraction needs to finish before the document can be edited
+	ctx, job := u.jobs.start(context.Background(), nil)
+	go func() {
+		v, err := doc.ExtractValueContext(ctx, "")
+		job.readDone()
+		var result any
+		var errs []jsondocument.PatchError
+		if err == nil {
+			result, errs = p.Apply(v)
+		}
+		fyne.Do(func() {
+			job.finish()
+			if err != nil {
+				completed() // the document was edited before it was extracted
+				return
+			}
+			if len(errs) > 0 {
+				defer completed()
+				d := dialog.NewCustom("Failed to apply patch", "Close", makePatchErrors(p, errs), u.window)
+				kxdialog.AddDialogKeyHandler(d, u.window)
+				d.Resize(fyne.NewSize(600, 300))
+				d.Show()
+				return
+			}
+			u.showDocumentWindow(fmt.Sprintf("Preview %s", name), result, makePatchSummary(p), completed)
+		})
+	}()
+}
+
+// makePatchSummary returns a summary of a successfully applied patch.
+func makePatchSummary(p *jsondocument.Patch) fyne.CanvasObject {
+	pr := message.NewPrinter(language.English)
+	var s string
+	if p.Kind == jsondocument.MergePatch {
+		s = pr.Sprintf("Applied %s", patchKindLabels[p.Kind])
+	} else {
+		s = pr.Sprintf("Applied %d operations of %s", len(p.Operations), patchKindLabels[p.Kind])
+	}
+	return container.NewVBox(widget.NewLabel(s), widget.NewSeparator())
+}
+
+// makePatchErrors returns a list of all failed operations of a patch, which was not applied.
+func makePatchErrors(p *jsondocument.Patch, errs []jsondocument.PatchError) fyne.CanvasObject {
+	pr := message.NewPrinter(language.English)
+	summary := widget.NewLabel(pr.Sprintf(
+		"%d of %d operations of %s failed. The patch was not applied.",
+		len(errs), len(p.Operations), patchKindLabels[p.Kind],
+	))
+	summary.Importance = widget.DangerImportance
+	failed := container.NewVBox()
+	for _, err := range errs {
+		l := widget.NewLabel(err.Error())
+		l.Wrapping = fyne.TextWrapWord
+		failed.Add(l)
+	}
+	scroll := container.NewVScroll(failed)
+	scroll.SetMinSize(fyne.NewSize(0, 120))
+	return container.NewBorder(summary, nil, nil, nil, scroll)
+}
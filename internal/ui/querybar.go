@@ -0,0 +1,291 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/ErikKalkoken/janice/internal/jq"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// query languages
+const (
+	queryLanguageJSONPath = "JSONPath"
+	queryLanguageJQ       = "jq"
+)
+
+// Delay after the last change of a jq expression before its output is updated
+const jqPreviewDelay = 400 * time.Millisecond
+
+// queryBar allows running a query on the JSON document.
+// All nodes matching a JSONPath query are shown in the results panel.
+// The output of a jq expression is shown as new document in it's own window,
+// which is updated with the output of each run and while the expression is changed.
+type queryBar struct {
+	widget.BaseWidget
+
+	activity     *widget.Activity
+	cancel       context.CancelFunc
+	language     *widget.Select
+	message      *widget.Label
+	output       *documentWindow // window with the output of the last jq expression or nil
+	previewCount int             // incremented for each change, so that outdated previews are ignored
+	queryEntry   *widget.Entry
+	queryButton  *ttwidget.Button
+	selected     *widget.Check
+	u            *UI
+}
+
+func newQueryBar(u *UI) *queryBar {
+	w := &queryBar{
+		activity:   widget.NewActivity(),
+		message:    widget.NewLabel(""),
+		queryEntry: widget.NewEntry(),
+		u:          u,
+	}
+	w.ExtendBaseWidget(w)
+	w.activity.Hide()
+	w.message.Importance = widget.DangerImportance
+	w.message.Wrapping = fyne.TextWrapWord
+	w.message.Hide()
+	w.queryEntry.OnSubmitted = func(string) {
+		w.runQuery(nil)
+	}
+	w.queryEntry.OnChanged = func(string) {
+		w.message.Hide()
+		w.schedulePreview()
+	}
+	w.queryButton = ttwidget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() {
+		w.runQuery(nil)
+	})
+	w.queryButton.SetToolTip("Run query")
+	w.selected = widget.NewCheck("Selected node only", nil)
+	w.language = widget.NewSelect([]string{queryLanguageJSONPath, queryLanguageJQ}, func(s string) {
+		w.message.Hide()
+		if s == queryLanguageJQ {
+			w.queryEntry.SetPlaceHolder("Enter jq expression, e.g. .items | map(select(.price > 10)) | group_by(.category)")
+			w.selected.Show()
+		} else {
+			w.queryEntry.SetPlaceHolder("Enter JSONPath query, e.g. $.items[?@.price > 10].name")
+			w.selected.Hide()
+		}
+		u.app.Preferences().SetString(preferenceLastQueryLanguage, s)
+	})
+	w.language.SetSelected(u.app.Preferences().StringWithFallback(preferenceLastQueryLanguage, queryLanguageJSONPath))
+	return w
+}
+
+func (w *queryBar) enable() {
+	w.queryEntry.Enable()
+	w.queryButton.Enable()
+	w.language.Enable()
+	w.selected.Enable()
+}
+
+func (w *queryBar) disable() {
+	w.queryEntry.Disable()
+	w.queryButton.Disable()
+	w.language.Disable()
+	w.selected.Disable()
+}
+
+// runQuery runs the query and shows the results.
+// A query which is still running is canceled.
+// completed is called after the query has finished, also when it failed, and can be nil.
+func (w *queryBar) runQuery(completed func()) {
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	if completed == nil {
+		completed = func() {}
+	}
+	if w.language.Selected == queryLanguageJQ {
+		w.runJQ(completed)
+	} else {
+		w.runJSONPath(completed)
+	}
+}
+
+func (w *queryBar) runJSONPath(completed func()) {
+	path, err := jsondocument.CompileJSONPath(w.queryEntry.Text)
+	if err != nil {
+		w.showError(err)
+		completed()
+		return
+	}
+	w.message.Hide()
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	doc := w.u.document
+	w.u.results.set("Running query...", nil)
+	ctx, job := w.u.jobs.start(ctx, func() {
+		w.u.results.clear()
+		completed()
+	})
+	go func() {
+		uids, err := doc.QueryJSONPath(ctx, path)
+		job.readDone()
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			if errors.Is(err, jsondocument.ErrCallerCanceled) || w.u.document != doc {
+				return
+			}
+			cancel()
+			w.cancel = nil
+			if err != nil {
+				w.u.results.clear()
+				w.u.showErrorDialog("Query failed", err)
+				return
+			}
+			p := message.NewPrinter(language.English)
+			w.u.results.set(p.Sprintf("%d results for %s", len(uids), path), uids)
+		})
+	}()
+}
+
+// runJQ runs a jq expression on the document or the selected node
+// and shows the output as derived document in the output window, which is opened when needed.
+// Multiple outputs are shown as array.
+func (w *queryBar) runJQ(completed func()) {
+	q, err := jq.Compile(w.queryEntry.Text)
+	if err != nil {
+		w.showError(err)
+		completed()
+		return
+	}
+	var uid widget.TreeNodeID
+	if w.selected.Checked {
+		uid = w.u.selection.selectedUID
+	}
+	w.message.Hide()
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	source := "document"
+	if uid != "" {
+		source = keyPathString(w.u.document.KeyPath(uid))
+	}
+	doc := w.u.document
+	w.activity.Start()
+	w.activity.Show()
+	// The query runs on a copy, so only the extraction needs to finish before the document can be edited
+	jobCtx, job := w.u.jobs.start(ctx, func() {
+		w.activity.Stop()
+		w.activity.Hide()
+		completed()
+	})
+	go func() {
+		v, err := doc.ExtractValueContext(jobCtx, uid)
+		job.readDone()
+		var outputs []any
+		if err == nil {
+			outputs, err = q.Run(ctx, v)
+		}
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			if errors.Is(err, context.Canceled) || errors.Is(err, jsondocument.ErrCallerCanceled) {
+				completed()
+				return
+			}
+			w.activity.Stop()
+			w.activity.Hide()
+			cancel()
+			w.cancel = nil
+			if err != nil {
+				w.showError(err)
+				completed()
+				return
+			}
+			var data any
+			var info string
+			p := message.NewPrinter(language.English)
+			if len(outputs) == 1 {
+				data = outputs[0]
+				info = fmt.Sprintf("Output of %s on %s", q, source)
+			} else {
+				data = outputs
+				info = p.Sprintf("%d outputs of %s on %s", len(outputs), q, source)
+			}
+			header := widget.NewLabel(info)
+			header.Wrapping = fyne.TextWrapWord
+			if w.output == nil {
+				o := w.u.newDocumentWindow("jq")
+				o.window.SetOnClosed(func() {
+					if w.output == o {
+						w.output = nil
+					}
+				})
+				o.window.Show()
+				w.output = o
+			}
+			w.output.set(data, header, completed)
+		})
+	}()
+}
+
+// schedulePreview runs a changed jq expression again after a short delay,
+// so that an open output window shows a live preview of the expression.
+// Expressions which are not valid, e.g. while they are typed, are not run.
+func (w *queryBar) schedulePreview() {
+	w.previewCount++
+	if w.output == nil || w.language.Selected != queryLanguageJQ {
+		return
+	}
+	count := w.previewCount
+	time.AfterFunc(jqPreviewDelay, func() {
+		fyne.Do(func() {
+			if count != w.previewCount || w.output == nil || w.language.Selected != queryLanguageJQ {
+				return
+			}
+			if _, err := jq.Compile(w.queryEntry.Text); err != nil {
+				return
+			}
+			w.runQuery(nil)
+		})
+	})
+}
+
+func (w *queryBar) showError(err error) {
+	w.message.SetText(err.Error())
+	w.message.Show()
+}
+
+// reset cancels a running query.
+func (w *queryBar) reset() {
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.activity.Stop()
+	w.activity.Hide()
+	w.message.Hide()
+}
+
+func (w *queryBar) CreateRenderer() fyne.WidgetRenderer {
+	c := container.NewVBox(
+		container.NewBorder(
+			nil,
+			nil,
+			w.language,
+			container.NewHBox(w.selected, w.activity, w.queryButton),
+			w.queryEntry,
+		),
+		w.message,
+	)
+	return widget.NewSimpleRenderer(c)
+}
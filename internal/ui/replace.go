@@ -0,0 +1,296 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// Scopes for find and replace
+const (
+	replaceScopeDocument  = "Whole document"
+	replaceScopeSelection = "Selected subtree"
+)
+
+// replaceDialog is a dialog for finding and replacing text in keys and values of the current document.
+// Matches are shown in a preview list, before they are replaced.
+type replaceDialog struct {
+	cancel       context.CancelFunc
+	dialog       *dialog.CustomDialog
+	find         *widget.Entry
+	ignoreCase   *widget.Check
+	keys         *widget.Check
+	list         *widget.List
+	numbers      *widget.Check
+	previewCount int // incremented for each preview, so that outdated previews are ignored
+	previewDoc   *jsondocument.JSONDocument
+	previewEdits int // number of edits of the previewed document when the preview was started
+	previewed    []jsondocument.Replacement
+	regex        *widget.Check
+	replace      *widget.Entry
+	replaceAll   *widget.Button
+	scope        *widget.RadioGroup
+	scopeUID     widget.TreeNodeID
+	strings      *widget.Check
+	summary      *widget.Label
+	u            *UI
+}
+
+// showReplaceDialog shows the dialog for finding and replacing text.
+func (u *UI) showReplaceDialog() {
+	if u.document.Size() == 0 {
+		return
+	}
+	d := newReplaceDialog(u)
+	d.dialog.Show()
+	u.window.Canvas().Focus(d.find)
+}
+
+func newReplaceDialog(u *UI) *replaceDialog {
+	d := &replaceDialog{
+		find:       widget.NewEntry(),
+		ignoreCase: widget.NewCheck("Ignore case", nil),
+		keys:       widget.NewCheck("Keys", nil),
+		numbers:    widget.NewCheck("Numbers", nil),
+		regex:      widget.NewCheck("Regular expression", nil),
+		replace:    widget.NewEntry(),
+		scope:      widget.NewRadioGroup([]string{replaceScopeDocument, replaceScopeSelection}, nil),
+		strings:    widget.NewCheck("Strings", nil),
+		summary:    widget.NewLabel(""),
+		u:          u,
+	}
+	d.find.SetPlaceHolder("Text to find")
+	d.replace.SetPlaceHolder("Replacement, can refer to groups of a regular expression with $1")
+	d.strings.SetChecked(true)
+	d.scope.Horizontal = true
+	d.scope.Required = true
+	d.scope.SetSelected(replaceScopeDocument)
+	d.scopeUID = u.selection.selectedUID
+	if d.scopeUID == "" {
+		d.scope.Disable()
+	}
+	d.list = widget.NewList(
+		func() int {
+			return len(d.previewed)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("Template")
+		},
+		func(id widget.ListItemID, co fyne.CanvasObject) {
+			if id >= len(d.previewed) {
+				return
+			}
+			r := d.previewed[id]
+			l := co.(*widget.Label)
+			if r.Err != nil {
+				l.Importance = widget.DangerImportance
+			} else {
+				l.Importance = widget.MediumImportance
+			}
+			l.SetText(d.label(r))
+		},
+	)
+	d.list.OnSelected = func(id widget.ListItemID) {
+		d.list.UnselectAll()
+		if id >= len(d.previewed) {
+			return
+		}
+		uid := d.previewed[id].UID
+		d.u.tree.scrollTo(uid)
+		d.u.selectElement(uid)
+	}
+	// Changing the search invalidates the preview
+	d.find.OnChanged = func(string) { d.reset() }
+	d.replace.OnChanged = func(string) { d.reset() }
+	for _, c := range []*widget.Check{d.ignoreCase, d.keys, d.numbers, d.regex, d.strings} {
+		c.OnChanged = func(bool) { d.reset() }
+	}
+	d.scope.OnChanged = func(string) { d.reset() }
+	d.find.OnSubmitted = func(string) { d.preview(nil) }
+	d.replace.OnSubmitted = func(string) { d.preview(nil) }
+
+	preview := widget.NewButtonWithIcon("Preview", theme.SearchIcon(), func() {
+		d.preview(nil)
+	})
+	d.replaceAll = widget.NewButtonWithIcon("Replace All", theme.SearchReplaceIcon(), d.apply)
+	d.replaceAll.Importance = widget.HighImportance
+	d.replaceAll.Disable()
+	closeButton := widget.NewButton("Close", func() {
+		d.dialog.Hide()
+	})
+	form := widget.NewForm(
+		widget.NewFormItem("Find", d.find),
+		widget.NewFormItem("Replace with", d.replace),
+		widget.NewFormItem("Options", container.NewHBox(d.regex, d.ignoreCase)),
+		widget.NewFormItem("Replace in", container.NewHBox(d.keys, d.strings, d.numbers)),
+		widget.NewFormItem("Scope", d.scope),
+	)
+	c := container.NewBorder(
+		container.NewVBox(form, container.NewHBox(preview, d.summary)),
+		nil,
+		nil,
+		nil,
+		d.list,
+	)
+	d.dialog = dialog.NewCustomWithoutButtons("Find and Replace", c, u.window)
+	d.dialog.SetButtons([]fyne.CanvasObject{closeButton, d.replaceAll})
+	d.dialog.SetOnClosed(func() {
+		if d.cancel != nil {
+			d.cancel()
+		}
+	})
+	kxdialog.AddDialogKeyHandler(d.dialog, u.window)
+	d.dialog.Resize(fyne.NewSize(700, 600))
+	return d
+}
+
+// options returns the entered options.
+func (d *replaceDialog) options() jsondocument.ReplaceOptions {
+	opts := jsondocument.ReplaceOptions{
+		Regex:      d.regex.Checked,
+		IgnoreCase: d.ignoreCase.Checked,
+	}
+	if d.keys.Checked {
+		opts.Targets |= jsondocument.ReplaceKeys
+	}
+	if d.strings.Checked {
+		opts.Targets |= jsondocument.ReplaceStrings
+	}
+	if d.numbers.Checked {
+		opts.Targets |= jsondocument.ReplaceNumbers
+	}
+	return opts
+}
+
+// label returns the text of a replacement shown in the preview list.
+func (d *replaceDialog) label(r jsondocument.Replacement) string {
+	p := d.u.document.Pointer(r.UID)
+	if r.Err != nil {
+		return fmt.Sprintf("%s: %q → %q skipped: %s", p, r.Old, r.New, r.Err)
+	}
+	if r.Key {
+		return fmt.Sprintf("%s: key %q → %q", p, r.Old, r.New)
+	}
+	return fmt.Sprintf("%s: %q → %q", p, r.Old, r.New)
+}
+
+// reset clears the preview.
+func (d *replaceDialog) reset() {
+	if d.cancel != nil {
+		d.cancel()
+		d.cancel = nil
+	}
+	d.previewCount++
+	d.previewDoc = nil
+	d.previewed = nil
+	d.list.Refresh()
+	d.summary.SetText("")
+	d.replaceAll.Disable()
+}
+
+// preview finds the replacements in the background and shows them in the preview list.
+// completed is called after the preview was shown, also when it failed or is outdated, and can be nil.
+func (d *replaceDialog) preview(completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	d.reset()
+	opts := d.options()
+	if opts.Targets == 0 {
+		d.summary.SetText("Select what to replace")
+		completed()
+		return
+	}
+	var uid widget.TreeNodeID
+	if d.scope.Selected == replaceScopeSelection {
+		uid = d.scopeUID
+	}
+	doc := d.u.document
+	edits := doc.Edits()
+	pattern, replacement := d.find.Text, d.replace.Text
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	count := d.previewCount
+	d.summary.SetText("Searching...")
+	ctx, job := d.u.jobs.start(ctx, func() {
+		d.reset()
+		completed()
+	})
+	go func() {
+		rs, err := doc.FindReplacements(ctx, uid, pattern, replacement, opts)
+		job.readDone()
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			if count != d.previewCount || doc != d.u.document {
+				return // outdated
+			}
+			d.cancel = nil
+			cancel()
+			if err != nil {
+				d.summary.SetText(fmt.Sprintf("Error: %s", err))
+				return
+			}
+			d.previewDoc, d.previewEdits = doc, edits
+			d.show(rs)
+		})
+	}()
+}
+
+// show shows replacements in the preview list.
+func (d *replaceDialog) show(rs []jsondocument.Replacement) {
+	d.previewed = rs
+	d.list.Refresh()
+	if len(rs) == 0 {
+		d.summary.SetText("No matches")
+		return
+	}
+	var invalid int
+	for _, r := range rs {
+		if r.Err != nil {
+			invalid++
+		}
+	}
+	p := message.NewPrinter(language.English)
+	s := p.Sprintf("%d replacements", len(rs)-invalid)
+	if invalid > 0 {
+		s += p.Sprintf(", %d invalid will be skipped", invalid)
+	}
+	d.summary.SetText(s)
+	if invalid < len(rs) {
+		d.replaceAll.Enable()
+	}
+}
+
+// apply applies the previewed replacements as one undoable edit.
+// The preview is updated instead, when the document was reloaded or edited after it was previewed.
+func (d *replaceDialog) apply() {
+	rs := d.previewed
+	if len(rs) == 0 {
+		return
+	}
+	if doc := d.u.document; doc != d.previewDoc || doc.Edits() != d.previewEdits {
+		d.preview(nil)
+		return
+	}
+	name := fmt.Sprintf("Replace %q with %q", d.find.Text, d.replace.Text)
+	d.u.jobs.stopAll() // can reset the dialog, when an outdated preview is still running
+	if err := d.u.document.ApplyReplacements(name, rs); err != nil {
+		d.u.showErrorDialog("Failed to replace", err)
+		return
+	}
+	d.dialog.Hide()
+	d.u.documentEdited("", true)
+}
@@ -0,0 +1,288 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// Minimum width of the results panel
+const resultsPanelWidth = 300
+
+// resultHighlight defines the characters of a result to highlight, e.g. for a fuzzy search.
+type resultHighlight struct {
+	part      jsondocument.MatchPart // whether the positions refer to the key or the value
+	positions []int                  // indexes of the runes in the key or value
+}
+
+var importance2colorName = map[widget.Importance]fyne.ThemeColorName{
+	widget.HighImportance:    theme.ColorNamePrimary,
+	widget.WarningImportance: theme.ColorNameWarning,
+	widget.SuccessImportance: theme.ColorNameSuccess,
+	widget.DangerImportance:  theme.ColorNameError,
+}
+
+// resultsPanel shows a list of nodes in the JSON document, e.g. the results of a query.
+type resultsPanel struct {
+	widget.BaseWidget
+
+	activity    *widget.Activity
+	closeButton *ttwidget.Button
+	export      *ttwidget.Button
+	highlights  map[widget.TreeNodeID]resultHighlight
+	list        *widget.List
+	onClear     func()
+	title       *widget.Label
+	uids        []widget.TreeNodeID
+	u           *UI
+}
+
+func newResultsPanel(u *UI) *resultsPanel {
+	w := &resultsPanel{
+		activity: widget.NewActivity(),
+		title:    widget.NewLabel(""),
+		u:        u,
+	}
+	w.ExtendBaseWidget(w)
+	w.activity.Hide()
+	w.title.TextStyle.Bold = true
+	w.title.Truncation = fyne.TextTruncateEllipsis
+	w.list = widget.NewList(
+		func() int {
+			return len(w.uids)
+		},
+		func() fyne.CanvasObject {
+			path := widget.NewLabel("")
+			path.Truncation = fyne.TextTruncateEllipsis
+			pathHighlighted := widget.NewRichText()
+			pathHighlighted.Truncation = fyne.TextTruncateEllipsis
+			pathHighlighted.Hide()
+			value := widget.NewLabel("")
+			value.Truncation = fyne.TextTruncateEllipsis
+			valueHighlighted := widget.NewRichText()
+			valueHighlighted.Truncation = fyne.TextTruncateEllipsis
+			valueHighlighted.Hide()
+			return container.New(
+				layout.NewCustomPaddedVBoxLayout(-theme.Padding()*2),
+				container.NewStack(path, pathHighlighted),
+				container.NewStack(value, valueHighlighted),
+			)
+		},
+		func(id widget.ListItemID, co fyne.CanvasObject) {
+			if id >= len(w.uids) {
+				return
+			}
+			uid := w.uids[id]
+			doc := w.u.document
+			node := doc.Value(uid)
+			c := co.(*fyne.Container)
+			pathStack := c.Objects[0].(*fyne.Container)
+			path := pathStack.Objects[0].(*widget.Label)
+			pathHighlighted := pathStack.Objects[1].(*widget.RichText)
+			valueStack := c.Objects[1].(*fyne.Container)
+			value := valueStack.Objects[0].(*widget.Label)
+			valueHighlighted := valueStack.Objects[1].(*widget.RichText)
+			pathText := keyPathString(doc.KeyPath(uid))
+			path.SetText(pathText)
+			value.Importance = type2importance[node.Type]
+			valueText := nodeText(node, doc.IsBranch(uid), false)
+			value.SetText(valueText)
+			h, ok := w.highlights[uid]
+			if ok && h.part == jsondocument.MatchKey {
+				offset := utf8.RuneCountInString(pathText) - utf8.RuneCountInString(node.Key)
+				pathHighlighted.Segments = highlightedText(pathText, h.positions, offset, theme.ColorNameForeground)
+				pathHighlighted.Refresh()
+				pathHighlighted.Show()
+				path.Hide()
+			} else {
+				pathHighlighted.Hide()
+				path.Show()
+			}
+			if ok && h.part == jsondocument.MatchValue {
+				var offset int
+				if node.Type == jsondocument.String {
+					offset = 1 // opening quote
+				}
+				valueHighlighted.Segments = highlightedText(valueText, h.positions, offset, importance2colorName[value.Importance])
+				valueHighlighted.Refresh()
+				valueHighlighted.Show()
+				value.Hide()
+			} else {
+				valueHighlighted.Hide()
+				value.Show()
+			}
+		},
+	)
+	w.list.OnSelected = func(id widget.ListItemID) {
+		if id < len(w.uids) {
+			w.u.tree.scrollTo(w.uids[id])
+		}
+	}
+	w.export = ttwidget.NewButtonWithIcon("", theme.DocumentSaveIcon(), func() {
+		values := make([]any, len(w.uids))
+		for i, uid := range w.uids {
+			values[i] = w.u.document.ExtractValue(uid)
+		}
+		saveJSONFile(w.u.window, values, "results.json")
+	})
+	w.export.SetToolTip("Export results as JSON array")
+	w.closeButton = ttwidget.NewButtonWithIcon("", theme.WindowCloseIcon(), func() {
+		w.clear()
+	})
+	w.closeButton.SetToolTip("Close results")
+	w.Hide()
+	return w
+}
+
+// set shows the panel with a list of nodes.
+func (w *resultsPanel) set(title string, uids []widget.TreeNodeID) {
+	w.release()
+	w.stopActivity()
+	w.uids = uids
+	w.highlights = nil
+	w.title.SetText(title)
+	if len(uids) > 0 {
+		w.export.Enable()
+	} else {
+		w.export.Disable()
+	}
+	w.list.UnselectAll()
+	w.list.Refresh()
+	w.list.ScrollToTop()
+	w.Show()
+}
+
+// start shows the empty panel for results which are added while they are found.
+// onClear is called when the results are cleared or replaced, e.g. to cancel a running search.
+func (w *resultsPanel) start(title string, onClear func()) {
+	w.set(title, nil)
+	w.onClear = onClear
+	w.activity.Show()
+	w.activity.Start()
+}
+
+// add adds nodes to the list of results.
+func (w *resultsPanel) add(uids []widget.TreeNodeID) {
+	if len(uids) == 0 {
+		return
+	}
+	w.uids = append(w.uids, uids...)
+	w.export.Enable()
+	w.list.Refresh()
+}
+
+// setHighlights sets which characters of the results to highlight.
+func (w *resultsPanel) setHighlights(highlights map[widget.TreeNodeID]resultHighlight) {
+	w.highlights = highlights
+	w.list.Refresh()
+}
+
+// finish ends adding results and updates the title.
+func (w *resultsPanel) finish(title string) {
+	w.stopActivity()
+	w.title.SetText(title)
+}
+
+func (w *resultsPanel) stopActivity() {
+	w.activity.Stop()
+	w.activity.Hide()
+}
+
+// release calls and removes the current clear handler.
+func (w *resultsPanel) release() {
+	if w.onClear == nil {
+		return
+	}
+	f := w.onClear
+	w.onClear = nil
+	f()
+}
+
+// clear removes all nodes and hides the panel.
+func (w *resultsPanel) clear() {
+	w.release()
+	w.stopActivity()
+	w.uids = nil
+	w.highlights = nil
+	w.list.UnselectAll()
+	w.list.Refresh()
+	w.Hide()
+}
+
+func (w *resultsPanel) CreateRenderer() fyne.WidgetRenderer {
+	spacer := canvas.NewRectangle(nil)
+	spacer.SetMinSize(fyne.NewSize(resultsPanelWidth, 0))
+	c := container.NewBorder(
+		nil,
+		nil,
+		widget.NewSeparator(),
+		nil,
+		container.NewStack(
+			spacer,
+			container.NewBorder(
+				container.NewBorder(nil, nil, nil, container.NewHBox(w.activity, w.export, w.closeButton), w.title),
+				nil,
+				nil,
+				nil,
+				w.list,
+			),
+		),
+	)
+	return widget.NewSimpleRenderer(c)
+}
+
+// keyPathString returns a readable path from keys, e.g. "store.book[0].title".
+func keyPathString(keys []string) string {
+	if len(keys) == 0 {
+		return "(root)"
+	}
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 && !strings.HasPrefix(k, "[") {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(k)
+	}
+	return sb.String()
+}
+
+// highlightedText returns rich text segments for a text with the runes at positions highlighted.
+// The positions are shifted by offset.
+func highlightedText(text string, positions []int, offset int, colorName fyne.ThemeColorName) []widget.RichTextSegment {
+	isHighlighted := make(map[int]bool)
+	for _, p := range positions {
+		isHighlighted[p+offset] = true
+	}
+	var segments []widget.RichTextSegment
+	var sb strings.Builder
+	var current bool
+	flush := func() {
+		if sb.Len() == 0 {
+			return
+		}
+		style := widget.RichTextStyle{Inline: true, ColorName: colorName}
+		if current {
+			style.TextStyle = fyne.TextStyle{Bold: true, Underline: true}
+		}
+		segments = append(segments, &widget.TextSegment{Text: sb.String(), Style: style})
+		sb.Reset()
+	}
+	for i, r := range []rune(text) {
+		if isHighlighted[i] != current {
+			flush()
+			current = isHighlighted[i]
+		}
+		sb.WriteRune(r)
+	}
+	flush()
+	return segments
+}
@@ -0,0 +1,304 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// Formats for saving documents
+const (
+	saveIndentTwoSpaces  = "2 spaces"
+	saveIndentFourSpaces = "4 spaces"
+	saveIndentTab        = "Tab"
+	saveIndentMinified   = "Minified"
+)
+
+var saveIndents = map[string]string{
+	saveIndentTwoSpaces:  "  ",
+	saveIndentFourSpaces: "    ",
+	saveIndentTab:        "\t",
+	saveIndentMinified:   "",
+}
+
+// Key orders for saving documents
+const (
+	saveKeyOrderDocument   = "As shown"
+	saveKeyOrderIgnoreCase = "Alphabetical, ignore case"
+	saveKeyOrderNatural    = "Natural (item2 before item10)"
+)
+
+var saveKeyOrders = map[string]jsondocument.KeyOrder{
+	saveKeyOrderDocument:   jsondocument.KeyOrderDocument,
+	saveKeyOrderIgnoreCase: jsondocument.KeyOrderIgnoreCase,
+	saveKeyOrderNatural:    jsondocument.KeyOrderNatural,
+}
+
+// saveOptions returns the options for saving documents from the settings.
+func (u *UI) saveOptions() jsondocument.EncodeOptions {
+	p := u.app.Preferences()
+	indent, ok := saveIndents[p.StringWithFallback(settingSaveIndent, settingSaveIndentDefault)]
+	if !ok {
+		indent = saveIndents[settingSaveIndentDefault]
+	}
+	return jsondocument.EncodeOptions{
+		Indent:   indent,
+		KeyOrder: saveKeyOrders[p.StringWithFallback(settingSaveKeyOrder, settingSaveKeyOrderDefault)],
+	}
+}
+
+// saveFile saves the current document to its file.
+// Documents which were not loaded from a file are saved to a new file instead.
+// completed is called after the document was saved or saving was canceled and can be nil.
+func (u *UI) saveFile(completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	if u.document.Size() == 0 {
+		completed()
+		return
+	}
+	if u.currentFile == nil || u.currentFile.Scheme() != "file" {
+		u.showSaveAsDialog(completed)
+		return
+	}
+	u.save(u.currentFile, u.saveOptions(), completed)
+}
+
+// showSaveAsDialog shows a dialog for choosing the format, the folder and the name of the file
+// for saving the current document.
+// completed is called after the document was saved or saving was canceled and can be nil.
+func (u *UI) showSaveAsDialog(completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	if u.document.Size() == 0 {
+		completed()
+		return
+	}
+	p := u.app.Preferences()
+	indent := widget.NewSelect([]string{saveIndentTwoSpaces, saveIndentFourSpaces, saveIndentTab, saveIndentMinified}, nil)
+	indent.SetSelected(p.StringWithFallback(settingSaveIndent, settingSaveIndentDefault))
+	keyOrder := widget.NewSelect([]string{saveKeyOrderDocument, saveKeyOrderIgnoreCase, saveKeyOrderNatural}, nil)
+	keyOrder.SetSelected(p.StringWithFallback(settingSaveKeyOrder, settingSaveKeyOrderDefault))
+	folder := widget.NewEntry()
+	folder.SetText(u.saveAsFolder())
+	folder.Validator = func(s string) error {
+		info, err := os.Stat(s)
+		if err != nil || !info.IsDir() {
+			return errors.New("folder does not exist")
+		}
+		return nil
+	}
+	browse := ttwidget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		d := dialog.NewFolderOpen(func(l fyne.ListableURI, err error) {
+			if err != nil {
+				u.showErrorDialog("Failed to open folder", err)
+				return
+			}
+			if l == nil {
+				return
+			}
+			folder.SetText(l.Path())
+		}, u.window)
+		if l, err := storage.ListerForURI(storage.NewFileURI(folder.Text)); err == nil {
+			d.SetLocation(l)
+		}
+		kxdialog.AddDialogKeyHandler(d, u.window)
+		d.Show()
+	})
+	browse.SetToolTip("Choose folder")
+	name := widget.NewEntry()
+	name.SetText("untitled.json")
+	if u.currentFile != nil {
+		name.SetText(u.currentFile.Name())
+	}
+	name.Validator = func(s string) error {
+		if s == "" {
+			return errors.New("file name is required")
+		}
+		if s == "." || s == ".." || strings.ContainsAny(s, `/\`) {
+			return errors.New("invalid file name")
+		}
+		return nil
+	}
+	items := []*widget.FormItem{
+		{Text: "Indentation", Widget: indent, HintText: "Streams are always saved with one value per line"},
+		{Text: "Key order", Widget: keyOrder, HintText: "Order of the members of objects"},
+		{Text: "Folder", Widget: container.NewBorder(nil, nil, nil, browse, folder)},
+		{Text: "File name", Widget: name},
+	}
+	d := dialog.NewForm("Save As", "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			completed()
+			return
+		}
+		p.SetString(settingSaveIndent, indent.Selected)
+		p.SetString(settingSaveKeyOrder, keyOrder.Selected)
+		u.saveAs(filepath.Join(folder.Text, name.Text), u.saveOptions(), completed)
+	}, u.window)
+	kxdialog.AddDialogKeyHandler(d, u.window)
+	d.Resize(fyne.NewSize(550, 300))
+	d.Show()
+	u.window.Canvas().Focus(name)
+}
+
+// saveAsFolder returns the folder proposed for saving the current document.
+// This is the folder of the current file if there is one and the home folder otherwise.
+func (u *UI) saveAsFolder() string {
+	if u.currentFile != nil && u.currentFile.Scheme() == "file" {
+		return filepath.Dir(u.currentFile.Path())
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home
+}
+
+// saveAs saves the current document to a file at path.
+// The user is asked to confirm before an existing file is replaced.
+// completed is called after the document was saved or saving was canceled and can be nil.
+func (u *UI) saveAs(path string, opts jsondocument.EncodeOptions, completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	uri := storage.NewFileURI(path)
+	if _, err := os.Stat(path); err != nil {
+		u.save(uri, opts, completed)
+		return
+	}
+	d := dialog.NewConfirm(
+		"Replace file",
+		fmt.Sprintf("%s already exists. Do you want to replace it?", filepath.Base(path)),
+		func(confirmed bool) {
+			if !confirmed {
+				completed()
+				return
+			}
+			u.save(uri, opts, completed)
+		}, u.window)
+	kxdialog.AddDialogKeyHandler(d, u.window)
+	d.Show()
+}
+
+// save writes the current document to a file.
+// Shows a modal while saving, which allows to cancel.
+// completed is called after saving has finished, also when it failed or was canceled, and can be nil.
+func (u *UI) save(uri fyne.URI, opts jsondocument.EncodeOptions, completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	doc := u.document
+	ctx, cancel := context.WithCancel(context.TODO())
+	pb := widget.NewProgressBarInfinite()
+	b := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		cancel()
+	})
+	c := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("Saving document: %s", uri.Name())),
+		container.NewBorder(nil, nil, nil, b, pb),
+	)
+	d := dialog.NewCustomWithoutButtons("Saving", c, u.window)
+	d.SetOnClosed(cancel)
+	d.Show()
+	ctx, job := u.jobs.start(ctx, func() {
+		d.Hide()
+		completed()
+	})
+	go func() {
+		err := doc.Save(ctx, uri.Path(), opts)
+		job.readDone()
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			d.Hide()
+			if errors.Is(err, jsondocument.ErrCallerCanceled) {
+				return
+			}
+			if err != nil {
+				u.showErrorDialog(fmt.Sprintf("Failed to save document: %s", uri), err)
+				return
+			}
+			doc.MarkSaved()
+			if u.document != doc {
+				return
+			}
+			u.currentFile = uri
+			u.currentRequest = nil
+			u.addRecentFile(uri)
+			u.setTitle(uri.Name())
+			u.watcher.documentSaved()
+			u.fileReload.Disabled = false
+			u.fileWatch.Disabled = !isWatchable(uri)
+			u.window.MainMenu().Refresh()
+		})
+	}()
+}
+
+// unsavedChangesDialog asks the user what to do with the unsaved changes of the current document
+// before it is replaced or closed.
+type unsavedChangesDialog struct {
+	dialog  *dialog.CustomDialog
+	save    *widget.Button
+	discard *widget.Button
+	cancel  *widget.Button
+}
+
+// newUnsavedChangesDialog returns a new dialog for the unsaved changes of the current document.
+// proceed is called after the changes were saved or when the user chooses to discard them.
+func (u *UI) newUnsavedChangesDialog(proceed func()) *unsavedChangesDialog {
+	d := &unsavedChangesDialog{}
+	d.save = widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+		d.dialog.Hide()
+		u.saveFile(func() {
+			if u.document.IsModified() {
+				return // saving failed or was canceled
+			}
+			proceed()
+		})
+	})
+	d.save.Importance = widget.HighImportance
+	d.discard = widget.NewButtonWithIcon("Discard", theme.DeleteIcon(), func() {
+		d.dialog.Hide()
+		proceed()
+	})
+	d.cancel = widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		d.dialog.Hide()
+	})
+	name := u.fileName
+	if name == "" {
+		name = "The document"
+	}
+	c := widget.NewLabel(fmt.Sprintf("%s has unsaved changes. Do you want to save them?", name))
+	d.dialog = dialog.NewCustomWithoutButtons("Unsaved changes", c, u.window)
+	d.dialog.SetButtons([]fyne.CanvasObject{d.cancel, d.discard, d.save})
+	kxdialog.AddDialogKeyHandler(d.dialog, u.window)
+	return d
+}
+
+// confirmUnsavedChanges calls proceed when the current document has no unsaved changes.
+// Otherwise the user is asked first whether to save or discard the changes or to cancel.
+func (u *UI) confirmUnsavedChanges(proceed func()) {
+	if !u.document.IsModified() {
+		proceed()
+		return
+	}
+	u.newUnsavedChangesDialog(proceed).dialog.Show()
+}
@@ -5,26 +5,52 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
 	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
 
 	"github.com/ErikKalkoken/janice/internal/jsondocument"
 )
 
 const (
-	searchTypeKey     = "key"
-	searchTypeString  = "string"
-	searchTypeNumber  = "number"
-	searchTypeKeyword = "keyword"
+	searchTypeCondition = "condition"
+	searchTypeKey       = "key"
+	searchTypeString    = "string"
+	searchTypeNumber    = "number"
+	searchTypeKeyword   = "keyword"
+	searchTypeAnything  = "anything"
 )
 
+const maxDepthAny = "any depth"
+
+var maxDepths = map[string]int{
+	maxDepthAny: 0,
+	"1 level":   1,
+	"2 levels":  2,
+	"3 levels":  3,
+	"5 levels":  5,
+	"10 levels": 10,
+}
+
+var searchTypes = map[string]jsondocument.SearchType{
+	searchTypeAnything:  jsondocument.SearchAnything,
+	searchTypeCondition: jsondocument.SearchCondition,
+	searchTypeKey:       jsondocument.SearchKey,
+	searchTypeKeyword:   jsondocument.SearchKeyword,
+	searchTypeNumber:    jsondocument.SearchNumber,
+	searchTypeString:    jsondocument.SearchString,
+}
+
 var type2importance = map[jsondocument.JSONType]widget.Importance{
 	jsondocument.Array:   widget.HighImportance,
 	jsondocument.Object:  widget.HighImportance,
@@ -34,46 +60,153 @@ var type2importance = map[jsondocument.JSONType]widget.Importance{
 	jsondocument.Null:    widget.DangerImportance,
 }
 
+const filterToolTip = "Show only matches and their ancestors"
+
 // searchBar represents a search bar for searching in the JSON document.
 type searchBar struct {
 	widget.BaseWidget
 
-	collapseAll  *ttwidget.Button
-	scrollBottom *ttwidget.Button
-	scrollTop    *ttwidget.Button
-	searchButton *ttwidget.Button
-	searchEntry  *widget.Entry
-	searchType   *ttwidget.Select
-	u            *UI
+	collapseAll     *ttwidget.Button
+	contains        *toggleButton
+	filter          *toggleButton
+	findAll         *ttwidget.Button
+	fuzzy           *toggleButton
+	history         *ttwidget.Button
+	ignoreCase      *toggleButton
+	matchCount      *widget.Label
+	maxDepth        *ttwidget.Select
+	matches         map[widget.TreeNodeID]searchMatch // matching nodes
+	message         *widget.Label
+	regex           *toggleButton
+	saved           *ttwidget.Button
+	scope           widget.TreeNodeID
+	scrollBottom    *ttwidget.Button
+	scrollTop       *ttwidget.Button
+	searchButton    *ttwidget.Button
+	searchEntry     *findEntry
+	searchPrevious  *ttwidget.Button
+	searchType      *ttwidget.Select
+	shape           string // cached shape of shapeDocument
+	shapeDocument   *jsondocument.JSONDocument
+	u               *UI
+	withinSelection *toggleButton
+	wrap            *toggleButton
 }
 
 func newSearchBar(u *UI) *searchBar {
 	w := &searchBar{
-		searchEntry: widget.NewEntry(),
+		matchCount:  widget.NewLabel(""),
+		message:     widget.NewLabel(""),
+		searchEntry: newFindEntry(),
 		u:           u,
 	}
 	w.ExtendBaseWidget(w)
+	w.message.Importance = widget.DangerImportance
+	w.message.Wrapping = fyne.TextWrapWord
+	w.message.Hide()
+	w.matchCount.Hide()
 	w.searchType = ttwidget.NewSelect(
 		[]string{
+			searchTypeAnything,
+			searchTypeCondition,
 			searchTypeKey,
 			searchTypeKeyword,
 			searchTypeNumber,
 			searchTypeString,
 		},
-		nil,
+		func(s string) {
+			switch s {
+			case searchTypeCondition:
+				w.searchEntry.SetPlaceHolder(`Enter condition, e.g. status = "failed" and price > 100`)
+			case searchTypeNumber:
+				w.searchEntry.SetPlaceHolder("Enter number, comparison like > 100, range like 0.5..0.9 or int, nonint, unsafe")
+			default:
+				w.searchEntry.SetPlaceHolder("Enter pattern to search for...")
+			}
+			if w.searchEntry.Text != "" {
+				w.validatePattern()
+			}
+		},
 	)
 	w.searchType.SetSelected(searchTypeKey)
 	w.searchType.SetToolTip("Select what to search")
 	w.searchType.Disable()
-	w.searchEntry.SetPlaceHolder(
-		"Enter pattern to search for...")
-	w.searchEntry.OnSubmitted = func(s string) {
-		w.doSearch()
+	w.searchEntry.onFind = func(backward bool) {
+		w.doSearch(backward, nil)
 	}
+	w.searchEntry.OnChanged = func(s string) {
+		w.validatePattern()
+	}
+	w.ignoreCase = newToggleButton("Aa", "Ignore case", nil)
+	w.contains = newToggleButton("ab", "Match when text contains the pattern", func(on bool) {
+		if on {
+			w.regex.SetOn(false)
+			w.fuzzy.SetOn(false)
+		}
+		w.validatePattern()
+	})
+	w.regex = newToggleButton(".*", "Use regular expression", func(on bool) {
+		if on {
+			w.contains.SetOn(false)
+			w.fuzzy.SetOn(false)
+		}
+		w.validatePattern()
+	})
+	w.fuzzy = newToggleButton("~", "Fuzzy matching, e.g. for typos. Find all ranks the results.", func(on bool) {
+		if on {
+			w.contains.SetOn(false)
+			w.regex.SetOn(false)
+		}
+		w.validatePattern()
+	})
+	w.wrap = newToggleButton("", "Wrap around at the end of the document", nil)
+	w.withinSelection = newToggleButton("{ }", "", func(on bool) {
+		if on {
+			w.setScope(w.u.selection.selectedUID)
+		} else {
+			w.setScope("")
+		}
+	})
+	w.setScope("")
+	w.maxDepth = ttwidget.NewSelect(
+		[]string{maxDepthAny, "1 level", "2 levels", "3 levels", "5 levels", "10 levels"},
+		nil,
+	)
+	w.maxDepth.SetSelected(maxDepthAny)
+	w.maxDepth.SetToolTip("Maximum depth to search below the scope")
+	w.maxDepth.Disable()
+	w.wrap.Icon = theme.ViewRefreshIcon()
 	w.searchButton = ttwidget.NewButtonWithIcon("", theme.SearchIcon(), func() {
-		w.doSearch()
+		w.doSearch(false, nil)
+	})
+	w.searchButton.SetToolTip("Find next (F3)")
+	w.searchPrevious = ttwidget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+		w.doSearch(true, nil)
+	})
+	w.searchPrevious.SetToolTip("Find previous (Shift+F3)")
+	w.findAll = ttwidget.NewButtonWithIcon("", theme.ListIcon(), func() {
+		w.doFindAll(nil)
 	})
-	w.searchButton.SetToolTip("Search")
+	w.findAll.SetToolTip("Find all")
+	w.filter = newToggleButton("", filterToolTip, func(on bool) {
+		if on {
+			w.doFilter(nil)
+		} else {
+			w.filter.SetToolTip(filterToolTip)
+			w.u.tree.clearFilter()
+		}
+	})
+	w.filter.Icon = theme.VisibilityOffIcon()
+	w.history = ttwidget.NewButtonWithIcon("", theme.HistoryIcon(), nil)
+	w.history.OnTapped = func() {
+		w.showMenu(w.history, w.historyMenuItems())
+	}
+	w.history.SetToolTip("Recent searches")
+	w.saved = ttwidget.NewButtonWithIcon("", theme.StorageIcon(), nil)
+	w.saved.OnTapped = func() {
+		w.showMenu(w.saved, w.savedMenuItems())
+	}
+	w.saved.SetToolTip("Saved searches")
 	w.scrollBottom = ttwidget.NewButtonWithIcon("", theme.NewThemedResource(resourceVerticalalignbottomSvg), func() {
 		w.u.tree.ScrollToBottom()
 	})
@@ -90,7 +223,19 @@ func newSearchBar(u *UI) *searchBar {
 }
 
 func (w *searchBar) enable() {
+	w.contains.Enable()
+	w.ignoreCase.Enable()
+	w.regex.Enable()
+	w.fuzzy.Enable()
+	w.history.Enable()
+	w.saved.Enable()
+	w.wrap.Enable()
+	w.withinSelection.Enable()
+	w.maxDepth.Enable()
 	w.searchButton.Enable()
+	w.searchPrevious.Enable()
+	w.findAll.Enable()
+	w.filter.Enable()
 	w.searchType.Enable()
 	w.searchEntry.Enable()
 	w.scrollBottom.Enable()
@@ -99,7 +244,19 @@ func (w *searchBar) enable() {
 }
 
 func (w *searchBar) disable() {
+	w.contains.Disable()
+	w.ignoreCase.Disable()
+	w.regex.Disable()
+	w.fuzzy.Disable()
+	w.history.Disable()
+	w.saved.Disable()
+	w.wrap.Disable()
+	w.withinSelection.Disable()
+	w.maxDepth.Disable()
 	w.searchButton.Disable()
+	w.searchPrevious.Disable()
+	w.findAll.Disable()
+	w.filter.Disable()
 	w.searchType.Disable()
 	w.searchEntry.Disable()
 	w.scrollBottom.Disable()
@@ -107,49 +264,122 @@ func (w *searchBar) disable() {
 	w.collapseAll.Disable()
 }
 
-// doSearch performs a search in the JSON document.
-func (w *searchBar) doSearch() {
+// options returns the search options as currently selected by the toggles.
+func (w *searchBar) options() jsondocument.SearchOptions {
+	var opts jsondocument.SearchOptions
+	switch {
+	case w.regex.IsOn():
+		opts.Mode = jsondocument.SearchRegex
+	case w.contains.IsOn():
+		opts.Mode = jsondocument.SearchContains
+	case w.fuzzy.IsOn():
+		opts.Mode = jsondocument.SearchFuzzy
+	}
+	opts.IgnoreCase = w.ignoreCase.IsOn()
+	opts.Wrap = w.wrap.IsOn()
+	opts.Scope = w.scope
+	opts.MaxDepth = maxDepths[w.maxDepth.Selected]
+	return opts
+}
+
+// showMenu shows a popup menu below a button.
+func (w *searchBar) showMenu(b *ttwidget.Button, items []*fyne.MenuItem) {
+	c := fyne.CurrentApp().Driver().CanvasForObject(b)
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(b)
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), c, pos.AddXY(0, b.Size().Height))
+}
+
+// setScope limits searches to the container at uid or the container of the node at uid.
+// The empty UID searches the whole document.
+func (w *searchBar) setScope(uid widget.TreeNodeID) {
+	if uid != "" && !w.u.document.IsBranch(uid) {
+		uid = w.u.document.Parent(uid)
+	}
+	w.scope = uid
+	if uid == "" {
+		w.withinSelection.SetToolTip("Search within selection")
+		return
+	}
+	w.withinSelection.SetToolTip(fmt.Sprintf("Searching within %s", w.u.document.Pointer(uid)))
+}
+
+// reset switches off searching within a selection and removes the filter.
+func (w *searchBar) reset() {
+	w.withinSelection.SetOn(false)
+	w.setScope("")
+	w.u.tree.resetFilter()
+	w.filter.SetOn(false)
+}
+
+// validatePattern shows an error below the entry when the current pattern is invalid
+// and reports whether it is valid.
+func (w *searchBar) validatePattern() bool {
+	err := jsondocument.ValidateSearch(w.searchEntry.Text, searchTypes[w.searchType.Selected], w.options())
+	if err != nil {
+		w.message.SetText(err.Error())
+		w.message.Show()
+		return false
+	}
+	w.message.Hide()
+	return true
+}
+
+// input returns the search type and pattern as entered by the user.
+// It reports false and shows an error below the entry when the input is not valid.
+func (w *searchBar) input() (jsondocument.SearchType, string, bool) {
 	search := w.searchEntry.Text
-	if len(search) == 0 {
+	if len(search) == 0 || !w.validatePattern() {
+		return 0, "", false
+	}
+	typ := searchTypes[w.searchType.Selected]
+	if typ == jsondocument.SearchKeyword && w.options().Mode == jsondocument.SearchWildcard {
+		search = strings.ToLower(search)
+		if search != "true" && search != "false" && search != "null" {
+			w.message.SetText("Allowed keywords are: true, false, null")
+			w.message.Show()
+			return 0, "", false
+		}
+	}
+	return typ, search, true
+}
+
+// doSearch searches for the next or previous match in the JSON document.
+// completed is called after the search has finished and can be nil.
+func (w *searchBar) doSearch(backward bool, completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	if w.searchEntry.Disabled() {
+		completed()
+		return
+	}
+	typ, search, ok := w.input()
+	if !ok {
+		completed()
 		return
 	}
+	w.addToHistory()
+	opts := w.options()
+	opts.Backward = backward
 	ctx, cancel := context.WithCancel(context.Background())
-	spinner := widget.NewActivity()
-	spinner.Start()
 	searchType := w.searchType.Selected
-	c := container.NewHBox(widget.NewLabel(fmt.Sprintf("Searching for %s with pattern: %s", searchType, search)), spinner)
-	b := widget.NewButton("Cancel", func() {
-		cancel()
-	})
-	d := dialog.NewCustomWithoutButtons("Search", container.NewVBox(c, b), w.u.window)
-	kxdialog.AddDialogKeyHandler(d, w.u.window)
-	d.Show()
-	d.SetOnClosed(func() {
-		cancel()
+	d := newSearchDialog("Search", fmt.Sprintf("Searching for %s with pattern: %s", searchType, search), w.u.window, cancel)
+	ctx, job := w.u.jobs.start(ctx, func() {
+		d.hide()
+		completed()
 	})
+	doc := w.u.document
+	start := w.u.selection.selectedUID
 	go func() {
-		var typ jsondocument.SearchType
-		switch searchType {
-		case searchTypeKey:
-			typ = jsondocument.SearchKey
-		case searchTypeKeyword:
-			typ = jsondocument.SearchKeyword
-			search = strings.ToLower(search)
-			if search != "true" && search != "false" && search != "null" {
-				fyne.Do(func() {
-					d.Hide()
-					w.u.showErrorDialog("Allowed keywords are: true, false, null", nil)
-				})
-				return
-			}
-		case searchTypeString:
-			typ = jsondocument.SearchString
-		case searchTypeNumber:
-			typ = jsondocument.SearchNumber
-		}
-		uid, err := w.u.document.Search(ctx, w.u.selection.selectedUID, search, typ)
+		d.setTotal(doc.SearchSize(search, typ, opts))
+		uid, err := doc.SearchWithProgress(ctx, start, search, typ, opts, d.update)
+		job.readDone()
 		fyne.Do(func() {
-			d.Hide()
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			d.hide()
 			if errors.Is(err, jsondocument.ErrCallerCanceled) {
 				return
 			} else if errors.Is(err, jsondocument.ErrNotFound) {
@@ -158,7 +388,7 @@ func (w *searchBar) doSearch() {
 					fmt.Sprintf("No %s found matching %s", searchType, search),
 					w.u.window,
 				)
-				kxdialog.AddDialogKeyHandler(d, w.u.window)
+				kxdialog.AddDialogKeyHandler(d2, w.u.window)
 				d2.Show()
 				return
 			} else if err != nil {
@@ -170,20 +400,468 @@ func (w *searchBar) doSearch() {
 	}()
 }
 
+// doFindAll searches for all matches in the JSON document and shows them in the results panel.
+// Matches are shown while they are found and highlighted in the tree.
+// completed is called after the search has finished, also when it failed or was canceled, and can be nil.
+func (w *searchBar) doFindAll(completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	if w.searchEntry.Disabled() {
+		completed()
+		return
+	}
+	typ, search, ok := w.input()
+	if !ok {
+		completed()
+		return
+	}
+	w.addToHistory()
+	opts := w.options()
+	if opts.Mode == jsondocument.SearchFuzzy {
+		w.doFuzzyFindAll(typ, search, opts, completed)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	matches := make(map[widget.TreeNodeID]searchMatch)
+	p := message.NewPrinter(language.English)
+	w.u.results.start(fmt.Sprintf("Searching for %s...", search), func() {
+		cancel()
+		w.setMatches(nil)
+	})
+	w.setMatches(matches)
+	jobCtx, job := w.u.jobs.start(ctx, func() {
+		w.u.results.clear()
+		completed()
+	})
+	doc := w.u.document
+	go func() {
+		total := doc.SearchSize(search, typ, opts)
+		var batch []jsondocument.SearchMatch
+		update := func(searched int) {
+			b := batch
+			batch = nil
+			fyne.Do(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				uids := make([]widget.TreeNodeID, len(b))
+				for i, m := range b {
+					sm := searchMatch{index: len(matches)}
+					if typ == jsondocument.SearchAnything {
+						sm.part = m.Part
+					}
+					matches[m.UID] = sm
+					uids[i] = m.UID
+				}
+				w.u.results.add(uids)
+				w.u.results.title.SetText(p.Sprintf("Searching for %s... %d%%", search, searched*100/max(total, 1)))
+				w.updateMatchCount()
+				w.u.tree.Refresh()
+			})
+		}
+		err := doc.SearchAllMatches(jobCtx, search, typ, opts, func(m jsondocument.SearchMatch) {
+			batch = append(batch, m)
+		}, update)
+		job.readDone()
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				w.u.results.clear()
+				w.u.showErrorDialog("Search failed", err)
+				return
+			}
+			w.u.results.finish(p.Sprintf("%d matches for %s", len(matches), search))
+		})
+	}()
+}
+
+// doFuzzyFindAll finds all fuzzy matches and shows them ranked by score with the matched characters highlighted.
+func (w *searchBar) doFuzzyFindAll(typ jsondocument.SearchType, search string, opts jsondocument.SearchOptions, completed func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := message.NewPrinter(language.English)
+	w.u.results.start(fmt.Sprintf("Searching for %s...", search), func() {
+		cancel()
+		w.setMatches(nil)
+	})
+	w.setMatches(nil)
+	jobCtx, job := w.u.jobs.start(ctx, func() {
+		w.u.results.clear()
+		completed()
+	})
+	doc := w.u.document
+	go func() {
+		total := doc.SearchSize(search, typ, opts)
+		results, err := doc.SearchFuzzy(jobCtx, search, typ, opts, func(searched int) {
+			fyne.Do(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				w.u.results.title.SetText(p.Sprintf("Searching for %s... %d%%", search, searched*100/max(total, 1)))
+			})
+		})
+		job.readDone()
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				w.u.results.clear()
+				w.u.showErrorDialog("Search failed", err)
+				return
+			}
+			matches := make(map[widget.TreeNodeID]searchMatch, len(results))
+			highlights := make(map[widget.TreeNodeID]resultHighlight, len(results))
+			uids := make([]widget.TreeNodeID, len(results))
+			for i, r := range results {
+				m := searchMatch{index: i}
+				if typ == jsondocument.SearchAnything {
+					m.part = r.Part
+				}
+				matches[r.UID] = m
+				highlights[r.UID] = resultHighlight{part: r.Part, positions: r.Positions}
+				uids[i] = r.UID
+			}
+			w.u.results.add(uids)
+			w.u.results.setHighlights(highlights)
+			w.setMatches(matches)
+			if len(results) == 1 {
+				w.u.results.finish(fmt.Sprintf("1 fuzzy match for %s", search))
+			} else {
+				w.u.results.finish(p.Sprintf("%d fuzzy matches for %s", len(results), search))
+			}
+		})
+	}()
+}
+
+// doFilter finds all matches and shows only them and their ancestors in the tree.
+// The filter is switched off again when the search fails or finds nothing.
+// completed is called after the filter was applied or switched off and can be nil.
+func (w *searchBar) doFilter(completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	typ, search, ok := w.input()
+	if !ok {
+		w.filter.SetOn(false)
+		completed()
+		return
+	}
+	w.addToHistory()
+	opts := w.options()
+	ctx, cancel := context.WithCancel(context.Background())
+	searchType := w.searchType.Selected
+	d := newSearchDialog("Filter", fmt.Sprintf("Filtering by %s with pattern: %s", searchType, search), w.u.window, cancel)
+	ctx, job := w.u.jobs.start(ctx, func() {
+		d.hide()
+		w.filter.SetOn(false)
+		completed()
+	})
+	doc := w.u.document
+	go func() {
+		d.setTotal(doc.SearchSize(search, typ, opts))
+		var uids []widget.TreeNodeID
+		var err error
+		if opts.Mode == jsondocument.SearchFuzzy {
+			var results []jsondocument.FuzzyResult
+			results, err = doc.SearchFuzzy(ctx, search, typ, opts, d.update)
+			for _, r := range results {
+				uids = append(uids, r.UID)
+			}
+		} else {
+			err = doc.SearchAll(ctx, search, typ, opts, func(uid widget.TreeNodeID) {
+				uids = append(uids, uid)
+			}, d.update)
+		}
+		var f *jsondocument.Filter
+		if err == nil {
+			f, err = doc.NewFilter(uids)
+		}
+		job.readDone()
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			d.hide()
+			if doc != w.u.document || !w.filter.IsOn() {
+				return
+			}
+			if errors.Is(err, jsondocument.ErrCallerCanceled) {
+				w.filter.SetOn(false)
+				return
+			} else if err != nil {
+				w.filter.SetOn(false)
+				w.u.showErrorDialog("Filter failed", err)
+				return
+			} else if f.MatchCount() == 0 {
+				w.filter.SetOn(false)
+				d2 := dialog.NewInformation(
+					"No match",
+					fmt.Sprintf("No %s found matching %s", searchType, search),
+					w.u.window,
+				)
+				kxdialog.AddDialogKeyHandler(d2, w.u.window)
+				d2.Show()
+				return
+			}
+			p := message.NewPrinter(language.English)
+			tip := p.Sprintf("Showing %d matches for %s. Click to show all nodes", f.MatchCount(), search)
+			if n := f.HiddenCount(""); n > 0 {
+				tip += p.Sprintf(" (%d hidden at top level)", n)
+			}
+			w.filter.SetToolTip(tip)
+			w.u.tree.setFilter(f)
+		})
+	}()
+}
+
+// searchDialog is a modal dialog, which shows the progress of a running search
+// and allows the user to cancel it.
+type searchDialog struct {
+	dialog   *dialog.CustomDialog
+	done     chan struct{} // closed when the elapsed time is no longer updated
+	elapsed  *widget.Label
+	progress *widget.ProgressBar
+	scanned  *widget.Label
+	started  time.Time
+	stop     chan struct{}
+	total    int
+}
+
+// newSearchDialog shows a new search dialog. cancel is called when the user cancels the search.
+func newSearchDialog(title, text string, w fyne.Window, cancel context.CancelFunc) *searchDialog {
+	d := &searchDialog{
+		done:     make(chan struct{}),
+		elapsed:  widget.NewLabel(""),
+		progress: widget.NewProgressBar(),
+		scanned:  widget.NewLabel(""),
+		started:  time.Now(),
+		stop:     make(chan struct{}),
+	}
+	b := widget.NewButton("Cancel", func() {
+		cancel()
+	})
+	c := container.NewVBox(
+		widget.NewLabel(text),
+		d.progress,
+		container.NewHBox(d.scanned, layout.NewSpacer(), d.elapsed),
+		b,
+	)
+	d.dialog = dialog.NewCustomWithoutButtons(title, c, w)
+	kxdialog.AddDialogKeyHandler(d.dialog, w)
+	d.dialog.SetOnClosed(func() {
+		cancel()
+	})
+	d.updateElapsed()
+	d.dialog.Show()
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				fyne.Do(d.updateElapsed)
+			}
+		}
+	}()
+	return d
+}
+
+// setTotal sets the number of nodes to scan. It can be called from any goroutine.
+func (d *searchDialog) setTotal(total int) {
+	fyne.Do(func() {
+		d.total = total
+		d.show(0)
+	})
+}
+
+// update shows the number of scanned nodes. It can be called from any goroutine.
+func (d *searchDialog) update(scanned int) {
+	fyne.Do(func() {
+		d.show(scanned)
+	})
+}
+
+// show shows the number of scanned nodes.
+func (d *searchDialog) show(scanned int) {
+	p := message.NewPrinter(language.English)
+	d.scanned.SetText(p.Sprintf("%d of %d nodes scanned", scanned, d.total))
+	d.progress.SetValue(float64(scanned) / float64(max(d.total, 1)))
+	d.updateElapsed()
+}
+
+// updateElapsed shows the time since the search was started.
+func (d *searchDialog) updateElapsed() {
+	d.elapsed.SetText(fmt.Sprintf("Elapsed: %s", time.Since(d.started).Round(time.Second)))
+}
+
+// hide closes the dialog after the elapsed time has stopped updating.
+func (d *searchDialog) hide() {
+	close(d.stop)
+	<-d.done
+	d.dialog.Hide()
+}
+
+// searchMatch is a node found by a find all search.
+type searchMatch struct {
+	index int
+	part  jsondocument.MatchPart // only set for searches which can match keys and values
+}
+
+// setMatches sets the matches found by the last find all search. Nil removes them.
+func (w *searchBar) setMatches(matches map[widget.TreeNodeID]searchMatch) {
+	w.matches = matches
+	w.updateMatchCount()
+	w.u.tree.Refresh()
+}
+
+// updateMatchCount shows the number of matches and the position of the selected node among them.
+func (w *searchBar) updateMatchCount() {
+	if w.matches == nil {
+		w.matchCount.Hide()
+		return
+	}
+	p := message.NewPrinter(language.English)
+	var s string
+	if m, ok := w.matches[w.u.selection.selectedUID]; ok {
+		s = p.Sprintf("%d of %d", m.index+1, len(w.matches))
+		if m.part != 0 {
+			s += " in " + m.part.String()
+		}
+	} else if len(w.matches) == 1 {
+		s = "1 match"
+	} else {
+		s = p.Sprintf("%d matches", len(w.matches))
+	}
+	w.matchCount.SetText(s)
+	w.matchCount.Show()
+}
+
+// isMatch reports whether a node was found by the last find all search.
+func (w *searchBar) isMatch(uid widget.TreeNodeID) bool {
+	_, ok := w.matches[uid]
+	return ok
+}
+
 func (w *searchBar) CreateRenderer() fyne.WidgetRenderer {
-	c := container.NewBorder(
-		nil,
-		nil,
-		w.searchType,
-		container.NewHBox(
-			w.searchButton,
-			container.NewPadded(),
-			layout.NewSpacer(),
-			w.scrollTop,
-			w.scrollBottom,
-			w.collapseAll,
+	c := container.NewVBox(
+		container.NewBorder(
+			nil,
+			nil,
+			w.searchType,
+			container.NewHBox(
+				w.history,
+				w.saved,
+				w.matchCount,
+				w.ignoreCase,
+				w.contains,
+				w.regex,
+				w.fuzzy,
+				w.wrap,
+				w.withinSelection,
+				w.maxDepth,
+				w.searchPrevious,
+				w.searchButton,
+				w.findAll,
+				w.filter,
+				container.NewPadded(),
+				layout.NewSpacer(),
+				w.scrollTop,
+				w.scrollBottom,
+				w.collapseAll,
+			),
+			w.searchEntry,
 		),
-		w.searchEntry,
+		w.message,
 	)
 	return widget.NewSimpleRenderer(c)
 }
+
+// handleFindKey runs a search when F3 was pressed and reports whether the key was handled.
+// The search direction is backward when shift is held down.
+func (w *searchBar) handleFindKey(key *fyne.KeyEvent, shift bool) bool {
+	if key.Name != fyne.KeyF3 {
+		return false
+	}
+	w.doSearch(shift, nil)
+	return true
+}
+
+// addFindKeyHandler enables searching with F3 on a canvas, when no widget has the focus.
+func (w *searchBar) addFindKeyHandler(c fyne.Canvas) {
+	var shift bool
+	if dc, ok := c.(desktop.Canvas); ok {
+		dc.SetOnKeyDown(func(key *fyne.KeyEvent) {
+			if isShiftKey(key.Name) {
+				shift = true
+			}
+		})
+		dc.SetOnKeyUp(func(key *fyne.KeyEvent) {
+			if isShiftKey(key.Name) {
+				shift = false
+			}
+		})
+	}
+	c.SetOnTypedKey(func(key *fyne.KeyEvent) {
+		w.handleFindKey(key, shift)
+	})
+}
+
+// isShiftKey reports whether a key is one of the shift keys.
+func isShiftKey(name fyne.KeyName) bool {
+	return name == desktop.KeyShiftLeft || name == desktop.KeyShiftRight
+}
+
+// findEntry is an entry for search patterns.
+// It starts a search with Enter and F3 and a backward search when shift is held down.
+type findEntry struct {
+	widget.Entry
+
+	onFind func(backward bool)
+	shift  bool
+}
+
+func newFindEntry() *findEntry {
+	w := &findEntry{}
+	w.ExtendBaseWidget(w)
+	return w
+}
+
+func (w *findEntry) KeyDown(key *fyne.KeyEvent) {
+	if isShiftKey(key.Name) {
+		w.shift = true
+	}
+	w.Entry.KeyDown(key)
+}
+
+func (w *findEntry) KeyUp(key *fyne.KeyEvent) {
+	if isShiftKey(key.Name) {
+		w.shift = false
+	}
+	w.Entry.KeyUp(key)
+}
+
+func (w *findEntry) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyReturn, fyne.KeyEnter, fyne.KeyF3:
+		if w.onFind != nil {
+			w.onFind(w.shift)
+		}
+		return
+	}
+	w.Entry.TypedKey(key)
+}
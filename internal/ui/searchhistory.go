@@ -0,0 +1,292 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	kxdialog "github.com/ErikKalkoken/fyne-kx/dialog"
+
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
+)
+
+// Maximum number of searches in the history
+const searchHistoryMax = 20
+
+// What a saved search applies to
+const (
+	savedSearchForAll   = "All documents"
+	savedSearchForFile  = "This file only"
+	savedSearchForShape = "Documents with the same structure"
+)
+
+// savedSearch is a search with its type and options as stored in the preferences.
+type savedSearch struct {
+	Name       string                  `json:"name,omitempty"`
+	Pattern    string                  `json:"pattern"`
+	Type       string                  `json:"type"`
+	Mode       jsondocument.SearchMode `json:"mode,omitempty"`
+	IgnoreCase bool                    `json:"ignoreCase,omitempty"`
+	Wrap       bool                    `json:"wrap,omitempty"`
+	MaxDepth   string                  `json:"maxDepth,omitempty"`
+	File       string                  `json:"file,omitempty"`  // URI of the file it is tied to
+	Shape      string                  `json:"shape,omitempty"` // shape of the documents it is tied to
+}
+
+// label returns a short description of a search, e.g. "key: name (ignore case)".
+func (s savedSearch) label() string {
+	var opts []string
+	switch s.Mode {
+	case jsondocument.SearchContains:
+		opts = append(opts, "contains")
+	case jsondocument.SearchRegex:
+		opts = append(opts, "regex")
+	case jsondocument.SearchFuzzy:
+		opts = append(opts, "fuzzy")
+	}
+	if s.IgnoreCase {
+		opts = append(opts, "ignore case")
+	}
+	if s.MaxDepth != "" && s.MaxDepth != maxDepthAny {
+		opts = append(opts, s.MaxDepth)
+	}
+	text := fmt.Sprintf("%s: %s", s.Type, s.Pattern)
+	if len(opts) > 0 {
+		text += fmt.Sprintf(" (%s)", strings.Join(opts, ", "))
+	}
+	if s.Name != "" {
+		text = fmt.Sprintf("%s - %s", s.Name, text)
+	}
+	return text
+}
+
+// appliesTo reports whether a saved search can be used for a document.
+// The shape is only calculated when needed.
+func (s savedSearch) appliesTo(file fyne.URI, shape func() string) bool {
+	switch {
+	case s.File != "":
+		return file != nil && file.String() == s.File
+	case s.Shape != "":
+		return shape() == s.Shape
+	}
+	return true
+}
+
+// loadSearches returns the searches stored under a preference key.
+func loadSearches(p fyne.Preferences, key string) []savedSearch {
+	var searches []savedSearch
+	for _, v := range p.StringList(key) {
+		var s savedSearch
+		if err := json.Unmarshal([]byte(v), &s); err != nil {
+			slog.Warn("Ignoring invalid search in preferences", "key", key, "err", err)
+			continue
+		}
+		searches = append(searches, s)
+	}
+	return searches
+}
+
+// storeSearches stores searches under a preference key.
+func storeSearches(p fyne.Preferences, key string, searches []savedSearch) {
+	var values []string
+	for _, s := range searches {
+		b, err := json.Marshal(s)
+		if err != nil {
+			slog.Error("Failed to store search", "key", key, "err", err)
+			continue
+		}
+		values = append(values, string(b))
+	}
+	p.SetStringList(key, values)
+}
+
+// currentSearch returns the search as currently entered in the search bar.
+func (w *searchBar) currentSearch() savedSearch {
+	opts := w.options()
+	return savedSearch{
+		Pattern:    w.searchEntry.Text,
+		Type:       w.searchType.Selected,
+		Mode:       opts.Mode,
+		IgnoreCase: opts.IgnoreCase,
+		Wrap:       opts.Wrap,
+		MaxDepth:   w.maxDepth.Selected,
+	}
+}
+
+// applySearch enters a search into the search bar.
+func (w *searchBar) applySearch(s savedSearch) {
+	if _, ok := searchTypes[s.Type]; ok {
+		w.searchType.SetSelected(s.Type)
+	}
+	w.contains.SetOn(s.Mode == jsondocument.SearchContains)
+	w.regex.SetOn(s.Mode == jsondocument.SearchRegex)
+	w.fuzzy.SetOn(s.Mode == jsondocument.SearchFuzzy)
+	w.ignoreCase.SetOn(s.IgnoreCase)
+	w.wrap.SetOn(s.Wrap)
+	if _, ok := maxDepths[s.MaxDepth]; ok {
+		w.maxDepth.SetSelected(s.MaxDepth)
+	} else {
+		w.maxDepth.SetSelected(maxDepthAny)
+	}
+	w.searchEntry.SetText(s.Pattern)
+}
+
+// addToHistory adds the current search on top of the search history.
+func (w *searchBar) addToHistory() {
+	p := w.u.app.Preferences()
+	s := w.currentSearch()
+	history := slices.DeleteFunc(loadSearches(p, preferenceSearchHistory), func(x savedSearch) bool {
+		return x == s
+	})
+	history = slices.Insert(history, 0, s)
+	if len(history) > searchHistoryMax {
+		history = history[:searchHistoryMax]
+	}
+	storeSearches(p, preferenceSearchHistory, history)
+}
+
+// historyMenuItems returns menu items for entering searches from the history.
+func (w *searchBar) historyMenuItems() []*fyne.MenuItem {
+	p := w.u.app.Preferences()
+	var items []*fyne.MenuItem
+	for _, s := range loadSearches(p, preferenceSearchHistory) {
+		items = append(items, fyne.NewMenuItem(s.label(), func() {
+			w.applySearch(s)
+		}))
+	}
+	if len(items) == 0 {
+		it := fyne.NewMenuItem("No recent searches", nil)
+		it.Disabled = true
+		return []*fyne.MenuItem{it}
+	}
+	items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Clear history", func() {
+		p.SetStringList(preferenceSearchHistory, nil)
+	}))
+	return items
+}
+
+// runSavedSearch enters a saved search in the search bar and finds all matches.
+// completed is called after the search has finished and can be nil.
+func (w *searchBar) runSavedSearch(s savedSearch, completed func()) {
+	w.applySearch(s)
+	w.doFindAll(completed)
+}
+
+// documentShape returns the shape of the current document.
+// It is cached, because calculating it requires walking the document.
+func (w *searchBar) documentShape() string {
+	if w.shapeDocument != w.u.document {
+		w.shape = w.u.document.Shape()
+		w.shapeDocument = w.u.document
+	}
+	return w.shape
+}
+
+// savedMenuItems returns menu items for running the saved searches, which apply to the current document,
+// and for managing saved searches.
+func (w *searchBar) savedMenuItems() []*fyne.MenuItem {
+	saved := loadSearches(w.u.app.Preferences(), preferenceSavedSearches)
+	var items []*fyne.MenuItem
+	for _, s := range saved {
+		if !s.appliesTo(w.u.currentFile, w.documentShape) {
+			continue
+		}
+		items = append(items, fyne.NewMenuItem(s.label(), func() {
+			w.runSavedSearch(s, nil)
+		}))
+	}
+	if len(items) == 0 {
+		it := fyne.NewMenuItem("No saved searches for this document", nil)
+		it.Disabled = true
+		items = append(items, it)
+	}
+	items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Save current search...", func() {
+		w.showSaveSearchDialog()
+	}))
+	if len(saved) > 0 {
+		var deleteItems []*fyne.MenuItem
+		for i, s := range saved {
+			deleteItems = append(deleteItems, fyne.NewMenuItem(s.label(), func() {
+				w.deleteSavedSearch(i)
+			}))
+		}
+		it := fyne.NewMenuItem("Delete", nil)
+		it.ChildMenu = fyne.NewMenu("", deleteItems...)
+		items = append(items, it)
+	}
+	return items
+}
+
+// showSaveSearchDialog shows a dialog for saving the current search under a name.
+func (w *searchBar) showSaveSearchDialog() {
+	s := w.currentSearch()
+	if s.Pattern == "" {
+		return
+	}
+	name := widget.NewEntry()
+	name.SetPlaceHolder("Failed jobs")
+	name.Validator = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return errors.New("name is required")
+		}
+		return nil
+	}
+	scopes := []string{savedSearchForAll, savedSearchForShape}
+	if w.u.currentFile != nil {
+		scopes = append(scopes, savedSearchForFile)
+	}
+	scope := widget.NewRadioGroup(scopes, nil)
+	scope.Required = true
+	scope.SetSelected(savedSearchForAll)
+	items := []*widget.FormItem{
+		{Text: "Name", Widget: name},
+		{Text: "Search", Widget: widget.NewLabel(s.label())},
+		{Text: "Applies to", Widget: scope, HintText: "Where the search is offered"},
+	}
+	d := dialog.NewForm("Save search", "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		s.Name = strings.TrimSpace(name.Text)
+		switch scope.Selected {
+		case savedSearchForFile:
+			s.File = w.u.currentFile.String()
+		case savedSearchForShape:
+			s.Shape = w.documentShape()
+		}
+		w.saveSearch(s)
+	}, w.u.window)
+	kxdialog.AddDialogKeyHandler(d, w.u.window)
+	d.Resize(fyne.NewSize(500, 300))
+	d.Show()
+	w.u.window.Canvas().Focus(name)
+}
+
+// saveSearch stores a named search. It replaces a saved search with the same name.
+func (w *searchBar) saveSearch(s savedSearch) {
+	p := w.u.app.Preferences()
+	saved := slices.DeleteFunc(loadSearches(p, preferenceSavedSearches), func(x savedSearch) bool {
+		return x.Name == s.Name
+	})
+	saved = append(saved, s)
+	slices.SortFunc(saved, func(a, b savedSearch) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	storeSearches(p, preferenceSavedSearches, saved)
+}
+
+// deleteSavedSearch removes a saved search.
+func (w *searchBar) deleteSavedSearch(i int) {
+	p := w.u.app.Preferences()
+	saved := loadSearches(p, preferenceSavedSearches)
+	if i >= len(saved) {
+		return
+	}
+	storeSearches(p, preferenceSavedSearches, slices.Delete(saved, i, i+1))
+}
@@ -17,6 +17,7 @@ type selection struct {
 	widget.BaseWidget
 
 	copyKeyClipboard *ttwidget.Button
+	copyPath         *ttwidget.Button
 	jumpToSelection  *ttwidget.Button
 	selectedPath     *fyne.Container
 	selectedUID      widget.TreeNodeID
@@ -40,17 +41,39 @@ func newSelection(u *UI) *selection {
 	})
 	w.copyKeyClipboard.SetToolTip("Copy key to clipboard")
 	w.copyKeyClipboard.Disable()
+	w.copyPath = ttwidget.NewButtonWithIcon("", theme.MenuDropDownIcon(), nil)
+	w.copyPath.OnTapped = func() {
+		m := fyne.NewMenu("", u.copyPathMenuItems(w.selectedUID)...)
+		c := fyne.CurrentApp().Driver().CanvasForObject(w.copyPath)
+		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(w.copyPath)
+		widget.ShowPopUpMenuAtPosition(m, c, pos.AddXY(0, w.copyPath.Size().Height))
+	}
+	w.copyPath.SetToolTip("Copy path to clipboard")
+	w.copyPath.Disable()
 	return w
 }
 
+// copyPathMenuItems returns menu items for copying the path of a node in all supported syntaxes.
+func (u *UI) copyPathMenuItems(uid widget.TreeNodeID) []*fyne.MenuItem {
+	var items []*fyne.MenuItem
+	for _, s := range jsondocument.PathSyntaxes {
+		items = append(items, fyne.NewMenuItem(s.String(), func() {
+			u.app.Clipboard().SetContent(u.document.FormatPath(uid, s))
+		}))
+	}
+	return items
+}
+
 func (w *selection) enable() {
 	w.jumpToSelection.Enable()
 	w.copyKeyClipboard.Enable()
+	w.copyPath.Enable()
 }
 
 func (w *selection) disable() {
 	w.jumpToSelection.Disable()
 	w.copyKeyClipboard.Disable()
+	w.copyPath.Disable()
 }
 
 func (w *selection) reset() {
@@ -99,7 +122,7 @@ func (w *selection) CreateRenderer() fyne.WidgetRenderer {
 		nil,
 		nil,
 		nil,
-		container.NewHBox(w.jumpToSelection, w.copyKeyClipboard),
+		container.NewHBox(w.jumpToSelection, w.copyKeyClipboard, w.copyPath),
 		container.NewHScroll(w.selectedPath),
 	)
 	return widget.NewSimpleRenderer(c)
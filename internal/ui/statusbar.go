@@ -8,12 +8,14 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 
 	"github.com/ErikKalkoken/janice/internal/github"
+	"github.com/ErikKalkoken/janice/internal/jsondocument"
 )
 
 const (
@@ -25,7 +27,11 @@ const (
 type statusBar struct {
 	widget.BaseWidget
 
+	changes       *ttwidget.Label
 	elementsCount *ttwidget.Label
+	index         *ttwidget.Label
+	nextChange    *ttwidget.Button
+	prevChange    *ttwidget.Button
 	updateLink    *ttwidget.Hyperlink
 	u             *UI
 }
@@ -33,12 +39,25 @@ type statusBar struct {
 func newStatusBar(u *UI) *statusBar {
 	x, _ := url.Parse(websiteURL + "/releases")
 	w := &statusBar{
+		changes:       ttwidget.NewLabel(""),
 		elementsCount: ttwidget.NewLabel(""),
+		index:         ttwidget.NewLabel(""),
 		updateLink:    ttwidget.NewHyperlink("Update available", x),
 		u:             u,
 	}
 	w.ExtendBaseWidget(w)
 	w.elementsCount.SetToolTip("Total count of elements in the JSON document")
+	w.changes.SetToolTip("Changes since the document was last loaded")
+	w.prevChange = ttwidget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+		w.u.gotoChange(false)
+	})
+	w.prevChange.SetToolTip("Previous change")
+	w.nextChange = ttwidget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
+		w.u.gotoChange(true)
+	})
+	w.nextChange.SetToolTip("Next change")
+	w.setChanges(nil)
+	w.index.Hide()
 	w.updateLink.Hide()
 	notifyUpdates := w.u.app.Preferences().BoolWithFallback(settingNotifyUpdates, settingNotifyUpdatesDefault)
 	if notifyUpdates {
@@ -63,6 +82,59 @@ func newStatusBar(u *UI) *statusBar {
 
 func (w *statusBar) reset() {
 	w.elementsCount.SetText("")
+	w.setChanges(nil)
+	w.setSearchIndex(false, false)
+}
+
+// setSearchIndex shows whether the search index is being built or available.
+func (w *statusBar) setSearchIndex(building, available bool) {
+	switch {
+	case building:
+		w.index.SetText("Indexing...")
+		w.index.SetToolTip("Building search index in the background")
+		w.index.Show()
+	case available:
+		w.index.SetText("Indexed")
+		w.index.SetToolTip("Searches for keys, words, numbers and keywords use the search index")
+		w.index.Show()
+	default:
+		w.index.Hide()
+	}
+}
+
+// setChanges shows a summary of the changes in a diff. Nil hides the summary.
+func (w *statusBar) setChanges(d *jsondocument.Diff) {
+	if d == nil {
+		w.changes.Hide()
+		w.prevChange.Hide()
+		w.nextChange.Hide()
+		return
+	}
+	p := message.NewPrinter(language.English)
+	if len(d.Changes) == 0 {
+		w.changes.SetText("No changes")
+	} else {
+		w.changes.SetText(p.Sprintf(
+			"%d added, %d removed, %d modified",
+			d.Count(jsondocument.Added),
+			d.Count(jsondocument.Removed),
+			d.Count(jsondocument.Modified),
+		))
+	}
+	w.changes.Show()
+	if len(d.Changes) == 0 {
+		w.prevChange.Hide()
+		w.nextChange.Hide()
+	} else {
+		w.prevChange.Show()
+		w.nextChange.Show()
+	}
+}
+
+// setChangePosition shows the position of the currently selected change.
+func (w *statusBar) setChangePosition(current, total int) {
+	p := message.NewPrinter(language.English)
+	w.changes.SetToolTip(p.Sprintf("Showing change %d of %d", current, total))
 }
 
 func (w *statusBar) set(size int) {
@@ -71,6 +143,14 @@ func (w *statusBar) set(size int) {
 }
 
 func (w *statusBar) CreateRenderer() fyne.WidgetRenderer {
-	c := container.NewHBox(w.elementsCount, layout.NewSpacer(), w.updateLink)
+	c := container.NewHBox(
+		w.elementsCount,
+		w.index,
+		layout.NewSpacer(),
+		w.changes,
+		w.prevChange,
+		w.nextChange,
+		w.updateLink,
+	)
 	return widget.NewSimpleRenderer(c)
 }
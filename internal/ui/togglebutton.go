@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2/widget"
+	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+)
+
+// toggleButton is a button which is switched on and off when tapped.
+// It is shown with high importance while switched on.
+type toggleButton struct {
+	ttwidget.Button
+
+	OnChanged func(on bool)
+
+	on bool
+}
+
+// newToggleButton returns a new instance of the [toggleButton] widget.
+func newToggleButton(text, toolTip string, changed func(on bool)) *toggleButton {
+	w := &toggleButton{OnChanged: changed}
+	w.Text = text
+	w.OnTapped = func() {
+		w.SetOn(!w.on)
+	}
+	w.ExtendBaseWidget(w)
+	w.SetToolTip(toolTip)
+	return w
+}
+
+// IsOn reports whether the button is switched on.
+func (w *toggleButton) IsOn() bool {
+	return w.on
+}
+
+// SetOn switches the button on or off.
+func (w *toggleButton) SetOn(on bool) {
+	if w.on == on {
+		return
+	}
+	w.on = on
+	if on {
+		w.Importance = widget.HighImportance
+	} else {
+		w.Importance = widget.MediumImportance
+	}
+	w.Refresh()
+	if w.OnChanged != nil {
+		w.OnChanged(on)
+	}
+}
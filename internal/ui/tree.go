@@ -2,17 +2,26 @@ package ui
 
 import (
 	"fmt"
+	"image/color"
 	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
 	"github.com/ErikKalkoken/janice/internal/jsondocument"
 )
 
 // jsonTree shows a JSON document in a tree structure.
 type jsonTree struct {
 	widget.Tree
-	u *UI
+	filter     *jsondocument.Filter // when set only the nodes of the filter are shown
+	shift      bool
+	u          *UI
+	unfiltered treeState // state of the tree before the filter was set
 }
 
 func newJSONTree(u *UI) *jsonTree {
@@ -20,9 +29,15 @@ func newJSONTree(u *UI) *jsonTree {
 	w.ExtendBaseWidget(w)
 
 	w.ChildUIDs = func(id widget.TreeNodeID) []widget.TreeNodeID {
+		if w.filter != nil {
+			return w.filter.ChildUIDs(id)
+		}
 		return u.document.ChildUIDs(id)
 	}
 	w.IsBranch = func(id widget.TreeNodeID) bool {
+		if w.filter != nil {
+			return w.filter.IsBranch(id)
+		}
 		return u.document.IsBranch(id)
 	}
 	w.CreateNode = func(branch bool) fyne.CanvasObject {
@@ -31,41 +46,19 @@ func newJSONTree(u *UI) *jsonTree {
 	w.UpdateNode = func(uid widget.TreeNodeID, branch bool, co fyne.CanvasObject) {
 		node := u.document.Value(uid)
 		obj := co.(*treeNode)
-		var text string
-		switch v := node.Value; node.Type {
-		case jsondocument.Array:
-			if branch {
-				if t := u.tree; t != nil && t.IsBranchOpen(uid) {
-					text = ""
-				} else {
-					text = "[...]"
-				}
-			} else {
-				text = "[]"
-			}
-		case jsondocument.Object:
-			if branch {
-				if t := u.tree; t != nil && t.IsBranchOpen(uid) {
-					text = ""
-				} else {
-					text = "{...}"
-				}
-			} else {
-				text = "{}"
+		isOpen := branch && u.tree != nil && u.tree.IsBranchOpen(uid)
+		text := nodeText(node, branch, isOpen)
+		if w.filter != nil && branch {
+			if n := w.filter.HiddenCount(uid); n > 0 {
+				p := message.NewPrinter(language.English)
+				text = strings.TrimSpace(p.Sprintf("%s (%d hidden)", text, n))
 			}
-		case jsondocument.String:
-			text = fmt.Sprintf("\"%s\"", v)
-		case jsondocument.Number:
-			x := v.(float64)
-			text = strconv.FormatFloat(x, 'f', -1, 64)
-		case jsondocument.Boolean:
-			text = fmt.Sprintf("%v", v)
-		case jsondocument.Null:
-			text = "null"
-		default:
-			text = fmt.Sprintf("%v", v)
 		}
 		obj.set(node.Key, text, type2importance[node.Type])
+		obj.setHighlight(w.highlightColor(uid))
+		obj.onSecondaryTap = func(pos fyne.Position) {
+			w.showContextMenu(uid, pos)
+		}
 	}
 	w.OnSelected = func(uid widget.TreeNodeID) {
 		u.selectElement(uid)
@@ -73,14 +66,247 @@ func newJSONTree(u *UI) *jsonTree {
 	return w
 }
 
+// newDocumentTree returns a plain tree for showing a JSON document in secondary windows.
+// The document is fetched on every update, so that it can be exchanged.
+// Nodes are highlighted according to their status, which can be nil.
+func newDocumentTree(document func() *jsondocument.JSONDocument, status func(uid widget.TreeNodeID) jsondocument.ChangeType) *widget.Tree {
+	t := &widget.Tree{}
+	t.ExtendBaseWidget(t)
+	initDocumentTree(t, document, status)
+	return t
+}
+
+// initDocumentTree sets up a tree for showing a JSON document like [newDocumentTree].
+// This allows to show documents with widgets which extend the tree.
+func initDocumentTree(t *widget.Tree, document func() *jsondocument.JSONDocument, status func(uid widget.TreeNodeID) jsondocument.ChangeType) {
+	t.ChildUIDs = func(uid widget.TreeNodeID) []widget.TreeNodeID {
+		return document().ChildUIDs(uid)
+	}
+	t.IsBranch = func(uid widget.TreeNodeID) bool {
+		return document().IsBranch(uid)
+	}
+	t.CreateNode = func(branch bool) fyne.CanvasObject {
+		return newTreeNode()
+	}
+	t.UpdateNode = func(uid widget.TreeNodeID, branch bool, co fyne.CanvasObject) {
+		node := document().Value(uid)
+		obj := co.(*treeNode)
+		isOpen := branch && t.IsBranchOpen(uid)
+		obj.set(node.Key, nodeText(node, branch, isOpen), type2importance[node.Type])
+		if status != nil {
+			obj.setHighlight(changeColor(status(uid)))
+		}
+	}
+}
+
+// nodeText returns the text for showing the value of a node in a tree.
+func nodeText(node jsondocument.Node, branch, isOpen bool) string {
+	var text string
+	switch v := node.Value; node.Type {
+	case jsondocument.Array:
+		if branch {
+			if isOpen {
+				text = ""
+			} else {
+				text = "[...]"
+			}
+		} else {
+			text = "[]"
+		}
+	case jsondocument.Object:
+		if branch {
+			if isOpen {
+				text = ""
+			} else {
+				text = "{...}"
+			}
+		} else {
+			text = "{}"
+		}
+	case jsondocument.String:
+		text = fmt.Sprintf("\"%s\"", v)
+	case jsondocument.Number:
+		x := v.(float64)
+		text = strconv.FormatFloat(x, 'f', -1, 64)
+	case jsondocument.Boolean:
+		text = fmt.Sprintf("%v", v)
+	case jsondocument.Null:
+		text = "null"
+	default:
+		text = fmt.Sprintf("%v", v)
+	}
+	return text
+}
+
+// highlightColor returns the background color for a node or nil if it has none.
+func (w *jsonTree) highlightColor(uid widget.TreeNodeID) color.Color {
+	if w.u.searchBar.isMatch(uid) || w.filter != nil && w.filter.IsMatch(uid) {
+		return withAlpha(theme.Color(theme.ColorNamePrimary), 0x50)
+	}
+	if d := w.u.diff; d != nil {
+		if c := changeColor(d.StatusB(uid)); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+func (w *jsonTree) KeyDown(key *fyne.KeyEvent) {
+	if isShiftKey(key.Name) {
+		w.shift = true
+	}
+}
+
+func (w *jsonTree) KeyUp(key *fyne.KeyEvent) {
+	if isShiftKey(key.Name) {
+		w.shift = false
+	}
+}
+
+func (w *jsonTree) TypedKey(key *fyne.KeyEvent) {
+	if w.u.searchBar.handleFindKey(key, w.shift) {
+		return
+	}
+	w.Tree.TypedKey(key)
+}
+
+// showContextMenu selects a node and shows a menu with actions for it.
+func (w *jsonTree) showContextMenu(uid widget.TreeNodeID, pos fyne.Position) {
+	u := w.u
+	w.Select(uid)
+	copyKey := fyne.NewMenuItem("Copy key", func() {
+		u.app.Clipboard().SetContent(u.document.Value(uid).Key)
+	})
+	copyKey.Icon = theme.ContentCopyIcon()
+	copyPath := fyne.NewMenuItem("Copy path as", nil)
+	copyPath.ChildMenu = fyne.NewMenu("", u.copyPathMenuItems(uid)...)
+	items := []*fyne.MenuItem{copyKey, copyPath, fyne.NewMenuItemSeparator()}
+	items = append(items, u.editMenuItems(uid)...)
+	m := fyne.NewMenu("", items...)
+	widget.ShowPopUpMenuAtPosition(m, u.window.Canvas(), pos)
+}
+
 func (w *jsonTree) scrollTo(uid widget.TreeNodeID) {
 	if uid == "" {
 		return
 	}
 	p := w.u.document.Path(uid)
 	for _, uid2 := range p {
-		w.OpenBranch(uid2)
+		if !w.IsBranchOpen(uid2) {
+			w.OpenBranch(uid2)
+		}
 	}
 	w.ScrollTo(uid)
 	w.Select(uid)
 }
+
+// refreshBranch refreshes a branch and its children, e.g. after children were added or removed.
+// The children of a closed branch are not shown and therefore not refreshed.
+func (w *jsonTree) refreshBranch(uid widget.TreeNodeID) {
+	if uid != "" {
+		w.RefreshItem(uid)
+		if !w.IsBranchOpen(uid) {
+			return
+		}
+	}
+	for _, uid2 := range w.ChildUIDs(uid) {
+		w.RefreshItem(uid2)
+	}
+}
+
+// treeState represents the expanded branches and the selection of a tree.
+// Nodes are identified by their key paths, so that a state can be restored
+// after a document was reloaded.
+type treeState struct {
+	open     [][]string
+	selected []string
+}
+
+// state returns the current state of the tree.
+// When a filter is set, it returns the state from before the filter with the current selection.
+func (w *jsonTree) state() treeState {
+	var s treeState
+	doc := w.u.document
+	if w.filter != nil {
+		s = w.unfiltered
+		if uid := w.u.selection.selectedUID; uid != "" {
+			s.selected = doc.KeyPath(uid)
+		}
+		return s
+	}
+	var walk func(uid widget.TreeNodeID)
+	walk = func(uid widget.TreeNodeID) {
+		for _, uid2 := range doc.ChildUIDs(uid) {
+			if doc.IsBranch(uid2) && w.IsBranchOpen(uid2) {
+				s.open = append(s.open, doc.KeyPath(uid2))
+				walk(uid2)
+			}
+		}
+	}
+	walk("")
+	if uid := w.u.selection.selectedUID; uid != "" {
+		s.selected = doc.KeyPath(uid)
+	}
+	return s
+}
+
+// restoreState restores a tree state for all paths which still exist.
+func (w *jsonTree) restoreState(s treeState) {
+	doc := w.u.document
+	w.CloseAllBranches()
+	for _, keys := range s.open {
+		uid, err := doc.FindKeyPath(keys)
+		if err != nil {
+			continue
+		}
+		w.OpenBranch(uid)
+	}
+	if s.selected == nil {
+		return
+	}
+	uid, err := doc.FindKeyPath(s.selected)
+	if err != nil {
+		return
+	}
+	w.scrollTo(uid)
+}
+
+// setFilter shows only the nodes of a filter and expands the ancestors of all matches.
+// The state of the tree from before is restored when the filter is cleared.
+func (w *jsonTree) setFilter(f *jsondocument.Filter) {
+	if w.filter == nil {
+		w.unfiltered = w.state()
+	}
+	w.filter = f
+	w.CloseAllBranches()
+	var open func(uid widget.TreeNodeID)
+	open = func(uid widget.TreeNodeID) {
+		for _, uid2 := range f.ChildUIDs(uid) {
+			if f.IsBranch(uid2) && !f.IsMatch(uid2) {
+				w.OpenBranch(uid2)
+				open(uid2)
+			}
+		}
+	}
+	open("")
+	w.Refresh()
+}
+
+// clearFilter removes the filter and restores the state of the tree from before it was set.
+// The current selection is kept.
+func (w *jsonTree) clearFilter() {
+	if w.filter == nil {
+		return
+	}
+	s := w.state()
+	w.resetFilter()
+	w.restoreState(s)
+}
+
+// resetFilter removes the filter without restoring the previous state,
+// e.g. after a new document was loaded.
+func (w *jsonTree) resetFilter() {
+	w.filter = nil
+	w.unfiltered = treeState{}
+	w.Refresh()
+}
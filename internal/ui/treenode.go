@@ -2,9 +2,11 @@ package ui
 
 import (
 	"fmt"
+	"image/color"
 	"strings"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 )
@@ -13,15 +15,18 @@ import (
 type treeNode struct {
 	widget.BaseWidget
 
-	key   *widget.Label
-	value *widget.Label
+	background     *canvas.Rectangle
+	key            *widget.Label
+	onSecondaryTap func(pos fyne.Position)
+	value          *widget.Label
 }
 
 // newTreeNode returns a new instance of the [treeNode] widget.
 func newTreeNode() *treeNode {
 	w := &treeNode{
-		key:   widget.NewLabel(""),
-		value: widget.NewLabel(""),
+		background: canvas.NewRectangle(color.Transparent),
+		key:        widget.NewLabel(""),
+		value:      widget.NewLabel(""),
 	}
 	w.ExtendBaseWidget(w)
 	return w
@@ -35,7 +40,29 @@ func (w *treeNode) set(key string, value string, importance widget.Importance) {
 	w.value.Truncation = fyne.TextTruncateEllipsis
 }
 
+// setHighlight sets the background color of a node. Nil removes the highlight.
+func (w *treeNode) setHighlight(c color.Color) {
+	if c == nil {
+		c = color.Transparent
+	}
+	if w.background.FillColor == c {
+		return
+	}
+	w.background.FillColor = c
+	w.background.Refresh()
+}
+
+// TappedSecondary shows the context menu for a node if one was defined.
+func (w *treeNode) TappedSecondary(pe *fyne.PointEvent) {
+	if w.onSecondaryTap != nil {
+		w.onSecondaryTap(pe.AbsolutePosition)
+	}
+}
+
 func (w *treeNode) CreateRenderer() fyne.WidgetRenderer {
-	c := container.NewBorder(nil, nil, w.key, nil, w.value)
+	c := container.NewStack(
+		w.background,
+		container.NewBorder(nil, nil, w.key, nil, w.value),
+	)
 	return widget.NewSimpleRenderer(c)
 }
@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -28,6 +29,7 @@ import (
 	"golang.org/x/text/message"
 
 	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/ErikKalkoken/janice/internal/remote"
 )
 
 const (
@@ -41,36 +43,71 @@ const (
 // preference keys
 const (
 	preferenceLastDetailShown    = "last-value-frame-shown"
+	preferenceLastQueryLanguage  = "last-query-language"
+	preferenceLastQueryShown     = "last-query-frame-shown"
 	preferenceLastSelectionShown = "last-selection-frame-shown"
 	preferenceLastWindowHeight   = "last-window-height"
 	preferenceLastWindowWidth    = "last-window-width"
+	preferenceSavedSearches      = "saved-searches"
+	preferenceSearchHistory      = "search-history"
 )
 
 // setting keys and defaults
 const (
-	settingColorTheme             = "color-theme"
-	settingExtensionDefault       = true
-	settingExtensionFilter        = "extension-filter"
-	settingNotifyUpdates          = "notify-updates"
-	settingNotifyUpdatesDefault   = true
-	settingRecentFileCount        = "recent-file-count"
-	settingRecentFileCountDefault = 5
+	settingColorTheme              = "color-theme"
+	settingExtensionDefault        = true
+	settingExtensionFilter         = "extension-filter"
+	settingHighlightChanges        = "highlight-changes"
+	settingHighlightChangesDefault = true
+	settingNotifyUpdates           = "notify-updates"
+	settingNotifyUpdatesDefault    = true
+	settingRecentFileCount         = "recent-file-count"
+	settingRecentFileCountDefault  = 5
+	settingSaveIndent              = "save-indent"
+	settingSaveIndentDefault       = saveIndentTwoSpaces
+	settingSaveKeyOrder            = "save-key-order"
+	settingSaveKeyOrderDefault     = saveKeyOrderDocument
+	settingSearchIndex             = "search-index"
+	settingSearchIndexDefault      = false
 )
 
 // UI represents the user interface of this app.
 type UI struct {
 	app                 fyne.App
 	currentFile         fyne.URI
+	currentRequest      *remote.Request
 	detail              *detail
+	diff                *jsondocument.Diff
+	diffIndex           int
+	diffUIDs            []widget.TreeNodeID
 	document            *jsondocument.JSONDocument
+	editRecent          *fyne.MenuItem
+	editRedo            *fyne.MenuItem
+	editReplace         *fyne.MenuItem
+	editUndo            *fyne.MenuItem
+	fileApplyPatch      *fyne.MenuItem
 	fileExportClipboard *fyne.MenuItem
 	fileExportFile      *fyne.MenuItem
+	fileName            string // name of the current file as shown in the title
 	fileNew             *fyne.MenuItem
 	fileOpenRecent      *fyne.MenuItem
 	fileReload          *fyne.MenuItem
+	fileSave            *fyne.MenuItem
+	fileSaveAs          *fyne.MenuItem
+	fileTail            *fyne.MenuItem
+	fileWatch           *fyne.MenuItem
 	goBottom            *fyne.MenuItem
+	goFindNext          *fyne.MenuItem
+	goFindPrevious      *fyne.MenuItem
+	goPath              *fyne.MenuItem
+	goNextChange        *fyne.MenuItem
+	goPrevChange        *fyne.MenuItem
 	goSelection         *fyne.MenuItem
 	goTop               *fyne.MenuItem
+	indexCancel         context.CancelFunc
+	jobs                backgroundJobs
+	queryBar            *queryBar
+	results             *resultsPanel
 	searchBar           *searchBar
 	selection           *selection
 	statusBar           *statusBar
@@ -78,7 +115,9 @@ type UI struct {
 	viewCollapseAll     *fyne.MenuItem
 	viewExpandAll       *fyne.MenuItem
 	viewShowDetail      *fyne.MenuItem
+	viewShowQuery       *fyne.MenuItem
 	viewShowSelection   *fyne.MenuItem
+	watcher             *fileWatcher
 	welcomeMessage      *fyne.Container
 	window              fyne.Window
 }
@@ -104,10 +143,13 @@ func NewUI(app fyne.App) (*UI, error) {
 	u.welcomeMessage = container.NewCenter(welcomeText)
 
 	u.detail = newDetail(u)
+	u.queryBar = newQueryBar(u)
+	u.results = newResultsPanel(u)
 	u.searchBar = newSearchBar(u)
 	u.selection = newSelection(u)
 	u.statusBar = newStatusBar(u)
 	u.tree = newJSONTree(u)
+	u.watcher = newFileWatcher(u)
 
 	if u.app.Preferences().BoolWithFallback(preferenceLastSelectionShown, false) {
 		u.selection.Show()
@@ -119,16 +161,22 @@ func NewUI(app fyne.App) (*UI, error) {
 	} else {
 		u.detail.Hide()
 	}
+	if u.app.Preferences().BoolWithFallback(preferenceLastQueryShown, false) {
+		u.queryBar.Show()
+	} else {
+		u.queryBar.Hide()
+	}
 
 	c := container.NewBorder(
-		container.NewVBox(u.searchBar, u.selection, u.detail, widget.NewSeparator()),
+		container.NewVBox(u.searchBar, u.queryBar, u.selection, u.detail, widget.NewSeparator()),
 		container.NewVBox(widget.NewSeparator(), u.statusBar),
 		nil,
-		nil,
+		u.results,
 		container.NewStack(u.welcomeMessage, u.tree))
 
 	u.window.SetContent(fynetooltip.AddWindowToolTipLayer(c, u.window.Canvas()))
 	u.window.SetMainMenu(u.makeMenu())
+	u.searchBar.addFindKeyHandler(u.window.Canvas())
 	u.toogleHasDocument(false)
 	u.updateRecentFilesMenu()
 	u.window.SetMaster()
@@ -137,23 +185,29 @@ func NewUI(app fyne.App) (*UI, error) {
 			return
 		}
 		uri := uris[0]
-		slog.Info("Loading dropped file", "uri", uri)
-		reader, err := storage.Reader(uri)
-		if err != nil {
-			u.showErrorDialog(fmt.Sprintf("Failed to load file: %s", uri), err)
-			return
-		}
-		u.loadDocument(reader, nil)
+		u.confirmUnsavedChanges(func() {
+			slog.Info("Loading dropped file", "uri", uri)
+			reader, err := storage.Reader(uri)
+			if err != nil {
+				u.showErrorDialog(fmt.Sprintf("Failed to load file: %s", uri), err)
+				return
+			}
+			u.loadDocument(reader, nil)
+		})
 	})
 	s := fyne.Size{
 		Width:  float32(app.Preferences().FloatWithFallback(preferenceLastWindowWidth, 800)),
 		Height: float32(app.Preferences().FloatWithFallback(preferenceLastWindowHeight, 600)),
 	}
 	u.window.Resize(s)
+	u.window.SetCloseIntercept(func() {
+		u.confirmUnsavedChanges(u.window.Close)
+	})
 	u.window.SetOnClosed(func() {
 		app.Preferences().SetFloat(preferenceLastWindowWidth, float64(u.window.Canvas().Size().Width))
 		app.Preferences().SetFloat(preferenceLastWindowHeight, float64(u.window.Canvas().Size().Height))
 		app.Preferences().SetBool(preferenceLastDetailShown, !u.detail.Hidden)
+		app.Preferences().SetBool(preferenceLastQueryShown, !u.queryBar.Hidden)
 		app.Preferences().SetBool(preferenceLastSelectionShown, !u.selection.Hidden)
 	})
 	return u, nil
@@ -161,6 +215,7 @@ func NewUI(app fyne.App) (*UI, error) {
 
 func (u *UI) selectElement(uid string) {
 	u.selection.set(uid)
+	u.searchBar.updateMatchCount()
 	u.selection.enable()
 	u.detail.set(uid)
 	u.fileExportFile.Disabled = false
@@ -191,19 +246,35 @@ func (u *UI) ShowAndRun(path string) {
 }
 
 func (u *UI) showErrorDialog(message string, err error) {
+	showErrorDialog(u.window, message, err)
+}
+
+// showErrorDialog shows an error message in a window and logs the error.
+func showErrorDialog(w fyne.Window, message string, err error) {
 	if err != nil {
 		slog.Error(message, "err", err)
 	}
-	d := dialog.NewInformation("Error", message, u.window)
-	kxdialog.AddDialogKeyHandler(d, u.window)
+	d := dialog.NewInformation("Error", message, w)
+	kxdialog.AddDialogKeyHandler(d, w)
 	d.Show()
 }
 
 func (u *UI) setTitle(fileName string) {
+	u.fileName = fileName
+	u.updateTitle()
+}
+
+// updateTitle shows the name of the current file in the window title
+// and whether the document was modified.
+func (u *UI) updateTitle() {
 	var s string
 	name := u.app.Metadata().Name
-	if fileName != "" {
-		s = fmt.Sprintf("%s - %s", fileName, name)
+	if u.fileName != "" {
+		if u.document.IsModified() {
+			s = fmt.Sprintf("%s (modified) - %s", u.fileName, name)
+		} else {
+			s = fmt.Sprintf("%s - %s", u.fileName, name)
+		}
 	} else {
 		s = name
 	}
@@ -213,10 +284,88 @@ func (u *UI) setTitle(fileName string) {
 // loadDocument loads a JSON file
 // Shows a loader modal while loading
 func (u *UI) loadDocument(reader fyne.URIReadCloser, completed func()) {
+	open := func(context.Context) (fyne.URIReadCloser, error) {
+		return reader, nil
+	}
+	u.loadDocumentFrom(reader.URI(), open, nil, completed)
+}
+
+// loadURL loads a JSON document from a HTTP(S) URL.
+// Shows a loader modal while loading
+func (u *UI) loadURL(req remote.Request, completed func()) {
+	uri, err := storage.ParseURI(req.URL)
+	if err != nil {
+		u.showErrorDialog(fmt.Sprintf("Not a valid URL: %s", req.URL), err)
+		return
+	}
+	open := func(ctx context.Context) (fyne.URIReadCloser, error) {
+		return remote.Open(ctx, req)
+	}
+	u.loadDocumentFrom(uri, open, &req, completed)
+}
+
+// loadDocumentFrom loads a JSON document from the reader returned by open
+// and makes it the current document.
+// req is the request for documents fetched from a URL and nil otherwise.
+// Shows a loader modal while loading
+func (u *UI) loadDocumentFrom(uri fyne.URI, open func(ctx context.Context) (fyne.URIReadCloser, error), req *remote.Request, completed func()) {
+	showLoader(u.window, uri, open, func(doc *jsondocument.JSONDocument) {
+		u.document = doc
+		u.statusBar.set(u.document.Size())
+		u.buildSearchIndex(nil)
+		u.welcomeMessage.Hide()
+		u.toogleHasDocument(true)
+		if doc.Size() > 1000 {
+			u.viewExpandAll.Disabled = true
+		} else {
+			u.viewExpandAll.Disabled = false
+		}
+		u.window.MainMenu().Refresh()
+		u.tree.Refresh()
+		if uri.Scheme() == "file" || isRemoteURI(uri) {
+			u.addRecentFile(uri)
+		}
+		u.setTitle(uri.Name())
+		u.updateEditMenu()
+		u.currentFile = uri
+		u.currentRequest = req
+		u.watcher.documentLoaded()
+		u.fileWatch.Disabled = !isWatchable(uri)
+		u.tree.UnselectAll()
+		u.setDiff(nil)
+		u.selection.reset()
+		u.detail.reset()
+		u.queryBar.reset()
+		u.searchBar.reset()
+		u.results.clear()
+	}, completed)
+}
+
+// showLoader loads a JSON document from the reader returned by open.
+// Shows a loader modal in window w while loading and calls loaded with the new document.
+// completed is called after the modal was closed, also when loading failed.
+func showLoader(w fyne.Window, uri fyne.URI, open func(ctx context.Context) (fyne.URIReadCloser, error), loaded func(doc *jsondocument.JSONDocument), completed func()) {
+	name := uri.Name()
+	isRemote := isRemoteURI(uri)
 	infoText := widget.NewLabel("")
 	pb1 := widget.NewProgressBarInfinite()
 	pb2 := widget.NewProgressBar()
 	pb2.Hide()
+	showProgress := func(p float64) {
+		if pb2.Hidden {
+			pb1.Stop()
+			pb1.Hide()
+			pb2.Show()
+		}
+		pb2.SetValue(p)
+	}
+	showActivity := func() {
+		if pb1.Hidden {
+			pb2.Hide()
+			pb1.Show()
+			pb1.Start()
+		}
+	}
 	progressInfo := binding.NewUntyped()
 	progressInfo.AddListener(binding.NewDataListener(func() {
 		x, err := progressInfo.Get()
@@ -228,29 +377,33 @@ func (u *UI) loadDocument(reader fyne.URIReadCloser, completed func()) {
 		if !ok {
 			return
 		}
-		uri := reader.URI()
-		name := uri.Name()
 		var text string
 		switch info.CurrentStep {
 		case 1:
-			text = fmt.Sprintf("Loading file from disk: %s", name)
+			if isRemote {
+				text = fmt.Sprintf("Downloading document: %s", uri)
+			} else {
+				text = fmt.Sprintf("Loading file from disk: %s", name)
+			}
+			if info.Progress > 0 {
+				showProgress(info.Progress)
+			}
 		case 2:
 			text = fmt.Sprintf("Calculating document size: %s", name)
+			showActivity()
 		case 3:
-			if pb2.Hidden {
-				pb1.Stop()
-				pb1.Hide()
-				pb2.Show()
-			}
 			p := message.NewPrinter(language.English)
 			text = p.Sprintf("Rendering document with %d elements: %s", info.Size, name)
-			pb2.SetValue(info.Progress)
+			showProgress(info.Progress)
 		default:
 			text = "?"
 		}
 		message := fmt.Sprintf("%d / %d: %s", info.CurrentStep, info.TotalSteps, text)
 		infoText.SetText(message)
 	}))
+	if isRemote {
+		infoText.SetText(fmt.Sprintf("Connecting to %s", uri.Authority()))
+	}
 	ctx, cancel := context.WithCancel(context.TODO())
 	b := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
 		cancel()
@@ -259,18 +412,28 @@ func (u *UI) loadDocument(reader fyne.URIReadCloser, completed func()) {
 		infoText,
 		container.NewBorder(nil, nil, nil, b, container.NewStack(pb1, pb2)),
 	)
-	d2 := dialog.NewCustomWithoutButtons("Loading", c, u.window)
+	d2 := dialog.NewCustomWithoutButtons("Loading", c, w)
 	d2.SetOnClosed(func() {
 		cancel()
 		if completed != nil {
 			completed()
 		}
 	})
-	kxdialog.AddDialogKeyHandler(d2, u.window)
+	kxdialog.AddDialogKeyHandler(d2, w)
 	d2.Show()
 	go func() {
 		doc := jsondocument.New()
-		if err := doc.Load(ctx, reader, progressInfo); err != nil {
+		err := func() error {
+			reader, err := open(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return jsondocument.ErrCallerCanceled
+				}
+				return err
+			}
+			return doc.Load(ctx, reader, progressInfo)
+		}()
+		if err != nil {
 			fyne.Do(func() {
 				d2.Hide()
 			})
@@ -278,30 +441,12 @@ func (u *UI) loadDocument(reader fyne.URIReadCloser, completed func()) {
 				return
 			}
 			fyne.Do(func() {
-				u.showErrorDialog(fmt.Sprintf("Failed to open document: %s", reader.URI()), err)
+				showErrorDialog(w, fmt.Sprintf("Failed to open document: %s", uri), err)
 			})
 			return
 		}
 		fyne.Do(func() {
-			u.document = doc
-			u.statusBar.set(u.document.Size())
-			u.welcomeMessage.Hide()
-			u.toogleHasDocument(true)
-			if doc.Size() > 1000 {
-				u.viewExpandAll.Disabled = true
-			} else {
-				u.viewExpandAll.Disabled = false
-			}
-			u.window.MainMenu().Refresh()
-			u.tree.Refresh()
-			uri := reader.URI()
-			if uri.Scheme() == "file" {
-				u.addRecentFile(uri)
-			}
-			u.setTitle(uri.Name())
-			u.currentFile = uri
-			u.selection.reset()
-			u.detail.reset()
+			loaded(doc)
 			d2.Hide()
 		})
 	}()
@@ -310,11 +455,20 @@ func (u *UI) loadDocument(reader fyne.URIReadCloser, completed func()) {
 func (u *UI) toogleHasDocument(enabled bool) {
 	if enabled {
 		u.searchBar.enable()
+		u.queryBar.enable()
+		u.editReplace.Disabled = false
+		u.fileApplyPatch.Disabled = false
 		u.fileExportClipboard.Disabled = false
 		u.fileExportFile.Disabled = u.selection.selectedUID == ""
 		u.fileNew.Disabled = false
 		u.fileReload.Disabled = false
+		u.fileSave.Disabled = false
+		u.fileSaveAs.Disabled = false
+		u.fileWatch.Disabled = !isWatchable(u.currentFile)
 		u.goBottom.Disabled = false
+		u.goFindNext.Disabled = false
+		u.goFindPrevious.Disabled = false
+		u.goPath.Disabled = false
 		u.goSelection.Disabled = false
 		u.goTop.Disabled = false
 		u.viewCollapseAll.Disabled = false
@@ -322,11 +476,20 @@ func (u *UI) toogleHasDocument(enabled bool) {
 
 	} else {
 		u.searchBar.disable()
+		u.queryBar.disable()
+		u.editReplace.Disabled = true
+		u.fileApplyPatch.Disabled = true
 		u.fileExportClipboard.Disabled = true
 		u.fileExportFile.Disabled = true
 		u.fileNew.Disabled = true
 		u.fileReload.Disabled = true
+		u.fileSave.Disabled = true
+		u.fileSaveAs.Disabled = true
+		u.fileWatch.Disabled = true
 		u.goBottom.Disabled = true
+		u.goFindNext.Disabled = true
+		u.goFindPrevious.Disabled = true
+		u.goPath.Disabled = true
 		u.goSelection.Disabled = true
 		u.goTop.Disabled = true
 		u.viewCollapseAll.Disabled = true
@@ -389,6 +552,25 @@ func (u *UI) showSettingsDialog() {
 	z := u.app.Preferences().BoolWithFallback(settingNotifyUpdates, settingNotifyUpdatesDefault)
 	notifyUpdates.SetOn(z)
 
+	highlightChanges := kxwidget.NewSwitch(func(v bool) {
+		u.app.Preferences().SetBool(settingHighlightChanges, v)
+	})
+	highlightChanges.SetOn(u.app.Preferences().BoolWithFallback(settingHighlightChanges, settingHighlightChangesDefault))
+
+	searchIndex := kxwidget.NewSwitch(func(v bool) {
+		u.app.Preferences().SetBool(settingSearchIndex, v)
+		if u.document.Size() == 0 {
+			return
+		}
+		if v {
+			u.buildSearchIndex(nil)
+		} else {
+			u.cancelSearchIndex()
+			u.document.DropIndex()
+		}
+	})
+	searchIndex.SetOn(u.app.Preferences().BoolWithFallback(settingSearchIndex, settingSearchIndexDefault))
+
 	// theme
 	theme := widget.NewRadioGroup([]string{colorThemeAuto, colorThemeLight, colorThemeDark}, func(s string) {
 		u.setColorTheme(s)
@@ -404,6 +586,14 @@ func (u *UI) showSettingsDialog() {
 			Text: "JSON file filter", Widget: extFilter,
 			HintText: "Wether to show files with .json extension only",
 		},
+		{
+			Text: "Highlight changes", Widget: highlightChanges,
+			HintText: "Wether to highlight what changed when a document is reloaded",
+		},
+		{
+			Text: "Search index", Widget: searchIndex,
+			HintText: "Wether to build an index after loading, which speeds up searches but needs more memory",
+		},
 		{
 			Text:   "Notify about updates",
 			Widget: notifyUpdates, HintText: "Wether to notify when an update is available (requires restart)",
@@ -420,7 +610,9 @@ func (u *UI) showSettingsDialog() {
 
 func (u *UI) makeMenu() *fyne.MainMenu {
 	// File menu
-	u.fileNew = fyne.NewMenuItem("New", u.newFile)
+	u.fileNew = fyne.NewMenuItem("New", func() {
+		u.confirmUnsavedChanges(u.newFile)
+	})
 	u.fileNew.Shortcut = mustMakeShortCut("fileNew", runtime.GOOS)
 	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.fileNew))
 
@@ -431,16 +623,51 @@ func (u *UI) makeMenu() *fyne.MainMenu {
 	fileSettings.Shortcut = mustMakeShortCut("fileSettings", runtime.GOOS)
 	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(fileSettings))
 
-	u.fileReload = fyne.NewMenuItem("Reload", u.reloadFile)
+	u.fileReload = fyne.NewMenuItem("Reload", func() {
+		u.confirmUnsavedChanges(u.reloadFile)
+	})
 	u.fileReload.Shortcut = mustMakeShortCut("fileReload", runtime.GOOS)
 	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.fileReload))
 
-	fileOpen := fyne.NewMenuItem("Open File...", u.openFile)
+	u.fileSave = fyne.NewMenuItem("Save", func() {
+		u.saveFile(nil)
+	})
+	u.fileSave.Shortcut = mustMakeShortCut("fileSave", runtime.GOOS)
+	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.fileSave))
+
+	u.fileSaveAs = fyne.NewMenuItem("Save As...", func() {
+		u.showSaveAsDialog(nil)
+	})
+	u.fileSaveAs.Shortcut = mustMakeShortCut("fileSaveAs", runtime.GOOS)
+	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.fileSaveAs))
+
+	fileOpen := fyne.NewMenuItem("Open File...", func() {
+		u.confirmUnsavedChanges(u.openFile)
+	})
 	fileOpen.Shortcut = mustMakeShortCut("fileOpen", runtime.GOOS)
 	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(fileOpen))
 
+	u.fileWatch = fyne.NewMenuItem("Watch For Changes", u.toggleWatchFile)
+	u.fileTail = fyne.NewMenuItem("Tail Mode", u.toggleTailFile)
+	u.fileTail.Disabled = true
+
+	fileOpenURL := fyne.NewMenuItem("Open URL...", func() {
+		var req remote.Request
+		if u.currentRequest != nil {
+			req = *u.currentRequest
+		}
+		u.confirmUnsavedChanges(func() {
+			u.showOpenURLDialog(req)
+		})
+	})
+
+	fileCompare := fyne.NewMenuItem("Compare Documents...", func() {
+		u.showCompareWindow(nil)
+	})
+	u.fileApplyPatch = fyne.NewMenuItem("Apply Patch...", u.showApplyPatchDialog)
+
 	fileQuit := fyne.NewMenuItem("Exit", func() {
-		u.app.Quit()
+		u.confirmUnsavedChanges(u.app.Quit)
 	})
 	fileQuit.IsQuit = true
 	fileQuit.Shortcut = mustMakeShortCut("fileQuit", runtime.GOOS)
@@ -482,11 +709,22 @@ func (u *UI) makeMenu() *fyne.MainMenu {
 		fileOpen,
 		u.fileOpenRecent,
 		fyne.NewMenuItem("Open From Clipboard", func() {
-			r := strings.NewReader(u.app.Clipboard().Content())
-			reader := jsondocument.MakeURIReadCloser(r, "CLIPBOARD")
-			u.loadDocument(reader, nil)
+			u.confirmUnsavedChanges(func() {
+				r := strings.NewReader(u.app.Clipboard().Content())
+				reader := jsondocument.MakeURIReadCloser(r, "CLIPBOARD")
+				u.loadDocument(reader, nil)
+			})
 		}),
+		fileOpenURL,
 		u.fileReload,
+		u.fileWatch,
+		u.fileTail,
+		fyne.NewMenuItemSeparator(),
+		u.fileSave,
+		u.fileSaveAs,
+		fyne.NewMenuItemSeparator(),
+		fileCompare,
+		u.fileApplyPatch,
 		fyne.NewMenuItemSeparator(),
 		u.fileExportFile,
 		u.fileExportClipboard,
@@ -495,6 +733,45 @@ func (u *UI) makeMenu() *fyne.MainMenu {
 		fileQuit,
 	)
 
+	// Edit menu
+	u.editUndo = fyne.NewMenuItem("Undo", u.undo)
+	u.editUndo.Shortcut = mustMakeShortCut("editUndo", runtime.GOOS)
+	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.editUndo))
+	u.editUndo.Disabled = true
+
+	u.editRedo = fyne.NewMenuItem("Redo", u.redo)
+	u.editRedo.Shortcut = mustMakeShortCut("editRedo", runtime.GOOS)
+	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.editRedo))
+	u.editRedo.Disabled = true
+	if runtime.GOOS != "darwin" {
+		// also support the redo shortcut common on Linux
+		u.window.Canvas().AddShortcut(&desktop.CustomShortcut{
+			KeyName:  fyne.KeyZ,
+			Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift,
+		}, func(fyne.Shortcut) {
+			if !u.editRedo.Disabled {
+				u.redo()
+			}
+		})
+	}
+
+	u.editRecent = fyne.NewMenuItem("Recent Changes", nil)
+	u.editRecent.ChildMenu = fyne.NewMenu("")
+	u.editRecent.Disabled = true
+
+	u.editReplace = fyne.NewMenuItem("Find and Replace...", u.showReplaceDialog)
+	u.editReplace.Shortcut = mustMakeShortCut("editReplace", runtime.GOOS)
+	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.editReplace))
+
+	editMenu := fyne.NewMenu("Edit",
+		u.editUndo,
+		u.editRedo,
+		fyne.NewMenuItemSeparator(),
+		u.editRecent,
+		fyne.NewMenuItemSeparator(),
+		u.editReplace,
+	)
+
 	// View menu
 	u.viewExpandAll = fyne.NewMenuItem("Expand All", func() {
 		u.tree.OpenAllBranches()
@@ -510,12 +787,17 @@ func (u *UI) makeMenu() *fyne.MainMenu {
 		u.toogleViewDetail()
 	})
 	u.viewShowDetail.Checked = !u.detail.Hidden
+	u.viewShowQuery = fyne.NewMenuItem("Show query bar", func() {
+		u.toogleViewQuery()
+	})
+	u.viewShowQuery.Checked = !u.queryBar.Hidden
 	viewMenu := fyne.NewMenu("View",
 		u.viewExpandAll,
 		u.viewCollapseAll,
 		fyne.NewMenuItemSeparator(),
 		u.viewShowSelection,
 		u.viewShowDetail,
+		u.viewShowQuery,
 	)
 
 	// Go menu
@@ -527,13 +809,50 @@ func (u *UI) makeMenu() *fyne.MainMenu {
 	u.goBottom.Shortcut = mustMakeShortCut("goBottom", runtime.GOOS)
 	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.goBottom))
 
+	u.goPath = fyne.NewMenuItem("Go to path...", u.showGoToPathDialog)
+	u.goPath.Shortcut = mustMakeShortCut("goPath", runtime.GOOS)
+	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.goPath))
+
 	u.goSelection = fyne.NewMenuItem("Go to selection", func() {
 		u.tree.scrollTo(u.selection.selectedUID)
 	})
+
+	// F3 is handled by the search bar, because Fyne only supports shortcuts with modifiers
+	u.goFindNext = fyne.NewMenuItem("Find next", func() {
+		u.searchBar.doSearch(false, nil)
+	})
+	u.goFindNext.Shortcut = mustMakeShortCut("goFindNext", runtime.GOOS)
+
+	u.goFindPrevious = fyne.NewMenuItem("Find previous", func() {
+		u.searchBar.doSearch(true, nil)
+	})
+	u.goFindPrevious.Shortcut = mustMakeShortCut("goFindPrevious", runtime.GOOS)
+
+	u.goNextChange = fyne.NewMenuItem("Go to next change", func() {
+		u.gotoChange(true)
+	})
+	u.goNextChange.Shortcut = mustMakeShortCut("goNextChange", runtime.GOOS)
+	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.goNextChange))
+	u.goNextChange.Disabled = true
+
+	u.goPrevChange = fyne.NewMenuItem("Go to previous change", func() {
+		u.gotoChange(false)
+	})
+	u.goPrevChange.Shortcut = mustMakeShortCut("goPrevChange", runtime.GOOS)
+	u.window.Canvas().AddShortcut(addShortcutFromMenuItem(u.goPrevChange))
+	u.goPrevChange.Disabled = true
+
 	goMenu := fyne.NewMenu("Go",
 		u.goTop,
 		u.goBottom,
 		u.goSelection,
+		u.goPath,
+		fyne.NewMenuItemSeparator(),
+		u.goFindNext,
+		u.goFindPrevious,
+		fyne.NewMenuItemSeparator(),
+		u.goNextChange,
+		u.goPrevChange,
 	)
 
 	// Help menu
@@ -548,7 +867,7 @@ func (u *UI) makeMenu() *fyne.MainMenu {
 		}),
 	)
 
-	main := fyne.NewMainMenu(fileMenu, viewMenu, goMenu, helpMenu)
+	main := fyne.NewMainMenu(fileMenu, editMenu, viewMenu, goMenu, helpMenu)
 	return main
 }
 
@@ -567,32 +886,147 @@ func (u *UI) openFile() {
 	d.Show()
 	filterEnabled := u.app.Preferences().BoolWithFallback(settingExtensionFilter, settingExtensionDefault)
 	if filterEnabled {
-		f := storage.NewExtensionFileFilter([]string{".json"})
+		f := storage.NewExtensionFileFilter([]string{".json", ".ndjson", ".jsonl"})
 		d.SetFilter(f)
 	}
 }
 
 // newFile resets the app to it's initial state
 func (u *UI) newFile() {
+	u.cancelSearchIndex()
 	u.document.Reset()
+	u.currentFile = nil
+	u.currentRequest = nil
+	u.watcher.stop()
+	u.setDiff(nil)
 	u.setTitle("")
+	u.updateEditMenu()
 	u.statusBar.reset()
 	u.welcomeMessage.Show()
 	u.toogleHasDocument(false)
 	u.selection.reset()
 	u.detail.reset()
+	u.queryBar.reset()
+	u.searchBar.reset()
+	u.results.clear()
 }
 
 func (u *UI) reloadFile() {
+	u.reload(nil)
+}
+
+// reload reloads the current document and restores the expanded branches
+// and the selection where their paths still exist.
+// completed is called after the document and its changes are shown and can be nil.
+func (u *UI) reload(completed func()) {
 	if u.currentFile == nil {
+		if completed != nil {
+			completed()
+		}
+		return
+	}
+	state := u.tree.state()
+	oldDoc := u.document
+	completed2 := func() {
+		if u.document != oldDoc {
+			u.tree.restoreState(state)
+			if u.app.Preferences().BoolWithFallback(settingHighlightChanges, settingHighlightChangesDefault) {
+				u.showChanges(oldDoc, completed)
+				return
+			}
+		}
+		if completed != nil {
+			completed()
+		}
+	}
+	if u.currentRequest != nil {
+		u.loadURL(*u.currentRequest, completed2)
 		return
 	}
 	reader, err := storage.Reader(u.currentFile)
 	if err != nil {
 		u.showErrorDialog("Failed to reload file", err)
+		if completed != nil {
+			completed()
+		}
 		return
 	}
-	u.loadDocument(reader, nil)
+	u.loadDocument(reader, completed2)
+}
+
+// documentAppended updates the UI after new elements were appended to the current document.
+// The search index only needs to be built again, when it was still being built.
+func (u *UI) documentAppended() {
+	u.statusBar.set(u.document.Size())
+	if !u.document.HasIndex() {
+		u.buildSearchIndex(nil)
+	}
+	u.tree.Refresh()
+	if u.selection.selectedUID == "" {
+		u.tree.ScrollToBottom()
+	}
+}
+
+// buildSearchIndex builds the search index for the current document in the background
+// when enabled in the settings.
+// completed is called after the index was built, also when it failed, and can be nil.
+func (u *UI) buildSearchIndex(completed func()) {
+	if completed == nil {
+		completed = func() {}
+	}
+	u.cancelSearchIndex()
+	if !u.app.Preferences().BoolWithFallback(settingSearchIndex, settingSearchIndexDefault) {
+		completed()
+		return
+	}
+	doc := u.document
+	ctx, cancel := context.WithCancel(context.Background())
+	u.indexCancel = cancel
+	u.statusBar.setSearchIndex(true, false)
+	jobCtx, job := u.jobs.start(ctx, completed)
+	go func() {
+		start := time.Now()
+		err := doc.BuildIndex(jobCtx)
+		job.readDone()
+		fyne.Do(func() {
+			if !job.finish() {
+				return
+			}
+			defer completed()
+			if ctx.Err() != nil || u.document != doc {
+				return
+			}
+			u.indexCancel = nil
+			if err != nil {
+				slog.Error("Failed to build search index", "err", err)
+			} else {
+				slog.Info("Built search index", "duration", time.Since(start))
+			}
+			u.statusBar.setSearchIndex(false, doc.HasIndex())
+		})
+	}()
+}
+
+// cancelSearchIndex stops building the search index.
+func (u *UI) cancelSearchIndex() {
+	if u.indexCancel != nil {
+		u.indexCancel()
+		u.indexCancel = nil
+	}
+	u.statusBar.setSearchIndex(false, false)
+}
+
+func (u *UI) toggleWatchFile() {
+	u.watcher.setEnabled(!u.watcher.enabled)
+	u.fileWatch.Checked = u.watcher.enabled
+	u.fileTail.Disabled = !u.watcher.enabled
+	u.window.MainMenu().Refresh()
+}
+
+func (u *UI) toggleTailFile() {
+	u.watcher.tail = !u.watcher.tail
+	u.fileTail.Checked = u.watcher.tail
+	u.window.MainMenu().Refresh()
 }
 
 func (u *UI) extractSelection() ([]byte, error) {
@@ -645,13 +1079,23 @@ func (u *UI) updateRecentFilesMenu() {
 				slog.Error("Failed to parse URI", "URI", f, "err", err)
 				continue
 			}
+			if isRemoteURI(uri) {
+				items[i] = fyne.NewMenuItem(uri.String(), func() {
+					u.confirmUnsavedChanges(func() {
+						u.showOpenURLDialog(remote.Request{URL: uri.String()})
+					})
+				})
+				continue
+			}
 			items[i] = fyne.NewMenuItem(uri.Path(), func() {
-				reader, err := storage.Reader(uri)
-				if err != nil {
-					dialog.ShowError(err, u.window)
-					return
-				}
-				u.loadDocument(reader, nil)
+				u.confirmUnsavedChanges(func() {
+					reader, err := storage.Reader(uri)
+					if err != nil {
+						dialog.ShowError(err, u.window)
+						return
+					}
+					u.loadDocument(reader, nil)
+				})
 			})
 		}
 		u.fileOpenRecent.ChildMenu.Items = items
@@ -679,6 +1123,17 @@ func (u *UI) toogleViewDetail() {
 	u.window.MainMenu().Refresh()
 }
 
+func (u *UI) toogleViewQuery() {
+	if u.queryBar.Hidden {
+		u.queryBar.Show()
+		u.window.Canvas().Focus(u.queryBar.queryEntry)
+	} else {
+		u.queryBar.Hide()
+	}
+	u.viewShowQuery.Checked = !u.queryBar.Hidden
+	u.window.MainMenu().Refresh()
+}
+
 // addShortcutFromMenuItem is a helper for defining shortcuts.
 // It allows to add an already defined shortcut from a menu item to the canvas.
 //
@@ -697,6 +1152,18 @@ func makeShortCut(name, goos string) (*desktop.CustomShortcut, error) {
 		name     fyne.KeyName
 		modifier fyne.KeyModifier
 	}{
+		"editRedo": {
+			"":    {fyne.KeyY, fyne.KeyModifierControl},
+			macOS: {fyne.KeyZ, fyne.KeyModifierSuper | fyne.KeyModifierShift},
+		},
+		"editReplace": {
+			"":    {fyne.KeyH, fyne.KeyModifierControl},
+			macOS: {fyne.KeyF, fyne.KeyModifierSuper | fyne.KeyModifierAlt},
+		},
+		"editUndo": {
+			"":    {fyne.KeyZ, fyne.KeyModifierControl},
+			macOS: {fyne.KeyZ, fyne.KeyModifierSuper},
+		},
 		"fileNew": {
 			"":    {fyne.KeyN, fyne.KeyModifierControl},
 			macOS: {fyne.KeyN, fyne.KeyModifierSuper},
@@ -712,14 +1179,38 @@ func makeShortCut(name, goos string) (*desktop.CustomShortcut, error) {
 		"fileReload": {
 			"": {fyne.KeyR, fyne.KeyModifierAlt},
 		},
+		"fileSave": {
+			"":    {fyne.KeyS, fyne.KeyModifierControl},
+			macOS: {fyne.KeyS, fyne.KeyModifierSuper},
+		},
+		"fileSaveAs": {
+			"":    {fyne.KeyS, fyne.KeyModifierControl | fyne.KeyModifierShift},
+			macOS: {fyne.KeyS, fyne.KeyModifierSuper | fyne.KeyModifierShift},
+		},
 		"fileSettings": {
 			"":    {fyne.KeyComma, fyne.KeyModifierControl},
 			macOS: {fyne.KeyComma, fyne.KeyModifierSuper},
 		},
+		"goFindNext": {
+			"": {fyne.KeyF3, 0},
+		},
+		"goFindPrevious": {
+			"": {fyne.KeyF3, fyne.KeyModifierShift},
+		},
+		"goNextChange": {
+			"": {fyne.KeyDown, fyne.KeyModifierAlt},
+		},
+		"goPrevChange": {
+			"": {fyne.KeyUp, fyne.KeyModifierAlt},
+		},
 		"goBottom": {
 			"":    {fyne.KeyEnd, fyne.KeyModifierControl},
 			macOS: {fyne.KeyDown, fyne.KeyModifierSuper},
 		},
+		"goPath": {
+			"":    {fyne.KeyG, fyne.KeyModifierControl},
+			macOS: {fyne.KeyG, fyne.KeyModifierSuper},
+		},
 		"goTop": {
 			"":    {fyne.KeyHome, fyne.KeyModifierControl},
 			macOS: {fyne.KeyUp, fyne.KeyModifierSuper},
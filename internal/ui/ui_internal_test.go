@@ -1,13 +1,23 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
 	"github.com/ErikKalkoken/janice/internal/jsondocument"
+	"github.com/ErikKalkoken/janice/internal/remote"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,20 +53,34 @@ func TestMakeShortCut(t *testing.T) {
 		wantModifier fyne.KeyModifier
 		wantIsError  bool
 	}{
+		{"editUndo", "", fyne.KeyZ, fyne.KeyModifierControl, false},
+		{"editRedo", "", fyne.KeyY, fyne.KeyModifierControl, false},
+		{"editReplace", "", fyne.KeyH, fyne.KeyModifierControl, false},
 		{"fileNew", "", fyne.KeyN, fyne.KeyModifierControl, false},
 		{"fileOpen", "", fyne.KeyO, fyne.KeyModifierControl, false},
 		{"fileReload", "", fyne.KeyR, fyne.KeyModifierAlt, false},
 		{"fileQuit", "", fyne.KeyQ, fyne.KeyModifierControl, false},
 		{"fileSettings", "", fyne.KeyComma, fyne.KeyModifierControl, false},
+		{"fileSave", "", fyne.KeyS, fyne.KeyModifierControl, false},
+		{"fileSaveAs", "", fyne.KeyS, fyne.KeyModifierControl | fyne.KeyModifierShift, false},
 		{"goBottom", "", fyne.KeyEnd, fyne.KeyModifierControl, false},
 		{"goTop", "", fyne.KeyHome, fyne.KeyModifierControl, false},
+		{"goNextChange", "", fyne.KeyDown, fyne.KeyModifierAlt, false},
+		{"goPrevChange", "", fyne.KeyUp, fyne.KeyModifierAlt, false},
+		{"goPath", "", fyne.KeyG, fyne.KeyModifierControl, false},
+		{"goFindNext", "", fyne.KeyF3, 0, false},
+		{"goFindPrevious", "", fyne.KeyF3, fyne.KeyModifierShift, false},
 
+		{"editUndo", macOS, fyne.KeyZ, fyne.KeyModifierSuper, false},
+		{"editRedo", macOS, fyne.KeyZ, fyne.KeyModifierSuper | fyne.KeyModifierShift, false},
 		{"fileNew", macOS, fyne.KeyN, fyne.KeyModifierSuper, false},
 		{"fileOpen", macOS, fyne.KeyO, fyne.KeyModifierSuper, false},
 		{"fileReload", macOS, fyne.KeyR, fyne.KeyModifierAlt, false},
 		{"fileSettings", macOS, fyne.KeyComma, fyne.KeyModifierSuper, false},
+		{"fileSave", macOS, fyne.KeyS, fyne.KeyModifierSuper, false},
 		{"goBottom", macOS, fyne.KeyDown, fyne.KeyModifierSuper, false},
 		{"goTop", macOS, fyne.KeyUp, fyne.KeyModifierSuper, false},
+		{"goPath", macOS, fyne.KeyG, fyne.KeyModifierSuper, false},
 
 		{"invalid", "", fyne.KeyN, fyne.KeyModifierControl, true},
 	}
@@ -88,3 +112,938 @@ func TestCanLoadDocument(t *testing.T) {
 	<-ch
 	assert.Equal(t, 2, u.document.Size())
 }
+
+func TestCanLoadURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"alpha": 1, "bravo": 2}`))
+	}))
+	defer srv.Close()
+	u := newTestUI(t)
+	req := remote.Request{
+		URL:    srv.URL + "/data.json",
+		Header: http.Header{"Authorization": []string{"Bearer abc"}},
+	}
+	ch := make(chan struct{})
+	u.loadURL(req, func() {
+		close(ch)
+	})
+	<-ch
+	assert.Equal(t, 3, u.document.Size())
+	assert.Equal(t, srv.URL+"/data.json", u.currentFile.String())
+	assert.Equal(t, req, *u.currentRequest)
+}
+
+func TestCanReloadAndKeepTreeState(t *testing.T) {
+	u := newTestUI(t)
+	p := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(p, []byte(`{"alpha": {"bravo": {"charlie": 1}}, "delta": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := storage.Reader(storage.NewFileURI(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadTestDocument(u, reader)
+	alphaID := u.document.ChildUIDs("")[0]
+	bravoID := u.document.ChildUIDs(alphaID)[0]
+	charlieID := u.document.ChildUIDs(bravoID)[0]
+	u.tree.scrollTo(charlieID)
+	if err := os.WriteFile(p, []byte(`{"alpha": {"bravo": {"charlie": 1, "echo": 3}}, "delta": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan struct{})
+	u.reload(func() {
+		close(ch)
+	})
+	<-ch
+	assert.Equal(t, 6, u.document.Size())
+	assert.Equal(t, []string{"alpha", "bravo", "charlie"}, u.document.KeyPath(u.selection.selectedUID))
+	uid, _ := u.document.FindKeyPath([]string{"alpha", "bravo"})
+	assert.True(t, u.tree.IsBranchOpen(uid))
+	assert.NotNil(t, u.diff)
+	echoID, _ := u.document.FindKeyPath([]string{"alpha", "bravo", "echo"})
+	assert.Equal(t, []string{echoID}, u.diffUIDs)
+	u.gotoChange(true)
+	assert.Equal(t, echoID, u.selection.selectedUID)
+	assert.Equal(t, "Showing change 1 of 1", u.statusBar.changes.ToolTip())
+	u.diffUIDs = []widget.TreeNodeID{echoID, ""}
+	u.gotoChange(true)
+	assert.Equal(t, "Showing change 2 of 2", u.statusBar.changes.ToolTip())
+}
+
+func TestCanRunQuery(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"items": [{"price": 5}, {"price": 15}]}`)
+	a := u.app
+	runQuery := func() {
+		ch := make(chan struct{})
+		u.queryBar.runQuery(func() {
+			close(ch)
+		})
+		<-ch
+	}
+	t.Run("should show matching nodes in results", func(t *testing.T) {
+		u.queryBar.queryEntry.SetText("$.items[?@.price > 10].price")
+		runQuery()
+		assert.Len(t, u.results.uids, 1)
+		assert.False(t, u.results.Hidden)
+		assert.Equal(t, []string{"items", "[1]", "price"}, u.document.KeyPath(u.results.uids[0]))
+	})
+	t.Run("should show error for invalid query", func(t *testing.T) {
+		u.queryBar.queryEntry.SetText("items")
+		runQuery()
+		assert.False(t, u.queryBar.message.Hidden)
+	})
+	t.Run("should show output of jq expression in new window", func(t *testing.T) {
+		n := len(a.Driver().AllWindows())
+		u.queryBar.language.SetSelected(queryLanguageJQ)
+		u.queryBar.queryEntry.SetText(".items | map(.price) | add")
+		runQuery()
+		assert.Len(t, a.Driver().AllWindows(), n+1)
+		assert.True(t, u.queryBar.message.Hidden)
+		assert.Equal(t, []any{20.0}, u.queryBar.output.doc.ExtractValue(""))
+	})
+	t.Run("should replace output of next jq expression in same window", func(t *testing.T) {
+		n := len(a.Driver().AllWindows())
+		u.queryBar.queryEntry.SetText(".items | map(.price)")
+		runQuery()
+		assert.Len(t, a.Driver().AllWindows(), n)
+		assert.Equal(t, []any{5.0, 15.0}, u.queryBar.output.doc.ExtractValue(""))
+	})
+	t.Run("should show error for invalid jq expression", func(t *testing.T) {
+		u.queryBar.language.SetSelected(queryLanguageJQ)
+		u.queryBar.queryEntry.SetText(".items |")
+		runQuery()
+		assert.False(t, u.queryBar.message.Hidden)
+	})
+}
+
+func TestSearchIndex(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"id": 1}, "bravo": [{"id": 2}]}`)
+	assert.False(t, u.document.HasIndex())
+	u.app.Preferences().SetBool(settingSearchIndex, true)
+	ch := make(chan struct{})
+	u.buildSearchIndex(func() {
+		close(ch)
+	})
+	<-ch
+	assert.True(t, u.document.HasIndex())
+	assert.Equal(t, "Indexed", u.statusBar.index.Text)
+	assert.True(t, u.statusBar.index.Visible())
+	u.searchBar.searchEntry.SetText("id")
+	findAll(u)
+	assert.Equal(t, "2 matches", u.searchBar.matchCount.Text)
+	u.newFile()
+	assert.False(t, u.statusBar.index.Visible())
+}
+
+func TestKeyPathString(t *testing.T) {
+	assert.Equal(t, "(root)", keyPathString([]string{}))
+	assert.Equal(t, "store.book[0].title", keyPathString([]string{"store", "book", "[0]", "title"}))
+}
+
+func TestSearchBar(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"Bravo": "Charlie Delta"}}`)
+	t.Run("should show error for invalid regular expression", func(t *testing.T) {
+		u.searchBar.regex.SetOn(true)
+		u.searchBar.searchEntry.SetText("(bravo")
+		assert.False(t, u.searchBar.message.Hidden)
+		u.searchBar.regex.SetOn(false)
+		assert.True(t, u.searchBar.message.Hidden)
+	})
+	t.Run("should find key ignoring case", func(t *testing.T) {
+		u.searchBar.regex.SetOn(false)
+		u.searchBar.ignoreCase.SetOn(true)
+		u.searchBar.searchEntry.SetText("bravo")
+		ch := make(chan struct{})
+		u.searchBar.doSearch(false, func() {
+			close(ch)
+		})
+		<-ch
+		uid, _ := u.document.FindKeyPath([]string{"alpha", "Bravo"})
+		assert.Equal(t, uid, u.selection.selectedUID)
+	})
+	t.Run("should find previous match with shift+enter", func(t *testing.T) {
+		u.searchBar.ignoreCase.SetOn(false)
+		u.searchBar.searchEntry.SetText("alpha")
+		ch := make(chan struct{})
+		var backward bool
+		u.searchBar.searchEntry.onFind = func(b bool) {
+			backward = b
+			u.searchBar.doSearch(b, func() {
+				close(ch)
+			})
+		}
+		u.searchBar.searchEntry.KeyDown(&fyne.KeyEvent{Name: desktop.KeyShiftLeft})
+		u.searchBar.searchEntry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyReturn})
+		u.searchBar.searchEntry.KeyUp(&fyne.KeyEvent{Name: desktop.KeyShiftLeft})
+		<-ch
+		assert.True(t, backward)
+		uid, _ := u.document.FindKeyPath([]string{"alpha"})
+		assert.Equal(t, uid, u.selection.selectedUID)
+	})
+}
+
+func TestFindAll(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"id": 1}, "bravo": [{"id": 2}, {"id": 3}]}`)
+	u.searchBar.searchEntry.SetText("id")
+	findAll(u)
+	assert.True(t, u.results.activity.Hidden)
+	assert.Len(t, u.results.uids, 3)
+	assert.Equal(t, "3 matches", u.searchBar.matchCount.Text)
+	uid, _ := u.document.FindKeyPath([]string{"bravo", "[0]", "id"})
+	assert.True(t, u.searchBar.isMatch(uid))
+	u.results.list.Select(1)
+	assert.Equal(t, "2 of 3", u.searchBar.matchCount.Text)
+	u.results.clear()
+	assert.False(t, u.searchBar.isMatch(uid))
+	assert.True(t, u.searchBar.matchCount.Hidden)
+}
+
+func TestFindAllAnything(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"42": 1, "alpha": 42, "bravo": "42"}`)
+	u.searchBar.searchType.SetSelected(searchTypeAnything)
+	u.searchBar.searchEntry.SetText("42")
+	findAll(u)
+	assert.Equal(t, "3 matches", u.searchBar.matchCount.Text)
+	u.results.list.Select(0)
+	assert.Equal(t, "1 of 3 in key", u.searchBar.matchCount.Text)
+	u.results.list.Select(2)
+	assert.Equal(t, "3 of 3 in value", u.searchBar.matchCount.Text)
+}
+
+func TestFindAllFuzzy(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"items": [{"recipient": 1}], "receivedAt": 2, "sentAt": 3}`)
+	u.searchBar.fuzzy.SetOn(true)
+	u.searchBar.searchEntry.SetText("recievedAt")
+	findAll(u)
+	assert.Equal(t, "1 fuzzy match for recievedAt", u.results.title.Text)
+	uid, _ := u.document.FindKeyPath([]string{"receivedAt"})
+	assert.Equal(t, []widget.TreeNodeID{uid}, u.results.uids)
+	assert.True(t, u.searchBar.isMatch(uid))
+	assert.Equal(t, jsondocument.MatchKey, u.results.highlights[uid].part)
+	u.searchBar.regex.SetOn(true)
+	assert.False(t, u.searchBar.fuzzy.IsOn())
+}
+
+func TestSearchHistory(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"id": 1}, "bravo": "x"}`)
+	a := u.app
+	u.searchBar.searchEntry.SetText("id")
+	findAll(u)
+	u.searchBar.searchType.SetSelected(searchTypeString)
+	u.searchBar.ignoreCase.SetOn(true)
+	u.searchBar.searchEntry.SetText("X")
+	findAll(u)
+	u.searchBar.searchType.SetSelected(searchTypeKey)
+	u.searchBar.ignoreCase.SetOn(false)
+	u.searchBar.searchEntry.SetText("id")
+	findAll(u)
+	history := loadSearches(a.Preferences(), preferenceSearchHistory)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, "key: id", history[0].label())
+		assert.Equal(t, "string: X (ignore case)", history[1].label())
+	}
+	items := u.searchBar.historyMenuItems()
+	items[1].Action()
+	assert.Equal(t, "X", u.searchBar.searchEntry.Text)
+	assert.Equal(t, searchTypeString, u.searchBar.searchType.Selected)
+	assert.True(t, u.searchBar.ignoreCase.IsOn())
+	items[len(items)-1].Action()
+	assert.Empty(t, loadSearches(a.Preferences(), preferenceSearchHistory))
+}
+
+func TestSavedSearches(t *testing.T) {
+	u := newTestUI(t)
+	a := u.app
+	load := func(s string) {
+		loadTestDocument(u, jsondocument.MakeURIReadCloser(strings.NewReader(s), "data.json"))
+	}
+	load(`{"jobs": [{"status": "failed"}]}`)
+	u.searchBar.saveSearch(savedSearch{Name: "everywhere", Pattern: "status", Type: searchTypeKey})
+	u.searchBar.saveSearch(savedSearch{Name: "failed jobs", Pattern: "failed", Type: searchTypeString, Shape: u.document.Shape()})
+	u.searchBar.saveSearch(savedSearch{Name: "other file", Pattern: "x", Type: searchTypeString, File: "file:///other.json"})
+	labels := func() []string {
+		var s []string
+		for _, it := range u.searchBar.savedMenuItems() {
+			if it.IsSeparator {
+				break
+			}
+			s = append(s, it.Label)
+		}
+		return s
+	}
+	t.Run("should offer saved searches which apply to the document", func(t *testing.T) {
+		assert.Equal(t, []string{"everywhere - key: status", "failed jobs - string: failed"}, labels())
+		load(`{"jobs": [{"status": "ok"}, {"status": "failed"}]}`)
+		assert.Equal(t, []string{"everywhere - key: status", "failed jobs - string: failed"}, labels())
+		load(`{"items": []}`)
+		assert.Equal(t, []string{"everywhere - key: status"}, labels())
+	})
+	t.Run("should run saved search", func(t *testing.T) {
+		load(`{"jobs": [{"status": "failed"}]}`)
+		saved := loadSearches(a.Preferences(), preferenceSavedSearches)
+		ch := make(chan struct{})
+		u.searchBar.runSavedSearch(saved[1], func() {
+			close(ch)
+		})
+		<-ch
+		assert.Equal(t, "failed", u.searchBar.searchEntry.Text)
+		assert.True(t, u.results.activity.Hidden)
+		assert.Len(t, u.results.uids, 1)
+	})
+	t.Run("should replace saved search with same name", func(t *testing.T) {
+		u.searchBar.saveSearch(savedSearch{Name: "everywhere", Pattern: "jobs", Type: searchTypeKey})
+		saved := loadSearches(a.Preferences(), preferenceSavedSearches)
+		assert.Len(t, saved, 3)
+		assert.Equal(t, "jobs", saved[0].Pattern)
+	})
+	t.Run("should delete saved search", func(t *testing.T) {
+		u.searchBar.deleteSavedSearch(0)
+		saved := loadSearches(a.Preferences(), preferenceSavedSearches)
+		assert.Len(t, saved, 2)
+		assert.Equal(t, "failed jobs", saved[0].Name)
+	})
+}
+
+func TestHighlightedText(t *testing.T) {
+	got := highlightedText(`"abcd"`, []int{0, 1, 3}, 1, theme.ColorNameWarning)
+	var texts []string
+	var bold []bool
+	for _, s := range got {
+		ts := s.(*widget.TextSegment)
+		texts = append(texts, ts.Text)
+		bold = append(bold, ts.Style.TextStyle.Bold)
+		assert.Equal(t, theme.ColorNameWarning, ts.Style.ColorName)
+	}
+	assert.Equal(t, []string{`"`, "ab", "c", "d", `"`}, texts)
+	assert.Equal(t, []bool{false, true, false, true, false}, bold)
+}
+
+func TestSearchWithinSelection(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"id": 1}, "bravo": [{"id": 2}, {"id": 3}]}`)
+	bravo0, _ := u.document.FindKeyPath([]string{"bravo", "[0]"})
+	u.selectElement(bravo0)
+	u.searchBar.withinSelection.SetOn(true)
+	bravo, _ := u.document.FindKeyPath([]string{"bravo"})
+	assert.Equal(t, bravo0, u.searchBar.scope)
+	u.selectElement(bravo)
+	u.searchBar.withinSelection.SetOn(false)
+	u.searchBar.withinSelection.SetOn(true)
+	assert.Equal(t, bravo, u.searchBar.scope)
+	u.searchBar.searchEntry.SetText("id")
+	findAll(u)
+	assert.Equal(t, "2 matches", u.searchBar.matchCount.Text)
+	u.searchBar.maxDepth.SetSelected("1 level")
+	findAll(u)
+	assert.Len(t, u.results.uids, 0)
+	u.searchBar.reset()
+	assert.False(t, u.searchBar.withinSelection.IsOn())
+	assert.Equal(t, "", u.searchBar.scope)
+}
+
+func TestSearchCondition(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"jobs": [{"status": "ok"}, {"status": "failed"}]}`)
+	u.searchBar.searchType.SetSelected(searchTypeCondition)
+	t.Run("should show error for invalid condition", func(t *testing.T) {
+		u.searchBar.searchEntry.SetText(`status =`)
+		assert.False(t, u.searchBar.message.Hidden)
+	})
+	t.Run("should select object with matching member", func(t *testing.T) {
+		u.searchBar.searchEntry.SetText(`status = "failed"`)
+		assert.True(t, u.searchBar.message.Hidden)
+		ch := make(chan struct{})
+		u.searchBar.doSearch(false, func() {
+			close(ch)
+		})
+		<-ch
+		uid, _ := u.document.FindKeyPath([]string{"jobs", "[1]"})
+		assert.Equal(t, uid, u.selection.selectedUID)
+	})
+}
+
+func TestFilter(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"id": 1, "name": "a"}, "bravo": [{"id": 2}, {"name": "b"}], "charlie": 3}`)
+	alpha, _ := u.document.FindKeyPath([]string{"alpha"})
+	bravo, _ := u.document.FindKeyPath([]string{"bravo"})
+	bravo0, _ := u.document.FindKeyPath([]string{"bravo", "[0]"})
+	u.tree.OpenBranch(alpha)
+	// switching the filter on is wrapped, so that the test can wait until the filter was applied
+	var ch chan struct{}
+	switchOff := u.searchBar.filter.OnChanged
+	u.searchBar.filter.OnChanged = func(on bool) {
+		if !on {
+			switchOff(on)
+			return
+		}
+		u.searchBar.doFilter(func() {
+			close(ch)
+		})
+	}
+	switchOn := func() {
+		ch = make(chan struct{})
+		u.searchBar.filter.SetOn(true)
+		<-ch
+	}
+	u.searchBar.searchEntry.SetText("name")
+	switchOn()
+	assert.NotNil(t, u.tree.filter)
+	assert.Equal(t, []widget.TreeNodeID{alpha, bravo}, u.tree.ChildUIDs(""))
+	assert.Len(t, u.tree.ChildUIDs(bravo), 1)
+	assert.True(t, u.tree.IsBranchOpen(bravo))
+	assert.Equal(t, 1, u.tree.filter.HiddenCount(bravo))
+	assert.Contains(t, u.searchBar.filter.ToolTip(), "(1 hidden at top level)")
+	u.searchBar.filter.SetOn(false)
+	assert.Nil(t, u.tree.filter)
+	assert.Len(t, u.tree.ChildUIDs(""), 3)
+	assert.True(t, u.tree.IsBranchOpen(alpha))
+	assert.False(t, u.tree.IsBranchOpen(bravo))
+	t.Run("should switch off when nothing matches", func(t *testing.T) {
+		u.searchBar.searchEntry.SetText("unknown")
+		switchOn()
+		assert.False(t, u.searchBar.filter.IsOn())
+		assert.Nil(t, u.tree.filter)
+	})
+	t.Run("should be removed by reset", func(t *testing.T) {
+		u.searchBar.searchEntry.SetText("id")
+		switchOn()
+		assert.NotNil(t, u.tree.filter)
+		assert.False(t, u.tree.filter.IsMatch(bravo0))
+		u.searchBar.reset()
+		assert.False(t, u.searchBar.filter.IsOn())
+		assert.Nil(t, u.tree.filter)
+	})
+}
+
+func TestSearchDialog(t *testing.T) {
+	a := test.NewTempApp(t)
+	w := a.NewWindow("test")
+	w.Resize(fyne.NewSize(800, 600))
+	w.Show()
+	d := newSearchDialog("Search", "Searching", w, func() {})
+	d.setTotal(200)
+	d.update(50)
+	d.hide()
+	assert.Equal(t, 0.25, d.progress.Value)
+	assert.Equal(t, "50 of 200 nodes scanned", d.scanned.Text)
+	assert.Equal(t, "Elapsed: 0s", d.elapsed.Text)
+}
+
+func TestEditDocument(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"id": 1}, "bravo": [1, 2]}`)
+	menuItem := func(uid widget.TreeNodeID, label string) *fyne.MenuItem {
+		for _, it := range u.editMenuItems(uid) {
+			if it.Label == label {
+				return it
+			}
+		}
+		return nil
+	}
+	alpha, _ := u.document.FindKeyPath([]string{"alpha"})
+	bravo0, _ := u.document.FindKeyPath([]string{"bravo", "[0]"})
+	assert.NotContains(t, u.window.Title(), "(modified)")
+	assert.Nil(t, menuItem(alpha, "Edit value..."))
+	assert.NotNil(t, menuItem(alpha, "Add member..."))
+	assert.Nil(t, menuItem(bravo0, "Rename key..."))
+	assert.NotNil(t, menuItem(bravo0, "Insert element after..."))
+	t.Run("should duplicate node and select copy", func(t *testing.T) {
+		menuItem(bravo0, "Duplicate").Action()
+		assert.Equal(t, []string{"bravo", "[1]"}, u.document.KeyPath(u.selection.selectedUID))
+		assert.Equal(t, 7, u.document.Size())
+		assert.Contains(t, u.window.Title(), "data.json (modified)")
+	})
+	t.Run("should delete node and reset selection", func(t *testing.T) {
+		u.tree.scrollTo(alpha)
+		menuItem(alpha, "Delete").Action()
+		assert.Equal(t, "", u.selection.selectedUID)
+		assert.Equal(t, 5, u.document.Size())
+		assert.Len(t, u.tree.ChildUIDs(""), 1)
+	})
+	t.Run("should keep selection and update keys of following elements after delete", func(t *testing.T) {
+		bravo, _ := u.document.FindKeyPath([]string{"bravo"})
+		bravo0, _ := u.document.FindKeyPath([]string{"bravo", "[0]"})
+		u.tree.scrollTo(bravo0)
+		u.tree.scrollTo(bravo)
+		menuItem(bravo0, "Delete").Action()
+		assert.Equal(t, bravo, u.selection.selectedUID)
+		assert.Equal(t, []string{"bravo :", "[0] :", "[1] :"}, shownKeys(u.tree))
+	})
+	t.Run("should remove modified mark when document is replaced", func(t *testing.T) {
+		u.newFile()
+		assert.NotContains(t, u.window.Title(), "(modified)")
+	})
+}
+
+func TestUndoRedo(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"id": 1}, "bravo": [1, 2]}`)
+	assert.True(t, u.editUndo.Disabled)
+	assert.True(t, u.editRedo.Disabled)
+	alpha, _ := u.document.FindKeyPath([]string{"alpha"})
+	bravo0, _ := u.document.FindKeyPath([]string{"bravo", "[0]"})
+	assert.NoError(t, u.document.Delete(alpha))
+	u.documentEdited("", true)
+	assert.NoError(t, u.document.Delete(bravo0))
+	u.documentEdited("", true)
+	assert.Equal(t, 3, u.document.Size())
+	assert.Equal(t, "Undo Delete /bravo/0", u.editUndo.Label)
+	assert.Len(t, u.editRecent.ChildMenu.Items, 2)
+	t.Run("should undo last edit", func(t *testing.T) {
+		u.editUndo.Action()
+		assert.Equal(t, 4, u.document.Size())
+		assert.False(t, u.editRedo.Disabled)
+		assert.Equal(t, "Redo Delete /bravo/0", u.editRedo.Label)
+		assert.Contains(t, u.window.Title(), "(modified)")
+	})
+	t.Run("should redo edit", func(t *testing.T) {
+		u.editRedo.Action()
+		assert.Equal(t, 3, u.document.Size())
+		assert.True(t, u.editRedo.Disabled)
+	})
+	t.Run("should undo to recent change", func(t *testing.T) {
+		u.editRecent.ChildMenu.Items[1].Action()
+		assert.Equal(t, 6, u.document.Size())
+		assert.True(t, u.editUndo.Disabled)
+		assert.NotContains(t, u.window.Title(), "(modified)")
+		assert.Len(t, u.tree.ChildUIDs(""), 2)
+	})
+}
+
+func TestBackgroundJobs(t *testing.T) {
+	t.Run("should stop running jobs and wait for them", func(t *testing.T) {
+		var jobs backgroundJobs
+		var stopped bool
+		ctx, job := jobs.start(context.Background(), func() {
+			stopped = true
+		})
+		read := make(chan struct{})
+		go func() {
+			<-ctx.Done()
+			close(read)
+			job.readDone()
+		}()
+		jobs.stopAll()
+		select {
+		case <-read:
+		default:
+			t.Fatal("job was not waited for")
+		}
+		assert.True(t, stopped)
+		assert.False(t, job.finish())
+	})
+	t.Run("should not stop finished jobs", func(t *testing.T) {
+		var jobs backgroundJobs
+		var stopped bool
+		_, job := jobs.start(context.Background(), func() {
+			stopped = true
+		})
+		job.readDone()
+		assert.True(t, job.finish())
+		jobs.stopAll()
+		assert.False(t, stopped)
+	})
+	t.Run("should stop jobs which were not yet finished on the UI goroutine", func(t *testing.T) {
+		var jobs backgroundJobs
+		ctx, job := jobs.start(context.Background(), nil)
+		job.readDone()
+		jobs.stopAll()
+		assert.Error(t, ctx.Err())
+		assert.False(t, job.finish())
+	})
+	t.Run("should stop jobs before editing the document", func(t *testing.T) {
+		u := newTestUIWithDocument(t, `{"alpha": "one", "bravo": "two"}`)
+		ctx, job := u.jobs.start(context.Background(), nil)
+		alpha, _ := u.document.FindKeyPath([]string{"alpha"})
+		go func() {
+			<-ctx.Done()
+			job.readDone()
+		}()
+		items := u.editMenuItems(alpha)
+		items[len(items)-1].Action() // Delete
+		assert.Error(t, ctx.Err())
+		assert.False(t, job.finish())
+		assert.Equal(t, 2, u.document.Size())
+	})
+}
+
+func TestSaveDocument(t *testing.T) {
+	u := newTestUI(t)
+	a := u.app
+	p := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(p, []byte(`{"alpha": 1, "bravo": [true]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := storage.Reader(storage.NewFileURI(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadTestDocument(u, reader)
+	alpha, _ := u.document.FindKeyPath([]string{"alpha"})
+	assert.NoError(t, u.document.SetValue(alpha, "x"))
+	u.documentEdited(alpha, false)
+	assert.Contains(t, u.window.Title(), "(modified)")
+	a.Preferences().SetString(settingSaveIndent, saveIndentMinified)
+	t.Run("should save document to current file", func(t *testing.T) {
+		done := make(chan struct{})
+		u.save(u.currentFile, u.saveOptions(), func() {
+			close(done)
+		})
+		<-done
+		assert.NotContains(t, u.window.Title(), "(modified)")
+		data, err := os.ReadFile(p)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"alpha":"x","bravo":[true]}`, string(data))
+	})
+	t.Run("should ask before replacing an existing file", func(t *testing.T) {
+		p2 := filepath.Join(t.TempDir(), "other.json")
+		if err := os.WriteFile(p2, []byte(`[]`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		u.saveAs(p2, u.saveOptions(), nil)
+		assert.NotNil(t, u.window.Canvas().Overlays().Top())
+		data, err := os.ReadFile(p2)
+		assert.NoError(t, err)
+		assert.Equal(t, `[]`, string(data))
+	})
+	t.Run("should save to a new file", func(t *testing.T) {
+		p2 := filepath.Join(t.TempDir(), "new.json")
+		done := make(chan struct{})
+		u.saveAs(p2, u.saveOptions(), func() {
+			close(done)
+		})
+		<-done
+		data, err := os.ReadFile(p2)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"alpha":"x","bravo":[true]}`, string(data))
+		assert.Equal(t, "new.json", u.currentFile.Name())
+	})
+}
+
+func TestUnsavedChanges(t *testing.T) {
+	u := newTestUI(t)
+	p := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(p, []byte(`{"alpha": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := storage.Reader(storage.NewFileURI(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadTestDocument(u, reader)
+	alpha, _ := u.document.FindKeyPath([]string{"alpha"})
+	edit := func() {
+		assert.NoError(t, u.document.SetValue(alpha, float64(2)))
+		u.documentEdited(alpha, false)
+	}
+	t.Run("should proceed without asking when document is not modified", func(t *testing.T) {
+		var called bool
+		u.confirmUnsavedChanges(func() {
+			called = true
+		})
+		assert.True(t, called)
+		assert.Nil(t, u.window.Canvas().Overlays().Top())
+	})
+	t.Run("should ask before proceeding when document is modified", func(t *testing.T) {
+		edit()
+		var called bool
+		u.confirmUnsavedChanges(func() {
+			called = true
+		})
+		assert.False(t, called)
+		assert.NotNil(t, u.window.Canvas().Overlays().Top())
+		u.window.Canvas().Overlays().Top().Hide()
+	})
+	t.Run("should not proceed when canceled", func(t *testing.T) {
+		var called bool
+		d := u.newUnsavedChangesDialog(func() {
+			called = true
+		})
+		d.dialog.Show()
+		test.Tap(d.cancel)
+		assert.False(t, called)
+		assert.True(t, u.document.IsModified())
+	})
+	t.Run("should proceed when changes are discarded", func(t *testing.T) {
+		var called bool
+		d := u.newUnsavedChangesDialog(func() {
+			called = true
+		})
+		d.dialog.Show()
+		test.Tap(d.discard)
+		assert.True(t, called)
+		data, err := os.ReadFile(p)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"alpha": 1}`, string(data))
+	})
+	t.Run("should save changes before proceeding", func(t *testing.T) {
+		done := make(chan struct{})
+		d := u.newUnsavedChangesDialog(func() {
+			close(done)
+		})
+		d.dialog.Show()
+		test.Tap(d.save)
+		<-done
+		assert.False(t, u.document.IsModified())
+		data, err := os.ReadFile(p)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"alpha": 2`)
+	})
+}
+
+func TestReplaceDialog(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": {"url": "http://old.com"}, "bravo": ["old", "new"]}`)
+	alpha, _ := u.document.FindKeyPath([]string{"alpha"})
+	u.tree.scrollTo(alpha)
+	u.selectElement(alpha)
+	d := newReplaceDialog(u)
+	d.dialog.Show()
+	preview := func() {
+		done := make(chan struct{})
+		d.preview(func() {
+			close(done)
+		})
+		<-done
+	}
+	t.Run("should preview replacements", func(t *testing.T) {
+		d.find.SetText("old")
+		d.replace.SetText("new")
+		preview()
+		assert.Len(t, d.previewed, 2)
+		assert.Equal(t, "2 replacements", d.summary.Text)
+		assert.False(t, d.replaceAll.Disabled())
+	})
+	t.Run("should reset preview when search changes", func(t *testing.T) {
+		d.regex.SetChecked(true)
+		assert.Empty(t, d.previewed)
+		assert.True(t, d.replaceAll.Disabled())
+	})
+	t.Run("should preview replacements in selected subtree", func(t *testing.T) {
+		d.scope.SetSelected(replaceScopeSelection)
+		d.find.SetText(`http://(\w+)`)
+		d.replace.SetText("https://$1")
+		preview()
+		assert.Equal(t, []string{"/alpha/url"}, []string{u.document.Pointer(d.previewed[0].UID)})
+	})
+	t.Run("should show errors", func(t *testing.T) {
+		d.find.SetText("(")
+		preview()
+		assert.Contains(t, d.summary.Text, "Error")
+		assert.True(t, d.replaceAll.Disabled())
+	})
+	t.Run("should show invalid numbers as skipped", func(t *testing.T) {
+		u := newTestUIWithDocument(t, `{"alpha": 80, "bravo": 8080}`)
+		d := newReplaceDialog(u)
+		d.strings.SetChecked(false)
+		d.numbers.SetChecked(true)
+		d.find.SetText("8")
+		d.replace.SetText("x")
+		done := make(chan struct{})
+		d.preview(func() {
+			close(done)
+		})
+		<-done
+		assert.Len(t, d.previewed, 2)
+		assert.Equal(t, "0 replacements, 2 invalid will be skipped", d.summary.Text)
+		assert.Contains(t, d.label(d.previewed[0]), "skipped")
+		assert.True(t, d.replaceAll.Disabled())
+	})
+	t.Run("should not replace when document was edited after preview", func(t *testing.T) {
+		d.scope.SetSelected(replaceScopeDocument)
+		d.regex.SetChecked(false)
+		d.find.SetText("old")
+		d.replace.SetText("new")
+		preview()
+		bravo0, _ := u.document.FindKeyPath([]string{"bravo", "[0]"})
+		if err := u.document.SetValue(bravo0, "changed"); err != nil {
+			t.Fatal(err)
+		}
+		d.replaceAll.OnTapped()
+		assert.Equal(t, map[string]any{"alpha": map[string]any{"url": "http://old.com"}, "bravo": []any{"changed", "new"}}, u.document.ExtractValue(""))
+		assert.Empty(t, d.previewed)
+		if _, err := u.document.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("should replace as one undoable edit", func(t *testing.T) {
+		d.scope.SetSelected(replaceScopeDocument)
+		d.regex.SetChecked(false)
+		d.find.SetText("old")
+		d.replace.SetText("new")
+		preview()
+		d.replaceAll.OnTapped()
+		assert.Equal(t, map[string]any{"alpha": map[string]any{"url": "http://new.com"}, "bravo": []any{"new", "new"}}, u.document.ExtractValue(""))
+		assert.Equal(t, `Undo Replace "old" with "new"`, u.editUndo.Label)
+		u.editUndo.Action()
+		assert.Equal(t, "old", u.document.ExtractValue("").(map[string]any)["bravo"].([]any)[0])
+	})
+}
+
+func TestApplyPatch(t *testing.T) {
+	u := newTestUIWithDocument(t, `{"alpha": 1}`)
+	apply := func(patch string) {
+		p, err := jsondocument.ReadPatch(strings.NewReader(patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan struct{})
+		u.applyPatch(p, "patch.json", func() {
+			close(done)
+		})
+		<-done
+	}
+	t.Run("should show result in new window", func(t *testing.T) {
+		n := len(u.app.Driver().AllWindows())
+		apply(`[{"op":"add","path":"/bravo","value":2}]`)
+		windows := u.app.Driver().AllWindows()
+		if assert.Len(t, windows, n+1) {
+			assert.Contains(t, windows[n].Title(), "Preview patch.json")
+		}
+		assert.Equal(t, map[string]any{"alpha": 1.0}, u.document.ExtractValue(""))
+	})
+	t.Run("should show errors instead of result when an operation fails", func(t *testing.T) {
+		n := len(u.app.Driver().AllWindows())
+		apply(`[{"op":"add","path":"/bravo","value":2},{"op":"test","path":"/alpha","value":2}]`)
+		assert.Len(t, u.app.Driver().AllWindows(), n)
+		assert.NotNil(t, u.window.Canvas().Overlays().Top())
+		assert.Equal(t, map[string]any{"alpha": 1.0}, u.document.ExtractValue(""))
+	})
+}
+
+func TestCompareWindow(t *testing.T) {
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = fmt.Sprint(i)
+	}
+	data := `{"items": [` + strings.Join(items, ",") + `]}`
+	u := newTestUIWithDocument(t, data)
+	done := make(chan struct{})
+	w := u.showCompareWindow(func() {
+		close(done)
+	})
+	<-done
+	t.Run("should compare snapshot of current document", func(t *testing.T) {
+		uid, _ := u.document.FindKeyPath([]string{"items"})
+		u.jobs.stopAll()
+		if err := u.document.Delete(uid); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "data.json", w.names[0].Text)
+		assert.Equal(t, 102, w.docs[0].Size())
+	})
+	t.Run("should compute differences", func(t *testing.T) {
+		doc := jsondocument.New()
+		elements := make([]any, 100)
+		for i := range elements {
+			elements[i] = float64(i)
+		}
+		if err := doc.LoadData(context.Background(), map[string]any{"added": true, "items": elements}); err != nil {
+			t.Fatal(err)
+		}
+		w.docs[1] = doc
+		done := make(chan struct{})
+		w.compare(func() {
+			close(done)
+		})
+		<-done
+		assert.Equal(t, 1, w.diff.Count(jsondocument.Added))
+	})
+	t.Run("should open and select counterpart in other tree", func(t *testing.T) {
+		uidA, _ := w.docs[0].FindKeyPath([]string{"items"})
+		uidB, _ := w.docs[1].FindKeyPath([]string{"items"})
+		w.trees[0].OpenBranch(uidA)
+		assert.True(t, w.trees[1].IsBranchOpen(uidB))
+		itemA, _ := w.docs[0].FindKeyPath([]string{"items", "[50]"})
+		itemB, _ := w.docs[1].FindKeyPath([]string{"items", "[50]"})
+		w.trees[0].Select(itemA)
+		assert.Equal(t, itemB, w.selected[1])
+	})
+	t.Run("should scroll other tree to the same offset", func(t *testing.T) {
+		w.trees[0].ScrollToTop()
+		w.trees[1].ScrollToTop()
+		w.trees[0].scroller.Scrolled(&fyne.ScrollEvent{Scrolled: fyne.NewDelta(0, -100)})
+		assert.Greater(t, w.trees[0].scroller.Offset.Y, float32(0))
+		assert.Equal(t, w.trees[0].scroller.Offset.Y, w.trees[1].scroller.Offset.Y)
+	})
+}
+
+func TestParseEditValue(t *testing.T) {
+	cases := []struct {
+		typ     jsondocument.JSONType
+		text    string
+		want    any
+		wantErr bool
+	}{
+		{jsondocument.String, " a ", " a ", false},
+		{jsondocument.Number, "1.5", 1.5, false},
+		{jsondocument.Number, "abc", nil, true},
+		{jsondocument.Number, "NaN", nil, true},
+		{jsondocument.Boolean, "false", false, false},
+		{jsondocument.Boolean, "yes", nil, true},
+		{jsondocument.Null, "", nil, false},
+		{jsondocument.Object, `{"a": 1}`, map[string]any{"a": 1.0}, false},
+		{jsondocument.Object, `[1]`, nil, true},
+		{jsondocument.Array, `[1]`, []any{1.0}, false},
+		{jsondocument.Array, `null`, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.typ.String()+" "+tc.text, func(t *testing.T) {
+			got, err := parseEditValue(tc.typ.String(), tc.text)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+// newTestUI returns a new UI for a test app and shows its window.
+func newTestUI(t *testing.T) *UI {
+	t.Helper()
+	a := test.NewTempApp(t)
+	u, err := NewUI(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.window.Show()
+	return u
+}
+
+// newTestUIWithDocument returns a new UI, which has loaded a document from data.
+func newTestUIWithDocument(t *testing.T, data string) *UI {
+	t.Helper()
+	u := newTestUI(t)
+	loadTestDocument(u, jsondocument.MakeURIReadCloser(strings.NewReader(data), "data.json"))
+	return u
+}
+
+// loadTestDocument loads a document into a UI and waits until loading has completed.
+func loadTestDocument(u *UI, reader fyne.URIReadCloser) {
+	ch := make(chan struct{})
+	u.loadDocument(reader, func() {
+		close(ch)
+	})
+	<-ch
+}
+
+// shownKeys returns the keys of the nodes currently shown in a tree.
+func shownKeys(tree *jsonTree) []string {
+	var keys []string
+	for _, o := range test.LaidOutObjects(tree) {
+		if n, ok := o.(*treeNode); ok && o.Visible() {
+			keys = append(keys, n.key.Text)
+		}
+	}
+	return keys
+}
+
+// findAll runs find all of the search bar and waits until it has completed.
+func findAll(u *UI) {
+	ch := make(chan struct{})
+	u.searchBar.doFindAll(func() {
+		close(ch)
+	})
+	<-ch
+}
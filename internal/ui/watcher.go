@@ -0,0 +1,253 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Time to wait for more changes before a changed file is reloaded
+const watchDebounceDelay = 300 * time.Millisecond
+
+// fileWatcher watches the current file for changes and reloads it.
+// When tail mode is enabled, new lines of a stream are appended instead.
+//
+// All methods must be called on the UI goroutine.
+type fileWatcher struct {
+	enabled bool
+	tail    bool
+	u       *UI
+
+	busy    bool // a reload or append is in progress
+	offset  int64
+	path    string
+	pending bool        // the file changed while busy
+	saved   os.FileInfo // the file as written when the document was saved
+	timer   *time.Timer
+	watcher *fsnotify.Watcher
+}
+
+func newFileWatcher(u *UI) *fileWatcher {
+	w := &fileWatcher{u: u}
+	return w
+}
+
+// isWatchable reports whether an URI can be watched for changes.
+func isWatchable(uri fyne.URI) bool {
+	return uri != nil && uri.Scheme() == "file"
+}
+
+// setEnabled enables or disables watching the current file.
+func (w *fileWatcher) setEnabled(enabled bool) {
+	w.enabled = enabled
+	if enabled {
+		w.start()
+	} else {
+		w.stop()
+	}
+}
+
+// documentLoaded updates the watcher after a new document was loaded.
+func (w *fileWatcher) documentLoaded() {
+	w.offset = w.u.document.LoadedBytes()
+	w.saved = nil
+	if !w.enabled {
+		return
+	}
+	if isWatchable(w.u.currentFile) && w.u.currentFile.Path() == w.path {
+		return // already watching this file
+	}
+	w.start()
+}
+
+// documentSaved updates the watcher after the document was saved to the current file.
+// The change of the file by saving does not trigger a reload.
+func (w *fileWatcher) documentSaved() {
+	path := w.u.currentFile.Path()
+	info, err := os.Stat(path)
+	if err != nil {
+		slog.Warn("Failed to read saved file", "path", path, "err", err)
+		return
+	}
+	w.saved = info
+	w.offset = info.Size()
+	if w.enabled && w.path != path {
+		w.start()
+	}
+}
+
+// isSaved reports whether the file is unchanged since the document was saved.
+func (w *fileWatcher) isSaved() bool {
+	if w.saved == nil {
+		return false
+	}
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(info, w.saved) && info.Size() == w.saved.Size() && info.ModTime().Equal(w.saved.ModTime())
+}
+
+// start starts watching the current file.
+func (w *fileWatcher) start() {
+	w.stop()
+	if !isWatchable(w.u.currentFile) {
+		return
+	}
+	path := w.u.currentFile.Path()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("Failed to create file watcher", "err", err)
+		return
+	}
+	// Watching the folder ensures files replaced by editors are still detected
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		slog.Error("Failed to watch file", "path", path, "err", err)
+		watcher.Close()
+		return
+	}
+	w.watcher = watcher
+	w.path = path
+	slog.Info("Watching file for changes", "path", path)
+	go func() {
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != path || !ev.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				fyne.Do(func() {
+					w.scheduleUpdate(watcher)
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("File watcher error", "path", path, "err", err)
+			}
+		}
+	}()
+}
+
+// stop stops watching.
+func (w *fileWatcher) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.watcher != nil {
+		w.watcher.Close()
+		w.watcher = nil
+	}
+	w.path = ""
+	w.pending = false
+}
+
+// scheduleUpdate schedules an update for a changed file.
+// Updates are delayed so that a burst of changes results in only one update.
+func (w *fileWatcher) scheduleUpdate(watcher *fsnotify.Watcher) {
+	if w.watcher != watcher {
+		return // outdated event
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(watchDebounceDelay, func() {
+		fyne.Do(func() {
+			if w.watcher != watcher {
+				return
+			}
+			w.update()
+		})
+	})
+}
+
+// update reloads the changed file or appends new lines in tail mode.
+// Modified documents are not updated, so that edits are not lost.
+func (w *fileWatcher) update() {
+	if w.u.document.IsModified() || w.isSaved() {
+		return
+	}
+	if w.busy {
+		w.pending = true
+		return
+	}
+	w.busy = true
+	done := func() {
+		w.busy = false
+		if w.pending {
+			w.pending = false
+			w.update()
+		}
+	}
+	if !w.tail || !w.u.document.IsStream() {
+		w.u.reload(done)
+		return
+	}
+	path, offset, doc := w.path, w.offset, w.u.document
+	go func() {
+		data, err := readFileTail(path, offset)
+		fyne.Do(func() {
+			defer done()
+			if w.u.document != doc || w.path != path {
+				return // document was changed in the meantime
+			}
+			if errors.Is(err, errFileTruncated) {
+				w.u.reload(nil)
+				return
+			}
+			if err != nil {
+				slog.Error("Failed to read file tail", "path", path, "err", err)
+				return
+			}
+			w.u.jobs.stopAll()
+			n, err := doc.Append(context.Background(), data)
+			if err != nil {
+				slog.Error("Failed to append new lines", "path", path, "err", err)
+				return
+			}
+			if n == 0 {
+				return
+			}
+			w.offset += n
+			w.u.documentAppended()
+		})
+	}()
+}
+
+var errFileTruncated = errors.New("file was truncated")
+
+// readFileTail returns a reader with the content of a file after offset.
+func readFileTail(path string, offset int64) (io.Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < offset {
+		return nil, errFileTruncated
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}